@@ -4,49 +4,69 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"gitconnect-backend/models"
+	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"github.com/joho/godotenv"
 )
 
-var DB *gorm.DB
-
-// ConnectDatabase initializes and connects to the database.
-func ConnectDatabase() error {
+// ConnectDatabase initializes and connects to the database. The schema is managed
+// by the migrations package, not by GORM.
+func ConnectDatabase() (*gorm.DB, error) {
 	// Load .env file for local development (Railway will inject env vars on deployment)
 	_ = godotenv.Load()
 
 	// Use Railway's DATABASE_URL
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		return fmt.Errorf("❌ DATABASE_URL is not set")
+		return nil, fmt.Errorf("❌ DATABASE_URL is not set")
 	}
-	
+
 	log.Println("🚀 Using DATABASE_URL from environment")
-	
+
 	dsn := databaseURL
-	
+
 	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		PrepareStmt: true,
+		PrepareStmt:    true,
+		TranslateError: true, // Lets repositories recognise unique violations as gorm.ErrDuplicatedKey
 	})
 	if err != nil {
-		return fmt.Errorf("❌ Failed to connect to database: %w", err)
+		return nil, fmt.Errorf("❌ Failed to connect to database: %w", err)
+	}
+
+	return database, nil
+}
+
+// BootstrapAdmins promotes the accounts listed in ADMIN_EMAILS (comma-separated) to admin,
+// so a fresh deployment has someone who can manage roles through the API.
+func BootstrapAdmins(db *gorm.DB) error {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails = append(emails, email)
+		}
 	}
-	
-	if err := database.AutoMigrate(&models.User{}, &models.Profile{}, &models.Post{}, &models.Comment{}); err != nil {
-		return fmt.Errorf("❌ Migration failed: %w", err)
+	if len(emails) == 0 {
+		return nil
+	}
+
+	result := db.Model(&models.User{}).
+		Where("LOWER(email) IN ? AND role <> ?", emails, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		return fmt.Errorf("❌ Failed to promote admins: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("👑 Promoted %d account(s) from ADMIN_EMAILS to admin", result.RowsAffected)
 	}
-	
-	DB = database
-	log.Println("✅ Database connected and migrated successfully")
 	return nil
 }
 
 // CloseDatabase gracefully closes the DB connection.
-func CloseDatabase() {
-	sqlDB, err := DB.DB()
+func CloseDatabase(db *gorm.DB) {
+	sqlDB, err := db.DB()
 	if err != nil {
 		log.Println("⚠️ Warning: Unable to retrieve DB instance for closing.")
 		return
@@ -57,4 +77,3 @@ func CloseDatabase() {
 		log.Println("✅ Database connection closed successfully")
 	}
 }
-
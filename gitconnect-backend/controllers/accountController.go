@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// verifyEmailTTL is how long an email verification link stays valid
+	verifyEmailTTL = 24 * time.Hour
+	// resetPasswordTTL is how long a password reset link stays valid
+	resetPasswordTTL = time.Hour
+)
+
+// TokenInput is the body accepted by VerifyEmail
+type TokenInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ForgotPasswordInput is the body accepted by ForgotPassword
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordInput is the body accepted by ResetPassword
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// UsernameInput is the body accepted by ChangeUsername
+type UsernameInput struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// @Summary Verify email address
+// @Description Confirms the user's email address with the token from the verification email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body TokenInput true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/verify [post]
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	var input TokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := ctrl.OneTimeTokens.VerifyEmail(c.Request.Context(), utils.HashToken(input.Token), time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// @Summary Resend verification email
+// @Description Sends a new verification link to the authenticated user, invalidating earlier ones
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/verify/resend [post]
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, err := ctrl.Users.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := ctrl.sendVerificationEmail(c.Request.Context(), user); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// @Summary Request a password reset
+// @Description Emails a password reset link if an account exists for the address. The response is the same either way.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordInput true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/forgot-password [post]
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Respond identically whether or not the account exists, so the endpoint
+	// cannot be used to discover registered addresses
+	response := gin.H{"message": "If an account exists for that email, a reset link has been sent"}
+
+	ctx := c.Request.Context()
+	user, err := ctrl.Users.FindByEmail(ctx, input.Email)
+	if err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	raw, err := ctrl.createOneTimeToken(ctx, user.ID, models.PurposeResetPassword, resetPasswordTTL)
+	if err == nil {
+		err = ctrl.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your GitConnect password",
+			Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your GitConnect account. "+
+				"If it was you, follow this link within the next hour:\n\n%s\n\n"+
+				"If you did not ask for this, you can ignore this email.",
+				user.Username, frontendURL("/reset-password", raw)),
+		})
+	}
+	if err != nil {
+		log.Println("❌ Failed to send password reset email:", err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Reset password
+// @Description Sets a new password using the token from the reset email, signs out every existing session and revokes every personal access token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordInput true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/reset-password [post]
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Following the emailed link also proves ownership of the address, ends any
+	// lockout, signs out every device and revokes every personal access token
+	err = ctrl.OneTimeTokens.ResetPassword(c.Request.Context(), utils.HashToken(input.Token), string(hashedPassword), time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please log in again."})
+}
+
+// @Summary Change username
+// @Description Changes the caller's username. The previous username keeps redirecting to the account, and nobody else can take it, until the account has moved on from 5 more usernames. Changing only the case of the username is allowed.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body UsernameInput true "New username"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/username [put]
+func (ctrl *AuthController) ChangeUsername(c *gin.Context) {
+	var input UsernameInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	username := strings.TrimSpace(input.Username)
+	if err := models.ValidateUsername(username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := ctrl.Users.FindByID(ctx, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = ctrl.Users.Rename(ctx, user.ID, username)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to change username:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change username"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Username changed", "username": username, "previous_username": user.Username})
+}
+
+// sendVerificationEmail issues a new verification token and mails the link to the user
+func (ctrl *AuthController) sendVerificationEmail(ctx context.Context, user models.User) error {
+	raw, err := ctrl.createOneTimeToken(ctx, user.ID, models.PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return ctrl.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GitConnect email",
+		Body: fmt.Sprintf("Welcome to GitConnect, %s!\n\nPlease confirm your email address by following this link:\n\n%s\n\n"+
+			"The link expires in 24 hours.",
+			user.Username, frontendURL("/verify-email", raw)),
+	})
+}
+
+// createOneTimeToken stores a new token for purpose and returns its raw value.
+// Earlier unused tokens for the same purpose are invalidated, so only the latest link works.
+func (ctrl *AuthController) createOneTimeToken(ctx context.Context, userID uint, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	return raw, ctrl.OneTimeTokens.Create(ctx, &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+}
+
+// frontendURL builds a link into the web app carrying a token.
+// APP_BASE_URL points at the frontend, e.g. http://localhost:5173 during development.
+func frontendURL(path, token string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "https://gitconnect-frontend.vercel.app"
+	}
+	return base + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultAdminPageSize applies when ListUsers is called without a limit
+	defaultAdminPageSize = 50
+	// maxAdminPageSize caps how many users ListUsers returns at once
+	maxAdminPageSize = 100
+)
+
+// AdminController serves the staff API for moderating users and content
+type AdminController struct {
+	Users    repository.UserRepository
+	Posts    repository.PostRepository
+	Comments repository.CommentRepository
+	Events   *realtime.Hub // Ends the event streams of suspended, banned and deleted users
+}
+
+// NewAdminController builds an AdminController from repos, ending streams through events
+func NewAdminController(repos repository.Repositories, events *realtime.Hub) *AdminController {
+	return &AdminController{Users: repos.Users, Posts: repos.Posts, Comments: repos.Comments, Events: events}
+}
+
+// SetRoleInput is the body accepted by SetUserRole
+type SetRoleInput struct {
+	Role models.Role `json:"role" binding:"required"`
+}
+
+// SuspendInput is the body accepted by SuspendUser. Either until or duration_hours must be set.
+type SuspendInput struct {
+	Until         *time.Time `json:"until"`
+	DurationHours int        `json:"duration_hours"`
+	Reason        string     `json:"reason" binding:"max=500"`
+}
+
+// BanInput is the body accepted by BanUser
+type BanInput struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// @Summary List users
+// @Description Lists users for moderation, optionally filtered by a username/email search and by role
+// @Tags Admin
+// @Produce json
+// @Param q query string false "Search username or email"
+// @Param role query string false "Filter by role"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of users to skip"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users [get]
+func (ctrl *AdminController) ListUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAdminPageSize)))
+	if err != nil || limit < 1 || limit > maxAdminPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxAdminPageSize)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	filter := repository.UserFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Role:   models.Role(c.Query("role")),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if filter.Role != "" && !filter.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	users, total, err := ctrl.Users.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	list := make([]gin.H, len(users))
+	for i, user := range users {
+		list[i] = adminUserJSON(user)
+	}
+	c.JSON(http.StatusOK, gin.H{"users": list, "total": total})
+}
+
+// @Summary Change a user's role
+// @Description Promotes or demotes a user. Admins cannot change their own role or that of another admin.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body SetRoleInput true "New role"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/role [put]
+func (ctrl *AdminController) SetUserRole(c *gin.Context) {
+	var input SetRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Users.SetRole(c.Request.Context(), target.ID, input.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	target.Role = input.Role
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "user": adminUserJSON(target)})
+}
+
+// @Summary Suspend a user
+// @Description Makes an account read-only, signs it out everywhere and ends its event streams until the given time
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body SuspendInput true "Suspension end and reason"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/suspend [post]
+func (ctrl *AdminController) SuspendUser(c *gin.Context) {
+	var input SuspendInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var until time.Time
+	switch {
+	case input.Until != nil:
+		until = *input.Until
+	case input.DurationHours > 0:
+		until = time.Now().Add(time.Duration(input.DurationHours) * time.Hour)
+	}
+	if !until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide a future until or a positive duration_hours"})
+		return
+	}
+
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Users.Suspend(c.Request.Context(), target.ID, until, input.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+	ctrl.Events.Disconnect(c.Request.Context(), realtime.UserTopic(target.ID))
+	target.SuspendedUntil, target.ModerationNote = &until, input.Reason
+
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended", "user": adminUserJSON(target)})
+}
+
+// @Summary Lift a suspension
+// @Description Restores a suspended account before its suspension ends
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/unsuspend [post]
+func (ctrl *AdminController) UnsuspendUser(c *gin.Context) {
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Users.Unsuspend(c.Request.Context(), target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift suspension"})
+		return
+	}
+	target.SuspendedUntil = nil
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension lifted", "user": adminUserJSON(target)})
+}
+
+// @Summary Ban a user
+// @Description Permanently blocks an account from signing in, revokes all of its sessions and tokens and ends its event streams
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body BanInput false "Reason"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/ban [post]
+func (ctrl *AdminController) BanUser(c *gin.Context) {
+	var input BanInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := ctrl.Users.Ban(c.Request.Context(), target.ID, now, input.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
+		return
+	}
+	ctrl.Events.Disconnect(c.Request.Context(), realtime.UserTopic(target.ID))
+	target.BannedAt, target.ModerationNote = &now, input.Reason
+
+	c.JSON(http.StatusOK, gin.H{"message": "User banned", "user": adminUserJSON(target)})
+}
+
+// @Summary Unban a user
+// @Description Lifts a ban so the account can sign in again
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/unban [post]
+func (ctrl *AdminController) UnbanUser(c *gin.Context) {
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Users.Unban(c.Request.Context(), target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban user"})
+		return
+	}
+	target.BannedAt = nil
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unbanned", "user": adminUserJSON(target)})
+}
+
+// @Summary Delete a user
+// @Description Permanently deletes an account together with its profile, posts, comments, reactions and credentials
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id} [delete]
+func (ctrl *AdminController) DeleteUser(c *gin.Context) {
+	target, ok := ctrl.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Users.Delete(c.Request.Context(), target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	ctrl.Events.Disconnect(c.Request.Context(), realtime.UserTopic(target.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// @Summary Force-delete a post
+// @Description Deletes any post regardless of its author
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/posts/{id} [delete]
+func (ctrl *AdminController) AdminDeletePost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	err = ctrl.Posts.Delete(c.Request.Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted"})
+}
+
+// @Summary Force-delete a comment
+// @Description Deletes any comment regardless of its author. A comment with replies is replaced by a tombstone.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/comments/{id} [delete]
+func (ctrl *AdminController) AdminDeleteComment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	err = ctrl.Comments.Delete(c.Request.Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// moderationTarget loads the user addressed by ":id" and checks the caller may act on them:
+// never on themselves, and only on users below them in the hierarchy.
+// It writes the error response itself. Must run after RequirePermission.
+func (ctrl *AdminController) moderationTarget(c *gin.Context) (models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return models.User{}, false
+	}
+	target, err := ctrl.Users.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return target, false
+	}
+
+	actor := c.MustGet("current_user").(models.User)
+	if actor.ID == target.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot moderate your own account"})
+		return target, false
+	}
+	if !actor.Role.Outranks(target.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only moderate users below your role"})
+		return target, false
+	}
+	return target, true
+}
+
+// adminUserJSON renders a user for the admin API, including moderation details hidden elsewhere
+func adminUserJSON(user models.User) gin.H {
+	return gin.H{
+		"id":              user.ID,
+		"username":        user.Username,
+		"email":           user.Email,
+		"email_verified":  user.EmailVerifiedAt != nil,
+		"role":            user.Role,
+		"suspended_until": user.SuspendedUntil,
+		"banned_at":       user.BannedAt,
+		"moderation_note": user.ModerationNote,
+		"created_at":      user.CreatedAt,
+	}
+}
@@ -1,27 +1,88 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
-	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
 	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"gitconnect-backend/repository"
 	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// AuthController serves registration, login and account security
+type AuthController struct {
+	Users         repository.UserRepository
+	Profiles      repository.ProfileRepository
+	Sessions      repository.SessionRepository
+	AccessTokens  repository.AccessTokenRepository
+	OneTimeTokens repository.OneTimeTokenRepository
+	MFA           repository.MFARepository
+	OAuthStates   repository.OAuthStateRepository
+	Limiter       *ratelimit.Limiter // Limits login attempts per account
+	Mailer        mailer.Mailer      // Sends verification and password reset links
+}
+
+// NewAuthController builds an AuthController from repos, limiting login attempts
+// with limiter and sending email through mail
+func NewAuthController(repos repository.Repositories, limiter *ratelimit.Limiter, mail mailer.Mailer) *AuthController {
+	return &AuthController{
+		Users:         repos.Users,
+		Profiles:      repos.Profiles,
+		Sessions:      repos.Sessions,
+		AccessTokens:  repos.AccessTokens,
+		OneTimeTokens: repos.OneTimeTokens,
+		MFA:           repos.MFA,
+		OAuthStates:   repos.OAuthStates,
+		Limiter:       limiter,
+		Mailer:        mail,
+	}
+}
+
+const (
+	// lockoutThreshold is how many consecutive failed sign-ins, by wrong password or
+	// wrong second factor, lock an account
+	lockoutThreshold = 5
+	// lockoutBase is the first lockout; each further failure doubles it, up to lockoutMax
+	lockoutBase = time.Minute
+	lockoutMax  = time.Hour
+)
+
+// RegisterInput is the body accepted by Register.
+// models.User hides the password from JSON, so it cannot be bound directly.
+type RegisterInput struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// LoginInput is the body accepted by Login
+type LoginInput struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 // @Summary Register a new user
-// @Description Creates a new user account with a hashed password
+// @Description Creates a new user account with a hashed password and emails a verification link. Usernames are 3 to 39 letters, digits, hyphens and underscores, with at least one letter, and are unique whatever their case.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param user body models.User true "User Data"
+// @Param user body RegisterInput true "User Data"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/auth/register [post]
-func Register(c *gin.Context) {
-	var input models.User
+func (ctrl *AuthController) Register(c *gin.Context) {
+	var input RegisterInput
 
 	// Bind JSON input
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -29,6 +90,22 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Check the username is valid and no account holds it, currently or as a previous username
+	input.Username = strings.TrimSpace(input.Username)
+	if err := models.ValidateUsername(input.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, _, err := ctrl.Users.FindByUsername(c.Request.Context(), input.Username)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -44,7 +121,11 @@ func Register(c *gin.Context) {
 	}
 
 	// Save user to DB
-	if err := config.DB.Create(&user).Error; err != nil {
+	if err := ctrl.Users.Create(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An account with this username or email already exists"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -54,41 +135,48 @@ func Register(c *gin.Context) {
 		UserID: user.ID,
 	}
 
-	if err := config.DB.Create(&profile).Error; err != nil {
+	if err := ctrl.Profiles.Create(c.Request.Context(), &profile); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
 		return
 	}
 
+	// Ask the user to confirm their address; registration succeeds even if mail is down,
+	// since a new link can be requested later
+	if err := ctrl.sendVerificationEmail(c.Request.Context(), user); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
+	}
+
 	// Return user and profile info
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
+		"message": "User registered successfully. Check your email to verify your account.",
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":                user.ID,
+			"username":          user.Username,
+			"email":             user.Email,
+			"email_verified_at": user.EmailVerifiedAt,
 		},
 		"profile": gin.H{
-			"id":     profile.ID,
+			"id":      profile.ID,
 			"user_id": profile.UserID,
 		},
 	})
 }
 
-
 // @Summary Login user
-// @Description Authenticates a user and returns a JWT token
+// @Description Authenticates a user and returns a short-lived JWT access token and a refresh token
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param credentials body models.User true "User Credentials"
+// @Param credentials body LoginInput true "User Credentials"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/auth/login [post]
-func Login(c *gin.Context) {
-	var input models.User
-	var user models.User
+func (ctrl *AuthController) Login(c *gin.Context) {
+	var input LoginInput
 
 	// Bind JSON input
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -96,35 +184,306 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Limit attempts per account as well as per IP, so spreading guesses across
+	// addresses does not help. Unknown emails are limited the same way.
+	if !ctrl.allowLoginAttempt(c, strings.ToLower(strings.TrimSpace(input.Email))) {
+		return
+	}
+
 	// Check if user exists
-	result := config.DB.Where("email = ?", input.Email).First(&user)
-	if result.Error != nil {
+	user, err := ctrl.Users.FindByEmail(c.Request.Context(), input.Email)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
+	// A locked account refuses passwords, even correct ones, until the lockout ends
+	if lockedOut(c, user) {
+		return
+	}
+
 	// Compare password
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
 	if err != nil {
+		if err := ctrl.recordFailedLogin(c.Request.Context(), user.ID); err != nil {
+			log.Println("❌ Failed to record failed login:", err)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID)
+	// Banned and suspended accounts cannot sign in
+	if reason, restricted := accountRestriction(user); restricted {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
+	// With two-factor login on, the password only earns a short-lived MFA token
+	// to be exchanged at /api/auth/login/2fa together with a code
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := utils.GenerateMFAToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int64(utils.MFATokenTTL.Seconds()),
+		})
+		return
+	}
+
+	// The failure count is only cleared once the login is complete, so that
+	// knowing the password does not reset the count of wrong second factors
+	ctrl.resetFailedLogins(c.Request.Context(), user)
+
+	// Generate an access token and start a new refresh token family
+	tokens, err := ctrl.issueTokens(c.Request.Context(), user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// ✅ Return token + user ID + username
+	// ✅ Return tokens + user ID + username
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Login successful",
-		"token":    token,
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
+			"id":                user.ID,
+			"username":          user.Username,
+			"email_verified_at": user.EmailVerifiedAt,
 		},
 	})
 }
 
+// RefreshInput is the body accepted by the refresh and logout endpoints
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can be used once; replaying one revokes every token from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body RefreshInput true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/refresh [post]
+func (ctrl *AuthController) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Spend the presented token, storing its successor in the same family
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	next := models.RefreshToken{TokenHash: utils.HashToken(raw), ExpiresAt: time.Now().Add(utils.RefreshTokenTTL)}
+	err = ctrl.Sessions.Rotate(ctx, utils.HashToken(input.RefreshToken), &next)
+	switch {
+	case errors.Is(err, repository.ErrReused):
+		// A rotated or revoked token was presented again: assume it was stolen.
+		// Every token descended from the same login has been revoked.
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected; please log in again"})
+		return
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	// Banned and suspended users lose the session instead of renewing it
+	user, err := ctrl.Users.FindByID(ctx, next.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	if _, restricted := accountRestriction(user); restricted {
+		if err := ctrl.Sessions.RevokeFamily(ctx, next.FamilyID); err != nil {
+			log.Println("❌ Failed to revoke refresh tokens:", err)
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account is suspended"})
+		return
+	}
+
+	access, err := utils.GenerateToken(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         access,
+		"refresh_token": raw,
+		"expires_in":    int64(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// @Summary Logout
+// @Description Revokes the access token used for the request and, when given, the refresh token family it belongs to
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body RefreshInput false "Refresh token to revoke"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/logout [post]
+func (ctrl *AuthController) Logout(c *gin.Context) {
+	value, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := value.(*utils.Claims)
+
+	// The body is optional; a client that lost its refresh token can still log out
+	var input RefreshInput
+	_ = c.ShouldBindJSON(&input)
+
+	ctx := c.Request.Context()
+	if _, err := ctrl.Sessions.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	if input.RefreshToken != "" {
+		stored, err := ctrl.Sessions.Find(ctx, utils.HashToken(input.RefreshToken))
+		if err == nil && stored.UserID == claims.UserID {
+			err = ctrl.Sessions.RevokeFamily(ctx, stored.FamilyID)
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// accountRestriction explains why a banned or suspended user may not sign in
+func accountRestriction(user models.User) (string, bool) {
+	if user.BannedAt != nil {
+		return "This account has been banned", true
+	}
+	if user.Restricted(time.Now()) {
+		return "This account is suspended until " + user.SuspendedUntil.UTC().Format(time.RFC3339), true
+	}
+	return "", false
+}
+
+// allowLoginAttempt takes a token from the login bucket of account, writing a 429
+// response if it is empty. Attempts are let through if the limiter is unavailable.
+func (ctrl *AuthController) allowLoginAttempt(c *gin.Context, account string) bool {
+	limit, err := ctrl.Limiter.Allow(c.Request.Context(), ratelimit.GroupLogin, account)
+	if err != nil {
+		log.Printf("⚠️ Rate limiter unavailable, allowing login: %v", err)
+		return true
+	}
+	limit.SetHeaders(c.Writer.Header())
+	if !limit.Allowed {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts; please try again later"})
+		return false
+	}
+	return true
+}
+
+// lockedOut writes a 429 response if failed sign-ins have locked the account
+func lockedOut(c *gin.Context, user models.User) bool {
+	if user.LockedUntil == nil || !time.Now().Before(*user.LockedUntil) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(time.Until(*user.LockedUntil).Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts; please try again later"})
+	return true
+}
+
+// resetFailedLogins clears the failure count and any lockout after a complete login
+func (ctrl *AuthController) resetFailedLogins(ctx context.Context, user models.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := ctrl.Users.ResetFailedLogins(ctx, user.ID); err != nil {
+		log.Println("❌ Failed to reset failed logins:", err)
+	}
+}
+
+// recordFailedLogin counts a wrong password or second factor and locks the account
+// once the failures reach lockoutThreshold, for longer with every further failure
+func (ctrl *AuthController) recordFailedLogin(ctx context.Context, userID uint) error {
+	failures, err := ctrl.Users.RecordFailedLogin(ctx, userID)
+	if err != nil || failures < lockoutThreshold {
+		return err
+	}
+	return ctrl.Users.LockLogin(ctx, userID, time.Now().Add(lockoutDuration(failures)))
+}
+
+// lockoutDuration doubles lockoutBase for every failure past the threshold, capped at lockoutMax
+func lockoutDuration(failures int) time.Duration {
+	duration := lockoutBase
+	for i := lockoutThreshold; i < failures && duration < lockoutMax; i++ {
+		duration *= 2
+	}
+	return min(duration, lockoutMax)
+}
+
+// tokenPair is an access token together with the refresh token that can renew it
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+// issueTokens signs an access token and stores a refresh token for the user,
+// starting a new refresh token family as happens on login
+func (ctrl *AuthController) issueTokens(ctx context.Context, userID uint) (tokenPair, error) {
+	var pair tokenPair
+
+	access, err := utils.GenerateToken(userID)
+	if err != nil {
+		return pair, err
+	}
+
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return pair, err
+	}
+
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return pair, err
+	}
+
+	refresh := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := ctrl.Sessions.Create(ctx, &refresh); err != nil {
+		return pair, err
+	}
+
+	pair = tokenPair{
+		AccessToken:  access,
+		RefreshToken: raw,
+		ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+	}
+	return pair, nil
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// BlockController serves the users a user has blocked. A blocked user's
+// mentions do not notify the user who blocked them.
+type BlockController struct {
+	Blocks repository.BlockRepository
+}
+
+// NewBlockController builds a BlockController from repos
+func NewBlockController(repos repository.Repositories) *BlockController {
+	return &BlockController{Blocks: repos.Blocks}
+}
+
+// @Summary Block a user
+// @Description Stops the user's mentions from notifying the caller. Blocking twice is a no-op.
+// @Tags Blocks
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/block [post]
+func (ctrl *BlockController) BlockUser(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	callerID := c.GetUint("user_id")
+	if targetID == callerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot block yourself"})
+		return
+	}
+
+	err := ctrl.Blocks.Block(c.Request.Context(), callerID, targetID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+// @Summary Unblock a user
+// @Description Lets the user's mentions notify the caller again. Unblocking someone not blocked is a no-op.
+// @Tags Blocks
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/block [delete]
+func (ctrl *BlockController) UnblockUser(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Blocks.Unblock(c.Request.Context(), c.GetUint("user_id"), targetID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+// @Summary List blocked users
+// @Description Lists the users the caller has blocked, most recent first
+// @Tags Blocks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/blocks [get]
+func (ctrl *BlockController) GetBlocks(c *gin.Context) {
+	blocks, err := ctrl.Blocks.List(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocked users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocks": blocks})
+}
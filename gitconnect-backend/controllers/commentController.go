@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// CommentController serves single comments: editing, deleting and reactions.
+// Listing and creating comments live on PostController under /api/posts/:id/comments.
+type CommentController struct {
+	Comments repository.CommentRepository
+	Notify   Notifier
+	Events   *realtime.Hub
+}
+
+// NewCommentController builds a CommentController from repos, pushing to events
+func NewCommentController(repos repository.Repositories, events *realtime.Hub) *CommentController {
+	return &CommentController{Comments: repos.Comments, Notify: NewNotifier(repos, events), Events: events}
+}
+
+// EditCommentInput is the body of a comment edit
+type EditCommentInput struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// @Summary Edit a comment
+// @Description Replaces the content of the caller's own comment and marks it as edited. Users mentioned for the first time are notified.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param comment body EditCommentInput true "New content"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/comments/{id} [put]
+func (ctrl *CommentController) UpdateComment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var input EditCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous, err := ctrl.Comments.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	err = ctrl.Comments.UpdateContent(c.Request.Context(), uint(id), input.Content, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	comment, err := ctrl.Comments.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return
+	}
+	ctrl.Notify.Mentions(c.Request.Context(), c.GetUint("user_id"), comment.Mentions, previous.Mentions, comment.PostID, &comment.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated", "comment": comment})
+}
+
+// @Summary Delete a comment
+// @Description Deletes the caller's own comment. A comment with replies is replaced by a tombstone so the replies stay in place.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/comments/{id} [delete]
+func (ctrl *CommentController) DeleteComment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	err = ctrl.Comments.Delete(c.Request.Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// @Summary Like a comment
+// @Description Records the caller's like on a comment and notifies its author. Repeating the call is a no-op; a previous dislike is switched to a like.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/comments/{id}/like [post]
+func (ctrl *CommentController) LikeComment(c *gin.Context) {
+	ctrl.reactToComment(c, models.ReactionLike, "Comment liked", "Failed to like comment")
+}
+
+// @Summary Dislike a comment
+// @Description Records the caller's dislike on a comment. Repeating the call is a no-op; a previous like is switched to a dislike.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/comments/{id}/dislike [post]
+func (ctrl *CommentController) DislikeComment(c *gin.Context) {
+	ctrl.reactToComment(c, models.ReactionDislike, "Comment disliked", "Failed to dislike comment")
+}
+
+// @Summary Remove a reaction from a comment
+// @Description Removes the caller's like or dislike from a comment. Removing a missing reaction is a no-op.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/comments/{id}/reaction [delete]
+func (ctrl *CommentController) RemoveCommentReaction(c *gin.Context) {
+	ctrl.reactToComment(c, "", "Reaction removed", "Failed to remove reaction")
+}
+
+// reactToComment moves the caller's reaction on the comment in the URL to kind
+// (an empty kind removes it) and responds with the fresh counters.
+func (ctrl *CommentController) reactToComment(c *gin.Context, kind models.ReactionKind, message, failure string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	// Only a new like notifies the author, not repeating one
+	var previous map[uint]models.ReactionKind
+	if kind == models.ReactionLike {
+		if previous, err = ctrl.Comments.ReactionsBy(c.Request.Context(), userID.(uint), []uint{uint(id)}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+			return
+		}
+	}
+
+	comment, err := ctrl.Comments.SetReaction(c.Request.Context(), uint(id), userID.(uint), kind)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+		return
+	}
+	if kind == models.ReactionLike && previous[comment.ID] != models.ReactionLike {
+		ctrl.Notify.Like(c.Request.Context(), userID.(uint), comment.UserID, comment.PostID, &comment.ID)
+	}
+	ctrl.Events.Publish(c.Request.Context(), realtime.EventReactionsUpdated, comment.ID,
+		realtime.ReactionCounts{PostID: comment.PostID, CommentID: &comment.ID, Likes: comment.Likes, Dislikes: comment.Dislikes}, realtime.PostTopic(comment.PostID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"likes":       comment.Likes,
+		"dislikes":    comment.Dislikes,
+		"my_reaction": comment.MyReaction,
+	})
+}
+
+// attachMyCommentReactions fills MyReaction on each comment for the authenticated caller, if any
+func attachMyCommentReactions(c *gin.Context, comments repository.CommentRepository, groups ...[]models.Comment) error {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+
+	var ids []uint
+	for _, group := range groups {
+		for _, comment := range group {
+			ids = append(ids, comment.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	kinds, err := comments.ReactionsBy(c.Request.Context(), userID.(uint), ids)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		for i := range group {
+			group[i].MyReaction = kinds[group[i].ID]
+		}
+	}
+	return nil
+}
+
+// groupReplies indexes replies by the comment they answer, keeping their order
+func groupReplies(replies []models.Comment) map[uint][]models.Comment {
+	children := make(map[uint][]models.Comment)
+	for _, reply := range replies {
+		if reply.ParentID != nil {
+			children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+		}
+	}
+	return children
+}
+
+// commentTree nests the replies of each thread under their parents
+func commentTree(roots, replies []models.Comment) []models.Comment {
+	children := groupReplies(replies)
+	var attach func(comments []models.Comment) []models.Comment
+	attach = func(comments []models.Comment) []models.Comment {
+		for i := range comments {
+			comments[i].Replies = attach(children[comments[i].ID])
+		}
+		return comments
+	}
+	return attach(roots)
+}
+
+// flattenComments lists every comment of each thread depth-first, so a client can
+// render the thread by indenting each comment by its depth
+func flattenComments(roots, replies []models.Comment) []models.Comment {
+	children := groupReplies(replies)
+	flat := make([]models.Comment, 0, len(roots)+len(replies))
+	var walk func(comments []models.Comment)
+	walk = func(comments []models.Comment) {
+		for _, comment := range comments {
+			flat = append(flat, comment)
+			walk(children[comment.ID])
+		}
+	}
+	walk(roots)
+	return flat
+}
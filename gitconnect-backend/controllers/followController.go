@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// FollowController serves the follow graph between users
+type FollowController struct {
+	Users   repository.UserRepository
+	Follows repository.FollowRepository
+	Notify  Notifier
+}
+
+// NewFollowController builds a FollowController from repos, pushing to events
+func NewFollowController(repos repository.Repositories, events *realtime.Hub) *FollowController {
+	return &FollowController{Users: repos.Users, Follows: repos.Follows, Notify: NewNotifier(repos, events)}
+}
+
+// @Summary Follow a user
+// @Description Adds the user to the caller's home feed and notifies them. Following twice is a no-op.
+// @Tags Follows
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/follow [post]
+func (ctrl *FollowController) FollowUser(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	callerID := c.GetUint("user_id")
+	if targetID == callerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	followed, err := ctrl.Follows.Follow(c.Request.Context(), callerID, targetID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+	if followed {
+		ctrl.Notify.Follow(c.Request.Context(), callerID, targetID)
+	}
+
+	ctrl.respondCounts(c, targetID, "User followed")
+}
+
+// @Summary Unfollow a user
+// @Description Removes the user from the caller's home feed. Unfollowing someone not followed is a no-op.
+// @Tags Follows
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/follow [delete]
+func (ctrl *FollowController) UnfollowUser(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Follows.Unfollow(c.Request.Context(), c.GetUint("user_id"), targetID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+
+	ctrl.respondCounts(c, targetID, "User unfollowed")
+}
+
+// @Summary List a user's followers
+// @Description Fetch a page of the users following this user, most recent first by default
+// @Tags Follows
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/followers [get]
+func (ctrl *FollowController) GetFollowers(c *gin.Context) {
+	ctrl.listFollows(c, "followers", ctrl.Follows.Followers, func(follow models.Follow) *models.User { return follow.Follower })
+}
+
+// @Summary List who a user follows
+// @Description Fetch a page of the users this user follows, most recent first by default
+// @Tags Follows
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/following [get]
+func (ctrl *FollowController) GetFollowing(c *gin.Context) {
+	ctrl.listFollows(c, "following", ctrl.Follows.Following, func(follow models.Follow) *models.User { return follow.Followee })
+}
+
+// listFollows responds with one page of a follow list of the user in the URL.
+// other picks the user on the far side of each edge.
+func (ctrl *FollowController) listFollows(c *gin.Context, key string,
+	list func(ctx context.Context, userID uint, page repository.Page) ([]models.Follow, *repository.Cursor, error),
+	other func(follow models.Follow) *models.User) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+
+	if _, err := ctrl.Users.FindByID(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	follows, next, err := list(c.Request.Context(), userID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + key})
+		return
+	}
+
+	users := make([]gin.H, len(follows))
+	for i, follow := range follows {
+		user := other(follow)
+		users[i] = gin.H{"id": user.ID, "username": user.Username, "followed_at": follow.CreatedAt}
+	}
+	respondPage(c, key, users, next)
+}
+
+// respondCounts answers a follow change with the target's fresh counts
+func (ctrl *FollowController) respondCounts(c *gin.Context, userID uint, message string) {
+	counts, err := ctrl.Follows.Counts(c.Request.Context(), []uint{userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count followers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "user_id": userID, "followers_count": counts[userID].Followers})
+}
+
+// userIDParam reads the ":id" path parameter as a user ID, responding with 400 if it is invalid
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
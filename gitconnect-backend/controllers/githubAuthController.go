@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// githubStateCookie binds an authorization request to the browser that started it
+	githubStateCookie = "github_oauth_state"
+	// githubStateTTL is how long the user has to complete the GitHub consent screen
+	githubStateTTL = 10 * time.Minute
+)
+
+var (
+	errGithubNotConfigured = errors.New("GitHub login is not configured")
+	errGithubNoEmail       = errors.New("your GitHub account has no verified primary email")
+	errGithubLinkedElse    = errors.New("this GitHub account is linked to another user")
+	errGithubEmailTaken    = errors.New("an account with this email already exists; log in and link GitHub from your settings")
+)
+
+// githubOAuthConfig builds the OAuth2 client configuration from the environment:
+//
+//	GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET   OAuth app credentials
+//	GITHUB_REDIRECT_URL                      this API's /api/auth/github/callback URL
+//	GITHUB_AUTH_URL, GITHUB_TOKEN_URL        provider endpoints, overridable for a local mock server
+func githubOAuthConfig() (*oauth2.Config, error) {
+	clientID := os.Getenv("GITHUB_CLIENT_ID")
+	clientSecret := os.Getenv("GITHUB_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, errGithubNotConfigured
+	}
+
+	authURL := os.Getenv("GITHUB_AUTH_URL")
+	if authURL == "" {
+		authURL = "https://github.com/login/oauth/authorize"
+	}
+	tokenURL := os.Getenv("GITHUB_TOKEN_URL")
+	if tokenURL == "" {
+		tokenURL = "https://github.com/login/oauth/access_token"
+	}
+
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   authURL,
+			TokenURL:  tokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}, nil
+}
+
+// @Summary Sign in with GitHub
+// @Description Redirects the browser to GitHub to authorize GitConnect
+// @Tags Auth
+// @Success 302
+// @Failure 503 {object} map[string]string
+// @Router /api/auth/github/login [get]
+func (ctrl *AuthController) GithubLogin(c *gin.Context) {
+	authURL, err := ctrl.startGithubAuthorization(c, nil)
+	if errors.Is(err, errGithubNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start GitHub login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// @Summary Link a GitHub account
+// @Description Starts the GitHub authorization flow for the authenticated user and returns the URL to send the browser to
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/auth/github/link [post]
+func (ctrl *AuthController) GithubLink(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id := userID.(uint)
+
+	authURL, err := ctrl.startGithubAuthorization(c, &id)
+	if errors.Is(err, errGithubNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start GitHub linking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// @Summary GitHub OAuth callback
+// @Description Completes the GitHub authorization, creating or linking the user, and redirects to the frontend with tokens in the URL fragment
+// @Tags Auth
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the authorization request"
+// @Success 302
+// @Router /api/auth/github/callback [get]
+func (ctrl *AuthController) GithubCallback(c *gin.Context) {
+	state := c.Query("state")
+	cookie, _ := c.Cookie(githubStateCookie)
+	c.SetCookie(githubStateCookie, "", -1, "/api/auth/github", "", isSecureRequest(c), true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		githubRedirect(c, url.Values{"error": {providerErr}})
+		return
+	}
+	if state == "" || cookie != state {
+		githubRedirect(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	// The state row is deleted as it is read, so a callback cannot be replayed
+	pending, err := ctrl.OAuthStates.Claim(c.Request.Context(), utils.HashToken(state), time.Now())
+	if err != nil {
+		githubRedirect(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	user, err := ctrl.completeGithubAuthorization(c.Request.Context(), pending, c.Query("code"))
+	switch {
+	case errors.Is(err, errGithubNoEmail):
+		githubRedirect(c, url.Values{"error": {"no_verified_email"}})
+		return
+	case errors.Is(err, errGithubLinkedElse):
+		githubRedirect(c, url.Values{"error": {"github_account_in_use"}})
+		return
+	case errors.Is(err, errGithubEmailTaken):
+		githubRedirect(c, url.Values{"error": {"email_in_use"}})
+		return
+	case err != nil:
+		log.Println("❌ GitHub login failed:", err)
+		githubRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	if _, restricted := accountRestriction(*user); restricted {
+		githubRedirect(c, url.Values{"error": {"account_suspended"}})
+		return
+	}
+
+	// GitHub replaces the password step only; the second factor is still required
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := utils.GenerateMFAToken(user.ID)
+		if err != nil {
+			githubRedirect(c, url.Values{"error": {"server_error"}})
+			return
+		}
+		githubRedirect(c, url.Values{"mfa_required": {"true"}, "mfa_token": {mfaToken}})
+		return
+	}
+
+	tokens, err := ctrl.issueTokens(c.Request.Context(), user.ID)
+	if err != nil {
+		githubRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	githubRedirect(c, url.Values{
+		"token":         {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+	})
+}
+
+// startGithubAuthorization stores a new state and PKCE verifier, sets the state
+// cookie, and returns the provider URL. linkUserID is set when linking an account.
+func (ctrl *AuthController) startGithubAuthorization(c *gin.Context, linkUserID *uint) (string, error) {
+	conf, err := githubOAuthConfig()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	pending := models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		CodeVerifier: verifier,
+		UserID:       linkUserID,
+		ExpiresAt:    time.Now().Add(githubStateTTL),
+	}
+	// Abandoned authorizations are cleaned up as new ones are stored
+	if err := ctrl.OAuthStates.Create(c.Request.Context(), &pending); err != nil {
+		return "", err
+	}
+
+	// The callback is a cross-site navigation from GitHub, so over HTTPS the
+	// cookie must be SameSite=None to be sent back
+	if isSecureRequest(c) {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie(githubStateCookie, state, int(githubStateTTL.Seconds()), "/api/auth/github", "", isSecureRequest(c), true)
+
+	return conf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// completeGithubAuthorization exchanges the code and resolves the GitHub identity to a user.
+// pending is the state row the caller has already claimed.
+func (ctrl *AuthController) completeGithubAuthorization(ctx context.Context, pending models.OAuthState, code string) (*models.User, error) {
+	conf, err := githubOAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(pending.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+
+	client := github.NewClient(conf.Client(ctx, token))
+	ghUser, err := client.AuthenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Linking: attach the identity to the user who started the flow
+	if pending.UserID != nil {
+		return ctrl.linkGithubProfile(ctx, *pending.UserID, ghUser)
+	}
+
+	// Returning user: the GitHub ID is already on a profile
+	profile, err := ctrl.Profiles.FindByGithubID(ctx, ghUser.ID)
+	if err == nil {
+		return ctrl.linkGithubProfile(ctx, profile.UserID, ghUser)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	email, err := client.PrimaryVerifiedEmail(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if email == "" {
+		return nil, errGithubNoEmail
+	}
+
+	// Existing account with the same address: link only if we verified it too,
+	// otherwise whoever registered the address first could take over the account
+	user, err := ctrl.Users.FindByEmail(ctx, email)
+	if err == nil {
+		if user.EmailVerifiedAt == nil {
+			return nil, errGithubEmailTaken
+		}
+		return ctrl.linkGithubProfile(ctx, user.ID, ghUser)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	// New account: GitHub has verified the address, and there is no password
+	now := time.Now()
+	username, err := ctrl.availableUsername(ctx, ghUser.Login)
+	if err != nil {
+		return nil, err
+	}
+	user = models.User{Username: username, Email: email, EmailVerifiedAt: &now}
+	if err := ctrl.Users.Create(ctx, &user); err != nil {
+		return nil, err
+	}
+	if err := ctrl.Profiles.Create(ctx, &models.Profile{UserID: user.ID, FullName: ghUser.Name}); err != nil {
+		return nil, err
+	}
+	return ctrl.linkGithubProfile(ctx, user.ID, ghUser)
+}
+
+// linkGithubProfile records the verified GitHub identity on the user's profile and returns the user
+func (ctrl *AuthController) linkGithubProfile(ctx context.Context, userID uint, ghUser *github.User) (*models.User, error) {
+	user, err := ctrl.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctrl.Profiles.LinkGithub(ctx, userID, ghUser.ID, ghUser.Login, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, errGithubLinkedElse
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// availableUsername returns login, or login with a numeric suffix if another
+// account holds it, currently or as a previous username. A login that is not a
+// valid username here, such as a reserved or all-digit one, gets a "gh-" prefix.
+func (ctrl *AuthController) availableUsername(ctx context.Context, login string) (string, error) {
+	if models.ValidateUsername(login) != nil {
+		login = "gh-" + login
+	}
+	candidate := login
+	for i := 2; ; i++ {
+		_, _, err := ctrl.Users.FindByUsername(ctx, candidate)
+		if errors.Is(err, repository.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		suffix := fmt.Sprintf("-%d", i)
+		candidate = login[:min(len(login), models.MaxUsernameLength-len(suffix))] + suffix
+	}
+}
+
+// githubRedirect sends the browser back to the frontend. Values travel in the
+// URL fragment so tokens never reach server logs or Referer headers.
+func githubRedirect(c *gin.Context, values url.Values) {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "https://gitconnect-frontend.vercel.app"
+	}
+	c.Redirect(http.StatusFound, base+"/oauth/github#"+values.Encode())
+}
+
+// isSecureRequest reports whether the client reached us over HTTPS
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"net/http"
+
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary JSON Web Key Set
+// @Description Publishes the public keys that verify GitConnect access tokens, selected by the kid header of a token
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	// Verifiers may cache the set, but not for longer than a rotation takes to roll out
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.PublicJWKS()})
+}
@@ -0,0 +1,470 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// MessageController serves private conversations between users. A conversation
+// started by someone a member does not follow lands in that member's message
+// requests until they accept it or reply.
+type MessageController struct {
+	Messages repository.MessageRepository
+	Follows  repository.FollowRepository
+	Blocks   repository.BlockRepository
+	Events   *realtime.Hub
+}
+
+// NewMessageController builds a MessageController from repos, pushing to events
+func NewMessageController(repos repository.Repositories, events *realtime.Hub) *MessageController {
+	return &MessageController{Messages: repos.Messages, Follows: repos.Follows, Blocks: repos.Blocks, Events: events}
+}
+
+// @Summary Start a conversation
+// @Description Starts a direct conversation with one user, or a group conversation with up to 9 others. Starting a direct conversation that already exists returns it. Users who do not follow the caller receive it as a message request. Users who blocked the caller, or whom the caller blocked, cannot be added.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param conversation body map[string]interface{} true "user_ids, and an optional title naming a group"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations [post]
+func (ctrl *MessageController) CreateConversation(c *gin.Context) {
+	var input struct {
+		UserIDs []uint `json:"user_ids" binding:"required"`
+		Title   string `json:"title" binding:"max=100"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	callerID := c.GetUint("user_id")
+	seen := map[uint]bool{callerID: true}
+	var userIDs []uint
+	for _, userID := range input.UserIDs {
+		if userID != 0 && !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Add at least one other user"})
+		return
+	}
+	if len(userIDs)+1 > models.MaxConversationMembers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A conversation can have at most " + strconv.Itoa(models.MaxConversationMembers) + " members"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	blocked, err := ctrl.Blocks.BlockedEitherWay(ctx, callerID, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start conversation"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot message this user"})
+		return
+	}
+
+	// Members who do not follow the caller get a message request
+	followers, err := ctrl.Follows.FollowersAmong(ctx, callerID, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start conversation"})
+		return
+	}
+	conversation := models.Conversation{CreatorID: &callerID, Title: strings.TrimSpace(input.Title)}
+	conversation.Members = append(conversation.Members, models.ConversationMember{UserID: callerID, State: models.MemberAccepted})
+	for _, userID := range userIDs {
+		state := models.MemberRequested
+		if followers[userID] {
+			state = models.MemberAccepted
+		}
+		conversation.Members = append(conversation.Members, models.ConversationMember{UserID: userID, State: state})
+	}
+	if len(userIDs) == 1 {
+		key := models.DirectKey(callerID, userIDs[0])
+		conversation.DirectKey, conversation.Title = &key, ""
+	}
+
+	created, err := ctrl.Messages.CreateConversation(ctx, &conversation)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start conversation"})
+		return
+	}
+
+	conversation, err = ctrl.Messages.FindConversation(ctx, conversation.ID, callerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversation"})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{"message": "Conversation already exists", "conversation": conversation})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Conversation started", "conversation": conversation})
+}
+
+// @Summary List conversations
+// @Description Fetch a page of the caller's inbox, or of their message requests, most recently active first by default. Each conversation has its members with their read receipts, its last message and the caller's unread_count.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param requests query bool false "List message requests instead of the inbox"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations [get]
+func (ctrl *MessageController) GetConversations(c *gin.Context) {
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+	filter := repository.ConversationFilter{Page: page}
+	if value := c.Query("requests"); value != "" {
+		requests, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "requests must be true or false"})
+			return
+		}
+		filter.Requests = requests
+	}
+
+	conversations, next, err := ctrl.Messages.ListConversations(c.Request.Context(), c.GetUint("user_id"), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	respondPage(c, "conversations", conversations, next)
+}
+
+// @Summary Count unread conversations
+// @Description Returns how many conversations in the caller's inbox have unread messages, and how many message requests are waiting
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/unread-count [get]
+func (ctrl *MessageController) GetUnreadCount(c *gin.Context) {
+	unread, requests, err := ctrl.Messages.Unread(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread, "requests": requests})
+}
+
+// @Summary Get a conversation
+// @Description Returns one of the caller's conversations with its members, their read receipts, its last message and the caller's unread_count
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id} [get]
+func (ctrl *MessageController) GetConversation(c *gin.Context) {
+	conversation, ok := ctrl.findConversation(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversation": conversation})
+}
+
+// @Summary Accept a message request
+// @Description Moves a conversation from the caller's message requests to their inbox. Replying accepts it too. Accepting a conversation already in the inbox is a no-op.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id}/accept [post]
+func (ctrl *MessageController) AcceptConversation(c *gin.Context) {
+	id, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := ctrl.Messages.Accept(c.Request.Context(), id, c.GetUint("user_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept conversation"})
+		return
+	}
+
+	conversation, ok := ctrl.findConversation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation accepted", "conversation": conversation})
+}
+
+// @Summary Leave a conversation
+// @Description Removes the caller from a conversation, which also declines a message request. Leaving a direct conversation deletes it for both members, with its messages; a group is deleted when its last member leaves.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id} [delete]
+func (ctrl *MessageController) LeaveConversation(c *gin.Context) {
+	id, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := ctrl.Messages.Leave(c.Request.Context(), id, c.GetUint("user_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave conversation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left conversation"})
+}
+
+// @Summary List messages
+// @Description Fetch a page of a conversation's messages, newest first by default
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id}/messages [get]
+func (ctrl *MessageController) GetMessages(c *gin.Context) {
+	conversation, ok := ctrl.findConversation(c)
+	if !ok {
+		return
+	}
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+
+	messages, next, err := ctrl.Messages.Messages(c.Request.Context(), conversation.ID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
+	respondPage(c, "messages", messages, next)
+}
+
+// @Summary Send a message
+// @Description Sends a message to a conversation and pushes it to the members' event streams. Replying to a message request accepts it. A direct conversation is closed to new messages while either member blocks the other.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param message body map[string]string true "content (max 4000 characters)"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id}/messages [post]
+func (ctrl *MessageController) SendMessage(c *gin.Context) {
+	conversation, ok := ctrl.findConversation(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message cannot be empty"})
+		return
+	}
+	if utf8.RuneCountInString(content) > models.MaxMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message must be at most " + strconv.Itoa(models.MaxMessageLength) + " characters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	callerID := c.GetUint("user_id")
+	if conversation.Direct {
+		var others []uint
+		for _, userID := range conversation.MemberIDs() {
+			if userID != callerID {
+				others = append(others, userID)
+			}
+		}
+		blocked, err := ctrl.Blocks.BlockedEitherWay(ctx, callerID, others)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot message this user"})
+			return
+		}
+	}
+
+	message := models.Message{ConversationID: conversation.ID, SenderID: callerID, Content: content}
+	if err := ctrl.Messages.Send(ctx, &message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+	if member, ok := conversation.Member(callerID); ok {
+		message.Sender = member.User
+	}
+
+	// The sender's other sessions receive the message too
+	ctrl.Events.Publish(ctx, realtime.EventMessageCreated, message.ID, message, inboxTopics(conversation)...)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Message sent", "data": message})
+}
+
+// @Summary Mark a conversation as read
+// @Description Moves the caller's read receipt up to message_id, or to the latest message when the body is empty, and pushes it to the other members. A receipt never moves back. Message requests must be accepted first.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param receipt body map[string]int false "message_id, the last message read"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/conversations/{id}/read [post]
+func (ctrl *MessageController) MarkRead(c *gin.Context) {
+	conversation, ok := ctrl.findConversation(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MessageID uint `json:"message_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	callerID := c.GetUint("user_id")
+	if member, _ := conversation.Member(callerID); member.State == models.MemberRequested {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accept the message request first"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	member, moved, err := ctrl.Messages.MarkRead(ctx, conversation.ID, callerID, input.MessageID, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark conversation as read"})
+		return
+	}
+	if moved {
+		receipt := models.ReadReceipt{ConversationID: conversation.ID, UserID: callerID, LastReadMessageID: *member.LastReadMessageID, LastReadAt: *member.LastReadAt}
+		ctrl.Events.Publish(ctx, realtime.EventConversationRead, conversation.ID, receipt, inboxTopics(conversation)...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation marked as read", "last_read_message_id": member.LastReadMessageID, "last_read_at": member.LastReadAt})
+}
+
+// findConversation loads the conversation in the id path parameter, responding
+// with an error if it is not one of the caller's
+func (ctrl *MessageController) findConversation(c *gin.Context) (models.Conversation, bool) {
+	id, ok := conversationIDParam(c)
+	if !ok {
+		return models.Conversation{}, false
+	}
+
+	conversation, err := ctrl.Messages.FindConversation(c.Request.Context(), id, c.GetUint("user_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return conversation, false
+	}
+	if err != nil {
+		log.Println("❌ Failed to fetch conversation:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversation"})
+		return conversation, false
+	}
+	return conversation, true
+}
+
+// inboxTopics returns the event topics of a conversation's members
+func inboxTopics(conversation models.Conversation) []string {
+	topics := make([]string, len(conversation.Members))
+	for i, member := range conversation.Members {
+		topics[i] = realtime.InboxTopic(member.UserID)
+	}
+	return topics
+}
+
+func conversationIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
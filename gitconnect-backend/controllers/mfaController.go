@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// totpIssuer is the account issuer shown in authenticator apps
+	totpIssuer = "GitConnect"
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+)
+
+// errSecondFactorInvalid is returned when neither a TOTP code nor a recovery code checks out
+var errSecondFactorInvalid = errors.New("invalid two-factor code")
+
+// SecondFactorInput carries either a TOTP code or a recovery code
+type SecondFactorInput struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFALoginInput is the body accepted by the second step of a two-factor login
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	SecondFactorInput
+}
+
+// DisableMFAInput is the body accepted by Disable2FA
+type DisableMFAInput struct {
+	Password string `json:"password"`
+	SecondFactorInput
+}
+
+// @Summary Start two-factor enrollment
+// @Description Generates a new TOTP secret for the authenticated user. Two-factor login is not enabled until the secret is confirmed with a code.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/setup [post]
+func (ctrl *AuthController) Setup2FA(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	// Starting over replaces any earlier, unconfirmed secret
+	if err := ctrl.MFA.SetSecret(c.Request.Context(), user.ID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor login once a code from the authenticator app matches, and returns one-time recovery codes. The codes are shown only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body SecondFactorInput true "Code from the authenticator app"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/confirm [post]
+func (ctrl *AuthController) Confirm2FA(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input SecondFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return
+	}
+
+	counter, valid := utils.ValidateTOTP(user.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = ctrl.MFA.Enable(c.Request.Context(), user.ID, counter, hashes)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor login after re-authenticating with the password and a current code or recovery code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body DisableMFAInput true "Password and second factor"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/disable [post]
+func (ctrl *AuthController) Disable2FA(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input DisableMFAInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// Accounts created through GitHub have no password; the second factor alone re-authenticates them
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	err := ctrl.verifySecondFactor(ctx, user, input.SecondFactorInput)
+	if err == nil {
+		err = ctrl.MFA.Disable(ctx, user.ID)
+	}
+	if errors.Is(err, errSecondFactorInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes after checking a current TOTP code. The new codes are shown only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body SecondFactorInput true "Code from the authenticator app"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/recovery-codes [post]
+func (ctrl *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input SecondFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// Only the authenticator counts here: a recovery code must not mint new ones
+	ctx := c.Request.Context()
+	var codes []string
+	err := ctrl.verifySecondFactor(ctx, user, SecondFactorInput{Code: input.Code})
+	if err == nil {
+		var hashes []string
+		if codes, hashes, err = generateRecoveryCodes(); err == nil {
+			err = ctrl.MFA.ReplaceRecoveryCodes(ctx, user.ID, hashes)
+		}
+	}
+	if errors.Is(err, errSecondFactorInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// @Summary Complete a two-factor login
+// @Description Exchanges the mfa_token from the password step and a TOTP or recovery code for access and refresh tokens. Each mfa_token allows one attempt: after a wrong code, log in again for a new one.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body MFALoginInput true "MFA token and second factor"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/login/2fa [post]
+func (ctrl *AuthController) LoginMFA(c *gin.Context) {
+	var input MFALoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateMFAToken(input.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Codes count against the same per-account limit as passwords
+	if !ctrl.allowLoginAttempt(c, fmt.Sprintf("user:%d", claims.UserID)) {
+		return
+	}
+
+	// The pending token allows a single attempt. Its jti is claimed on its own,
+	// before the code is checked, so a wrong code spends it as well and guessing
+	// means going through the password step again.
+	ctx := c.Request.Context()
+	claimed, err := ctrl.Sessions.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	user, err := ctrl.Users.FindByID(ctx, claims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if reason, restricted := accountRestriction(user); restricted {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+	if lockedOut(c, user) {
+		return
+	}
+
+	err = ctrl.verifySecondFactor(ctx, user, input.SecondFactorInput)
+	if errors.Is(err, errSecondFactorInvalid) {
+		// Wrong codes lock the account just like wrong passwords
+		if err := ctrl.recordFailedLogin(ctx, user.ID); err != nil {
+			log.Println("❌ Failed to record failed login:", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	ctrl.resetFailedLogins(ctx, user)
+
+	tokens, err := ctrl.issueTokens(ctx, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user": gin.H{
+			"id":                user.ID,
+			"username":          user.Username,
+			"email_verified_at": user.EmailVerifiedAt,
+		},
+	})
+}
+
+// verifySecondFactor accepts a TOTP code newer than the last one used, or an unused
+// recovery code, recording the use so neither can be replayed
+func (ctrl *AuthController) verifySecondFactor(ctx context.Context, user models.User, input SecondFactorInput) error {
+	if user.TOTPEnabledAt == nil {
+		return errSecondFactorInvalid
+	}
+
+	used := false
+	var err error
+	switch {
+	case input.Code != "":
+		counter, valid := utils.ValidateTOTP(user.TOTPSecret, input.Code, time.Now())
+		if !valid {
+			return errSecondFactorInvalid
+		}
+		used, err = ctrl.MFA.UseTOTP(ctx, user.ID, counter)
+	case input.RecoveryCode != "":
+		codeHash := utils.HashToken(utils.NormalizeRecoveryCode(input.RecoveryCode))
+		used, err = ctrl.MFA.UseRecoveryCode(ctx, user.ID, codeHash, time.Now())
+	}
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errSecondFactorInvalid
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns a fresh set of recovery codes and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// currentUser loads the authenticated user, writing a 401 response if there is none
+func (ctrl *AuthController) currentUser(c *gin.Context) (models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return models.User{}, false
+	}
+	user, err := ctrl.Users.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController serves the caller's notifications, their read state
+// and which types the caller wants
+type NotificationController struct {
+	Notifications repository.NotificationRepository
+}
+
+// NewNotificationController builds a NotificationController from repos
+func NewNotificationController(repos repository.Repositories) *NotificationController {
+	return &NotificationController{Notifications: repos.Notifications}
+}
+
+// @Summary List notifications
+// @Description Fetch a page of the caller's notifications, most recently active first by default. Bursts of the same event are aggregated: actor is the latest actor, actor_count counts them all, and summary reads like "alice and 4 others liked your post". A notification names the post and, where relevant, the comment it is about.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications [get]
+func (ctrl *NotificationController) GetNotifications(c *gin.Context) {
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+	filter := repository.NotificationFilter{Page: page}
+	if value := c.Query("unread"); value != "" {
+		unread, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unread must be true or false"})
+			return
+		}
+		filter.UnreadOnly = unread
+	}
+
+	notifications, next, err := ctrl.Notifications.List(c.Request.Context(), c.GetUint("user_id"), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	for i := range notifications {
+		notifications[i].Summary = notifications[i].Describe()
+	}
+
+	respondPage(c, "notifications", notifications, next)
+}
+
+// @Summary Count unread notifications
+// @Description Returns how many of the caller's notifications are unread
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/unread-count [get]
+func (ctrl *NotificationController) GetUnreadCount(c *gin.Context) {
+	count, err := ctrl.Notifications.UnreadCount(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// @Summary Mark a notification as read
+// @Description Marks one of the caller's notifications as read. Marking it again is a no-op.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/{id}/read [post]
+func (ctrl *NotificationController) MarkRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	err = ctrl.Notifications.MarkRead(c.Request.Context(), c.GetUint("user_id"), uint(id), time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+		return
+	}
+
+	ctrl.respondUnread(c, "Notification marked as read")
+}
+
+// @Summary Mark all notifications as read
+// @Description Marks every unread notification of the caller as read
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/read-all [post]
+func (ctrl *NotificationController) MarkAllRead(c *gin.Context) {
+	marked, err := ctrl.Notifications.MarkAllRead(c.Request.Context(), c.GetUint("user_id"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "marked": marked, "unread": 0})
+}
+
+// @Summary Get notification preferences
+// @Description Returns whether each notification type (comment, reply, reaction, follow, endorsement, mention) is on for the caller. Every type is on until turned off.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/preferences [get]
+func (ctrl *NotificationController) GetPreferences(c *gin.Context) {
+	preferences, err := ctrl.Notifications.Preferences(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// @Summary Update notification preferences
+// @Description Turns notification types on or off for the caller, e.g. {"reaction": false}. Types left out keep their setting.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param preferences body map[string]bool true "Notification type to on (true) or off (false)"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/preferences [put]
+func (ctrl *NotificationController) UpdatePreferences(c *gin.Context) {
+	var input map[models.NotificationType]bool
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for notificationType := range input {
+		if !notificationType.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type: " + string(notificationType)})
+			return
+		}
+	}
+
+	userID := c.GetUint("user_id")
+	if err := ctrl.Notifications.SetPreferences(c.Request.Context(), userID, input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	preferences, err := ctrl.Notifications.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notification preferences updated", "preferences": preferences})
+}
+
+// respondUnread responds with message and the caller's unread count
+func (ctrl *NotificationController) respondUnread(c *gin.Context, message string) {
+	count, err := ctrl.Notifications.UnreadCount(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "unread": count})
+}
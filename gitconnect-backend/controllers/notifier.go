@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"log"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+)
+
+// Notifier records the notifications produced by the controllers and pushes them
+// to their recipients' event streams. Failing to notify is logged and never fails
+// the request that caused it.
+type Notifier struct {
+	Notifications repository.NotificationRepository
+	Blocks        repository.BlockRepository
+	Users         repository.UserRepository
+	Events        *realtime.Hub
+}
+
+// NewNotifier builds a Notifier from repos, pushing to events
+func NewNotifier(repos repository.Repositories, events *realtime.Hub) Notifier {
+	return Notifier{Notifications: repos.Notifications, Blocks: repos.Blocks, Users: repos.Users, Events: events}
+}
+
+// Mentions notifies the users in mentions who are not in previous, so editing a
+// post or a comment only notifies newly mentioned users. commentID is nil for a
+// mention in a post.
+func (n Notifier) Mentions(ctx context.Context, actorID uint, mentions, previous []models.Mention, postID uint, commentID *uint) {
+	mentioned := make(map[uint]bool, len(previous))
+	for _, mention := range previous {
+		mentioned[mention.UserID] = true
+	}
+	var recipients []uint
+	for _, mention := range mentions {
+		if !mentioned[mention.UserID] {
+			mentioned[mention.UserID] = true
+			recipients = append(recipients, mention.UserID)
+		}
+	}
+	n.notify(ctx, models.Notification{ActorID: actorID, Type: models.NotificationMention, PostID: &postID, CommentID: commentID}, recipients...)
+}
+
+// Comment notifies the author of the post a new comment is on, and for a reply
+// the author of the parent comment. parentAuthorID is 0 for a top-level comment.
+func (n Notifier) Comment(ctx context.Context, comment models.Comment, postAuthorID, parentAuthorID uint) {
+	if comment.ParentID != nil && parentAuthorID != 0 {
+		n.notify(ctx, models.Notification{ActorID: comment.UserID, Type: models.NotificationReply, PostID: &comment.PostID, CommentID: comment.ParentID}, parentAuthorID)
+		if parentAuthorID == postAuthorID {
+			return
+		}
+	}
+	n.notify(ctx, models.Notification{ActorID: comment.UserID, Type: models.NotificationComment, PostID: &comment.PostID}, postAuthorID)
+}
+
+// Like notifies the author of a liked post or comment. commentID is nil for a post.
+func (n Notifier) Like(ctx context.Context, actorID, authorID, postID uint, commentID *uint) {
+	n.notify(ctx, models.Notification{ActorID: actorID, Type: models.NotificationReaction, PostID: &postID, CommentID: commentID}, authorID)
+}
+
+// Follow notifies a user of a new follower
+func (n Notifier) Follow(ctx context.Context, followerID, followeeID uint) {
+	n.notify(ctx, models.Notification{ActorID: followerID, Type: models.NotificationFollow}, followeeID)
+}
+
+// Endorse notifies a user that a skill on their profile was endorsed
+func (n Notifier) Endorse(ctx context.Context, endorserID, ownerID, skillID uint) {
+	n.notify(ctx, models.Notification{ActorID: endorserID, Type: models.NotificationEndorsement, SkillID: &skillID}, ownerID)
+}
+
+// notify sends a copy of notification to each recipient, skipping the actor,
+// users who turned the type off and users who blocked the actor
+func (n Notifier) notify(ctx context.Context, notification models.Notification, recipients ...uint) {
+	var userIDs []uint
+	for _, userID := range recipients {
+		if userID != 0 && userID != notification.ActorID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	disabled, err := n.Notifications.Disabled(ctx, notification.Type, userIDs)
+	if err != nil {
+		log.Println("❌ Failed to notify users:", err)
+		return
+	}
+	blockers, err := n.Blocks.BlockersOf(ctx, notification.ActorID, userIDs)
+	if err != nil {
+		log.Println("❌ Failed to notify users:", err)
+		return
+	}
+
+	var notifications []models.Notification
+	for _, userID := range userIDs {
+		if !disabled[userID] && !blockers[userID] {
+			notification.UserID = userID
+			notifications = append(notifications, notification)
+		}
+	}
+	if len(notifications) == 0 {
+		return
+	}
+	if err := n.Notifications.Create(ctx, notifications); err != nil {
+		log.Println("❌ Failed to notify users:", err)
+		return
+	}
+
+	actor, err := n.Users.FindByID(ctx, notification.ActorID)
+	if err != nil {
+		log.Println("❌ Failed to push notifications:", err)
+		return
+	}
+	for _, created := range notifications {
+		created.Actor = &actor
+		created.Summary = created.Describe()
+		n.Events.Publish(ctx, realtime.EventNotification, created.ID, created, realtime.UserTopic(created.UserID))
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listQuery holds the pagination and filter parameters shared by the list endpoints:
+// limit, sort (newest or oldest), cursor, author (a user ID) and since/until (RFC 3339)
+type listQuery struct {
+	Page     repository.Page
+	AuthorID uint
+	Created  repository.TimeRange
+}
+
+// parsePage reads limit, sort and cursor, responding with 400 if any is invalid
+func parsePage(c *gin.Context, defaultSort repository.Sort) (repository.Page, bool) {
+	var page repository.Page
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		return page, false
+	}
+	page.Limit = limit
+
+	page.Sort = repository.Sort(c.DefaultQuery("sort", string(defaultSort)))
+	if !page.Sort.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be newest or oldest"})
+		return page, false
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := repository.DecodeCursor(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return page, false
+		}
+		page.After = &cursor
+	}
+
+	return page, true
+}
+
+// parseListQuery reads the page and filter parameters, responding with 400 if any is invalid
+func parseListQuery(c *gin.Context, defaultSort repository.Sort) (listQuery, bool) {
+	var query listQuery
+
+	page, ok := parsePage(c, defaultSort)
+	if !ok {
+		return query, false
+	}
+	query.Page = page
+
+	if value := c.Query("author"); value != "" {
+		author, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+			return query, false
+		}
+		query.AuthorID = uint(author)
+	}
+
+	bounds := []struct {
+		param string
+		value **time.Time
+	}{{"since", &query.Created.Since}, {"until", &query.Created.Until}}
+	for _, bound := range bounds {
+		if value := c.Query(bound.param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": bound.param + " must be an RFC 3339 timestamp"})
+				return query, false
+			}
+			*bound.value = &at
+		}
+	}
+
+	return query, true
+}
+
+// respondPage writes one page of a list under key, with next_cursor in the body and
+// an RFC 8288 Link header pointing at the next page when there is one
+func respondPage(c *gin.Context, key string, items any, next *repository.Cursor) {
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+
+		nextURL := *c.Request.URL
+		params := nextURL.Query()
+		params.Set("cursor", encoded)
+		nextURL.RawQuery = params.Encode()
+		c.Header("Link", "<"+nextURL.RequestURI()+`>; rel="next"`)
+	}
+
+	c.JSON(http.StatusOK, gin.H{key: items, "next_cursor": nextCursor})
+}
@@ -1,16 +1,45 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
 
-	"github.com/gin-gonic/gin"
-	"gitconnect-backend/config"
 	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
 )
 
+// PostController serves posts, their reactions and their comments, and the home feed
+type PostController struct {
+	Posts    repository.PostRepository
+	Comments repository.CommentRepository
+	Feed     repository.FeedRepository
+	Tags     repository.TagRepository
+	Notify   Notifier
+	Events   *realtime.Hub
+	// MaxCommentDepth is how many levels replies may nest under a top-level comment
+	MaxCommentDepth int
+}
+
+// defaultMaxCommentDepth applies unless COMMENT_MAX_DEPTH is set
+const defaultMaxCommentDepth = 5
+
+// NewPostController builds a PostController from repos, pushing to events
+func NewPostController(repos repository.Repositories, events *realtime.Hub) *PostController {
+	maxDepth := defaultMaxCommentDepth
+	if depth, err := strconv.Atoi(os.Getenv("COMMENT_MAX_DEPTH")); err == nil && depth >= 0 {
+		maxDepth = depth
+	}
+	return &PostController{Posts: repos.Posts, Comments: repos.Comments, Feed: repos.Feed, Tags: repos.Tags, Notify: NewNotifier(repos, events), Events: events, MaxCommentDepth: maxDepth}
+}
+
 // @Summary Create a new post
-// @Description Allows an authenticated user to create a new post
+// @Description Allows an authenticated user to create a new post. #hashtags in the content become the post's tags; @usernames of existing users become mentions, returned as code point ranges [start, end), and notify the mentioned users.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -21,7 +50,7 @@ import (
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts [post]
-func CreatePost(c *gin.Context) {
+func (ctrl *PostController) CreatePost(c *gin.Context) {
 	// Get user ID from token
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -35,36 +64,146 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
-	// Assign the authenticated user to the post
+	// Assign the authenticated user to the post; counters only move through reactions
 	post.UserID = userID.(uint)
+	post.Likes, post.Dislikes = 0, 0
 
 	// Save post
-	if err := config.DB.Create(&post).Error; err != nil {
+	if err := ctrl.Posts.Create(c.Request.Context(), &post); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
+	ctrl.Notify.Mentions(c.Request.Context(), post.UserID, post.Mentions, nil, post.ID, nil)
+	ctrl.publishPost(c.Request.Context(), post.ID)
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Post created", "post": post})
 }
 
 // @Summary Get all posts
-// @Description Fetch all posts with user details
+// @Description Fetch a page of posts with user details, newest first by default. Follow next_cursor (or the Link header) for the next page.
 // @Tags Posts
 // @Accept json
 // @Produce json
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param author query int false "Only posts by this user ID"
+// @Param since query string false "Only posts created at or after this RFC 3339 time"
+// @Param until query string false "Only posts created before this RFC 3339 time"
+// @Param has_comments query bool false "Only posts with (true) or without (false) comments"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts [get]
-func GetPosts(c *gin.Context) {
-	var posts []models.Post
+func (ctrl *PostController) GetPosts(c *gin.Context) {
+	query, ok := parseListQuery(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+	filter := repository.PostFilter{AuthorID: query.AuthorID, Created: query.Created, Page: query.Page}
+	if value := c.Query("has_comments"); value != "" {
+		hasComments, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "has_comments must be true or false"})
+			return
+		}
+		filter.HasComments = &hasComments
+	}
 
 	// Include user details in the response
-	if err := config.DB.Preload("User").Find(&posts).Error; err != nil {
+	posts, next, err := ctrl.Posts.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := ctrl.attachMyReactions(c, posts); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	respondPage(c, "posts", posts, next)
+}
+
+// @Summary Get the home feed
+// @Description Fetch a page of posts by the caller, the users they follow and the hashtags they follow, newest first by default
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/feed [get]
+func (ctrl *PostController) GetFeed(c *gin.Context) {
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+
+	posts, next, err := ctrl.Feed.Home(c.Request.Context(), c.GetUint("user_id"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	if err := ctrl.attachMyReactions(c, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	respondPage(c, "posts", posts, next)
+}
+
+// @Summary Get the posts of a hashtag
+// @Description Fetch a page of posts tagged with the hashtag, newest first by default
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param name path string true "Hashtag, with or without the #"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param author query int false "Only posts by this user ID"
+// @Param since query string false "Only posts created at or after this RFC 3339 time"
+// @Param until query string false "Only posts created before this RFC 3339 time"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/tags/{name}/posts [get]
+func (ctrl *PostController) GetTagPosts(c *gin.Context) {
+	name, ok := tagNameParam(c)
+	if !ok {
+		return
+	}
+	query, ok := parseListQuery(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+
+	if _, err := ctrl.Tags.FindByName(c.Request.Context(), name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	filter := repository.PostFilter{AuthorID: query.AuthorID, Created: query.Created, Tag: name, Page: query.Page}
+	posts, next, err := ctrl.Posts.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := ctrl.attachMyReactions(c, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	respondPage(c, "posts", posts, next)
 }
 
 // @Summary Get a single post
@@ -77,9 +216,7 @@ func GetPosts(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /api/posts/{id} [get]
-func GetPost(c *gin.Context) {
-	var post models.Post
-
+func (ctrl *PostController) GetPost(c *gin.Context) {
 	// Convert ID param to uint
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -88,16 +225,23 @@ func GetPost(c *gin.Context) {
 	}
 
 	// Find post
-	if err := config.DB.Preload("User").First(&post, id).Error; err != nil {
+	post, err := ctrl.Posts.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"post": post})
+	posts := []models.Post{post}
+	if err := ctrl.attachMyReactions(c, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": posts[0]})
 }
 
 // @Summary Delete a post
-// @Description Deletes a post (Only the author can delete)
+// @Description Deletes a post (only the author or a moderator can delete)
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -110,16 +254,7 @@ func GetPost(c *gin.Context) {
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id} [delete]
-func DeletePost(c *gin.Context) {
-	// Get user ID from token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	var post models.Post
-
+func (ctrl *PostController) DeletePost(c *gin.Context) {
 	// Convert ID param to uint
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -127,20 +262,13 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	// Find post
-	if err := config.DB.First(&post, id).Error; err != nil {
+	// Delete post
+	err = ctrl.Posts.Delete(c.Request.Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
-
-	// Check if the logged-in user is the post owner
-	if post.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own post"})
-		return
-	}
-
-	// Delete post
-	if err := config.DB.Delete(&post).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
 		return
 	}
@@ -149,7 +277,7 @@ func DeletePost(c *gin.Context) {
 }
 
 // @Summary Update a post
-// @Description Updates an existing post
+// @Description Updates an existing post (only the author or an admin can update). The tags follow the new content.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -158,12 +286,11 @@ func DeletePost(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id} [put]
-func UpdatePost(c *gin.Context) {
-	var post models.Post
-
+func (ctrl *PostController) UpdatePost(c *gin.Context) {
 	// Convert ID param to uint
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -172,24 +299,39 @@ func UpdatePost(c *gin.Context) {
 	}
 
 	// Find post
-	if err := config.DB.First(&post, id).Error; err != nil {
+	post, err := ctrl.Posts.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	// Bind request data to the post object
-	if err := c.ShouldBindJSON(&post); err != nil {
+	// Bind request data; only the content is editable
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Save updated post
-	config.DB.Save(&post)
+	if err := ctrl.Posts.UpdateContent(c.Request.Context(), post.ID, input.Content); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+
+	// Only users mentioned for the first time are notified
+	updated, err := ctrl.Posts.FindByID(c.Request.Context(), post.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	ctrl.Notify.Mentions(c.Request.Context(), c.GetUint("user_id"), updated.Mentions, post.Mentions, post.ID, nil)
+	post = updated
 	c.JSON(http.StatusOK, gin.H{"message": "Post updated", "post": post})
 }
 
 // @Summary Like a post
-// @Description Allows a user to like a post
+// @Description Records the caller's like on a post and notifies its author. Repeating the call is a no-op; a previous dislike is switched to a like.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -200,34 +342,12 @@ func UpdatePost(c *gin.Context) {
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/like [post]
-func LikePost(c *gin.Context) {
-	var post models.Post
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-		return
-	}
-
-	// Find the post
-	if err := config.DB.First(&post, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
-	}
-
-	// Increment the like counter
-	post.LikePost()
-
-	// Save post with updated likes
-	if err := config.DB.Save(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like post"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Post liked", "likes": post.Likes})
+func (ctrl *PostController) LikePost(c *gin.Context) {
+	ctrl.reactToPost(c, models.ReactionLike, "Post liked", "Failed to like post")
 }
 
 // @Summary Dislike a post
-// @Description Allows a user to dislike a post
+// @Description Records the caller's dislike on a post. Repeating the call is a no-op; a previous like is switched to a dislike.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -238,46 +358,115 @@ func LikePost(c *gin.Context) {
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/dislike [post]
-func DislikePost(c *gin.Context) {
-	var post models.Post
+func (ctrl *PostController) DislikePost(c *gin.Context) {
+	ctrl.reactToPost(c, models.ReactionDislike, "Post disliked", "Failed to dislike post")
+}
+
+// @Summary Remove a reaction
+// @Description Removes the caller's like or dislike from a post. Removing a missing reaction is a no-op.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/reaction [delete]
+func (ctrl *PostController) RemovePostReaction(c *gin.Context) {
+	ctrl.reactToPost(c, "", "Reaction removed", "Failed to remove reaction")
+}
+
+// reactToPost moves the caller's reaction on the post in the URL to kind
+// (an empty kind removes it) and responds with the fresh counters.
+func (ctrl *PostController) reactToPost(c *gin.Context, kind models.ReactionKind, message, failure string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
 		return
 	}
 
-	// Find the post
-	if err := config.DB.First(&post, id).Error; err != nil {
+	// Only a new like notifies the author, not repeating one
+	var previous map[uint]models.ReactionKind
+	if kind == models.ReactionLike {
+		if previous, err = ctrl.Posts.ReactionsBy(c.Request.Context(), userID.(uint), []uint{uint(id)}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+			return
+		}
+	}
+
+	post, err := ctrl.Posts.SetReaction(c.Request.Context(), uint(id), userID.(uint), kind)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+		return
+	}
+	if kind == models.ReactionLike && previous[post.ID] != models.ReactionLike {
+		ctrl.Notify.Like(c.Request.Context(), userID.(uint), post.UserID, post.ID, nil)
+	}
+	ctrl.Events.Publish(c.Request.Context(), realtime.EventReactionsUpdated, post.ID,
+		realtime.ReactionCounts{PostID: post.ID, Likes: post.Likes, Dislikes: post.Dislikes}, realtime.PostTopic(post.ID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"likes":       post.Likes,
+		"dislikes":    post.Dislikes,
+		"my_reaction": post.MyReaction,
+	})
+}
+
+// attachMyReactions fills MyReaction on each post for the authenticated caller, if any
+func (ctrl *PostController) attachMyReactions(c *gin.Context, posts []models.Post) error {
+	userID, exists := c.Get("user_id")
+	if !exists || len(posts) == 0 {
+		return nil
+	}
 
-	// Increment the dislike counter
-	post.DislikePost()
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
 
-	// Save post with updated dislikes
-	if err := config.DB.Save(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dislike post"})
-		return
+	kinds, err := ctrl.Posts.ReactionsBy(c.Request.Context(), userID.(uint), ids)
+	if err != nil {
+		return err
 	}
+	for i := range posts {
+		posts[i].MyReaction = kinds[posts[i].ID]
+	}
+	return nil
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Post disliked", "dislikes": post.Dislikes})
+// CommentInput is the body of a new comment; parent_id makes it a reply
+type CommentInput struct {
+	Content  string `json:"content" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
 }
 
 // @Summary Comment on a post
-// @Description Allows a user to comment on a post
+// @Description Allows a user to comment on a post, or reply to one of its comments with parent_id. Replies nest up to COMMENT_MAX_DEPTH levels. The post's author and, for a reply, the parent comment's author are notified; @usernames of existing users become mentions and notify the mentioned users.
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
-// @Param comment body models.Comment true "Comment Data"
+// @Param comment body CommentInput true "Comment Data"
 // @Security BearerAuth
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/comments [post]
-func CommentOnPost(c *gin.Context) {
+func (ctrl *PostController) CommentOnPost(c *gin.Context) {
 	// Get user ID from token
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -292,57 +481,142 @@ func CommentOnPost(c *gin.Context) {
 		return
 	}
 
-	var comment models.Comment
-	// Bind comment data to the model
-	if err := c.ShouldBindJSON(&comment); err != nil {
+	var input CommentInput
+	// Bind comment data
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	postAuthorID, err := ctrl.Posts.OwnerID(c.Request.Context(), uint(postID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
 	// Assign the post ID and user ID
-	comment.PostID = uint(postID)
-	comment.UserID = userID.(uint)
+	comment := models.Comment{PostID: uint(postID), UserID: userID.(uint), Content: input.Content}
+
+	// A reply joins its parent's thread one level deeper
+	var parentAuthorID uint
+	if input.ParentID != nil {
+		parent, err := ctrl.Comments.FindByID(c.Request.Context(), *input.ParentID)
+		if err != nil || parent.PostID != comment.PostID || parent.Deleted() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		}
+		if parent.Depth >= ctrl.MaxCommentDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replies cannot be nested more than " + strconv.Itoa(ctrl.MaxCommentDepth) + " levels deep"})
+			return
+		}
+
+		parentAuthorID = parent.UserID
+		comment.ParentID, comment.RootID, comment.Depth = &parent.ID, parent.RootID, parent.Depth+1
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+	}
 
 	// Save the comment
-	if err := config.DB.Create(&comment).Error; err != nil {
+	if err := ctrl.Comments.Create(c.Request.Context(), &comment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post comment"})
 		return
 	}
+	ctrl.Notify.Comment(c.Request.Context(), comment, postAuthorID, parentAuthorID)
+	ctrl.Notify.Mentions(c.Request.Context(), comment.UserID, comment.Mentions, nil, comment.PostID, &comment.ID)
+	ctrl.publishComment(c.Request.Context(), comment.ID)
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Comment added", "comment": comment})
 }
 
 // @Summary Get all comments for a post
-// @Description Fetch all comments for a specific post
+// @Description Fetch a page of top-level comments on a post, oldest first by default, each with its whole reply thread. The flat view (default) lists every comment depth-first with its depth; the tree view nests replies under their parents. Filters apply to the top-level comments.
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
+// @Param view query string false "flat (default) or tree"
+// @Param limit query int false "Page size in top-level comments (max 100)"
+// @Param sort query string false "oldest (default) or newest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param author query int false "Only comments by this user ID"
+// @Param since query string false "Only comments created at or after this RFC 3339 time"
+// @Param until query string false "Only comments created before this RFC 3339 time"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/comments [get]
-func GetCommentsForPost(c *gin.Context) {
-    postID, err := strconv.Atoi(c.Param("id"))
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-        return
-    }
+func (ctrl *PostController) GetCommentsForPost(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	view := c.DefaultQuery("view", "flat")
+	if view != "flat" && view != "tree" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be flat or tree"})
+		return
+	}
+
+	query, ok := parseListQuery(c, repository.SortOldest)
+	if !ok {
+		return
+	}
 
-    var comments []models.Comment
+	// Fetch the page of threads with their authors
+	filter := repository.CommentFilter{AuthorID: query.AuthorID, Created: query.Created, Page: query.Page}
+	roots, next, err := ctrl.Comments.ListForPost(c.Request.Context(), uint(postID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
 
-    // Fetch comments with Post and User details only if necessary
-    query := config.DB.Where("post_id = ?", postID)
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+	replies, err := ctrl.Comments.Replies(c.Request.Context(), rootIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
 
-    // Remove Preload if it's causing issues
-    query = query.Preload("User") // If User exists, keep this
+	if err := attachMyCommentReactions(c, ctrl.Comments, roots, replies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
 
-    if err := query.Find(&comments).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
-        return
-    }
+	if view == "tree" {
+		respondPage(c, "comments", commentTree(roots, replies), next)
+		return
+	}
+	respondPage(c, "comments", flattenComments(roots, replies), next)
+}
+
+// publishPost pushes a new post to the event streams of those following its
+// author or one of its hashtags
+func (ctrl *PostController) publishPost(ctx context.Context, id uint) {
+	post, err := ctrl.Posts.FindByID(ctx, id)
+	if err != nil {
+		log.Println("❌ Failed to publish post:", err)
+		return
+	}
 
-    c.JSON(http.StatusOK, gin.H{"comments": comments})
+	topics := []string{realtime.AuthorTopic(post.UserID)}
+	for _, tag := range post.Tags {
+		topics = append(topics, realtime.TagTopic(tag))
+	}
+	ctrl.Events.Publish(ctx, realtime.EventPostCreated, post.ID, post, topics...)
 }
 
+// publishComment pushes a new comment to the event streams watching its post
+func (ctrl *PostController) publishComment(ctx context.Context, id uint) {
+	comment, err := ctrl.Comments.FindByID(ctx, id)
+	if err != nil {
+		log.Println("❌ Failed to publish comment:", err)
+		return
+	}
+	ctrl.Events.Publish(ctx, realtime.EventCommentCreated, comment.ID, comment, realtime.PostTopic(comment.PostID))
+}
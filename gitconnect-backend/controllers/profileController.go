@@ -1,14 +1,55 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 
-	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/media"
 	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/storage"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
 )
 
+// ProfileController serves user profiles
+type ProfileController struct {
+	Users        repository.UserRepository
+	Profiles     repository.ProfileRepository
+	Follows      repository.FollowRepository
+	Skills       repository.SectionRepository[models.Skill]
+	Experiences  repository.SectionRepository[models.Experience]
+	Educations   repository.SectionRepository[models.Education]
+	Links        repository.SectionRepository[models.ProfileLink]
+	GithubRepos  repository.GithubRepoRepository
+	Endorsements repository.EndorsementRepository
+	Blocks       repository.BlockRepository
+	Notify       Notifier
+	GitHub       *github.Client     // Fetches the repositories of profiles' GitHub accounts
+	Blobs        storage.BlobStore  // Profile pictures
+	repoSyncs    singleflight.Group // Collapses concurrent syncs of a profile's repositories
+}
+
+// NewProfileController builds a ProfileController from repos, pushing to events and
+// keeping profile pictures in blobs
+func NewProfileController(repos repository.Repositories, events *realtime.Hub, blobs storage.BlobStore) *ProfileController {
+	return &ProfileController{
+		Users: repos.Users, Profiles: repos.Profiles, Follows: repos.Follows,
+		Skills: repos.Skills, Experiences: repos.Experiences, Educations: repos.Educations, Links: repos.Links,
+		GithubRepos: repos.GithubRepos, Endorsements: repos.Endorsements, Blocks: repos.Blocks, Notify: NewNotifier(repos, events),
+		GitHub: github.NewAppClient(), Blobs: blobs,
+	}
+}
+
 // @Summary Create a new profile
 // @Description Allows an authenticated user to create a new profile
 // @Tags Profiles
@@ -21,48 +62,87 @@ import (
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles [post]
-func CreateProfile(c *gin.Context) {
+func (ctrl *ProfileController) CreateProfile(c *gin.Context) {
 	var profile models.Profile
-	fmt.Println("📥 Received request to create profile") // Debug log
 
 	// Bind request JSON to profile struct
 	if err := c.ShouldBindJSON(&profile); err != nil {
-		fmt.Println("❌ Error binding JSON:", err) // Debug log
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Profiles are always created for the caller, and the verified GitHub
+	// identity can only be set by the GitHub OAuth flow
+	profile.UserID = c.GetUint("user_id")
+	profile.GithubID, profile.GithubLogin, profile.GithubVerifiedAt = nil, "", nil
+	// Sections are edited through their own endpoints
+	profile.Skills, profile.Experience, profile.Education, profile.Links, profile.PinnedRepos = nil, nil, nil, nil, nil
+
 	// Check if the UserID exists in the Users table
-	var user models.User
-	if err := config.DB.First(&user, profile.UserID).Error; err != nil {
-		fmt.Println("❌ Invalid UserID, user does not exist") // Debug log
+	user, err := ctrl.Users.FindByID(c.Request.Context(), profile.UserID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UserID: user does not exist"})
 		return
 	}
 
-	// Save to database
-	if err := config.DB.Create(&profile).Error; err != nil {
-		fmt.Println("❌ Failed to create profile:", err) // Debug log
+	profile.Username = user.Username
+
+	// Save to database; each user has at most one profile, usually created at registration
+	err = ctrl.Profiles.Create(c.Request.Context(), &profile)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Profile already exists"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to create profile:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
 		return
 	}
 
-	fmt.Println("✅ Profile created successfully") // Debug log
 	c.JSON(http.StatusCreated, gin.H{"message": "Profile created successfully", "profile": profile})
 }
 
 // @Summary Get all profiles
-// @Description Fetch all profiles
+// @Description Fetch a page of profiles, newest first by default
 // @Tags Profiles
 // @Accept json
 // @Produce json
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param author query int false "Only the profile of this user ID"
+// @Param since query string false "Only profiles created at or after this RFC 3339 time"
+// @Param until query string false "Only profiles created before this RFC 3339 time"
+// @Param include query string false "Comma-separated sections to include: skills, experience, education, links, pinned_repos"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles [get]
-func GetProfiles(c *gin.Context) {
-	var profiles []models.Profile
-	config.DB.Find(&profiles)
-	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+func (ctrl *ProfileController) GetProfiles(c *gin.Context) {
+	query, ok := parseListQuery(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+	include, ok := parseInclude(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.ProfileFilter{UserID: query.AuthorID, Created: query.Created, Page: query.Page}
+	profiles, next, err := ctrl.Profiles.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+	if err := ctrl.attachFollowCounts(c, profiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+	if err := ctrl.attachSections(c, profiles, include); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+	respondPage(c, "profiles", profiles, next)
 }
 
 // @Summary Get a specific profile
@@ -71,21 +151,110 @@ func GetProfiles(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Profile ID"
+// @Param include query string false "Comma-separated sections to include: skills, experience, education, links, pinned_repos"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [get]
-func GetProfile(c *gin.Context) {
-	var profile models.Profile
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+func (ctrl *ProfileController) GetProfile(c *gin.Context) {
+	include, ok := parseInclude(c)
+	if !ok {
+		return
+	}
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+
+	profiles := []models.Profile{profile}
+	if err := ctrl.attachFollowCounts(c, profiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	if err := ctrl.attachSections(c, profiles, include); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profile": profiles[0]})
+}
+
+// @Summary Look up a user by username
+// @Description Fetch a user's public page: the user, their profile (null if they have none) and their follower and following counts. Usernames match whatever their case, and a number is taken as a user ID. A previous username of an account redirects to its current one.
+// @Tags Users
+// @Produce json
+// @Param username path string true "Username or user ID"
+// @Param include query string false "Comma-separated profile sections to include: skills, experience, education, links, pinned_repos"
+// @Success 200 {object} map[string]interface{}
+// @Success 302 "Redirect to the account's current username"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{username} [get]
+func (ctrl *ProfileController) GetUser(c *gin.Context) {
+	include, ok := parseInclude(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"profile": profile})
+
+	// The segment shares its wildcard with the /api/users/:id routes
+	ctx := c.Request.Context()
+	var user models.User
+	var renamed bool
+	var err error
+	if id, parseErr := strconv.ParseUint(c.Param("id"), 10, 64); parseErr == nil {
+		user, err = ctrl.Users.FindByID(ctx, uint(id))
+	} else {
+		user, renamed, err = ctrl.Users.FindByUsername(ctx, c.Param("id"))
+	}
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && user.BannedAt != nil) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to fetch user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if renamed {
+		location := "/api/users/" + url.PathEscape(user.Username)
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		// Not permanent: the old username may be released and taken by another account
+		c.Redirect(http.StatusFound, location)
+		return
+	}
+
+	profiles, _, err := ctrl.Profiles.List(ctx, repository.ProfileFilter{UserID: user.ID, Page: repository.Page{Limit: 1, Sort: repository.SortNewest}})
+	if err == nil {
+		err = ctrl.attachFollowCounts(c, profiles)
+	}
+	if err == nil {
+		err = ctrl.attachSections(c, profiles, include)
+	}
+	var counts map[uint]models.FollowCounts
+	if err == nil {
+		counts, err = ctrl.Follows.Counts(ctx, []uint{user.ID})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	var profile *models.Profile
+	if len(profiles) > 0 {
+		profile = &profiles[0]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"user":    gin.H{"id": user.ID, "username": user.Username, "created_at": user.CreatedAt},
+		"profile": profile,
+		"counts":  counts[user.ID],
+	})
 }
 
 // @Summary Update a profile
-// @Description Update a profile by ID
+// @Description Update a profile by ID (only the owner or an admin can update)
 // @Tags Profiles
 // @Accept json
 // @Produce json
@@ -94,169 +263,331 @@ func GetProfile(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [put]
-func UpdateProfile(c *gin.Context) {
-	var profile models.Profile
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+func (ctrl *ProfileController) UpdateProfile(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
 		return
 	}
+	stored := profile
 	if err := c.ShouldBindJSON(&profile); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	config.DB.Save(&profile)
-	c.JSON(http.StatusOK, gin.H{"message": "Profile updated", "profile": profile})
+
+	// The body cannot retarget the update at another row or user, and the verified
+	// GitHub identity can only be changed by the GitHub OAuth flow; once verified,
+	// the displayed handle follows it
+	profile.ID, profile.UserID, profile.CreatedAt = stored.ID, stored.UserID, stored.CreatedAt
+	profile.GithubID, profile.GithubLogin, profile.GithubVerifiedAt = stored.GithubID, stored.GithubLogin, stored.GithubVerifiedAt
+	if profile.GithubID != nil {
+		profile.Github = profile.GithubLogin
+	}
+	profile.Skills, profile.Experience, profile.Education, profile.Links, profile.PinnedRepos = nil, nil, nil, nil, nil // Edited through their own endpoints
+	if err := ctrl.Profiles.Update(c.Request.Context(), &profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	profiles := []models.Profile{profile}
+	if err := ctrl.attachFollowCounts(c, profiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated", "profile": profiles[0]})
 }
 
 // @Summary Delete a profile
-// @Description Delete a profile by ID
+// @Description Delete a profile by ID (only the owner or an admin can delete)
 // @Tags Profiles
 // @Accept json
 // @Produce json
 // @Param id path int true "Profile ID"
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} 
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [delete]
-func DeleteProfile(c *gin.Context) {
-	var profile models.Profile
+func (ctrl *ProfileController) DeleteProfile(c *gin.Context) {
 	// Find profile by ID
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
 		return
 	}
 
 	// Delete the profile
-	if err := config.DB.Delete(&profile).Error; err != nil {
+	if err := ctrl.Profiles.Delete(c.Request.Context(), profile.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile"})
 		return
 	}
+	ctrl.releasePicture(c.Request.Context(), profile.PictureID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile deleted successfully"})
 }
 
-/*
-// UploadProfileImage handles uploading a profile image for a given user.
-// @Summary Upload Profile Image
-// @Description Upload a profile image for the given user. Expects a multipart form with the field "image".
-// @Tags Profile
-// @Accept multipart/form-data
-// @Produce json
-// @Param userId path string true "User ID"
-// @Param image formData file true "Profile Image"
-// @Success 200 {object} map[string]interface{} "Profile image uploaded successfully"
-// @Failure 400 {object} map[string]interface{} "Image file is required"
-// @Failure 500 {object} map[string]interface{} "Failed to save image or update profile picture"
-// @Router /api/profiles/{userId}/image [post]
-func UploadProfileImage(c *gin.Context) {
-	userId := c.Param("userId")
-
-	file, err := c.FormFile("image")
+// findProfile loads the profile addressed by ":id", writing the error response itself
+func (ctrl *ProfileController) findProfile(c *gin.Context) (models.Profile, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
-		return
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+		return models.Profile{}, false
 	}
 
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	uploadDir := "uploads"
-
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, os.ModePerm)
+	profile, err := ctrl.Profiles.FindByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return profile, false
 	}
+	return profile, true
+}
 
-	fullPath := filepath.Join(uploadDir, filename)
-
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-		return
+// attachFollowCounts fills the username and the follower and following counts of
+// each profile's user, along with the picture URLs
+func (ctrl *ProfileController) attachFollowCounts(c *gin.Context, profiles []models.Profile) error {
+	if len(profiles) == 0 {
+		return nil
 	}
+	attachPictures(profiles)
 
-	if err := models.UpdateProfilePicture(userId, filename); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
-		return
+	userIDs := make([]uint, len(profiles))
+	for i, profile := range profiles {
+		userIDs[i] = profile.UserID
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Profile image uploaded successfully",
-		"filename": filename,
-	})
+	usernames, err := ctrl.Users.Usernames(c.Request.Context(), userIDs)
+	if err != nil {
+		return err
+	}
+	counts, err := ctrl.Follows.Counts(c.Request.Context(), userIDs)
+	if err != nil {
+		return err
+	}
+	for i := range profiles {
+		profiles[i].Username = usernames[profiles[i].UserID]
+		profiles[i].FollowersCount = counts[profiles[i].UserID].Followers
+		profiles[i].FollowingCount = counts[profiles[i].UserID].Following
+	}
+	return nil
 }
 
-// UpdateProfilePicture handles profile picture uploads.
-// @Summary Update Profile Picture
-// @Description Upload a new profile picture for the user.
-// @Tags Profile
+// @Summary Upload a profile picture
+// @Description Upload a JPEG, PNG or GIF picture for a profile (only the owner or an admin can upload). Expects a multipart form with the field "image". The picture is cropped to a square and stored in several sizes, without its metadata.
+// @Tags Profiles
 // @Accept multipart/form-data
 // @Produce json
-// @Param userId path string true "User ID"
-// @Param file formData file true "Profile Picture"
-// @Success 200 {object} map[string]interface{} "Successfully uploaded"
-// @Failure 400 {object} map[string]interface{} "File upload failed"
-// @Failure 500 {object} map[string]interface{} "Failed to save image or update profile picture"
-// @Router /api/profiles/{userId}/upload [post]
-func UpdateProfilePicture(c *gin.Context) {
-	userId := c.Param("userId")
-
-	file, err := c.FormFile("file")
+// @Param id path int true "Profile ID"
+// @Param image formData file true "Profile picture (max 5 MiB)"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/image [post]
+func (ctrl *ProfileController) UploadProfileImage(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, media.MaxImageBytes+64<<10)
+	header, err := c.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrTooLarge.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxImageBytes+1))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File upload failed"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file could not be read"})
 		return
 	}
 
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	uploadDir := "uploads"
+	avatar, err := media.ProcessAvatar(data)
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, os.ModePerm)
+	// Variants are named after the upload's hash, so re-uploading the same file
+	// rewrites the same blobs
+	ctx := c.Request.Context()
+	for _, variant := range avatar.Variants {
+		if err := ctrl.Blobs.Put(ctx, pictureKey(avatar.ID, variant.Size), variant.Data, variant.ContentType); err != nil {
+			log.Println("❌ Failed to store profile picture:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			return
+		}
+	}
+	previous, err := ctrl.Profiles.SetPicture(ctx, profile.ID, avatar.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
+		return
+	}
+	if previous != avatar.ID {
+		ctrl.releasePicture(ctx, previous)
 	}
 
-	fullPath := filepath.Join(uploadDir, filename)
+	profile.PictureID = avatar.ID
+	profiles := []models.Profile{profile}
+	if err := ctrl.attachFollowCounts(c, profiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Profile picture updated", "profile": profiles[0]})
+}
 
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+// @Summary Get a profile picture
+// @Description Serve a profile's picture in one of the sizes 64, 128, 256 (default) or 512. The URLs in a profile's profile_pictures carry a version and may be cached indefinitely.
+// @Tags Profiles
+// @Produce image/jpeg
+// @Produce image/png
+// @Param id path int true "Profile ID"
+// @Param size query int false "Side of the square picture in pixels"
+// @Param v query string false "Picture version, from the profile's picture URLs"
+// @Success 200 {file} file "The picture"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/image [get]
+func (ctrl *ProfileController) GetProfileImage(c *gin.Context) {
+	size := media.DefaultAvatarSize
+	if value := c.Query("size"); value != "" {
+		size, _ = strconv.Atoi(value)
+		if !slices.Contains(media.AvatarSizes, size) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be one of %v", media.AvatarSizes)})
+			return
+		}
+	}
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	if profile.PictureID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile picture not found"})
 		return
 	}
 
-	if err := models.UpdateProfilePicture(userId, filename); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
+	// A versioned URL always names the same picture; an unversioned one changes
+	// with the next upload
+	headers := map[string]string{
+		"Cache-Control":          "public, max-age=300",
+		"ETag":                   fmt.Sprintf(`"%s-%d"`, profile.PictureID, size),
+		"X-Content-Type-Options": "nosniff",
+	}
+	if c.Query("v") == pictureVersion(profile.PictureID) {
+		headers["Cache-Control"] = "public, max-age=31536000, immutable"
+	}
+	if c.GetHeader("If-None-Match") == headers["ETag"] {
+		for name, value := range headers {
+			c.Header(name, value)
+		}
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Profile picture updated successfully",
-		"filename": filename,
-	})
+	blob, err := ctrl.Blobs.Get(c.Request.Context(), pictureKey(profile.PictureID, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile picture not found"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to read profile picture:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile picture"})
+		return
+	}
+	defer blob.Close()
+	c.DataFromReader(http.StatusOK, blob.Size, blob.ContentType, blob, headers)
 }
 
-// GetProfileImage serves the profile image for a given user.
-// @Summary Get Profile Image
-// @Description Serve the profile image for the given user.
-// @Tags Profile
-// @Produce image/*
-// @Param userId path string true "User ID"
-// @Success 200 {file} file "Returns the profile image"
-// @Failure 404 {object} map[string]interface{} "Profile or image not found"
-// @Router /api/profiles/{userId}/image [get]
-func GetProfileImage(c *gin.Context) {
-	userId := c.Param("userId")
+// @Summary Remove a profile picture
+// @Description Remove a profile's picture (only the owner or an admin can remove it)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/image [delete]
+func (ctrl *ProfileController) DeleteProfileImage(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
 
-	profile, err := models.GetProfileByID(userId)
-	if err != nil || profile == nil || profile.ProfilePicture == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile or image not found"})
+	previous, err := ctrl.Profiles.SetPicture(c.Request.Context(), profile.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove profile picture"})
 		return
 	}
+	ctrl.releasePicture(c.Request.Context(), previous)
 
-	imagePath := filepath.Join("uploads", profile.ProfilePicture)
+	c.JSON(http.StatusOK, gin.H{"message": "Profile picture removed"})
+}
 
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		return
+// pictureKey is the blob key of a picture's variant
+func pictureKey(pictureID string, size int) string {
+	return fmt.Sprintf("profile-pictures/%s/%d", pictureID, size)
+}
+
+// pictureVersion is the version a picture's URLs carry
+func pictureVersion(pictureID string) string {
+	return pictureID[:12]
+}
+
+// attachPictures fills the picture URLs of each profile that has a picture
+func attachPictures(profiles []models.Profile) {
+	for i := range profiles {
+		profile := &profiles[i]
+		if profile.PictureID == "" {
+			continue
+		}
+		profile.ProfilePictures = make(map[string]string, len(media.AvatarSizes))
+		for _, size := range media.AvatarSizes {
+			url := fmt.Sprintf("/api/profiles/%d/image?size=%d&v=%s", profile.ID, size, pictureVersion(profile.PictureID))
+			profile.ProfilePictures[strconv.Itoa(size)] = url
+			if size == media.DefaultAvatarSize {
+				profile.ProfilePicture = url
+			}
+		}
 	}
+}
 
-	c.File(imagePath)
+// releasePicture deletes a picture's variants once no profile shows it. The same
+// upload may be shared by several profiles, as variants are content-addressed.
+func (ctrl *ProfileController) releasePicture(ctx context.Context, pictureID string) {
+	if pictureID == "" {
+		return
+	}
+	inUse, err := ctrl.Profiles.PictureInUse(ctx, pictureID)
+	if err != nil || inUse {
+		return
+	}
+	for _, size := range media.AvatarSizes {
+		if err := ctrl.Blobs.Delete(ctx, pictureKey(pictureID, size)); err != nil {
+			log.Println("❌ Failed to delete profile picture:", err)
+		}
+	}
 }
-*/
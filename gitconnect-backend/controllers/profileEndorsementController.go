@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List a skill's endorsements
+// @Description Fetch a page of the users who endorsed a skill on a profile, most recent first by default
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Skill ID"
+// @Param limit query int false "Page size (max 100)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{entryId}/endorsements [get]
+func (ctrl *ProfileController) GetSkillEndorsements(c *gin.Context) {
+	page, ok := parsePage(c, repository.SortNewest)
+	if !ok {
+		return
+	}
+	_, skill, ok := ctrl.findSkill(c)
+	if !ok {
+		return
+	}
+
+	endorsements, next, err := ctrl.Endorsements.List(c.Request.Context(), skill.ID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch endorsements"})
+		return
+	}
+
+	users := make([]gin.H, len(endorsements))
+	for i, endorsement := range endorsements {
+		users[i] = gin.H{"id": endorsement.EndorserID, "username": endorsement.Endorser.Username, "endorsed_at": endorsement.CreatedAt}
+	}
+	respondPage(c, "endorsements", users, next)
+}
+
+// @Summary Endorse a skill
+// @Description Vouch for a skill on someone else's profile. Only users who follow each other can endorse each other's skills. Endorsing a skill again is a no-op; the profile's owner is notified of a new endorsement.
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Skill ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{entryId}/endorsements [post]
+func (ctrl *ProfileController) EndorseSkill(c *gin.Context) {
+	profile, skill, ok := ctrl.findSkill(c)
+	if !ok {
+		return
+	}
+	callerID := c.GetUint("user_id")
+	if profile.UserID == callerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot endorse your own skills"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	connected, err := ctrl.connected(ctx, callerID, profile.UserID)
+	if err != nil {
+		log.Println("❌ Failed to check connection:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to endorse skill"})
+		return
+	}
+	if !connected {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only endorse the skills of users you follow who follow you back"})
+		return
+	}
+
+	created, err := ctrl.Endorsements.Endorse(ctx, skill.ID, callerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to endorse skill"})
+		return
+	}
+	if created {
+		ctrl.Notify.Endorse(ctx, callerID, profile.UserID, skill.ID)
+	}
+
+	ctrl.respondEndorsements(c, skill, "Skill endorsed")
+}
+
+// @Summary Withdraw an endorsement
+// @Description Remove the caller's endorsement of a skill. Withdrawing an endorsement that was never given is a no-op.
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Skill ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{entryId}/endorsements [delete]
+func (ctrl *ProfileController) UnendorseSkill(c *gin.Context) {
+	_, skill, ok := ctrl.findSkill(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Endorsements.Unendorse(c.Request.Context(), skill.ID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw endorsement"})
+		return
+	}
+
+	ctrl.respondEndorsements(c, skill, "Endorsement withdrawn")
+}
+
+// findSkill loads the profile addressed by ":id" and its skill addressed by
+// ":entryId", writing the error response itself
+func (ctrl *ProfileController) findSkill(c *gin.Context) (models.Profile, models.Skill, bool) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return profile, models.Skill{}, false
+	}
+	id, ok := entryIDParam(c, skillSection)
+	if !ok {
+		return profile, models.Skill{}, false
+	}
+
+	skills, err := ctrl.Skills.List(c.Request.Context(), profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skill"})
+		return profile, models.Skill{}, false
+	}
+	for _, skill := range skills {
+		if skill.ID == id {
+			return profile, skill, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+	return profile, models.Skill{}, false
+}
+
+// connected reports whether two users follow each other and no block stands between them
+func (ctrl *ProfileController) connected(ctx context.Context, userID, otherID uint) (bool, error) {
+	for _, pair := range [][2]uint{{userID, otherID}, {otherID, userID}} {
+		followers, err := ctrl.Follows.FollowersAmong(ctx, pair[1], []uint{pair[0]})
+		if err != nil || !followers[pair[0]] {
+			return false, err
+		}
+		blockers, err := ctrl.Blocks.BlockersOf(ctx, pair[0], []uint{pair[1]})
+		if err != nil || blockers[pair[1]] {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// respondEndorsements answers an endorsement change with the skill's fresh summary
+func (ctrl *ProfileController) respondEndorsements(c *gin.Context, skill models.Skill, message string) {
+	skills := []models.Skill{skill}
+	if err := ctrl.attachEndorsements(c, skills); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count endorsements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "skill": skills[0]})
+}
+
+// attachEndorsements fills each skill's endorsement summary, as seen by the caller
+func (ctrl *ProfileController) attachEndorsements(c *gin.Context, skills []models.Skill) error {
+	if len(skills) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(skills))
+	for i, skill := range skills {
+		ids[i] = skill.ID
+	}
+
+	summaries, err := ctrl.Endorsements.Summaries(c.Request.Context(), ids, c.GetUint("user_id"))
+	if err != nil {
+		return err
+	}
+	for i := range skills {
+		summary := summaries[skills[i].ID]
+		skills[i].Endorsements = &summary
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// repoSyncInterval is how long fetched repositories are served before they are fetched again
+	repoSyncInterval = time.Hour
+	// repoFetchTimeout bounds the GitHub requests of one sync
+	repoFetchTimeout = 20 * time.Second
+	// maxLanguageFetches caps the languages requests of one sync; the other
+	// repositories get their languages on later syncs
+	maxLanguageFetches = 20
+	// repoRetryDelay is how long a profile waits after a failed fetch before the
+	// next; it doubles with each failure in a row, up to repoSyncInterval
+	repoRetryDelay = time.Minute
+)
+
+// Reasons a fetch failed, recorded so that later requests can answer without asking GitHub again
+const (
+	repoSyncNotFound    = "not_found"   // GitHub has no such user
+	repoSyncUnavailable = "unavailable" // GitHub could not be reached or answered with an error
+)
+
+// errRepoSyncBackoff is returned while a profile waits to retry a failed fetch
+var errRepoSyncBackoff = errors.New("fetching from GitHub failed recently; waiting to retry")
+
+// pinRequest lists the repository IDs to pin, in display order
+type pinRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// @Summary List a profile's GitHub repositories
+// @Description Fetch the public repositories of the profile's GitHub account. They are cached and fetched again from GitHub at most hourly; if GitHub cannot be reached, the cached ones are served and fetching is retried with backoff.
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param sort query string false "pushed (default) or stars"
+// @Param pinned query bool false "Only the pinned repositories, in pin order"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/profiles/{id}/repos [get]
+func (ctrl *ProfileController) GetProfileRepos(c *gin.Context) {
+	sort := repository.RepoSort(c.DefaultQuery("sort", string(repository.RepoSortPushed)))
+	if !sort.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be pushed or stars"})
+		return
+	}
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	login := profile.GithubHandle()
+	if login == "" {
+		c.JSON(http.StatusOK, gin.H{"login": nil, "synced_at": nil, "repos": []models.GithubRepo{}})
+		return
+	}
+
+	state, err := ctrl.syncRepos(c.Request.Context(), profile.ID, login, false)
+	if err != nil {
+		if !errors.Is(err, errRepoSyncBackoff) {
+			log.Println("❌ Failed to sync GitHub repositories:", err)
+		}
+		// Serve the cache unless there is none or it belongs to another account
+		if state.SyncedAt == nil || !strings.EqualFold(state.Login, login) {
+			githubError(c, err)
+			return
+		}
+	}
+	ctrl.respondRepos(c, profile.ID, state, repository.GithubRepoFilter{Pinned: c.Query("pinned") == "true", Sort: sort})
+}
+
+// @Summary Refresh a profile's GitHub repositories
+// @Description Fetch the profile's repositories from GitHub now (only the owner or an admin can refresh)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/profiles/{id}/repos/sync [post]
+func (ctrl *ProfileController) SyncProfileRepos(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	login := profile.GithubHandle()
+	if login == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profile has no GitHub username"})
+		return
+	}
+
+	state, err := ctrl.syncRepos(c.Request.Context(), profile.ID, login, true)
+	if err != nil {
+		log.Println("❌ Failed to sync GitHub repositories:", err)
+		githubError(c, err)
+		return
+	}
+	ctrl.respondRepos(c, profile.ID, state, repository.GithubRepoFilter{})
+}
+
+// @Summary Pin GitHub repositories
+// @Description Pin up to 6 of the profile's repositories, in display order; the others are unpinned (only the owner or an admin can pin)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param pins body pinRequest true "Repository IDs to pin, in display order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/repos/pins [put]
+func (ctrl *ProfileController) PinProfileRepos(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	var request pinRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.IDs) > models.MaxPinnedRepos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d repositories can be pinned", models.MaxPinnedRepos)})
+		return
+	}
+	seen := make(map[uint]bool, len(request.IDs))
+	for _, id := range request.IDs {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not repeat a repository"})
+			return
+		}
+		seen[id] = true
+	}
+
+	err := ctrl.GithubRepos.Pin(c.Request.Context(), profile.ID, request.IDs)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must only list this profile's repositories"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin repositories"})
+		return
+	}
+
+	pinned, err := ctrl.GithubRepos.List(c.Request.Context(), repository.GithubRepoFilter{ProfileIDs: []uint{profile.ID}, Pinned: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repositories"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pinned repositories saved", "repos": pinned})
+}
+
+// respondRepos responds with the profile's cached repositories
+func (ctrl *ProfileController) respondRepos(c *gin.Context, profileID uint, state models.GithubRepoSync, filter repository.GithubRepoFilter) {
+	filter.ProfileIDs = []uint{profileID}
+	repos, err := ctrl.GithubRepos.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repositories"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"login": state.Login, "synced_at": state.SyncedAt, "repos": repos})
+}
+
+// githubError responds to a failed fetch from GitHub
+func githubError(c *gin.Context, err error) {
+	if errors.Is(err, github.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "GitHub user not found"})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch repositories from GitHub"})
+}
+
+// syncRepos fetches the repositories of login for a profile unless they were
+// fetched within repoSyncInterval, and returns the state of the cache. Concurrent
+// syncs of a profile share one fetch. Unless force is set, a profile whose last
+// fetch failed is not fetched again until its backoff has passed. On error the
+// previous state is returned.
+func (ctrl *ProfileController) syncRepos(ctx context.Context, profileID uint, login string, force bool) (models.GithubRepoSync, error) {
+	key := fmt.Sprintf("%d:%s:%t", profileID, strings.ToLower(login), force)
+	// The fetch is shared by every waiting request, so it outlives the one that started it
+	result := ctrl.repoSyncs.DoChan(key, func() (interface{}, error) {
+		return ctrl.fetchRepos(context.WithoutCancel(ctx), profileID, login, force)
+	})
+	select {
+	case res := <-result:
+		return res.Val.(models.GithubRepoSync), res.Err
+	case <-ctx.Done():
+		return models.GithubRepoSync{}, ctx.Err()
+	}
+}
+
+// fetchRepos does the work of syncRepos. The fetch is conditional on the previous
+// response's ETag, and languages are only fetched again for repositories pushed to since.
+func (ctrl *ProfileController) fetchRepos(ctx context.Context, profileID uint, login string, force bool) (models.GithubRepoSync, error) {
+	state, err := ctrl.GithubRepos.SyncState(ctx, profileID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return state, err
+	}
+	sameAccount := strings.EqualFold(state.Login, login)
+	if !force && sameAccount && state.SyncedAt != nil && time.Since(*state.SyncedAt) < repoSyncInterval {
+		return state, nil
+	}
+	if !force && state.Failures > 0 && state.CheckedAt != nil && time.Since(*state.CheckedAt) < repoRetryBackoff(state.Failures) {
+		if state.LastError == repoSyncNotFound {
+			return state, fmt.Errorf("%w: %w", errRepoSyncBackoff, github.ErrNotFound)
+		}
+		return state, errRepoSyncBackoff
+	}
+
+	known := map[int64]models.GithubRepo{}
+	etag := ""
+	if sameAccount {
+		cached, err := ctrl.GithubRepos.List(ctx, repository.GithubRepoFilter{ProfileIDs: []uint{profileID}})
+		if err != nil {
+			return state, err
+		}
+		etag = state.ETag
+		for _, repo := range cached {
+			known[repo.GithubID] = repo
+			if repo.Languages == nil {
+				etag = "" // Fetch in full to catch up on the missing languages
+			}
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, repoFetchTimeout)
+	defer cancel()
+	fetched, etag, notModified, err := ctrl.GitHub.UserRepos(fetchCtx, login, etag)
+	now := time.Now()
+	if err != nil {
+		reason := repoSyncUnavailable
+		if errors.Is(err, github.ErrNotFound) {
+			reason = repoSyncNotFound
+		}
+		if err := ctrl.GithubRepos.RecordFailure(ctx, profileID, login, now, reason); err != nil {
+			log.Println("❌ Failed to record GitHub repository sync failure:", err)
+		}
+		return state, err
+	}
+	if notModified {
+		if err := ctrl.GithubRepos.Touch(ctx, profileID, now); err != nil {
+			return state, err
+		}
+		state.SyncedAt, state.CheckedAt = &now, &now
+		state.Failures, state.LastError = 0, ""
+		return state, nil
+	}
+
+	repos := make([]models.GithubRepo, len(fetched))
+	languageFetches := 0
+	for i, repo := range fetched {
+		repos[i] = models.GithubRepo{
+			GithubID: repo.ID, Name: repo.Name, FullName: repo.FullName, Description: repo.Description,
+			URL: repo.HTMLURL, Homepage: repo.Homepage, Language: repo.Language, Topics: repo.Topics,
+			Stars: repo.StargazersCount, Forks: repo.ForksCount, Fork: repo.Fork, Archived: repo.Archived, PushedAt: repo.PushedAt,
+		}
+		// Languages only change with a push
+		if previous, ok := known[repo.ID]; ok && previous.Languages != nil && samePush(previous.PushedAt, repo.PushedAt) {
+			repos[i].Languages = previous.Languages
+			continue
+		}
+		if languageFetches == maxLanguageFetches || fetchCtx.Err() != nil {
+			continue
+		}
+		languageFetches++
+		repos[i].Languages, err = ctrl.GitHub.Languages(fetchCtx, repo.FullName)
+		switch {
+		case errors.Is(err, github.ErrNotFound):
+			repos[i].Languages = map[string]int64{} // Not worth asking again
+		case err != nil:
+			log.Println("❌ Failed to fetch repository languages:", err)
+		}
+	}
+
+	synced := models.GithubRepoSync{ProfileID: profileID, Login: login, ETag: etag, SyncedAt: &now, CheckedAt: &now}
+	if err := ctrl.GithubRepos.Replace(ctx, synced, repos); err != nil {
+		return state, err
+	}
+	return synced, nil
+}
+
+// repoRetryBackoff returns how long to wait after failures fetches failed in a row
+func repoRetryBackoff(failures int) time.Duration {
+	return min(repoRetryDelay<<min(failures-1, 10), repoSyncInterval)
+}
+
+// samePush reports whether two push times are the same, either being unknown
+func samePush(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
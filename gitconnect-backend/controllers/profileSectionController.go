@@ -0,0 +1,686 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// profileSection describes a section of profiles for the responses about it
+type profileSection struct {
+	key    string // include= name and key of the entry list, e.g. "skills"
+	field  string // Key of a single entry, e.g. "skill"
+	title  string // An entry in messages, e.g. "Skill"
+	plural string // Entries in messages, e.g. "skills"
+	limit  int
+}
+
+var (
+	skillSection      = profileSection{key: "skills", field: "skill", title: "Skill", plural: "skills", limit: models.MaxSkills}
+	experienceSection = profileSection{key: "experience", field: "experience", title: "Experience entry", plural: "experience entries", limit: models.MaxExperiences}
+	educationSection  = profileSection{key: "education", field: "education", title: "Education entry", plural: "education entries", limit: models.MaxEducations}
+	linkSection       = profileSection{key: "links", field: "link", title: "Link", plural: "links", limit: models.MaxLinks}
+)
+
+// parseInclude reads include=, a comma-separated list of the sections to fill in
+// (skills, experience, education, links and pinned_repos), responding with 400 if it names another
+func parseInclude(c *gin.Context) (map[string]bool, bool) {
+	include := map[string]bool{}
+	for _, key := range strings.Split(c.Query("include"), ",") {
+		key = strings.TrimSpace(key)
+		switch key {
+		case "":
+		case skillSection.key, experienceSection.key, educationSection.key, linkSection.key, "pinned_repos":
+			include[key] = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include must list skills, experience, education, links or pinned_repos"})
+			return nil, false
+		}
+	}
+	return include, true
+}
+
+// attachSections fills the included sections of each profile
+func (ctrl *ProfileController) attachSections(c *gin.Context, profiles []models.Profile, include map[string]bool) error {
+	if len(profiles) == 0 || len(include) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(profiles))
+	for i, profile := range profiles {
+		ids[i] = profile.ID
+	}
+	ctx := c.Request.Context()
+
+	if include[skillSection.key] {
+		skills, err := ctrl.Skills.List(ctx, ids...)
+		if err != nil {
+			return err
+		}
+		if err := ctrl.attachEndorsements(c, skills); err != nil {
+			return err
+		}
+		byProfile := make(map[uint][]models.Skill, len(ids))
+		for _, skill := range skills {
+			byProfile[skill.ProfileID] = append(byProfile[skill.ProfileID], skill)
+		}
+		for i := range profiles {
+			profiles[i].Skills = byProfile[profiles[i].ID]
+		}
+	}
+	if include[experienceSection.key] {
+		experiences, err := entriesByProfile(ctx, ctrl.Experiences, ids)
+		if err != nil {
+			return err
+		}
+		for i := range profiles {
+			profiles[i].Experience = experiences[profiles[i].ID]
+		}
+	}
+	if include[educationSection.key] {
+		educations, err := entriesByProfile(ctx, ctrl.Educations, ids)
+		if err != nil {
+			return err
+		}
+		for i := range profiles {
+			profiles[i].Education = educations[profiles[i].ID]
+		}
+	}
+	if include[linkSection.key] {
+		links, err := entriesByProfile(ctx, ctrl.Links, ids)
+		if err != nil {
+			return err
+		}
+		for i := range profiles {
+			profiles[i].Links = links[profiles[i].ID]
+		}
+	}
+	if include["pinned_repos"] {
+		pinned, err := ctrl.GithubRepos.List(ctx, repository.GithubRepoFilter{ProfileIDs: ids, Pinned: true})
+		if err != nil {
+			return err
+		}
+		byProfile := make(map[uint][]models.GithubRepo, len(ids))
+		for _, repo := range pinned {
+			byProfile[repo.ProfileID] = append(byProfile[repo.ProfileID], repo)
+		}
+		for i := range profiles {
+			profiles[i].PinnedRepos = byProfile[profiles[i].ID]
+		}
+	}
+	return nil
+}
+
+// entriesByProfile lists the entries of the profiles' section, grouped by profile
+func entriesByProfile[T any, P models.ProfileEntry[T]](ctx context.Context, entries repository.SectionRepository[T], profileIDs []uint) (map[uint][]T, error) {
+	list, err := entries.List(ctx, profileIDs...)
+	if err != nil {
+		return nil, err
+	}
+	byProfile := make(map[uint][]T, len(profileIDs))
+	for _, entry := range list {
+		profileID := P(&entry).Item().ProfileID
+		byProfile[profileID] = append(byProfile[profileID], entry)
+	}
+	return byProfile, nil
+}
+
+// reorderRequest lists entry IDs in the order they should be shown
+type reorderRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// @Summary List a profile's skills
+// @Description Fetch the skills of a profile, in the order the owner set. Each skill has its endorsement count, whether the caller endorsed it and the endorsers the caller follows.
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills [get]
+func (ctrl *ProfileController) GetSkills(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+
+	skills, err := ctrl.Skills.List(c.Request.Context(), profile.ID)
+	if err == nil {
+		err = ctrl.attachEndorsements(c, skills)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{skillSection.key: skills})
+}
+
+// @Summary Add a skill
+// @Description Append an entry to a profile's skills (only the owner or an admin can add). A profile lists at most 50 skills.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entry body models.Skill true "Skill"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills [post]
+func (ctrl *ProfileController) AddSkill(c *gin.Context) {
+	createEntry(ctrl, c, ctrl.Skills, skillSection)
+}
+
+// @Summary Update a skill
+// @Description Update an entry of a profile's skills (only the owner or an admin can update). Endorsements vouch for the skill's name, so renaming the skill withdraws them; changing only its case does not.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Skill ID"
+// @Param entry body models.Skill true "Skill"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{entryId} [put]
+func (ctrl *ProfileController) UpdateSkill(c *gin.Context) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	id, ok := entryIDParam(c, skillSection)
+	if !ok {
+		return
+	}
+	var skill models.Skill
+	if !bindEntry(c, &skill) {
+		return
+	}
+
+	skills, err := ctrl.Skills.List(c.Request.Context(), profile.ID)
+	if !entryError(c, err, skillSection) {
+		return
+	}
+	i := slices.IndexFunc(skills, func(s models.Skill) bool { return s.ID == id })
+	if i < 0 {
+		entryError(c, repository.ErrNotFound, skillSection)
+		return
+	}
+	renamed := !strings.EqualFold(skills[i].Name, skill.Name)
+
+	skill.ID, skill.ProfileID = id, profile.ID
+	if !entryError(c, ctrl.Skills.Update(c.Request.Context(), &skill), skillSection) {
+		return
+	}
+	if renamed {
+		if err := ctrl.Endorsements.Clear(c.Request.Context(), id); err != nil {
+			log.Println("❌ Failed to withdraw the endorsements of a renamed skill:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save skill"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": skillSection.title + " updated", skillSection.field: skill})
+}
+
+// @Summary Delete a skill
+// @Description Remove an entry from a profile's skills (only the owner or an admin can delete)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Skill ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{entryId} [delete]
+func (ctrl *ProfileController) DeleteSkill(c *gin.Context) {
+	deleteEntry(ctrl, c, ctrl.Skills, skillSection)
+}
+
+// @Summary Reorder a profile's skills
+// @Description Show the listed skills first, in the given order; the others follow in their current order (only the owner or an admin can reorder)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param order body reorderRequest true "Skill IDs in display order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/order [put]
+func (ctrl *ProfileController) ReorderSkills(c *gin.Context) {
+	reorderEntries(ctrl, c, ctrl.Skills, skillSection)
+}
+
+// @Summary List a profile's experience entries
+// @Description Fetch the experience entries of a profile, in the order the owner set
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/experience [get]
+func (ctrl *ProfileController) GetExperience(c *gin.Context) {
+	listEntries(ctrl, c, ctrl.Experiences, experienceSection)
+}
+
+// @Summary Add a experience entry
+// @Description Append an entry to a profile's experience entries (only the owner or an admin can add). A profile lists at most 30 experience entries.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entry body models.Experience true "Experience entry"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/experience [post]
+func (ctrl *ProfileController) AddExperience(c *gin.Context) {
+	createEntry(ctrl, c, ctrl.Experiences, experienceSection)
+}
+
+// @Summary Update a experience entry
+// @Description Update an entry of a profile's experience entries (only the owner or an admin can update)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Experience entry ID"
+// @Param entry body models.Experience true "Experience entry"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/experience/{entryId} [put]
+func (ctrl *ProfileController) UpdateExperience(c *gin.Context) {
+	updateEntry(ctrl, c, ctrl.Experiences, experienceSection)
+}
+
+// @Summary Delete a experience entry
+// @Description Remove an entry from a profile's experience entries (only the owner or an admin can delete)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Experience entry ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/experience/{entryId} [delete]
+func (ctrl *ProfileController) DeleteExperience(c *gin.Context) {
+	deleteEntry(ctrl, c, ctrl.Experiences, experienceSection)
+}
+
+// @Summary Reorder a profile's experience entries
+// @Description Show the listed experience entries first, in the given order; the others follow in their current order (only the owner or an admin can reorder)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param order body reorderRequest true "Experience entry IDs in display order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/experience/order [put]
+func (ctrl *ProfileController) ReorderExperience(c *gin.Context) {
+	reorderEntries(ctrl, c, ctrl.Experiences, experienceSection)
+}
+
+// @Summary List a profile's education entries
+// @Description Fetch the education entries of a profile, in the order the owner set
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/education [get]
+func (ctrl *ProfileController) GetEducation(c *gin.Context) {
+	listEntries(ctrl, c, ctrl.Educations, educationSection)
+}
+
+// @Summary Add a education entry
+// @Description Append an entry to a profile's education entries (only the owner or an admin can add). A profile lists at most 20 education entries.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entry body models.Education true "Education entry"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/education [post]
+func (ctrl *ProfileController) AddEducation(c *gin.Context) {
+	createEntry(ctrl, c, ctrl.Educations, educationSection)
+}
+
+// @Summary Update a education entry
+// @Description Update an entry of a profile's education entries (only the owner or an admin can update)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Education entry ID"
+// @Param entry body models.Education true "Education entry"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/education/{entryId} [put]
+func (ctrl *ProfileController) UpdateEducation(c *gin.Context) {
+	updateEntry(ctrl, c, ctrl.Educations, educationSection)
+}
+
+// @Summary Delete a education entry
+// @Description Remove an entry from a profile's education entries (only the owner or an admin can delete)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Education entry ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/education/{entryId} [delete]
+func (ctrl *ProfileController) DeleteEducation(c *gin.Context) {
+	deleteEntry(ctrl, c, ctrl.Educations, educationSection)
+}
+
+// @Summary Reorder a profile's education entries
+// @Description Show the listed education entries first, in the given order; the others follow in their current order (only the owner or an admin can reorder)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param order body reorderRequest true "Education entry IDs in display order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/education/order [put]
+func (ctrl *ProfileController) ReorderEducation(c *gin.Context) {
+	reorderEntries(ctrl, c, ctrl.Educations, educationSection)
+}
+
+// @Summary List a profile's links
+// @Description Fetch the links of a profile, in the order the owner set
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/links [get]
+func (ctrl *ProfileController) GetLinks(c *gin.Context) {
+	listEntries(ctrl, c, ctrl.Links, linkSection)
+}
+
+// @Summary Add a link
+// @Description Append an entry to a profile's links (only the owner or an admin can add). A profile lists at most 10 links.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entry body models.ProfileLink true "Link"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/links [post]
+func (ctrl *ProfileController) AddLink(c *gin.Context) {
+	createEntry(ctrl, c, ctrl.Links, linkSection)
+}
+
+// @Summary Update a link
+// @Description Update an entry of a profile's links (only the owner or an admin can update)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Link ID"
+// @Param entry body models.ProfileLink true "Link"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/links/{entryId} [put]
+func (ctrl *ProfileController) UpdateLink(c *gin.Context) {
+	updateEntry(ctrl, c, ctrl.Links, linkSection)
+}
+
+// @Summary Delete a link
+// @Description Remove an entry from a profile's links (only the owner or an admin can delete)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param entryId path int true "Link ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/links/{entryId} [delete]
+func (ctrl *ProfileController) DeleteLink(c *gin.Context) {
+	deleteEntry(ctrl, c, ctrl.Links, linkSection)
+}
+
+// @Summary Reorder a profile's links
+// @Description Show the listed links first, in the given order; the others follow in their current order (only the owner or an admin can reorder)
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param order body reorderRequest true "Link IDs in display order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/links/order [put]
+func (ctrl *ProfileController) ReorderLinks(c *gin.Context) {
+	reorderEntries(ctrl, c, ctrl.Links, linkSection)
+}
+
+// listEntries responds with the entries of a profile's section
+func listEntries[T any, P models.ProfileEntry[T]](ctrl *ProfileController, c *gin.Context, entries repository.SectionRepository[T], section profileSection) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+
+	list, err := entries.List(c.Request.Context(), profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + section.plural})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{section.key: list})
+}
+
+// createEntry appends the entry in the request body to a profile's section
+func createEntry[T any, P models.ProfileEntry[T]](ctrl *ProfileController, c *gin.Context, entries repository.SectionRepository[T], section profileSection) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	var entry T
+	if !bindEntry[T, P](c, &entry) {
+		return
+	}
+
+	P(&entry).Item().ProfileID = profile.ID
+	err := entries.Create(c.Request.Context(), &entry)
+	if !entryError(c, err, section) {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": section.title + " added", section.field: entry})
+}
+
+// updateEntry saves the entry in the request body over the one addressed by ":entryId"
+func updateEntry[T any, P models.ProfileEntry[T]](ctrl *ProfileController, c *gin.Context, entries repository.SectionRepository[T], section profileSection) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	id, ok := entryIDParam(c, section)
+	if !ok {
+		return
+	}
+	var entry T
+	if !bindEntry[T, P](c, &entry) {
+		return
+	}
+
+	item := P(&entry).Item()
+	item.ID, item.ProfileID = id, profile.ID
+	err := entries.Update(c.Request.Context(), &entry)
+	if !entryError(c, err, section) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": section.title + " updated", section.field: entry})
+}
+
+// deleteEntry removes the entry addressed by ":entryId" from a profile's section
+func deleteEntry[T any, P models.ProfileEntry[T]](ctrl *ProfileController, c *gin.Context, entries repository.SectionRepository[T], section profileSection) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	id, ok := entryIDParam(c, section)
+	if !ok {
+		return
+	}
+
+	err := entries.Delete(c.Request.Context(), profile.ID, id)
+	if !entryError(c, err, section) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": section.title + " deleted"})
+}
+
+// reorderEntries moves the entries listed in the request body to the top of a
+// profile's section and responds with the section in its new order
+func reorderEntries[T any, P models.ProfileEntry[T]](ctrl *ProfileController, c *gin.Context, entries repository.SectionRepository[T], section profileSection) {
+	profile, ok := ctrl.findProfile(c)
+	if !ok {
+		return
+	}
+	var request reorderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := entries.Reorder(c.Request.Context(), profile.ID, request.IDs)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must only list this profile's " + section.plural})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder " + section.plural})
+		return
+	}
+
+	list, err := entries.List(c.Request.Context(), profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + section.plural})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order saved", section.key: list})
+}
+
+// bindEntry reads and validates an entry from the request body, responding with 400 if it is invalid
+func bindEntry[T any, P models.ProfileEntry[T]](c *gin.Context, entry *T) bool {
+	if err := c.ShouldBindJSON(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := P(entry).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// entryError writes the response for an error saving an entry and reports whether there was none
+func entryError(c *gin.Context, err error, section profileSection) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": section.title + " not found"})
+	case errors.Is(err, repository.ErrLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A profile can list at most %d %s", section.limit, section.plural)})
+	case errors.Is(err, repository.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": section.title + " is already listed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save " + section.field})
+	}
+	return false
+}
+
+// entryIDParam parses ":entryId", responding with 400 if it is not an ID
+func entryIDParam(c *gin.Context, section profileSection) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("entryId"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + section.field + " ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
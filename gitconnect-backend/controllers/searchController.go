@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQueryLength bounds the q parameter so one request cannot build a huge tsquery
+const maxSearchQueryLength = 200
+
+// SearchController serves full-text search across posts, profiles and comments
+type SearchController struct {
+	Search repository.SearchRepository
+}
+
+// NewSearchController builds a SearchController from repos
+func NewSearchController(repos repository.Repositories) *SearchController {
+	return &SearchController{Search: repos.Search}
+}
+
+// @Summary Search posts, profiles and comments
+// @Description Full-text search, best matches first. q accepts words, "quoted phrases", OR and -excluded words. Each result has a snippet of HTML-escaped text with the matched terms wrapped in <mark>.
+// @Tags Search
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query"
+// @Param type query string false "Comma-separated types to include: post, profile, comment (default all)"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/search [get]
+func (ctrl *SearchController) SearchAll(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || len(query) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must be between 1 and " + strconv.Itoa(maxSearchQueryLength) + " characters"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	filter := repository.SearchFilter{Query: query, Limit: limit, Offset: offset}
+	if value := c.Query("type"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			searchType := models.SearchType(strings.TrimSpace(name))
+			if !searchType.Valid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type must be a comma-separated list of post, profile and comment"})
+				return
+			}
+			filter.Types = append(filter.Types, searchType)
+		}
+	}
+
+	results, total, err := ctrl.Search.Search(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "total": total})
+}
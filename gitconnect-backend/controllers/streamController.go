@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// streamHeartbeat keeps idle streams from being closed by proxies
+const streamHeartbeat = 25 * time.Second
+
+// maxWatchedPosts bounds how many posts one stream can watch
+const maxWatchedPosts = 50
+
+// StreamController serves the event streams that push new activity to clients:
+// posts in the caller's feed, comments and reaction counts of the posts they are
+// viewing, their notifications and their messages
+type StreamController struct {
+	Follows repository.FollowRepository
+	Tags    repository.TagRepository
+	Events  *realtime.Hub
+}
+
+// NewStreamController builds a StreamController from repos, streaming from events
+func NewStreamController(repos repository.Repositories, events *realtime.Hub) *StreamController {
+	return &StreamController{Follows: repos.Follows, Tags: repos.Tags, Events: events}
+}
+
+// streamCommand is a message a WebSocket client sends to change the posts it watches
+type streamCommand struct {
+	Action string `json:"action"` // watch or unwatch
+	PostID uint   `json:"post_id"`
+}
+
+// @Summary Get a stream ticket
+// @Description Returns a ticket that opens an event stream within a minute, for browsers whose EventSource and WebSocket cannot send the Authorization header. Pass it as the ticket query parameter. The ticket stops working when the session that requested it is revoked.
+// @Tags Stream
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/stream/ticket [post]
+func (ctrl *StreamController) CreateTicket(c *gin.Context) {
+	// SessionOnly guarantees the caller is a login session with claims
+	claims := c.MustGet("claims").(*utils.Claims)
+	ticket, err := utils.GenerateStreamTicket(claims.UserID, claims.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_in": int(utils.StreamTicketTTL.Seconds())})
+}
+
+// @Summary Stream events (Server-Sent Events)
+// @Description Pushes post.created for posts in the caller's feed, comment.created and reactions.updated for the watched posts, notification.created for the caller's notifications, and message.created and conversation.read for the caller's conversations (tokens need the messages:read scope for those). Each event's data is {"type","id","data"}; data is left out and truncated set when it was too large to relay, so fetch the resource by id. Follows made after connecting apply on the next connection.
+// @Tags Stream
+// @Produce text/event-stream
+// @Param ticket query string false "Ticket from /api/stream/ticket, instead of the Authorization header"
+// @Param watch query string false "Comma-separated IDs of the posts being viewed (max 50)"
+// @Security BearerAuth
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/stream [get]
+func (ctrl *StreamController) StreamSSE(c *gin.Context) {
+	sub, ok := ctrl.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Tell nginx not to buffer the stream
+	c.Status(http.StatusOK)
+	c.Writer.Flush() // Send the headers now rather than with the first event
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, open := <-sub.Events():
+			if !open {
+				return false
+			}
+			event.Topics = nil
+			c.SSEvent(event.Type, event)
+		case <-heartbeat.C:
+			_, _ = io.WriteString(w, ": ping\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
+
+// @Summary Stream events (WebSocket)
+// @Description The events of /api/stream as JSON messages over a WebSocket. Send {"action":"watch","post_id":1} or {"action":"unwatch","post_id":1} to change the watched posts. {"type":"ping"} is sent when idle.
+// @Tags Stream
+// @Param ticket query string false "Ticket from /api/stream/ticket, instead of the Authorization header"
+// @Param watch query string false "Comma-separated IDs of the posts being viewed (max 50)"
+// @Security BearerAuth
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/stream/ws [get]
+func (ctrl *StreamController) StreamWebSocket(c *gin.Context) {
+	sub, ok := ctrl.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	server := websocket.Server{
+		// Tickets and headers authenticate the stream, not cookies, so any origin may connect
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			go ctrl.readCommands(ws, sub)
+
+			heartbeat := time.NewTicker(streamHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case event, open := <-sub.Events():
+					if !open {
+						return
+					}
+					event.Topics = nil
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					if err := websocket.JSON.Send(ws, gin.H{"type": "ping"}); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// readCommands applies the client's watch and unwatch commands until the
+// connection closes, then closes the subscription. Up to maxWatchedPosts posts
+// can be watched this way, on top of those in the watch query parameter.
+func (ctrl *StreamController) readCommands(ws *websocket.Conn, sub *realtime.Subscription) {
+	defer sub.Close()
+
+	watched := map[uint]bool{}
+	for {
+		var command streamCommand
+		if err := websocket.JSON.Receive(ws, &command); err != nil {
+			return
+		}
+		switch {
+		case command.PostID == 0:
+		case command.Action == "watch" && len(watched) < maxWatchedPosts:
+			watched[command.PostID] = true
+			sub.Watch(realtime.PostTopic(command.PostID))
+		case command.Action == "unwatch":
+			delete(watched, command.PostID)
+			sub.Unwatch(realtime.PostTopic(command.PostID))
+		}
+	}
+}
+
+// subscribe subscribes to the caller's topics and the posts in the watch query
+// parameter, responding with an error if it cannot
+func (ctrl *StreamController) subscribe(c *gin.Context) (*realtime.Subscription, bool) {
+	userID := c.GetUint("user_id")
+	topics := []string{realtime.UserTopic(userID), realtime.AuthorTopic(userID)}
+	if middlewares.HasScope(c, models.ScopeMessagesRead) {
+		topics = append(topics, realtime.InboxTopic(userID))
+	}
+
+	if value := c.Query("watch"); value != "" {
+		ids := strings.Split(value, ",")
+		if len(ids) > maxWatchedPosts {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At most " + strconv.Itoa(maxWatchedPosts) + " posts can be watched"})
+			return nil, false
+		}
+		for _, id := range ids {
+			postID, err := strconv.Atoi(strings.TrimSpace(id))
+			if err != nil || postID < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "watch must be a comma-separated list of post IDs"})
+				return nil, false
+			}
+			topics = append(topics, realtime.PostTopic(uint(postID)))
+		}
+	}
+
+	// The feed: posts by the users and hashtags the caller follows
+	following, _, err := ctrl.Follows.Following(c.Request.Context(), userID, repository.Page{})
+	if err != nil {
+		log.Println("❌ Failed to load follows for stream:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stream"})
+		return nil, false
+	}
+	for _, follow := range following {
+		topics = append(topics, realtime.AuthorTopic(follow.FolloweeID))
+	}
+	tags, err := ctrl.Tags.Followed(c.Request.Context(), userID)
+	if err != nil {
+		log.Println("❌ Failed to load followed tags for stream:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stream"})
+		return nil, false
+	}
+	for _, tag := range tags {
+		topics = append(topics, realtime.TagTopic(tag.Name))
+	}
+
+	return ctrl.Events.Subscribe(topics...), true
+}
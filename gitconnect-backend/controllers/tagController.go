@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+// TagController serves hashtags: trending tags and following them. A tag's
+// posts are listed by PostController.GetTagPosts.
+type TagController struct {
+	Tags repository.TagRepository
+}
+
+// NewTagController builds a TagController from repos
+func NewTagController(repos repository.Repositories) *TagController {
+	return &TagController{Tags: repos.Tags}
+}
+
+// @Summary Get trending hashtags
+// @Description Ranks the hashtags used within a sliding window by how many distinct authors, then posts, used them
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param window query string false "Window as a duration such as 6h or 72h (default 24h, max 168h)"
+// @Param limit query int false "Number of tags (default 10, max 50)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/tags/trending [get]
+func (ctrl *TagController) GetTrending(c *gin.Context) {
+	window := defaultTrendingWindow
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < time.Minute || parsed > maxTrendingWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration between 1m and " + maxTrendingWindow.String()})
+			return
+		}
+		window = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultTrendingLimit)))
+	if err != nil || limit < 1 || limit > maxTrendingLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxTrendingLimit)})
+		return
+	}
+
+	tags, err := ctrl.Tags.Trending(c.Request.Context(), time.Now().Add(-window), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags, "window": window.String()})
+}
+
+// @Summary Follow a hashtag
+// @Description Adds the hashtag's posts to the caller's home feed. Following twice is a no-op.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param name path string true "Hashtag, with or without the #"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/tags/{name}/follow [post]
+func (ctrl *TagController) FollowTag(c *gin.Context) {
+	name, ok := tagNameParam(c)
+	if !ok {
+		return
+	}
+
+	tag, err := ctrl.Tags.Follow(c.Request.Context(), c.GetUint("user_id"), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag followed", "tag": tag})
+}
+
+// @Summary Unfollow a hashtag
+// @Description Removes the hashtag's posts from the caller's home feed. Unfollowing a tag not followed is a no-op.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param name path string true "Hashtag, with or without the #"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/tags/{name}/follow [delete]
+func (ctrl *TagController) UnfollowTag(c *gin.Context) {
+	name, ok := tagNameParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.Tags.Unfollow(c.Request.Context(), c.GetUint("user_id"), name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag unfollowed"})
+}
+
+// @Summary List followed hashtags
+// @Description Lists the hashtags the caller follows, by name
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/tags/following [get]
+func (ctrl *TagController) GetFollowedTags(c *gin.Context) {
+	tags, err := ctrl.Tags.Followed(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// tagNameParam reads the ":name" path parameter as a normalized hashtag, responding with 400 if it is invalid
+func tagNameParam(c *gin.Context) (string, bool) {
+	name, ok := models.NormalizeTag(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTokenLifetimeDays applies when a token is created without expires_in_days
+	defaultTokenLifetimeDays = 30
+	// maxTokenLifetimeDays caps how long a personal access token can live
+	maxTokenLifetimeDays = 365
+)
+
+// CreateAccessTokenInput is the body accepted by CreateAccessToken
+type CreateAccessTokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// @Summary Create a personal access token
+// @Description Creates a named, scoped, expiring token for scripts and CI. The token is returned only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body CreateAccessTokenInput true "Token name, scopes and lifetime"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens [post]
+func (ctrl *AuthController) CreateAccessToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreateAccessTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = defaultTokenLifetimeDays
+	}
+	if input.ExpiresInDays < 1 || input.ExpiresInDays > maxTokenLifetimeDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and " + strconv.Itoa(maxTokenLifetimeDays)})
+		return
+	}
+
+	scopes, ok := normalizeScopes(input.Scopes)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope; valid scopes are " + strings.Join(models.TokenScopes, ", ")})
+		return
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	raw := models.PersonalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID.(uint),
+		Name:      input.Name,
+		Prefix:    raw[:len(models.PersonalAccessTokenPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: time.Now().AddDate(0, 0, input.ExpiresInDays),
+	}
+	if err := ctrl.AccessTokens.Create(c.Request.Context(), &token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	response := accessTokenJSON(token)
+	response["token"] = raw
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Token created. Copy it now; it will not be shown again.",
+		"access_token": response,
+	})
+}
+
+// @Summary List personal access tokens
+// @Description Lists the caller's personal access tokens without their secret values
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens [get]
+func (ctrl *AuthController) ListAccessTokens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	tokens, err := ctrl.AccessTokens.List(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	list := make([]gin.H, len(tokens))
+	for i, token := range tokens {
+		list[i] = accessTokenJSON(token)
+	}
+	c.JSON(http.StatusOK, gin.H{"access_tokens": list})
+}
+
+// @Summary Revoke a personal access token
+// @Description Revokes one of the caller's personal access tokens immediately
+// @Tags Auth
+// @Produce json
+// @Param id path int true "Token ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens/{id} [delete]
+func (ctrl *AuthController) RevokeAccessToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	// Scoping the lookup to the caller means other users' tokens are simply not found
+	err = ctrl.AccessTokens.Revoke(c.Request.Context(), userID.(uint), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+// normalizeScopes validates requested scopes and removes duplicates
+func normalizeScopes(requested []string) ([]string, bool) {
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range requested {
+		valid := false
+		for _, known := range models.TokenScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, false
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// accessTokenJSON renders a token for API responses, never including its hash
+func accessTokenJSON(token models.PersonalAccessToken) gin.H {
+	return gin.H{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"revoked_at":   token.RevokedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
@@ -0,0 +1,186 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public GitHub REST API
+const DefaultBaseURL = "https://api.github.com"
+
+// Client is a minimal GitHub REST API client.
+// BaseURL can point at a local stub server during development and tests.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for GITHUB_API_URL (or the public API) that sends
+// requests through httpClient, which is expected to add any authentication
+func NewClient(httpClient *http.Client) *Client {
+	base := os.Getenv("GITHUB_API_URL")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{BaseURL: strings.TrimRight(base, "/"), HTTP: httpClient}
+}
+
+// NewAppClient returns a client for GITHUB_API_URL for requests the server makes
+// on its own behalf. They are authenticated with GITHUB_API_TOKEN if it is set;
+// anonymous requests are limited to 60 an hour per IP address.
+func NewAppClient() *Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if token := os.Getenv("GITHUB_API_TOKEN"); token != "" {
+		client.Transport = tokenTransport{token: token, next: http.DefaultTransport}
+	}
+	return NewClient(client)
+}
+
+// tokenTransport adds a bearer token to each request
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// ErrNotFound is returned when GitHub has no such user or repository
+var ErrNotFound = errors.New("github: not found")
+
+// User is the subset of a GitHub user used by GitConnect
+type User struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// Email is one of the authenticated user's email addresses
+type Email struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// AuthenticatedUser returns the user the client's token belongs to
+func (c *Client) AuthenticatedUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.get(ctx, "/user", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Emails returns the authenticated user's addresses (requires the user:email scope)
+func (c *Client) Emails(ctx context.Context) ([]Email, error) {
+	var emails []Email
+	if err := c.get(ctx, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// PrimaryVerifiedEmail returns the user's primary address if GitHub has verified it
+func (c *Client) PrimaryVerifiedEmail(ctx context.Context) (string, error) {
+	emails, err := c.Emails(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			return email.Email, nil
+		}
+	}
+	return "", nil
+}
+
+// Repo is the subset of a GitHub repository used by GitConnect
+type Repo struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	FullName        string     `json:"full_name"`
+	Description     string     `json:"description"`
+	HTMLURL         string     `json:"html_url"`
+	Homepage        string     `json:"homepage"`
+	Language        string     `json:"language"`
+	Topics          []string   `json:"topics"`
+	StargazersCount int        `json:"stargazers_count"`
+	ForksCount      int        `json:"forks_count"`
+	Fork            bool       `json:"fork"`
+	Archived        bool       `json:"archived"`
+	PushedAt        *time.Time `json:"pushed_at"`
+}
+
+// MaxRepos is how many repositories UserRepos returns: the most recently pushed
+const MaxRepos = 100
+
+// UserRepos returns the public repositories owned by login, most recently pushed
+// first, and the ETag of the response. If etag is the ETag of an earlier response
+// and nothing changed since, notModified is set and no repositories are returned;
+// GitHub does not count such requests against the rate limit.
+func (c *Client) UserRepos(ctx context.Context, login, etag string) (repos []Repo, newETag string, notModified bool, err error) {
+	path := fmt.Sprintf("/users/%s/repos?type=owner&sort=pushed&per_page=%d", url.PathEscape(login), MaxRepos)
+	newETag, notModified, err = c.getConditional(ctx, path, etag, &repos)
+	return repos, newETag, notModified, err
+}
+
+// Languages returns how many bytes of code a repository has in each language
+func (c *Client) Languages(ctx context.Context, fullName string) (map[string]int64, error) {
+	owner, name, _ := strings.Cut(fullName, "/")
+	languages := map[string]int64{}
+	if err := c.get(ctx, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name)+"/languages", &languages); err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// get fetches path and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	_, _, err := c.getConditional(ctx, path, "", out)
+	return err
+}
+
+// getConditional fetches path unless it still matches etag, decodes the JSON
+// response into out and returns the response's ETag
+func (c *Client) getConditional(ctx context.Context, path, etag string, out interface{}) (string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("ETag"), false, json.NewDecoder(resp.Body).Decode(out)
+	case http.StatusNotModified:
+		return etag, true, nil
+	case http.StatusNotFound:
+		return "", false, ErrNotFound
+	}
+	return "", false, fmt.Errorf("github: GET %s returned %s", path, resp.Status)
+}
@@ -0,0 +1,88 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newStub serves the repositories of octocat with a fixed ETag, honouring If-None-Match
+func newStub(t *testing.T) (*Client, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/octocat/repos", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == `W/"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `W/"v1"`)
+		w.Write([]byte(`[{"id": 1, "name": "hello", "full_name": "octocat/hello", "stargazers_count": 3, "pushed_at": "2024-01-01T00:00:00Z"}]`))
+	})
+	mux.HandleFunc("GET /repos/octocat/hello/languages", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Write([]byte(`{"Go": 1200, "Shell": 40}`))
+	})
+	mux.HandleFunc("GET /repos/octocat/broken/languages", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Server Error"}`, http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return &Client{BaseURL: server.URL, HTTP: server.Client()}, &requests
+}
+
+func TestUserReposIsConditional(t *testing.T) {
+	client, requests := newStub(t)
+	ctx := context.Background()
+
+	repos, etag, notModified, err := client.UserRepos(ctx, "octocat", "")
+	if err != nil || notModified {
+		t.Fatalf("first fetch: %v, notModified %v", err, notModified)
+	}
+	if len(repos) != 1 || repos[0].FullName != "octocat/hello" || repos[0].StargazersCount != 3 || repos[0].PushedAt == nil {
+		t.Fatalf("got repos %+v", repos)
+	}
+	if etag != `W/"v1"` {
+		t.Fatalf("got ETag %q", etag)
+	}
+	first := (*requests)[0]
+	if first.Header.Get("If-None-Match") != "" || first.URL.Query().Get("per_page") != "100" || first.URL.Query().Get("sort") != "pushed" {
+		t.Fatalf("first request was %s with If-None-Match %q", first.URL, first.Header.Get("If-None-Match"))
+	}
+
+	// A 304 keeps the ETag and returns no repositories
+	repos, etag, notModified, err = client.UserRepos(ctx, "octocat", etag)
+	if err != nil || !notModified || repos != nil || etag != `W/"v1"` {
+		t.Fatalf("conditional fetch: got %v, %q, %v, %v", repos, etag, notModified, err)
+	}
+	if (*requests)[1].Header.Get("If-None-Match") != `W/"v1"` {
+		t.Fatal("conditional fetch did not send If-None-Match")
+	}
+
+	// A stale ETag gets the full response
+	if _, _, notModified, _ := client.UserRepos(ctx, "octocat", `W/"v0"`); notModified {
+		t.Fatal("stale ETag was reported as not modified")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	client, _ := newStub(t)
+	ctx := context.Background()
+
+	if _, _, _, err := client.UserRepos(ctx, "ghost", ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown user: got %v, want ErrNotFound", err)
+	}
+	if _, err := client.Languages(ctx, "octocat/broken"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("server error: got %v", err)
+	}
+
+	languages, err := client.Languages(ctx, "octocat/hello")
+	if err != nil || languages["Go"] != 1200 || languages["Shell"] != 40 {
+		t.Fatalf("got languages %v, %v", languages, err)
+	}
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each message as an .eml file in Dir, so links can be
+// followed locally without any mail server
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+// Send writes the message to a new file
+func (m FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.Dir, name), compose(m.From, msg), 0o600)
+}
@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as verification and password reset links
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Configure builds the mailer from the environment:
+//
+//	MAIL_DRIVER    smtp, file or log (default)
+//	MAIL_FROM      sender address
+//	SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD   for the smtp driver
+//	MAIL_DIR       output directory for the file driver (default: mail)
+func Configure() (Mailer, error) {
+	var mailer Mailer
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "GitConnect <no-reply@gitconnect.local>"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "log":
+		mailer = LogMailer{From: from}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		mailer = FileMailer{From: from, Dir: dir}
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is not set")
+		}
+		port := 587
+		if value := os.Getenv("SMTP_PORT"); value != "" {
+			var err error
+			if port, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q", value)
+			}
+		}
+		mailer = SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported MAIL_DRIVER %q", driver)
+	}
+
+	log.Printf("📧 Using %T for outgoing mail", mailer)
+	return mailer, nil
+}
+
+// LogMailer writes messages to the application log; useful in development
+type LogMailer struct {
+	From string
+}
+
+// Send logs the message instead of delivering it
+func (m LogMailer) Send(msg Message) error {
+	log.Printf("📧 Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// compose renders msg as an RFC 5322 message
+func compose(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// headerValue strips line breaks so a value cannot inject extra headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
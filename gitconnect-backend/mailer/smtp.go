@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer delivers messages through an SMTP server.
+// STARTTLS is used whenever the server offers it; credentials are optional so
+// a local fake SMTP server can be used during development.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message to the configured SMTP server
+func (m SMTPMailer) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, compose(m.From, msg))
+}
@@ -0,0 +1,170 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP is an SMTP server that accepts one session and records it
+type fakeSMTP struct {
+	addr *net.TCPAddr
+	done chan struct{}
+	auth string // Decoded AUTH PLAIN response, if the client authenticated
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTP listens on a local port; advertiseAuth offers AUTH PLAIN
+func startFakeSMTP(t *testing.T, advertiseAuth bool) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{addr: listener.Addr().(*net.TCPAddr), done: make(chan struct{})}
+	go func() {
+		defer close(server.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		server.serve(conn, advertiseAuth)
+	}()
+	return server
+}
+
+func (s *fakeSMTP) serve(conn net.Conn, advertiseAuth bool) {
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			if advertiseAuth {
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 localhost")
+			}
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.auth = string(decoded)
+			reply("235 Authenticated")
+		case "MAIL":
+			s.from = line
+			reply("250 OK")
+		case "RCPT":
+			s.to = append(s.to, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data = data.String()
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerDeliversMessage(t *testing.T) {
+	server := startFakeSMTP(t, false)
+	m := SMTPMailer{Host: "127.0.0.1", Port: server.addr.Port, From: "GitConnect <no-reply@gitconnect.test>"}
+
+	err := m.Send(Message{To: "Octo Cat <octocat@example.com>", Subject: "Verify your email", Body: "Follow the link"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "MAIL FROM:<no-reply@gitconnect.test>" {
+		t.Errorf("got %q, want the bare sender address", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "RCPT TO:<octocat@example.com>" {
+		t.Errorf("got recipients %q", server.to)
+	}
+	if server.auth != "" {
+		t.Error("authenticated without credentials")
+	}
+	for _, want := range []string{
+		"From: GitConnect <no-reply@gitconnect.test>\r\n",
+		"To: Octo Cat <octocat@example.com>\r\n",
+		"Subject: Verify your email\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\nFollow the link\r\n",
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("message is missing %q:\n%s", want, server.data)
+		}
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	server := startFakeSMTP(t, true)
+	m := SMTPMailer{Host: "127.0.0.1", Port: server.addr.Port, Username: "mailer", Password: "secret", From: "no-reply@gitconnect.test"}
+
+	if err := m.Send(Message{To: "octocat@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.auth != "\x00mailer\x00secret" {
+		t.Errorf("got AUTH PLAIN %q", server.auth)
+	}
+}
+
+func TestSMTPMailerRejectsBadAddresses(t *testing.T) {
+	m := SMTPMailer{Host: "127.0.0.1", Port: 1, From: "no-reply@gitconnect.test"}
+	if err := m.Send(Message{To: "not an address", Subject: "Hi"}); err == nil {
+		t.Error("sent to an invalid recipient")
+	}
+	m.From = "not an address"
+	if err := m.Send(Message{To: "octocat@example.com", Subject: "Hi"}); err == nil {
+		t.Error("sent from an invalid sender")
+	}
+}
+
+func TestComposeStripsHeaderInjection(t *testing.T) {
+	message := string(compose("no-reply@gitconnect.test", Message{
+		To:      "octocat@example.com",
+		Subject: "Hello\r\nBcc: victim@example.com",
+		Body:    "Body",
+	}))
+
+	if strings.Contains(message, "\r\nBcc:") {
+		t.Fatalf("subject injected a header:\n%s", message)
+	}
+	if !strings.Contains(message, "Subject: HelloBcc: victim@example.com\r\n") {
+		t.Fatalf("subject was not kept on one line:\n%s", message)
+	}
+}
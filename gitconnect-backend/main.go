@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
+	"time"
 
-	_ "gitconnect-backend/docs" // Import Swagger docs
 	"gitconnect-backend/config"
+	_ "gitconnect-backend/docs" // Import Swagger docs
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/ratelimit"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
 	"gitconnect-backend/routes"
+	"gitconnect-backend/storage"
+	"gitconnect-backend/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -25,20 +34,70 @@ import (
 // @host 0.0.0.0:8080
 // @BasePath /api
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
 	mode := os.Getenv("GIN_MODE")
 	if mode == "" {
 		mode = "debug"
 	}
 	gin.SetMode(mode)
 
-	if err := config.ConnectDatabase(); err != nil {
+	db, err := config.ConnectDatabase()
+	if err != nil {
 		log.Fatalf("❌ Database connection failed: %v", err)
 	}
 	log.Println("✅ Database connected successfully.")
 
+	if err := ensureSchema(db); err != nil {
+		log.Fatalf("❌ Database schema check failed: %v", err)
+	}
+
+	if err := config.BootstrapAdmins(db); err != nil {
+		log.Fatalf("❌ Admin bootstrap failed: %v", err)
+	}
+
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatalf("❌ JWT signing keys could not be loaded: %v", err)
+	}
+
+	mail, err := mailer.Configure()
+	if err != nil {
+		log.Fatalf("❌ Mailer configuration failed: %v", err)
+	}
+
+	limiter, err := ratelimit.Configure()
+	if err != nil {
+		log.Fatalf("❌ Rate limiter configuration failed: %v", err)
+	}
+
+	blobs, err := storage.Configure()
+	if err != nil {
+		log.Fatalf("❌ Blob store configuration failed: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("❌ Database handle unavailable: %v", err)
+	}
+	events, err := realtime.Configure(sqlDB)
+	if err != nil {
+		log.Fatalf("❌ Realtime configuration failed: %v", err)
+	}
+
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
-	router.SetTrustedProxies(nil)
+	// Per-IP rate limits need the real client address; trust X-Forwarded-For only from
+	// the proxies listed in TRUSTED_PROXIES (comma-separated IPs or CIDRs)
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		trustedProxies = strings.Split(value, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Updated CORS configuration
 	router.Use(cors.New(cors.Config{
@@ -50,17 +109,29 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	routes.AuthRoutes(router)
-	routes.PostRoutes(router)
-	routes.ProfileRoutes(router)
+	repos := repository.NewGorm(db)
+	go purgeRevokedTokens(repos.Sessions)
 
-  // Add this line before swagger route
-router.GET("/health", func(c *gin.Context) {
-    c.JSON(200, gin.H{
-        "status": "ok",
-    })
-})
+	deps := routes.Dependencies{Repos: repos, Events: events, Blobs: blobs, Limiter: limiter, Mailer: mail}
+	routes.AuthRoutes(router, deps)
+	routes.PostRoutes(router, deps)
+	routes.CommentRoutes(router, deps)
+	routes.ProfileRoutes(router, deps)
+	routes.FollowRoutes(router, deps)
+	routes.TagRoutes(router, deps)
+	routes.BlockRoutes(router, deps)
+	routes.NotificationRoutes(router, deps)
+	routes.MessageRoutes(router, deps)
+	routes.StreamRoutes(router, deps)
+	routes.SearchRoutes(router, deps)
+	routes.AdminRoutes(router, deps)
 
+	// Add this line before swagger route
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status": "ok",
+		})
+	})
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -76,3 +147,16 @@ router.GET("/health", func(c *gin.Context) {
 	}
 }
 
+// revokedTokenPurgeInterval is how often the access token denylist is cleaned up
+const revokedTokenPurgeInterval = time.Hour
+
+// purgeRevokedTokens drops the denylist entries of access tokens that have expired
+// anyway, at startup and then every revokedTokenPurgeInterval
+func purgeRevokedTokens(sessions repository.SessionRepository) {
+	for {
+		if err := sessions.PurgeRevoked(context.Background(), time.Now()); err != nil {
+			log.Println("❌ Failed to purge revoked tokens:", err)
+		}
+		time.Sleep(revokedTokenPurgeInterval)
+	}
+}
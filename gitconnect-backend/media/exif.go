@@ -0,0 +1,108 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientationTag is the EXIF tag telling how the camera was held
+const exifOrientationTag = 0x0112
+
+// exifOrientation reads the orientation, from 1 to 8, that the EXIF data of a
+// JPEG file asks viewers to apply. 1, no change, is returned when there is none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the marker segments up to the image data, looking for APP1 "Exif"
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// orient applies an EXIF orientation to img, so it displays upright without the tag
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	// Orientations 5 to 8 turn the image a quarter, swapping its sides
+	bounds := image.Rect(0, 0, width, height)
+	if orientation >= 5 {
+		bounds = image.Rect(0, 0, height, width)
+	}
+	dst := image.NewRGBA(bounds)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // Rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // Mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // Rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // Mirrored along the top-right diagonal
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], img.Pix[img.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,160 @@
+// Package media validates uploaded images and renders the sizes they are served in
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"sort"
+
+	_ "image/gif" // Register the GIF decoder; only the first frame is kept
+)
+
+const (
+	// MaxImageBytes caps the size of an uploaded image file
+	MaxImageBytes = 5 << 20
+	// MaxImagePixels caps the decoded size of an image, so a small file cannot
+	// expand into gigabytes of pixels
+	MaxImagePixels = 40_000_000
+	// jpegQuality is the quality opaque images are re-encoded at
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("only JPEG, PNG and GIF images are supported")
+	ErrTooLarge        = errors.New("image file is too large")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+)
+
+// AvatarSizes are the square sizes, in pixels, profile pictures are rendered in
+var AvatarSizes = []int{64, 128, 256, 512}
+
+// DefaultAvatarSize is the size served when none is asked for
+const DefaultAvatarSize = 256
+
+// Variant is an image rendered at one size
+type Variant struct {
+	Size        int
+	Data        []byte
+	ContentType string
+}
+
+// Avatar is a processed profile picture. ID is the SHA-256 of the uploaded file,
+// so the same upload always yields the same ID and the same variants.
+type Avatar struct {
+	ID       string
+	Variants []Variant
+}
+
+// ProcessAvatar checks an uploaded image and renders it in every AvatarSizes
+// size, cropped to the centered square; a smaller picture is not enlarged. The file's type is sniffed from its
+// content rather than trusted from the upload. Re-encoding drops EXIF and any
+// other metadata, after the EXIF orientation has been applied to the pixels.
+// Opaque images are encoded as JPEG and the others as PNG.
+func ProcessAvatar(data []byte) (Avatar, error) {
+	if len(data) > MaxImageBytes {
+		return Avatar{}, ErrTooLarge
+	}
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Avatar{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width < 1 || config.Height < 1 {
+		return Avatar{}, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return Avatar{}, ErrTooManyPixels
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Avatar{}, ErrInvalidImage
+	}
+
+	img := toRGBA(decoded)
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+	img = cropSquare(img)
+
+	// Render the largest size first and halve from there, which is both faster
+	// and as sharp as scaling the original each time
+	sizes := append([]int(nil), AvatarSizes...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	sum := sha256.Sum256(data)
+	avatar := Avatar{ID: hex.EncodeToString(sum[:])}
+	for _, size := range sizes {
+		img = downscale(img, min(size, img.Bounds().Dx()))
+		variant := Variant{Size: size}
+		if variant.Data, variant.ContentType, err = encode(img); err != nil {
+			return Avatar{}, err
+		}
+		avatar.Variants = append(avatar.Variants, variant)
+	}
+	return avatar, nil
+}
+
+// encode writes img as JPEG if it is opaque and as PNG otherwise
+func encode(img *image.RGBA) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
+
+// toRGBA copies img into an RGBA image whose bounds start at the origin
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// cropSquare returns the largest centered square of img
+func cropSquare(img *image.RGBA) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	side := min(width, height)
+	x, y := (width-side)/2, (height-side)/2
+	return toRGBA(img.SubImage(image.Rect(x, y, x+side, y+side)))
+}
+
+// downscale shrinks a square image to size by averaging the block of source
+// pixels behind each destination pixel. size must not exceed the source side.
+func downscale(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	if size == side {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r, g, b, a = r+uint32(row[i]), g+uint32(row[i+1]), b+uint32(row[i+2]), a+uint32(row[i+3])
+					n++
+				}
+			}
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset], dst.Pix[offset+1], dst.Pix[offset+2], dst.Pix[offset+3] =
+				uint8((r+n/2)/n), uint8((g+n/2)/n), uint8((b+n/2)/n), uint8((a+n/2)/n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,226 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// quadrants draws a width×height image whose top-left, top-right, bottom-left and
+// bottom-right quarters are filled with the given colors
+func quadrants(width, height int, tl, tr, bl, br color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := tl
+			switch {
+			case x >= width/2 && y >= height/2:
+				c = br
+			case y >= height/2:
+				c = bl
+			case x >= width/2:
+				c = tr
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// encodeJPEG encodes img and inserts an EXIF segment with the orientation after the SOI marker
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+
+	// A big-endian TIFF header and one IFD holding only the orientation
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = append(tiff, 0, 3, 0, 0, 0, 1) // SHORT, one value
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // Padding, then no next IFD
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+// markers lists the JPEG segment markers before the image data
+func markers(data []byte) []byte {
+	var found []byte
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		found = append(found, data[i+1])
+		if data[i+1] == 0xDA {
+			break
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return found
+}
+
+// nearest names which of the quadrant colors c is closest to, allowing for JPEG loss
+func nearest(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	best, bestDistance := "", uint32(1<<32-1)
+	for name, want := range map[string]color.RGBA{"red": red, "green": green, "blue": blue, "white": white} {
+		dr, dg, db := int(r>>8)-int(want.R), int(g>>8)-int(want.G), int(b>>8)-int(want.B)
+		if distance := uint32(dr*dr + dg*dg + db*db); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+func TestProcessAvatarAppliesEXIFOrientation(t *testing.T) {
+	// A landscape picture as stored by the camera
+	stored := quadrants(128, 64, red, green, blue, white)
+
+	// The quarters of each upright, centered square: top left, top right, bottom left, bottom right
+	for orientation, want := range map[uint16][4]string{
+		1: {"red", "green", "blue", "white"},
+		2: {"green", "red", "white", "blue"},
+		3: {"white", "blue", "green", "red"},
+		4: {"blue", "white", "red", "green"},
+		5: {"red", "blue", "green", "white"},
+		6: {"blue", "red", "white", "green"},
+		7: {"white", "green", "blue", "red"},
+		8: {"green", "white", "red", "blue"},
+	} {
+		data := encodeJPEG(t, stored, orientation)
+		if got := exifOrientation(data); got != int(orientation) {
+			t.Fatalf("orientation %d read back as %d", orientation, got)
+		}
+
+		avatar, err := ProcessAvatar(data)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", orientation, err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(avatar.Variants[0].Data))
+		if err != nil {
+			t.Fatalf("orientation %d: decoding output: %v", orientation, err)
+		}
+		got := [4]string{nearest(img.At(16, 16)), nearest(img.At(48, 16)), nearest(img.At(16, 48)), nearest(img.At(48, 48))}
+		if got != want {
+			t.Errorf("orientation %d: got quarters %v, want %v", orientation, got, want)
+		}
+	}
+}
+
+func TestProcessAvatarStripsMetadata(t *testing.T) {
+	data := encodeJPEG(t, quadrants(64, 64, red, green, blue, white), 6)
+	if !bytes.Contains(markers(data), []byte{0xE1}) {
+		t.Fatal("the test upload has no EXIF segment")
+	}
+
+	avatar, err := ProcessAvatar(data)
+	if err != nil {
+		t.Fatalf("processing: %v", err)
+	}
+	for _, variant := range avatar.Variants {
+		if variant.ContentType != "image/jpeg" {
+			t.Fatalf("opaque image encoded as %s", variant.ContentType)
+		}
+		if bytes.Contains(markers(variant.Data), []byte{0xE1}) {
+			t.Errorf("the %dpx variant kept an APP1 segment", variant.Size)
+		}
+		if exifOrientation(variant.Data) != 1 {
+			t.Errorf("the %dpx variant is still rotated by EXIF", variant.Size)
+		}
+	}
+}
+
+func TestProcessAvatarRejectsHugeDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+	data := buf.Bytes()
+
+	// Claim 100000×100000 pixels in the IHDR chunk and fix up its checksum; the
+	// file stays a few dozen bytes
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, err := ProcessAvatar(data); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("got %v, want ErrTooManyPixels", err)
+	}
+}
+
+func TestProcessAvatarSniffsTheType(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"html":      []byte("<html><script>alert(1)</script></html>"),
+		"svg":       []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+		"pdf":       []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"),
+		"zip":       []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"),
+		"empty":     {},
+		"bmp":       append([]byte("BM"), make([]byte, 64)...),
+		"truncated": []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"),
+	} {
+		_, err := ProcessAvatar(data)
+		want := ErrUnsupportedType
+		if name == "truncated" {
+			want = ErrInvalidImage
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", name, err, want)
+		}
+	}
+
+	if _, err := ProcessAvatar(make([]byte, MaxImageBytes+1)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized file: got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestProcessAvatarDoesNotUpscale(t *testing.T) {
+	// A transparent picture smaller than every size, and wider than it is tall
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	img.Set(20, 15, color.NRGBA{255, 0, 0, 128})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+
+	avatar, err := ProcessAvatar(buf.Bytes())
+	if err != nil {
+		t.Fatalf("processing: %v", err)
+	}
+	if len(avatar.Variants) != len(AvatarSizes) {
+		t.Fatalf("got %d variants, want %d", len(avatar.Variants), len(AvatarSizes))
+	}
+	for _, variant := range avatar.Variants {
+		if variant.ContentType != "image/png" {
+			t.Fatalf("transparent image encoded as %s", variant.ContentType)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatalf("decoding the %dpx variant: %v", variant.Size, err)
+		}
+		if config.Width != 30 || config.Height != 30 {
+			t.Errorf("the %dpx variant is %d×%d, want the 30px centered square", variant.Size, config.Width, config.Height)
+		}
+	}
+
+	// The same upload always gets the same ID
+	again, _ := ProcessAvatar(buf.Bytes())
+	if again.ID != avatar.ID || len(avatar.ID) != 64 {
+		t.Fatalf("got IDs %q and %q, want one stable SHA-256", avatar.ID, again.ID)
+	}
+}
@@ -1,43 +1,210 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
 	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware verifies the JWT token in the request header.
-func AuthMiddleware() gin.HandlerFunc {
+// errTokenRevoked is returned for a valid token whose jti is on the denylist
+var errTokenRevoked = errors.New("token has been revoked")
+
+// AuthMiddleware verifies the JWT token or personal access token in the request header.
+// repos holds the revocation denylist and the personal access tokens.
+func AuthMiddleware(repos repository.Repositories) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := c.GetHeader("Authorization")
+		if requireAuth(c, repos) {
+			c.Next()
+		}
+	}
+}
 
-		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
-			c.Abort()
+// requireAuth authenticates the request header like AuthMiddleware, writing the
+// error response itself if it cannot
+func requireAuth(c *gin.Context, repos repository.Repositories) bool {
+	tokenString := c.GetHeader("Authorization")
+
+	if tokenString == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
+		c.Abort()
+		return false
+	}
+
+	// Ensure token is in "Bearer <token>" format
+	parts := strings.Split(tokenString, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		c.Abort()
+		return false
+	}
+
+	// Validate token and check it has not been revoked
+	err := authenticate(c, repos, parts[1])
+	if errors.Is(err, errTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// OptionalAuthMiddleware identifies the caller when a valid token is present,
+// but lets anonymous requests through to public routes.
+func OptionalAuthMiddleware(repos repository.Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			_ = authenticate(c, repos, parts[1])
+		}
+		c.Next()
+	}
+}
+
+// StreamAuthMiddleware authenticates an event stream with a ticket from the
+// ticket query parameter, for browsers that cannot set headers on EventSource and
+// WebSocket requests, or else like AuthMiddleware. A ticket stops working when the
+// session it was issued to is revoked. Banned and suspended users cannot stream.
+func StreamAuthMiddleware(repos repository.Repositories) gin.HandlerFunc {
+	access := Access{Users: repos.Users}
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			if !requireAuth(c, repos) {
+				return
+			}
+		} else if !authenticateTicket(c, repos, ticket) {
 			return
 		}
 
-		// Ensure token is in "Bearer <token>" format
-		parts := strings.Split(tokenString, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		if _, ok := access.activeUser(c); ok {
+			c.Next()
+		}
+	}
+}
+
+// authenticateTicket validates a stream ticket and the session it was issued to,
+// writing the error response itself if either is invalid
+func authenticateTicket(c *gin.Context, repos repository.Repositories, ticket string) bool {
+	claims, err := utils.ValidateStreamTicket(ticket)
+	if err != nil || claims.SessionID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
+		c.Abort()
+		return false
+	}
+
+	revoked, err := repos.Sessions.AccessTokenRevoked(c.Request.Context(), claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
+		c.Abort()
+		return false
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", claims.UserID)
+	return true
+}
+
+// RequireScope only lets a personal access token through if it was granted scope.
+// Login sessions carry no scopes and are not restricted.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Validate token
-		claims, err := utils.ValidateToken(parts[1])
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+// HasScope reports whether the caller may use scope: login sessions may use any,
+// personal access tokens only those they were granted
+func HasScope(c *gin.Context, scope string) bool {
+	scopes, isToken := c.Get("token_scopes")
+	if !isToken {
+		return true
+	}
+	for _, granted := range scopes.([]string) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionOnly rejects personal access tokens, for account management routes that
+// must not be reachable with a leaked script credential.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isToken := c.Get("token_scopes"); isToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot be used here; please log in"})
 			c.Abort()
 			return
 		}
-
-		// Pass user ID to the request context
-		c.Set("user_id", claims.UserID)
 		c.Next()
 	}
 }
 
+// authenticate validates a JWT or personal access token and stores the caller in the context.
+// JWTs set "user_id" and "claims"; personal access tokens set "user_id" and "token_scopes".
+func authenticate(c *gin.Context, repos repository.Repositories, tokenString string) error {
+	if strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+		return authenticateAccessToken(c, repos.AccessTokens, tokenString)
+	}
+
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil {
+		return err
+	}
+
+	// Reject tokens whose jti is on the revocation denylist
+	revoked, err := repos.Sessions.AccessTokenRevoked(c.Request.Context(), claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errTokenRevoked
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("claims", claims)
+	return nil
+}
+
+// authenticateAccessToken looks up a personal access token by its hash
+func authenticateAccessToken(c *gin.Context, tokens repository.AccessTokenRepository, tokenString string) error {
+	token, err := tokens.FindByHash(c.Request.Context(), utils.HashToken(tokenString))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		return errTokenRevoked
+	}
+	if now.After(token.ExpiresAt) {
+		return errors.New("token has expired")
+	}
+
+	// Usage is recorded at most once a minute to avoid a write on every request
+	_ = tokens.Touch(c.Request.Context(), token.ID, now)
+
+	c.Set("user_id", token.UserID)
+	c.Set("token_scopes", token.ScopeList())
+	return nil
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc picks the bucket a request counts against
+type KeyFunc func(c *gin.Context) string
+
+// ByIP counts requests per client IP
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts requests per authenticated user, falling back to the client IP.
+// Must run after AuthMiddleware to see the user.
+func ByUser(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return ByIP(c)
+}
+
+// RateLimit limits requests using the limit limiter has for group, and reports the
+// limit in RateLimit-* headers. If the store is unreachable, requests are let through.
+func RateLimit(limiter *ratelimit.Limiter, group string, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := limiter.Allow(c.Request.Context(), group, key(c))
+		if err != nil {
+			log.Printf("⚠️ Rate limiter unavailable, allowing request: %v", err)
+			c.Next()
+			return
+		}
+
+		result.SetHeaders(c.Writer.Header())
+		if !result.Allowed {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests; please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// OwnerLookup returns the ID of the user who owns the resource addressed by the request
+type OwnerLookup func(c *gin.Context) (uint, error)
+
+// errInvalidID is returned by an OwnerLookup when the path parameter is not a number
+var errInvalidID = errors.New("invalid id")
+
+// OwnerOf looks up the owner of the resource with the ":id" path parameter using
+// a repository's OwnerID method
+func OwnerOf(ownerID func(ctx context.Context, id uint) (uint, error)) OwnerLookup {
+	return func(c *gin.Context) (uint, error) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id < 1 {
+			return 0, errInvalidID
+		}
+		return ownerID(c.Request.Context(), uint(id))
+	}
+}
+
+// Access builds the authorization middlewares. They must run after AuthMiddleware.
+type Access struct {
+	Users repository.UserRepository
+}
+
+// RequirePermission lets the request through only if the caller's role grants perm
+func (a Access) RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := a.activeUser(c)
+		if !ok {
+			return
+		}
+
+		if !user.Role.Can(perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOwner lets the request through only if the caller owns the resource found by lookup
+func (a Access) RequireOwner(lookup OwnerLookup) gin.HandlerFunc {
+	return a.RequireOwnerOr("", lookup)
+}
+
+// RequireOwnerOr lets the request through if the caller owns the resource found by
+// lookup, or if their role grants perm over everyone's resources
+func (a Access) RequireOwnerOr(perm models.Permission, lookup OwnerLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := a.activeUser(c)
+		if !ok {
+			return
+		}
+
+		ownerID, err := lookup(c)
+		switch {
+		case errors.Is(err, errInvalidID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			c.Abort()
+			return
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check ownership"})
+			c.Abort()
+			return
+		}
+
+		if ownerID != user.ID && (perm == "" || !user.Role.Can(perm)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own content"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ActiveAccount rejects banned or suspended callers on mutating routes that need
+// no particular permission, such as editing one's own profile
+func (a Access) ActiveAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := a.activeUser(c); ok {
+			c.Next()
+		}
+	}
+}
+
+// activeUser loads the authenticated user once per request and rejects banned or
+// suspended accounts, which are read-only. It writes the error response itself.
+func (a Access) activeUser(c *gin.Context) (models.User, bool) {
+	if cached, exists := c.Get("current_user"); exists {
+		return cached.(models.User), true
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return models.User{}, false
+	}
+	user, err := a.Users.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return user, false
+	}
+
+	if user.Restricted(time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your account is suspended"})
+		c.Abort()
+		return user, false
+	}
+
+	c.Set("current_user", user)
+	return user, true
+}
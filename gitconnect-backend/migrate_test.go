@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gitconnect-backend/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// cliSchema keeps these tests apart from other packages' tests on the same database
+const cliSchema = "migrate_cli_test"
+
+// TestMain runs the migrate subcommand instead of the tests when the test binary is
+// started by migrate below, since it exits the process
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv("GITCONNECT_MIGRATE_ARGS"); ok {
+		runMigrate(strings.Fields(args))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// migrate runs `main migrate args...` against databaseURL, returning its output and exit code
+func migrate(t *testing.T, databaseURL string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "GITCONNECT_MIGRATE_ARGS="+strings.Join(args, " "), "DATABASE_URL="+databaseURL)
+	output, err := cmd.CombinedOutput()
+
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(output), exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("running migrate %v: %v", args, err)
+	}
+	return string(output), 0
+}
+
+func TestMigrateUsage(t *testing.T) {
+	// Without a command there is nothing to connect for
+	output, code := migrate(t, "")
+	if code != 2 || !strings.Contains(output, "usage: main migrate <command>") {
+		t.Fatalf("exit %d with %q, want the usage and exit 2", code, output)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	admin, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	if err := admin.Exec("DROP SCHEMA IF EXISTS " + cliSchema + " CASCADE").Error; err != nil {
+		t.Fatalf("resetting schema: %v", err)
+	}
+	if err := admin.Exec("CREATE SCHEMA " + cliSchema).Error; err != nil {
+		t.Fatalf("resetting schema: %v", err)
+	}
+	if sqlDB, err := admin.DB(); err == nil {
+		sqlDB.Close()
+	}
+	databaseURL = withSearchPath(databaseURL, cliSchema)
+
+	// pending counts the migrations status lists as not applied
+	pending := func() int {
+		t.Helper()
+		output, code := migrate(t, databaseURL, "status")
+		if code != 0 || !strings.Contains(output, "baseline") {
+			t.Fatalf("migrate status: exit %d with %q", code, output)
+		}
+		return strings.Count(output, "pending")
+	}
+	run := func(args ...string) {
+		t.Helper()
+		if output, code := migrate(t, databaseURL, args...); code != 0 {
+			t.Fatalf("migrate %v: exit %d with %q", args, code, output)
+		}
+	}
+
+	known, err := migrations.All()
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	all := len(known)
+	if n := pending(); n != all {
+		t.Fatalf("status lists %d pending migrations on an empty database, want %d", n, all)
+	}
+	run("up")
+	if n := pending(); n != 0 {
+		t.Fatalf("%d migrations pending after up", n)
+	}
+	run("down")
+	if n := pending(); n != 1 {
+		t.Fatalf("%d migrations pending after down, want 1", n)
+	}
+	run("to", "3")
+	if n := pending(); n != all-3 {
+		t.Fatalf("%d migrations pending after migrating to 3, want %d", n, all-3)
+	}
+	run("to", "0")
+	if n := pending(); n != all {
+		t.Fatalf("%d migrations pending after migrating to 0, want %d", n, all)
+	}
+
+	for _, bad := range []struct {
+		args string
+		code int
+		want string
+	}{
+		{"to abc", 1, "Invalid version"},
+		{"to 9999", 1, "unknown migration version"},
+		{"to", 2, "usage: main migrate"},
+		{"sideways", 2, "usage: main migrate"},
+	} {
+		output, code := migrate(t, databaseURL, strings.Fields(bad.args)...)
+		if code != bad.code || !strings.Contains(output, bad.want) {
+			t.Errorf("migrate %s: exit %d with %q, want exit %d and %q", bad.args, code, output, bad.code, bad.want)
+		}
+	}
+}
+
+// withSearchPath sets the schema of every connection made with databaseURL, which
+// is either a postgres:// URL or a list of key=value settings
+func withSearchPath(databaseURL, schema string) string {
+	if !strings.Contains(databaseURL, "://") {
+		return databaseURL + " search_path=" + schema
+	}
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS
+    personal_access_tokens,
+    recovery_codes,
+    o_auth_states,
+    one_time_tokens,
+    revoked_tokens,
+    refresh_tokens,
+    post_reactions,
+    comments,
+    posts,
+    profiles,
+    users;
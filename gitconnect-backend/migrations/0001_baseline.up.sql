@@ -0,0 +1,164 @@
+-- Baseline: the schema previously created by AutoMigrate. Every statement is
+-- idempotent so databases that were set up by AutoMigrate adopt it as is.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                bigserial PRIMARY KEY,
+    username          text NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    email             text NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    password          text,
+    email_verified_at timestamptz,
+    totp_secret       text,
+    totp_enabled_at   timestamptz,
+    totp_last_counter bigint,
+    failed_logins     bigint,
+    locked_until      timestamptz,
+    role              varchar(16) NOT NULL DEFAULT 'user',
+    suspended_until   timestamptz,
+    banned_at         timestamptz,
+    moderation_note   text,
+    created_at        timestamptz,
+    updated_at        timestamptz
+);
+
+-- Columns added after the first release, missing from databases created back then
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz,
+    ADD COLUMN IF NOT EXISTS totp_secret text,
+    ADD COLUMN IF NOT EXISTS totp_enabled_at timestamptz,
+    ADD COLUMN IF NOT EXISTS totp_last_counter bigint,
+    ADD COLUMN IF NOT EXISTS failed_logins bigint,
+    ADD COLUMN IF NOT EXISTS locked_until timestamptz,
+    ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS suspended_until timestamptz,
+    ADD COLUMN IF NOT EXISTS banned_at timestamptz,
+    ADD COLUMN IF NOT EXISTS moderation_note text;
+
+CREATE TABLE IF NOT EXISTS profiles (
+    id                 bigserial PRIMARY KEY,
+    user_id            bigint NOT NULL CONSTRAINT uni_profiles_user_id UNIQUE
+                       CONSTRAINT fk_users_profile REFERENCES users (id) ON DELETE CASCADE,
+    full_name          text,
+    bio                text,
+    github             text,
+    github_id          bigint,
+    github_login       text,
+    github_verified_at timestamptz,
+    profile_picture    text,
+    created_at         timestamptz,
+    updated_at         timestamptz
+);
+
+ALTER TABLE profiles
+    ADD COLUMN IF NOT EXISTS github_id bigint,
+    ADD COLUMN IF NOT EXISTS github_login text,
+    ADD COLUMN IF NOT EXISTS github_verified_at timestamptz;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_profiles_github_id ON profiles (github_id);
+CREATE INDEX IF NOT EXISTS idx_profiles_user_id ON profiles (user_id);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id         bigserial PRIMARY KEY,
+    content    text,
+    user_id    bigint NOT NULL CONSTRAINT fk_posts_user REFERENCES users (id),
+    likes      bigint DEFAULT 0,
+    dislikes   bigint DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id         bigserial PRIMARY KEY,
+    post_id    bigint NOT NULL CONSTRAINT fk_posts_comments REFERENCES posts (id) ON DELETE CASCADE,
+    user_id    bigint CONSTRAINT fk_comments_user REFERENCES users (id),
+    content    text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
+
+CREATE TABLE IF NOT EXISTS post_reactions (
+    post_id    bigint CONSTRAINT fk_post_reactions_post REFERENCES posts (id) ON DELETE CASCADE,
+    user_id    bigint CONSTRAINT fk_post_reactions_user REFERENCES users (id) ON DELETE CASCADE,
+    kind       varchar(16) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (post_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_user_id ON post_reactions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          bigserial PRIMARY KEY,
+    user_id     bigint NOT NULL CONSTRAINT fk_refresh_tokens_user REFERENCES users (id) ON DELETE CASCADE,
+    family_id   varchar(64) NOT NULL,
+    token_hash  varchar(64) NOT NULL,
+    expires_at  timestamptz NOT NULL,
+    revoked_at  timestamptz,
+    replaced_by bigint,
+    created_at  timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        varchar(64) PRIMARY KEY,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL CONSTRAINT fk_one_time_tokens_user REFERENCES users (id) ON DELETE CASCADE,
+    purpose    varchar(32) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_one_time_tokens_token_hash ON one_time_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_id ON one_time_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS o_auth_states (
+    id            bigserial PRIMARY KEY,
+    state_hash    varchar(64) NOT NULL,
+    code_verifier text NOT NULL,
+    user_id       bigint,
+    expires_at    timestamptz NOT NULL,
+    created_at    timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_o_auth_states_state_hash ON o_auth_states (state_hash);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL CONSTRAINT fk_recovery_codes_user REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  varchar(64) NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id           bigserial PRIMARY KEY,
+    user_id      bigint NOT NULL CONSTRAINT fk_personal_access_tokens_user REFERENCES users (id) ON DELETE CASCADE,
+    name         varchar(100) NOT NULL,
+    prefix       varchar(16) NOT NULL,
+    token_hash   varchar(64) NOT NULL,
+    scopes       text NOT NULL,
+    expires_at   timestamptz NOT NULL,
+    last_used_at timestamptz,
+    revoked_at   timestamptz,
+    created_at   timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_access_tokens_token_hash ON personal_access_tokens (token_hash);
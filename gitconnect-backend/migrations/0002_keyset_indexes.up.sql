@@ -0,0 +1,5 @@
+-- Keyset pagination walks (created_at, id) in both directions
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at_id ON posts (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_profiles_created_at_id ON profiles (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at_id ON comments (post_id, created_at, id);
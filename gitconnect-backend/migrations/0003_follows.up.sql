@@ -0,0 +1,11 @@
+CREATE TABLE follows (
+    follower_id bigint NOT NULL CONSTRAINT fk_follows_follower REFERENCES users (id) ON DELETE CASCADE,
+    followee_id bigint NOT NULL CONSTRAINT fk_follows_followee REFERENCES users (id) ON DELETE CASCADE,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT chk_follows_not_self CHECK (follower_id <> followee_id)
+);
+
+-- Follower and following lists are keyset-paginated on (created_at, other user)
+CREATE INDEX idx_follows_followee_created_at ON follows (followee_id, created_at, follower_id);
+CREATE INDEX idx_follows_follower_created_at ON follows (follower_id, created_at, followee_id);
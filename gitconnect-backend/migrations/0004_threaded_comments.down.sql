@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS comment_reactions;
+
+DROP INDEX IF EXISTS idx_comments_parent_id;
+DROP INDEX IF EXISTS idx_comments_root_id;
+DROP INDEX IF EXISTS idx_comments_post_id_roots;
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at_id ON comments (post_id, created_at, id);
+
+-- Replies cannot be represented without parent_id; they become top-level comments
+ALTER TABLE comments
+    DROP COLUMN deleted_at,
+    DROP COLUMN edited_at,
+    DROP COLUMN dislikes,
+    DROP COLUMN likes,
+    DROP COLUMN depth,
+    DROP COLUMN root_id,
+    DROP COLUMN parent_id;
@@ -0,0 +1,25 @@
+ALTER TABLE comments
+    ADD COLUMN parent_id bigint CONSTRAINT fk_comments_parent REFERENCES comments (id) ON DELETE CASCADE,
+    ADD COLUMN root_id   bigint CONSTRAINT fk_comments_root REFERENCES comments (id) ON DELETE CASCADE,
+    ADD COLUMN depth     integer NOT NULL DEFAULT 0,
+    ADD COLUMN likes     bigint DEFAULT 0,
+    ADD COLUMN dislikes  bigint DEFAULT 0,
+    ADD COLUMN edited_at timestamptz,
+    ADD COLUMN deleted_at timestamptz;
+
+-- Comment listings page through top-level comments and load whole threads by root
+DROP INDEX IF EXISTS idx_comments_post_id_created_at_id;
+CREATE INDEX idx_comments_post_id_roots ON comments (post_id, created_at, id) WHERE parent_id IS NULL;
+CREATE INDEX idx_comments_root_id ON comments (root_id, created_at, id);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+
+CREATE TABLE comment_reactions (
+    comment_id bigint CONSTRAINT fk_comment_reactions_comment REFERENCES comments (id) ON DELETE CASCADE,
+    user_id    bigint CONSTRAINT fk_comment_reactions_user REFERENCES users (id) ON DELETE CASCADE,
+    kind       varchar(16) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_reactions_user_id ON comment_reactions (user_id);
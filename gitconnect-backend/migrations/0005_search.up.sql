@@ -0,0 +1,20 @@
+-- Search vectors are generated columns, so Postgres keeps them current on every
+-- write and the application never maintains them
+ALTER TABLE posts
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED;
+
+-- Names and GitHub handles outrank the bio; the github.com prefix is dropped so a
+-- search for the handle matches
+ALTER TABLE profiles
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(full_name, '')), 'A') ||
+        setweight(to_tsvector('english', regexp_replace(coalesce(github, ''), '^(https?://)?(www\.)?github\.com/', '', 'i')), 'A') ||
+        setweight(to_tsvector('english', coalesce(bio, '')), 'B')
+    ) STORED;
+
+ALTER TABLE comments
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED;
+
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+CREATE INDEX idx_profiles_search_vector ON profiles USING GIN (search_vector);
+CREATE INDEX idx_comments_search_vector ON comments USING GIN (search_vector) WHERE deleted_at IS NULL;
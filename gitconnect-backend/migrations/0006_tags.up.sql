@@ -0,0 +1,37 @@
+CREATE TABLE tags (
+    id         bigserial PRIMARY KEY,
+    name       varchar(50) NOT NULL CONSTRAINT uni_tags_name UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE post_tags (
+    post_id    bigint NOT NULL CONSTRAINT fk_post_tags_post REFERENCES posts (id) ON DELETE CASCADE,
+    tag_id     bigint NOT NULL CONSTRAINT fk_post_tags_tag REFERENCES tags (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+-- Topic pages list a tag's posts; trending counts the links made within a window
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id, post_id);
+CREATE INDEX idx_post_tags_created_at ON post_tags (created_at, tag_id);
+
+CREATE TABLE tag_follows (
+    user_id    bigint NOT NULL CONSTRAINT fk_tag_follows_user REFERENCES users (id) ON DELETE CASCADE,
+    tag_id     bigint NOT NULL CONSTRAINT fk_tag_follows_tag REFERENCES tags (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, tag_id)
+);
+
+CREATE INDEX idx_tag_follows_tag_id ON tag_follows (tag_id);
+
+-- Tag the existing posts the way models.ExtractHashtags tags new ones
+CREATE TEMPORARY TABLE existing_post_tags ON COMMIT DROP AS
+SELECT DISTINCT posts.id AS post_id, lower(match[1]) AS name, coalesce(posts.created_at, now()) AS created_at
+FROM posts, regexp_matches(posts.content, '(?:^|[^[:alnum:]_&#/])#([[:alnum:]_]+)', 'g') AS match
+WHERE length(match[1]) <= 50 AND match[1] ~ '[[:alpha:]]';
+
+INSERT INTO tags (name) SELECT DISTINCT name FROM existing_post_tags;
+
+INSERT INTO post_tags (post_id, tag_id, created_at)
+SELECT existing_post_tags.post_id, tags.id, existing_post_tags.created_at
+FROM existing_post_tags JOIN tags ON tags.name = existing_post_tags.name;
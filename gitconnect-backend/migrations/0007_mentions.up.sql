@@ -0,0 +1,40 @@
+CREATE TABLE blocks (
+    blocker_id bigint NOT NULL CONSTRAINT fk_blocks_blocker REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id bigint NOT NULL CONSTRAINT fk_blocks_blocked REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT chk_blocks_not_self CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_blocks_blocked_id ON blocks (blocked_id);
+
+-- A mention sits in exactly one post or comment and goes with it
+CREATE TABLE mentions (
+    id           bigserial PRIMARY KEY,
+    post_id      bigint CONSTRAINT fk_mentions_post REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id   bigint CONSTRAINT fk_mentions_comment REFERENCES comments (id) ON DELETE CASCADE,
+    user_id      bigint NOT NULL CONSTRAINT fk_mentions_user REFERENCES users (id) ON DELETE CASCADE,
+    start_offset integer NOT NULL,
+    end_offset   integer NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT chk_mentions_one_target CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE INDEX idx_mentions_post_id ON mentions (post_id);
+CREATE INDEX idx_mentions_comment_id ON mentions (comment_id);
+CREATE INDEX idx_mentions_user_id ON mentions (user_id);
+
+-- Notifications go with their recipient, their actor and what they point at
+CREATE TABLE notifications (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL CONSTRAINT fk_notifications_user REFERENCES users (id) ON DELETE CASCADE,
+    actor_id   bigint NOT NULL CONSTRAINT fk_notifications_actor REFERENCES users (id) ON DELETE CASCADE,
+    type       varchar(32) NOT NULL,
+    post_id    bigint CONSTRAINT fk_notifications_post REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id bigint CONSTRAINT fk_notifications_comment REFERENCES comments (id) ON DELETE CASCADE,
+    read_at    timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Notification lists are keyset-paginated per recipient
+CREATE INDEX idx_notifications_user_created_at ON notifications (user_id, created_at, id);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_actors;
+
+DROP INDEX IF EXISTS idx_notifications_unread;
+DROP INDEX IF EXISTS idx_notifications_user_updated_at;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at ON notifications (user_id, created_at, id);
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS actor_count;
@@ -0,0 +1,30 @@
+-- Aggregated notifications count their actors and move up when one joins
+ALTER TABLE notifications
+    ADD COLUMN actor_count integer NOT NULL DEFAULT 1,
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+
+UPDATE notifications SET updated_at = created_at;
+
+DROP INDEX idx_notifications_user_created_at;
+CREATE INDEX idx_notifications_user_updated_at ON notifications (user_id, updated_at, id);
+CREATE INDEX idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Each actor is counted once per notification
+CREATE TABLE notification_actors (
+    notification_id bigint NOT NULL CONSTRAINT fk_notification_actors_notification REFERENCES notifications (id) ON DELETE CASCADE,
+    actor_id        bigint NOT NULL CONSTRAINT fk_notification_actors_actor REFERENCES users (id) ON DELETE CASCADE,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (notification_id, actor_id)
+);
+
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+SELECT id, actor_id, created_at FROM notifications;
+
+-- Types without a row are on
+CREATE TABLE notification_preferences (
+    user_id    bigint NOT NULL CONSTRAINT fk_notification_preferences_user REFERENCES users (id) ON DELETE CASCADE,
+    type       varchar(32) NOT NULL,
+    enabled    boolean NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, type)
+);
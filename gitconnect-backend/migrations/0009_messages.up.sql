@@ -0,0 +1,34 @@
+-- A direct conversation has a key naming its two members, so there is one per pair
+CREATE TABLE conversations (
+    id         bigserial PRIMARY KEY,
+    creator_id bigint CONSTRAINT fk_conversations_creator REFERENCES users (id) ON DELETE SET NULL,
+    title      varchar(100) NOT NULL DEFAULT '',
+    direct_key varchar(41) CONSTRAINT uni_conversations_direct_key UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE conversation_members (
+    conversation_id      bigint NOT NULL CONSTRAINT fk_conversation_members_conversation REFERENCES conversations (id) ON DELETE CASCADE,
+    user_id              bigint NOT NULL CONSTRAINT fk_conversation_members_user REFERENCES users (id) ON DELETE CASCADE,
+    state                varchar(16) NOT NULL,
+    last_read_message_id bigint,
+    last_read_at         timestamptz,
+    created_at           timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+-- Inboxes and message requests are listed per member
+CREATE INDEX idx_conversation_members_user_id ON conversation_members (user_id, state);
+
+CREATE TABLE messages (
+    id              bigserial PRIMARY KEY,
+    conversation_id bigint NOT NULL CONSTRAINT fk_messages_conversation REFERENCES conversations (id) ON DELETE CASCADE,
+    sender_id       bigint NOT NULL CONSTRAINT fk_messages_sender REFERENCES users (id) ON DELETE CASCADE,
+    content         text NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+-- Messages are keyset-paginated per conversation; unread counts scan past the read receipt
+CREATE INDEX idx_messages_conversation_created_at ON messages (conversation_id, created_at, id);
+CREATE INDEX idx_messages_conversation_id ON messages (conversation_id, id);
@@ -0,0 +1,2 @@
+ALTER TABLE profiles ADD COLUMN profile_picture text;
+ALTER TABLE profiles DROP COLUMN IF EXISTS picture_id;
@@ -0,0 +1,5 @@
+-- Profile pictures are stored as content-addressed variants in the blob store;
+-- picture_id is the SHA-256 of the uploaded file. The free-form profile_picture
+-- column is dropped: no upload route ever wrote to it, so nothing it held was served.
+ALTER TABLE profiles ADD COLUMN picture_id varchar(64);
+ALTER TABLE profiles DROP COLUMN profile_picture;
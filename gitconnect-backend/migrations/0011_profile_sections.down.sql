@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS profile_links;
+DROP TABLE IF EXISTS educations;
+DROP TABLE IF EXISTS experiences;
+DROP TABLE IF EXISTS skills;
@@ -0,0 +1,59 @@
+-- Sections of a profile; each lists its entries by position
+CREATE TABLE skills (
+    id          bigserial PRIMARY KEY,
+    profile_id  bigint NOT NULL CONSTRAINT fk_skills_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    position    integer NOT NULL DEFAULT 0,
+    name        varchar(50) NOT NULL,
+    proficiency varchar(16) NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+-- A profile lists each skill once, whatever its case
+CREATE UNIQUE INDEX uni_skills_profile_name ON skills (profile_id, lower(name));
+
+-- Dates are a year and month (YYYY-MM); an empty end date means ongoing
+CREATE TABLE experiences (
+    id          bigserial PRIMARY KEY,
+    profile_id  bigint NOT NULL CONSTRAINT fk_experiences_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    position    integer NOT NULL DEFAULT 0,
+    title       varchar(100) NOT NULL,
+    company     varchar(100) NOT NULL,
+    location    varchar(100) NOT NULL DEFAULT '',
+    start_date  varchar(7) NOT NULL,
+    end_date    varchar(7) NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_experiences_profile_id ON experiences (profile_id, position, id);
+
+CREATE TABLE educations (
+    id             bigserial PRIMARY KEY,
+    profile_id     bigint NOT NULL CONSTRAINT fk_educations_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    position       integer NOT NULL DEFAULT 0,
+    school         varchar(100) NOT NULL,
+    degree         varchar(100) NOT NULL DEFAULT '',
+    field_of_study varchar(100) NOT NULL DEFAULT '',
+    start_date     varchar(7) NOT NULL DEFAULT '',
+    end_date       varchar(7) NOT NULL DEFAULT '',
+    description    text NOT NULL DEFAULT '',
+    created_at     timestamptz NOT NULL DEFAULT now(),
+    updated_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_educations_profile_id ON educations (profile_id, position, id);
+
+CREATE TABLE profile_links (
+    id         bigserial PRIMARY KEY,
+    profile_id bigint NOT NULL CONSTRAINT fk_profile_links_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    position   integer NOT NULL DEFAULT 0,
+    kind       varchar(16) NOT NULL,
+    label      varchar(50) NOT NULL DEFAULT '',
+    url        varchar(2048) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_profile_links_profile_id ON profile_links (profile_id, position, id);
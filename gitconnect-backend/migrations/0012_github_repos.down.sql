@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS github_repo_syncs;
+DROP TABLE IF EXISTS github_repos;
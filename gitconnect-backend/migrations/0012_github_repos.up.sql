@@ -0,0 +1,31 @@
+-- Public repositories of each profile's GitHub account, cached from the GitHub API
+CREATE TABLE github_repos (
+    id           bigserial PRIMARY KEY,
+    profile_id   bigint NOT NULL CONSTRAINT fk_github_repos_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    github_id    bigint NOT NULL,
+    name         varchar(100) NOT NULL,
+    full_name    varchar(200) NOT NULL,
+    description  text NOT NULL DEFAULT '',
+    url          varchar(2048) NOT NULL DEFAULT '',
+    homepage     varchar(2048) NOT NULL DEFAULT '',
+    language     varchar(100) NOT NULL DEFAULT '',
+    languages    jsonb,
+    topics       jsonb,
+    stars        integer NOT NULL DEFAULT 0,
+    forks        integer NOT NULL DEFAULT 0,
+    fork         boolean NOT NULL DEFAULT false,
+    archived     boolean NOT NULL DEFAULT false,
+    pushed_at    timestamptz,
+    pin_position integer,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT uni_github_repos_profile_github UNIQUE (profile_id, github_id)
+);
+
+-- When each profile's repositories were last fetched, with the ETag for the next conditional fetch
+CREATE TABLE github_repo_syncs (
+    profile_id bigint PRIMARY KEY CONSTRAINT fk_github_repo_syncs_profile REFERENCES profiles (id) ON DELETE CASCADE,
+    login      varchar(39) NOT NULL,
+    etag       varchar(200) NOT NULL DEFAULT '',
+    synced_at  timestamptz NOT NULL
+);
@@ -0,0 +1,4 @@
+DELETE FROM notifications WHERE type = 'endorsement';
+ALTER TABLE notifications DROP COLUMN IF EXISTS skill_id;
+
+DROP TABLE IF EXISTS endorsements;
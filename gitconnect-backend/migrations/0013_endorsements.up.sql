@@ -0,0 +1,14 @@
+-- Users vouch for skills on other users' profiles, once each
+CREATE TABLE endorsements (
+    skill_id    bigint NOT NULL CONSTRAINT fk_endorsements_skill REFERENCES skills (id) ON DELETE CASCADE,
+    endorser_id bigint NOT NULL CONSTRAINT fk_endorsements_endorser REFERENCES users (id) ON DELETE CASCADE,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (skill_id, endorser_id)
+);
+
+CREATE INDEX idx_endorsements_skill_created_at ON endorsements (skill_id, created_at, endorser_id);
+CREATE INDEX idx_endorsements_endorser_id ON endorsements (endorser_id);
+
+-- Endorsement notifications point at the endorsed skill
+ALTER TABLE notifications
+    ADD COLUMN skill_id bigint CONSTRAINT fk_notifications_skill REFERENCES skills (id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS username_redirects;
+
+DROP INDEX IF EXISTS uni_users_username_lower;
+ALTER TABLE users ADD CONSTRAINT uni_users_username UNIQUE (username);
@@ -0,0 +1,65 @@
+-- Previous usernames keep resolving to their account. Lookups prefer an exact
+-- match, so previous names that differ only in case may belong to different
+-- accounts (see the backfill below).
+CREATE TABLE username_redirects (
+    id         bigserial PRIMARY KEY,
+    username   text NOT NULL,
+    user_id    bigint NOT NULL CONSTRAINT fk_username_redirects_user REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uni_username_redirects_username ON username_redirects (username);
+CREATE INDEX idx_username_redirects_username_lower ON username_redirects (lower(username));
+CREATE INDEX idx_username_redirects_user_id ON username_redirects (user_id, created_at);
+
+-- Usernames become unique whatever their case, and must be reachable: an
+-- all-digit name reads as a user ID, and reserved names belong to the site. Of
+-- accounts whose usernames differ only in case, all but the oldest get their ID
+-- appended; accounts with an unreachable name become user-<id>. A number is
+-- appended in turn until the new name is free whatever its case, and the old
+-- name redirects to the account.
+DO $$
+DECLARE
+    account   record;
+    base      text;
+    candidate text;
+    attempt   integer;
+BEGIN
+    FOR account IN
+        SELECT id, username, unreachable FROM (
+            SELECT id, username,
+                   username ~ '^[0-9]+$' OR lower(username) IN (
+                       'about', 'account', 'admin', 'administrator', 'api', 'auth', 'blocks', 'conversations',
+                       'docs', 'edit', 'explore', 'feed', 'gitconnect', 'help', 'home', 'login', 'logout', 'me',
+                       'messages', 'moderator', 'new', 'notifications', 'null', 'oauth', 'official', 'posts',
+                       'privacy', 'profile', 'profiles', 'register', 'root', 'search', 'security', 'settings',
+                       'signin', 'signup', 'staff', 'static', 'status', 'stream', 'support', 'swagger', 'system',
+                       'tags', 'terms', 'undefined', 'user', 'users', 'www'
+                   ) AS unreachable,
+                   ROW_NUMBER() OVER (PARTITION BY lower(username) ORDER BY id) AS n
+            FROM users
+        ) AS ranked
+        WHERE n > 1 OR unreachable
+        ORDER BY id
+    LOOP
+        IF account.unreachable THEN
+            base := 'user-' || account.id;
+        ELSE
+            base := account.username || '-' || account.id;
+        END IF;
+        candidate := base;
+        attempt := 1;
+        WHILE EXISTS (SELECT 1 FROM users WHERE lower(username) = lower(candidate))
+           OR EXISTS (SELECT 1 FROM username_redirects WHERE lower(username) = lower(candidate)) LOOP
+            attempt := attempt + 1;
+            candidate := base || '-' || attempt;
+        END LOOP;
+
+        UPDATE users SET username = candidate WHERE id = account.id;
+        INSERT INTO username_redirects (username, user_id) VALUES (account.username, account.id);
+    END LOOP;
+END
+$$;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_username;
+CREATE UNIQUE INDEX uni_users_username_lower ON users (lower(username));
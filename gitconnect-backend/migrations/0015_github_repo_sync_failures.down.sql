@@ -0,0 +1,7 @@
+DELETE FROM github_repo_syncs WHERE synced_at IS NULL;
+
+ALTER TABLE github_repo_syncs
+    DROP COLUMN last_error,
+    DROP COLUMN failures,
+    DROP COLUMN checked_at,
+    ALTER COLUMN synced_at SET NOT NULL;
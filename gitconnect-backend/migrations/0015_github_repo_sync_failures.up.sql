@@ -0,0 +1,10 @@
+-- Failed fetches are recorded so that profiles whose repositories cannot be
+-- fetched are retried with backoff. A profile whose first fetch failed has a
+-- row without synced_at.
+ALTER TABLE github_repo_syncs
+    ALTER COLUMN synced_at DROP NOT NULL,
+    ADD COLUMN checked_at timestamptz,
+    ADD COLUMN failures   integer NOT NULL DEFAULT 0,
+    ADD COLUMN last_error varchar(20) NOT NULL DEFAULT '';
+
+UPDATE github_repo_syncs SET checked_at = synced_at;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ALTER COLUMN failed_logins DROP NOT NULL,
+    ALTER COLUMN failed_logins DROP DEFAULT;
@@ -0,0 +1,7 @@
+-- Accounts created before the lockout was added have no failure count, and
+-- NULL + 1 stays NULL, so they were never locked. Count from zero instead.
+UPDATE users SET failed_logins = 0 WHERE failed_logins IS NULL;
+
+ALTER TABLE users
+    ALTER COLUMN failed_logins SET DEFAULT 0,
+    ALTER COLUMN failed_logins SET NOT NULL;
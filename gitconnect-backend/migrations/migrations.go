@@ -0,0 +1,321 @@
+// Package migrations holds the versioned SQL that defines the database schema
+// and the migrator that applies it.
+//
+// Each schema change is a pair of files named NNNN_description.up.sql and
+// NNNN_description.down.sql. They are embedded into the binary and applied in
+// version order, each in its own transaction, with the applied versions
+// recorded in schema_migrations. Applied files must never be edited; change
+// the schema by adding the next version.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock that serialises migrators across replicas
+const lockKey = 7_353_118_604
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// ErrSchemaBehind is returned by Check when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// All returns the embedded migrations in version order
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(files, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		all = append(all, *migration)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+// Migrator applies the embedded migrations to a Postgres database
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	// Logf reports each applied step; nil keeps the migrator quiet
+	Logf func(format string, args ...any)
+}
+
+// New returns a migrator for the embedded migrations
+func New(db *sql.DB) (*Migrator, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: all}, nil
+}
+
+// Latest returns the newest version known to this binary
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.Migrations[i].Version]; ok {
+				return m.revert(ctx, conn, m.Migrations[i])
+			}
+		}
+		return nil
+	})
+}
+
+// To migrates up or down until exactly the migrations up to version are applied
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.revert(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration with when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind if any known migration has not been applied.
+// A database that is ahead of the binary (mid-rollout) is accepted.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) %v", ErrSchemaBehind, len(pending), pending)
+	}
+	return nil
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// locked runs fn on a dedicated connection holding the migration advisory lock,
+// so replicas starting together never apply the same migration twice
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	m.logf("⬆️ Applied migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("revert %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	m.logf("⬇️ Reverted migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
+
+func (m *Migrator) logf(format string, args ...any) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	}
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
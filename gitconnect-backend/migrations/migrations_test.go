@@ -0,0 +1,393 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testSchema keeps these tests apart from other packages' tests on the same database
+const testSchema = "migrations_test"
+
+func TestAllAreNumberedInOrder(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	for i, migration := range all {
+		if migration.Version != int64(i+1) {
+			t.Fatalf("migration %d is %04d_%s; versions must run from 1 without gaps", i, migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestUpDownUp(t *testing.T) {
+	db := cleanSchema(t)
+	migrator := newTestMigrator(t, db)
+	ctx := context.Background()
+
+	if err := migrator.Check(ctx); err == nil {
+		t.Fatal("Check passed on an empty database")
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("Check after migrating up: %v", err)
+	}
+	want := snapshot(t, db)
+
+	// Every down file reverts exactly its own version
+	for i := len(migrator.Migrations) - 1; i >= 0; i-- {
+		if err := migrator.Down(ctx); err != nil {
+			t.Fatalf("reverting %d: %v", migrator.Migrations[i].Version, err)
+		}
+		if got := appliedUpTo(t, migrator); got != migrator.Migrations[i].Version-1 {
+			t.Fatalf("after reverting %d, applied up to %d", migrator.Migrations[i].Version, got)
+		}
+	}
+	if tables := tableNames(t, db); !reflect.DeepEqual(tables, []string{"schema_migrations"}) {
+		t.Fatalf("reverting everything left tables %v", tables)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up again: %v", err)
+	}
+	if got := snapshot(t, db); !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema differs after down and up again:\n got %v\nwant %v", got, want)
+	}
+
+	// To goes both ways, and Up with nothing pending is a no-op
+	if err := migrator.To(ctx, 3); err != nil || appliedUpTo(t, migrator) != 3 {
+		t.Fatalf("migrating to 3: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up from 3: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up with nothing pending: %v", err)
+	}
+	if err := migrator.To(ctx, migrator.Latest()+1); err == nil {
+		t.Fatal("migrating to an unknown version succeeded")
+	}
+}
+
+// Databases created before versioned migrations were set up by GORM's AutoMigrate.
+// These are the models of the first release as AutoMigrate saw them.
+type legacyUser struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Username  string `gorm:"unique;not null"`
+	Email     string `gorm:"unique;not null"`
+	Password  string
+	Profile   *legacyProfile `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type legacyProfile struct {
+	ID             uint        `gorm:"primaryKey;autoIncrement"`
+	UserID         uint        `gorm:"not null;unique;index"`
+	User           *legacyUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	FullName       string
+	Bio            string
+	Github         string
+	ProfilePicture string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type legacyPost struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	Content   string
+	UserID    uint            `gorm:"not null;index"`
+	User      legacyUser      `gorm:"foreignKey:UserID"`
+	Likes     int             `gorm:"default:0"`
+	Dislikes  int             `gorm:"default:0"`
+	Comments  []legacyComment `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type legacyComment struct {
+	ID        uint `gorm:"primaryKey"`
+	PostID    uint `gorm:"not null;index"`
+	UserID    uint
+	User      legacyUser `gorm:"foreignKey:UserID"`
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (legacyUser) TableName() string    { return "users" }
+func (legacyProfile) TableName() string { return "profiles" }
+func (legacyPost) TableName() string    { return "posts" }
+func (legacyComment) TableName() string { return "comments" }
+
+func TestBaselineAdoptsAutoMigrateSchema(t *testing.T) {
+	db := cleanSchema(t)
+	migrator := newTestMigrator(t, db)
+	ctx := context.Background()
+
+	// The schema a fresh database gets
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up: %v", err)
+	}
+	want := columns(t, db)
+	db = cleanSchema(t)
+	migrator = newTestMigrator(t, db)
+
+	// A first-release database, with data in it
+	if err := db.AutoMigrate(&legacyUser{}, &legacyProfile{}, &legacyPost{}, &legacyComment{}); err != nil {
+		t.Fatalf("auto-migrating: %v", err)
+	}
+	user := legacyUser{Username: "octocat", Email: "octocat@example.com", Password: "hash"}
+	db.Create(&user)
+	db.Create(&legacyProfile{UserID: user.ID, FullName: "Octo Cat"})
+	post := legacyPost{UserID: user.ID, Content: "hello", Likes: 3}
+	db.Create(&post)
+	db.Create(&legacyComment{PostID: post.ID, UserID: user.ID, Content: "first"})
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating the legacy schema up: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("Check after adopting the legacy schema: %v", err)
+	}
+	if got := columns(t, db); !reflect.DeepEqual(got, want) {
+		for table, cols := range want {
+			if !reflect.DeepEqual(got[table], cols) {
+				t.Errorf("%s: got columns %v, want %v", table, got[table], cols)
+			}
+		}
+		t.FailNow()
+	}
+
+	// The data survives, and the new columns take their defaults
+	var row struct {
+		Username     string
+		Role         string
+		FailedLogins int
+		Likes        int
+		Comments     int
+	}
+	err := db.Raw(`SELECT u.username, u.role, u.failed_logins, p.likes,
+		(SELECT count(*) FROM comments c WHERE c.post_id = p.id) AS comments
+		FROM users u JOIN posts p ON p.user_id = u.id`).Scan(&row).Error
+	if err != nil || row.Username != "octocat" || row.Role != "user" || row.FailedLogins != 0 || row.Likes != 3 || row.Comments != 1 {
+		t.Fatalf("got %+v (%v) after adopting the legacy schema", row, err)
+	}
+}
+
+func TestMigratorsTakeTurns(t *testing.T) {
+	db := cleanSchema(t)
+	ctx := context.Background()
+
+	// Replicas starting together all succeed, and each migration is applied once
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		migrator := newTestMigrator(t, db)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- migrator.Up(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent migration: %v", err)
+		}
+	}
+	var applied int64
+	db.Raw("SELECT count(*) FROM schema_migrations").Scan(&applied)
+	if migrator := newTestMigrator(t, db); applied != int64(len(migrator.Migrations)) {
+		t.Fatalf("schema_migrations has %d rows, want %d", applied, len(migrator.Migrations))
+	}
+
+	// A migrator waits for whoever holds the lock
+	sqlDB, _ := db.DB()
+	holder, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		t.Fatalf("taking the lock: %v", err)
+	}
+
+	waiting := newTestMigrator(t, db)
+	done := make(chan error, 1)
+	go func() { done <- waiting.Down(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("migrated while another session held the lock: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+		t.Fatalf("releasing the lock: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("migrating after the lock was released: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("migrator still waiting after the lock was released")
+	}
+}
+
+// cleanSchema connects to TEST_DATABASE_URL with testSchema emptied and first on the
+// search path, and skips the test if it is not set
+func cleanSchema(t *testing.T) *gorm.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(databaseURL), config)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	err = admin.Exec("DROP SCHEMA IF EXISTS " + testSchema + " CASCADE").Error
+	if err == nil {
+		err = admin.Exec("CREATE SCHEMA " + testSchema).Error
+	}
+	if err != nil {
+		t.Fatalf("resetting schema: %v", err)
+	}
+	if sqlDB, err := admin.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(databaseURL, testSchema)), config)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// withSearchPath sets the schema of every connection made with databaseURL, which
+// is either a postgres:// URL or a list of key=value settings
+func withSearchPath(databaseURL, schema string) string {
+	if !strings.Contains(databaseURL, "://") {
+		return databaseURL + " search_path=" + schema
+	}
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func newTestMigrator(t *testing.T, db *gorm.DB) *Migrator {
+	t.Helper()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	migrator, err := New(sqlDB)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	return migrator
+}
+
+// appliedUpTo returns the newest applied version, checking that no later one is
+// applied and no earlier one is missing
+func appliedUpTo(t *testing.T, migrator *Migrator) int64 {
+	t.Helper()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var latest int64
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			break
+		}
+		latest = status.Version
+	}
+	for _, status := range statuses[latest:] {
+		if status.AppliedAt != nil {
+			t.Fatalf("%04d_%s is applied but %d is not", status.Version, status.Name, latest+1)
+		}
+	}
+	return latest
+}
+
+// tableNames lists the tables in the test schema
+func tableNames(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	err := db.Raw(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() ORDER BY table_name`).Scan(&names).Error
+	if err != nil {
+		t.Fatalf("listing tables: %v", err)
+	}
+	return names
+}
+
+// columns describes every column in the test schema, by table
+func columns(t *testing.T, db *gorm.DB) map[string][]string {
+	t.Helper()
+	rows, err := db.Raw(`SELECT table_name, column_name, data_type, is_nullable, coalesce(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'
+		ORDER BY table_name, column_name`).Rows()
+	if err != nil {
+		t.Fatalf("listing columns: %v", err)
+	}
+	defer rows.Close()
+
+	described := map[string][]string{}
+	for rows.Next() {
+		var table, column, dataType, nullable, columnDefault string
+		if err := rows.Scan(&table, &column, &dataType, &nullable, &columnDefault); err != nil {
+			t.Fatalf("listing columns: %v", err)
+		}
+		// Sequence names depend on how the table was created, not on the schema
+		if strings.HasPrefix(columnDefault, "nextval(") {
+			columnDefault = "nextval"
+		}
+		described[table] = append(described[table], fmt.Sprintf("%s %s null=%s default=%s", column, dataType, nullable, columnDefault))
+	}
+	return described
+}
+
+// snapshot describes the columns, indexes and constraints of the test schema
+func snapshot(t *testing.T, db *gorm.DB) map[string][]string {
+	t.Helper()
+	described := columns(t, db)
+
+	var indexes, constraints []string
+	db.Raw(`SELECT tablename || ' ' || indexdef FROM pg_indexes
+		WHERE schemaname = current_schema() ORDER BY 1`).Scan(&indexes)
+	db.Raw(`SELECT conrelid::regclass || ' ' || conname || ' ' || pg_get_constraintdef(oid) FROM pg_constraint
+		WHERE connamespace = current_schema()::regnamespace ORDER BY 1`).Scan(&constraints)
+	described[" indexes"] = indexes
+	described[" constraints"] = constraints
+	return described
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Block records that one user has blocked another. Blocked users cannot
+// notify the blocker, for example by mentioning them.
+type Block struct {
+	BlockerID uint      `json:"blocker_id" gorm:"primaryKey;autoIncrement:false"`
+	BlockedID uint      `json:"blocked_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Blocker   *User     `json:"-" gorm:"foreignKey:BlockerID;constraint:OnDelete:CASCADE"`
+	Blocked   *User     `json:"blocked,omitempty" gorm:"foreignKey:BlockedID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -3,12 +3,26 @@ package models
 import "time"
 
 type Comment struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	PostID    uint      `json:"post_id" gorm:"not null;index"` // Foreign key with index
-	UserID    uint      `json:"user_id"`
-	User      User      `json:"user" gorm:"foreignKey:UserID"` // Relation with User
-	Content   string    `json:"content" binding:"required"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	PostID     uint         `json:"post_id" gorm:"not null;index"` // Foreign key with index
+	ParentID   *uint        `json:"parent_id"`                     // Comment this one replies to; nil for top-level comments
+	RootID     *uint        `json:"-"`                             // Top-level comment of the thread, so a thread loads in one query
+	Depth      int          `json:"depth" gorm:"not null;default:0"`
+	UserID     uint         `json:"user_id"`
+	User       User         `json:"user" gorm:"foreignKey:UserID"` // Relation with User
+	Content    string       `json:"content" binding:"required"`
+	Mentions   []Mention    `json:"mentions" gorm:"-"`         // @usernames in the content that name users
+	Likes      int          `json:"likes" gorm:"default:0"`    // Maintained from CommentReaction, never written directly
+	Dislikes   int          `json:"dislikes" gorm:"default:0"` // Maintained from CommentReaction, never written directly
+	MyReaction ReactionKind `json:"my_reaction,omitempty" gorm:"-"`
+	EditedAt   *time.Time   `json:"edited_at"`                  // Set when the author changes the content
+	DeletedAt  *time.Time   `json:"deleted_at,omitempty"`       // Tombstone: content and author are cleared but replies stay
+	Replies    []Comment    `json:"replies,omitempty" gorm:"-"` // Filled in the tree view
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
 }
 
+// Deleted reports whether the comment is a tombstone
+func (c Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Endorsement records that a user vouches for a skill on someone else's
+// profile. The composite primary key allows one endorsement per endorser per skill.
+type Endorsement struct {
+	SkillID    uint      `json:"skill_id" gorm:"primaryKey;autoIncrement:false"`
+	EndorserID uint      `json:"endorser_id" gorm:"primaryKey;autoIncrement:false"`
+	Endorser   *User     `json:"endorser,omitempty" gorm:"foreignKey:EndorserID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EndorsementPreviewSize is how many of the endorsers a viewer follows a skill names
+const EndorsementPreviewSize = 3
+
+// EndorsementSummary sums up a skill's endorsements for the user viewing it
+type EndorsementSummary struct {
+	Count         int64         `json:"count"`
+	Endorsed      bool          `json:"endorsed"`           // Whether the viewer endorsed the skill
+	FollowedCount int64         `json:"followed_count"`     // How many endorsers the viewer follows
+	Followed      []EndorserRef `json:"followed,omitempty"` // The latest of them, up to EndorsementPreviewSize
+}
+
+// EndorserRef names an endorser in a summary
+type EndorserRef struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Follow records that one user follows another. The composite primary key
+// makes following idempotent.
+type Follow struct {
+	FollowerID uint      `json:"follower_id" gorm:"primaryKey;autoIncrement:false"`
+	FolloweeID uint      `json:"followee_id" gorm:"primaryKey;autoIncrement:false"`
+	Follower   *User     `json:"follower,omitempty" gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE"`
+	Followee   *User     `json:"followee,omitempty" gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FollowCounts are the sizes of a user's follower and following lists
+type FollowCounts struct {
+	Followers int64 `json:"followers"`
+	Following int64 `json:"following"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxPinnedRepos caps how many repositories a profile pins
+const MaxPinnedRepos = 6
+
+// GithubRepo is a public repository of a profile's GitHub account, cached from the GitHub API
+type GithubRepo struct {
+	ID          uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProfileID   uint             `json:"profile_id" gorm:"not null"`
+	GithubID    int64            `json:"github_id" gorm:"not null"`
+	Name        string           `json:"name"`
+	FullName    string           `json:"full_name"`
+	Description string           `json:"description"`
+	URL         string           `json:"url"`
+	Homepage    string           `json:"homepage"`
+	Language    string           `json:"language"`                         // Primary language
+	Languages   map[string]int64 `json:"languages" gorm:"serializer:json"` // Bytes of code per language; null until fetched
+	Topics      []string         `json:"topics" gorm:"serializer:json"`
+	Stars       int              `json:"stars"`
+	Forks       int              `json:"forks"`
+	Fork        bool             `json:"fork"`
+	Archived    bool             `json:"archived"`
+	PushedAt    *time.Time       `json:"pushed_at"`
+	PinPosition *int             `json:"pin_position"` // Set while the owner has the repository pinned
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// GithubRepoSync records when a profile's repositories were last fetched, and
+// the ETag GitHub sent, so the next fetch can be conditional. Failed fetches are
+// counted so that the next attempt can back off.
+type GithubRepoSync struct {
+	ProfileID uint       `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Login     string     `json:"login"`
+	ETag      string     `json:"-" gorm:"column:etag"`
+	SyncedAt  *time.Time `json:"synced_at"` // Last successful fetch; nil if none succeeded yet
+	CheckedAt *time.Time `json:"-"`         // Last fetch, successful or not
+	Failures  int        `json:"-"`         // Fetches failed in a row
+	LastError string     `json:"-"`         // Why the last fetch failed, e.g. not_found
+}
+
+// githubLogin matches GitHub usernames: alphanumerics and single inner hyphens
+var githubLogin = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9]){0,38}$`)
+
+// GithubHandle returns the GitHub login the profile shows, or "" if it has none.
+// The verified login wins; otherwise Github may hold a login, @login or a profile URL.
+func (p *Profile) GithubHandle() string {
+	if p.GithubLogin != "" {
+		return p.GithubLogin
+	}
+	handle := strings.TrimSpace(p.Github)
+	for _, prefix := range []string{"https://", "http://", "www.", "github.com/", "@"} {
+		if len(handle) >= len(prefix) && strings.EqualFold(handle[:len(prefix)], prefix) {
+			handle = handle[len(prefix):]
+		}
+	}
+	handle = strings.TrimSuffix(handle, "/")
+	if !githubLogin.MatchString(handle) {
+		return ""
+	}
+	return handle
+}
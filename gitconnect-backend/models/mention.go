@@ -0,0 +1,64 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxMentions caps how many distinct users one post or comment can mention
+const MaxMentions = 20
+
+// Mention links an @username in a post or a comment to the user it names.
+// Start and End delimit the "@username" text as Unicode code point offsets
+// into the content, End exclusive, so clients can render it as a link.
+type Mention struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	PostID    *uint     `json:"-" gorm:"index"` // Set for a mention in a post
+	CommentID *uint     `json:"-" gorm:"index"` // Set for a mention in a comment
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Username  string    `json:"username" gorm:"-"` // Current username of the mentioned user
+	Start     int       `json:"start" gorm:"column:start_offset;not null"`
+	End       int       `json:"end" gorm:"column:end_offset;not null"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// MentionMatch is an @username found in content, before it is resolved to a user
+type MentionMatch struct {
+	Username   string
+	Start, End int // Code point offsets of "@username", End exclusive
+}
+
+// mentionPattern matches "@name" at the start of the content or after a character
+// that cannot be part of an email address, a URL or another mention
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@/])(@[\p{L}\p{N}_][\p{L}\p{N}_.-]*)`)
+
+// ExtractMentions returns every @username in content in order of appearance,
+// for up to MaxMentions distinct usernames
+func ExtractMentions(content string) []MentionMatch {
+	matches := []MentionMatch{}
+	distinct := map[string]bool{}
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := loc[2], loc[3]
+		// A trailing "." or "-" ends the sentence rather than the username
+		for end > start+2 && strings.ContainsRune(".-", rune(content[end-1])) {
+			end--
+		}
+		username := content[start+1 : end]
+		if !distinct[strings.ToLower(username)] {
+			if len(distinct) == MaxMentions {
+				continue
+			}
+			distinct[strings.ToLower(username)] = true
+		}
+
+		runeStart := utf8.RuneCountInString(content[:start])
+		matches = append(matches, MentionMatch{
+			Username: username,
+			Start:    runeStart,
+			End:      runeStart + utf8.RuneCountInString(content[start:end]),
+		})
+	}
+	return matches
+}
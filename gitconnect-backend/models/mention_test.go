@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	for content, want := range map[string][]MentionMatch{
+		"@alice":                      {{"alice", 0, 6}},
+		"hi @alice and @bob.":         {{"alice", 3, 9}, {"bob", 14, 18}},
+		"(@alice), @bob-":             {{"alice", 1, 7}, {"bob", 10, 14}},
+		"@first.last and @snake_case": {{"first.last", 0, 11}, {"snake_case", 16, 27}},
+		// Offsets count code points, not bytes
+		"héllo 👋 @zoë!": {{"zoë", 8, 12}},
+		// Repeats are all linked
+		"@alice @ALICE": {{"alice", 0, 6}, {"ALICE", 7, 13}},
+		// Emails, URLs and doubled signs are not mentions
+		"mail me@example.com":            {},
+		"see https://example.com/@alice": {},
+		"@@alice":                        {},
+		"@":                              {},
+		"@.alice":                        {},
+	} {
+		if got := ExtractMentions(content); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestExtractMentionsCapsDistinctUsers(t *testing.T) {
+	var names []string
+	for i := 0; i < MaxMentions+5; i++ {
+		names = append(names, fmt.Sprintf("@user%d", i))
+	}
+	// A user already mentioned is still linked past the cap
+	content := strings.Join(names, " ") + " @user0"
+
+	matches := ExtractMentions(content)
+	if len(matches) != MaxMentions+1 {
+		t.Fatalf("got %d matches, want %d", len(matches), MaxMentions+1)
+	}
+	if last := matches[len(matches)-1]; last.Username != "user0" || last.End != len([]rune(content)) {
+		t.Fatalf("got last match %+v, want the repeated @user0 at the end", last)
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// MaxConversationMembers caps a group conversation, its creator included
+const MaxConversationMembers = 10
+
+// MaxMessageLength caps the content of a message, in characters
+const MaxMessageLength = 4000
+
+// MemberState is where a conversation shows up for one of its members
+type MemberState string
+
+const (
+	MemberAccepted  MemberState = "accepted"  // In the member's inbox
+	MemberRequested MemberState = "requested" // A message request: the member does not follow whoever added them
+)
+
+// Conversation is a private exchange of messages between its members. A direct
+// conversation has exactly two members and there is at most one per pair.
+type Conversation struct {
+	ID          uint                 `json:"id" gorm:"primaryKey"`
+	CreatorID   *uint                `json:"creator_id"` // Nil once the creator's account is deleted
+	Title       string               `json:"title" gorm:"size:100;not null;default:''"`
+	DirectKey   *string              `json:"-" gorm:"size:41;uniqueIndex"` // Both member IDs, lowest first; nil for groups
+	Direct      bool                 `json:"direct" gorm:"-"`
+	Members     []ConversationMember `json:"members,omitempty"`
+	LastMessage *Message             `json:"last_message,omitempty" gorm:"-"`
+	UnreadCount int64                `json:"unread_count" gorm:"-"` // Messages from others after the caller's read receipt
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"` // When the latest message was sent
+}
+
+// DirectKey identifies the direct conversation between two users
+func DirectKey(a, b uint) string {
+	if a > b {
+		a, b = b, a
+	}
+	return strconv.FormatUint(uint64(a), 10) + ":" + strconv.FormatUint(uint64(b), 10)
+}
+
+// Member returns the membership of userID, if they are a member
+func (c Conversation) Member(userID uint) (ConversationMember, bool) {
+	for _, member := range c.Members {
+		if member.UserID == userID {
+			return member, true
+		}
+	}
+	return ConversationMember{}, false
+}
+
+// MemberIDs returns the IDs of the conversation's members
+func (c Conversation) MemberIDs() []uint {
+	ids := make([]uint, len(c.Members))
+	for i, member := range c.Members {
+		ids[i] = member.UserID
+	}
+	return ids
+}
+
+// ConversationMember is one user's place in a conversation. The last message
+// they read is their read receipt, which the other members can see.
+type ConversationMember struct {
+	ConversationID    uint        `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	UserID            uint        `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	User              *User       `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	State             MemberState `json:"state" gorm:"size:16;not null"`
+	LastReadMessageID *uint       `json:"last_read_message_id"`
+	LastReadAt        *time.Time  `json:"last_read_at"`
+	CreatedAt         time.Time   `json:"joined_at"`
+}
+
+// Message is one message in a conversation
+type Message struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ConversationID uint      `json:"conversation_id" gorm:"not null"`
+	SenderID       uint      `json:"sender_id" gorm:"not null"`
+	Sender         *User     `json:"sender,omitempty" gorm:"foreignKey:SenderID;constraint:OnDelete:CASCADE"`
+	Content        string    `json:"content" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReadReceipt reports how far a member has read a conversation
+type ReadReceipt struct {
+	ConversationID    uint      `json:"conversation_id"`
+	UserID            uint      `json:"user_id"`
+	LastReadMessageID uint      `json:"last_read_message_id"`
+	LastReadAt        time.Time `json:"last_read_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that can replace a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the normalized code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// NotificationType is the event a notification reports
+type NotificationType string
+
+const (
+	NotificationMention     NotificationType = "mention"     // The actor mentioned the user in a post or a comment
+	NotificationComment     NotificationType = "comment"     // The actor commented on the user's post
+	NotificationReply       NotificationType = "reply"       // The actor replied to the user's comment
+	NotificationReaction    NotificationType = "reaction"    // The actor liked the user's post or comment
+	NotificationFollow      NotificationType = "follow"      // The actor started following the user
+	NotificationEndorsement NotificationType = "endorsement" // The actor endorsed a skill on the user's profile
+)
+
+// NotificationTypes lists every notification type, in the order preferences are shown
+var NotificationTypes = []NotificationType{NotificationComment, NotificationReply, NotificationReaction, NotificationFollow, NotificationEndorsement, NotificationMention}
+
+// NotificationBurstWindow is how long an unread notification keeps absorbing
+// the same event from other actors, as in "Alice and 4 others liked your post"
+const NotificationBurstWindow = 24 * time.Hour
+
+// Valid reports whether t is a known notification type
+func (t NotificationType) Valid() bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Aggregates reports whether notifications of this type are grouped by what
+// they point at. Each mention stands on its own.
+func (t NotificationType) Aggregates() bool {
+	return t != NotificationMention
+}
+
+// Notification tells a user about something other users did that concerns them.
+// An aggregated notification counts every actor once and names the latest one.
+type Notification struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	UserID     uint             `json:"-" gorm:"not null"` // Recipient
+	ActorID    uint             `json:"actor_id" gorm:"not null"`
+	Actor      *User            `json:"actor,omitempty" gorm:"foreignKey:ActorID;constraint:OnDelete:CASCADE"`
+	ActorCount int              `json:"actor_count" gorm:"not null;default:1"`
+	Type       NotificationType `json:"type" gorm:"type:varchar(32);not null"`
+	PostID     *uint            `json:"post_id,omitempty"`
+	CommentID  *uint            `json:"comment_id,omitempty"`
+	SkillID    *uint            `json:"skill_id,omitempty"`
+	Summary    string           `json:"summary" gorm:"-"`
+	ReadAt     *time.Time       `json:"read_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"` // When the latest actor joined
+}
+
+// Describe returns a one-line summary such as "alice and 4 others liked your post".
+// Actor must be loaded.
+func (n Notification) Describe() string {
+	who := "Someone"
+	if n.Actor != nil {
+		who = n.Actor.Username
+	}
+	switch others := n.ActorCount - 1; {
+	case others == 1:
+		who += " and 1 other"
+	case others > 1:
+		who += " and " + strconv.Itoa(others) + " others"
+	}
+
+	switch n.Type {
+	case NotificationMention:
+		if n.CommentID != nil {
+			return who + " mentioned you in a comment"
+		}
+		return who + " mentioned you in a post"
+	case NotificationComment:
+		return who + " commented on your post"
+	case NotificationReply:
+		return who + " replied to your comment"
+	case NotificationReaction:
+		if n.CommentID != nil {
+			return who + " liked your comment"
+		}
+		return who + " liked your post"
+	case NotificationFollow:
+		return who + " followed you"
+	case NotificationEndorsement:
+		return who + " endorsed your skill"
+	}
+	return who + " did something"
+}
+
+// NotificationActor records each user counted in an aggregated notification
+type NotificationActor struct {
+	NotificationID uint `gorm:"primaryKey;autoIncrement:false"`
+	ActorID        uint `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt      time.Time
+}
+
+// NotificationPreference turns one type of notification on or off for a user.
+// Types without a stored preference are on.
+type NotificationPreference struct {
+	UserID    uint             `gorm:"primaryKey;autoIncrement:false"`
+	Type      NotificationType `gorm:"primaryKey;type:varchar(32)"`
+	Enabled   bool             `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OAuthState is a pending OAuth2 authorization request.
+// It is created when the user is sent to the provider and deleted when the
+// provider redirects back, so each state value can be used exactly once.
+type OAuthState struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	StateHash    string    `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	CodeVerifier string    `json:"-" gorm:"not null"` // PKCE verifier sent with the code exchange
+	UserID       *uint     `json:"user_id"`           // Set when an authenticated user is linking their account
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
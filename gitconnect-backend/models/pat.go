@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes that can be granted to a personal access token
+const (
+	ScopePostsRead          = "posts:read"
+	ScopePostsWrite         = "posts:write"
+	ScopeProfileRead        = "profile:read"
+	ScopeProfileWrite       = "profile:write"
+	ScopeFollowsWrite       = "follows:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeMessagesRead       = "messages:read"
+	ScopeMessagesWrite      = "messages:write"
+	ScopeEndorsementsWrite  = "endorsements:write"
+)
+
+// TokenScopes lists every scope a personal access token may request
+var TokenScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeProfileRead, ScopeProfileWrite, ScopeFollowsWrite, ScopeNotificationsRead, ScopeNotificationsWrite,
+	ScopeMessagesRead, ScopeMessagesWrite, ScopeEndorsementsWrite}
+
+// PersonalAccessTokenPrefix starts every personal access token, which tells them apart from JWTs
+const PersonalAccessTokenPrefix = "gcp_"
+
+// PersonalAccessToken is a long-lived, named credential for scripts and CI.
+// Only the SHA-256 hash of the token is stored; Prefix keeps a few characters
+// so the owner can recognise it in listings.
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Scopes     string     `json:"-" gorm:"not null"` // Space-separated, as in OAuth scope strings
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ScopeList returns the token's scopes as a slice
+func (t PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
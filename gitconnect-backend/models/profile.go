@@ -1,17 +1,170 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
 
 // Profile represents a user's profile
 type Profile struct {
+	ID               uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID           uint              `json:"user_id" gorm:"not null;unique;index"`
+	User             *User             `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Prevent circular JSON recursion
+	Username         string            `json:"username" gorm:"-"`                                      // The user's current username, filled when the profile is read
+	FullName         string            `json:"full_name" binding:"required"`
+	Bio              string            `json:"bio"`
+	Github           string            `json:"github"`
+	GithubID         *int64            `json:"github_id" gorm:"uniqueIndex"` // Set only by the GitHub OAuth flow
+	GithubLogin      string            `json:"github_login"`                 // Verified GitHub login, set only by the GitHub OAuth flow
+	GithubVerifiedAt *time.Time        `json:"github_verified_at"`
+	PictureID        string            `json:"-" gorm:"type:varchar(64)"`           // SHA-256 of the uploaded picture, set only by the upload route
+	ProfilePicture   string            `json:"profile_picture" gorm:"-"`            // URL of the picture in the default size, filled when the profile is read
+	ProfilePictures  map[string]string `json:"profile_pictures,omitempty" gorm:"-"` // URL of the picture in each size, keyed by size
+	FollowersCount   int64             `json:"followers_count" gorm:"-"`            // Filled from the follow graph when the profile is read
+	FollowingCount   int64             `json:"following_count" gorm:"-"`
+	Skills           []Skill           `json:"skills,omitempty" gorm:"-"` // Sections are filled when asked for with include=
+	Experience       []Experience      `json:"experience,omitempty" gorm:"-"`
+	Education        []Education       `json:"education,omitempty" gorm:"-"`
+	Links            []ProfileLink     `json:"links,omitempty" gorm:"-"`
+	PinnedRepos      []GithubRepo      `json:"pinned_repos,omitempty" gorm:"-"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// Caps on how many entries each profile section holds
+const (
+	MaxSkills      = 50
+	MaxExperiences = 30
+	MaxEducations  = 20
+	MaxLinks       = 10
+)
+
+// ProfileItem holds the fields shared by the entries of every profile section.
+// Entries are listed by Position, which the owner sets by reordering the section.
+type ProfileItem struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    uint      `json:"user_id" gorm:"not null;unique;index"`
-	User      *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Prevent circular JSON recursion
-	FullName  string    `json:"full_name" binding:"required"`
-	Bio       string    `json:"bio"`
-	Github    string    `json:"github"`
-  ProfilePicture string   `json:"profile_picture"`
+	ProfileID uint      `json:"profile_id" gorm:"not null"`
+	Position  int       `json:"position"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Item returns the shared fields, so every section can be handled alike
+func (i *ProfileItem) Item() *ProfileItem {
+	return i
+}
+
+// ProfileEntry is implemented by pointers to the entries of profile sections
+type ProfileEntry[T any] interface {
+	*T
+	Item() *ProfileItem
+	// Validate tidies the entry and checks what its binding tags cannot
+	Validate() error
+}
+
+// Proficiency is how well a developer knows a skill
+type Proficiency string
+
+const (
+	ProficiencyBeginner     Proficiency = "beginner"
+	ProficiencyIntermediate Proficiency = "intermediate"
+	ProficiencyAdvanced     Proficiency = "advanced"
+	ProficiencyExpert       Proficiency = "expert"
+)
+
+// Skill is a skill listed on a profile; a profile lists each name once, ignoring case
+type Skill struct {
+	ProfileItem
+	Name         string              `json:"name" binding:"required,max=50"`
+	Proficiency  Proficiency         `json:"proficiency" binding:"omitempty,oneof=beginner intermediate advanced expert"`
+	Endorsements *EndorsementSummary `json:"endorsements,omitempty" gorm:"-"` // Filled when skills are listed
+}
+
+func (s *Skill) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Endorsements = nil // Counted by the server
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// Experience is a position held, listed on a profile. Dates are a year and month,
+// e.g. 2021-09; a position without an end date is the current one.
+type Experience struct {
+	ProfileItem
+	Title       string `json:"title" binding:"required,max=100"`
+	Company     string `json:"company" binding:"required,max=100"`
+	Location    string `json:"location" binding:"max=100"`
+	StartDate   string `json:"start_date" binding:"required,datetime=2006-01"`
+	EndDate     string `json:"end_date" binding:"omitempty,datetime=2006-01"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+func (e *Experience) Validate() error {
+	e.Title, e.Company, e.Location = strings.TrimSpace(e.Title), strings.TrimSpace(e.Company), strings.TrimSpace(e.Location)
+	if e.Title == "" || e.Company == "" {
+		return errors.New("title and company are required")
+	}
+	return checkPeriod(e.StartDate, e.EndDate)
+}
+
+// Education is a course of study listed on a profile, with dates like Experience
+type Education struct {
+	ProfileItem
+	School       string `json:"school" binding:"required,max=100"`
+	Degree       string `json:"degree" binding:"max=100"`
+	FieldOfStudy string `json:"field_of_study" binding:"max=100"`
+	StartDate    string `json:"start_date" binding:"omitempty,datetime=2006-01"`
+	EndDate      string `json:"end_date" binding:"omitempty,datetime=2006-01"`
+	Description  string `json:"description" binding:"max=2000"`
+}
+
+func (e *Education) Validate() error {
+	e.School, e.Degree, e.FieldOfStudy = strings.TrimSpace(e.School), strings.TrimSpace(e.Degree), strings.TrimSpace(e.FieldOfStudy)
+	if e.School == "" {
+		return errors.New("school is required")
+	}
+	return checkPeriod(e.StartDate, e.EndDate)
+}
+
+// checkPeriod rejects an end date before the start date. The dates sort as strings.
+func checkPeriod(start, end string) error {
+	if start != "" && end != "" && end < start {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// LinkKind says what a profile link points at, so clients can pick its icon
+type LinkKind string
+
+const (
+	LinkWebsite  LinkKind = "website"
+	LinkBlog     LinkKind = "blog"
+	LinkGithub   LinkKind = "github"
+	LinkLinkedIn LinkKind = "linkedin"
+	LinkTwitter  LinkKind = "twitter"
+	LinkMastodon LinkKind = "mastodon"
+	LinkOther    LinkKind = "other"
+)
+
+// ProfileLink is a website or social account listed on a profile
+type ProfileLink struct {
+	ProfileItem
+	Kind  LinkKind `json:"kind" binding:"required,oneof=website blog github linkedin twitter mastodon other"`
+	Label string   `json:"label" binding:"max=50"`
+	URL   string   `json:"url" binding:"required,max=2048,url"`
+}
+
+func (l *ProfileLink) Validate() error {
+	l.Label = strings.TrimSpace(l.Label)
+	// Only web links, so a profile cannot carry javascript: or data: URLs
+	parsed, err := url.Parse(l.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an http or https URL")
+	}
+	return nil
+}
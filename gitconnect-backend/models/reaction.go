@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// ReactionKind is the kind of reaction a user can leave on a post
+type ReactionKind string
+
+const (
+	ReactionLike    ReactionKind = "like"
+	ReactionDislike ReactionKind = "dislike"
+)
+
+// CounterColumn returns the posts column that caches the count for this kind
+func (k ReactionKind) CounterColumn() string {
+	if k == ReactionDislike {
+		return "dislikes"
+	}
+	return "likes"
+}
+
+// PostReaction records one user's reaction to one post.
+// The composite primary key guarantees a user holds at most one reaction per post;
+// Post.Likes and Post.Dislikes are counters maintained from this ledger.
+type PostReaction struct {
+	PostID    uint         `json:"post_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint         `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Post      *Post        `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	User      *User        `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Kind      ReactionKind `json:"kind" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// CommentReaction records one user's reaction to one comment, like PostReaction
+type CommentReaction struct {
+	CommentID uint         `json:"comment_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint         `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Comment   *Comment     `json:"-" gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE"`
+	User      *User        `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Kind      ReactionKind `json:"kind" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
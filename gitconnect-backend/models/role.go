@@ -0,0 +1,74 @@
+package models
+
+// Role is a user's position in the permission hierarchy
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is an action that is not implied by owning the affected resource
+type Permission string
+
+const (
+	PermPostCreate       Permission = "post:create"
+	PermPostReact        Permission = "post:react"
+	PermPostUpdateAny    Permission = "post:update:any"
+	PermPostDeleteAny    Permission = "post:delete:any"
+	PermCommentCreate    Permission = "comment:create"
+	PermCommentReact     Permission = "comment:react"
+	PermUserFollow       Permission = "user:follow"
+	PermMessageSend      Permission = "message:send"
+	PermSkillEndorse     Permission = "skill:endorse"
+	PermCommentDeleteAny Permission = "comment:delete:any"
+	PermProfileUpdateAny Permission = "profile:update:any"
+	PermProfileDeleteAny Permission = "profile:delete:any"
+	PermUserList         Permission = "user:list"
+	PermUserSuspend      Permission = "user:suspend"
+	PermUserBan          Permission = "user:ban"
+	PermUserManageRoles  Permission = "user:manage_roles"
+	PermUserDelete       Permission = "user:delete"
+)
+
+// rolePermissions is the permission matrix. Each role includes the permissions of the roles below it.
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermPostCreate, PermPostReact, PermCommentCreate, PermCommentReact, PermUserFollow, PermMessageSend, PermSkillEndorse,
+	},
+	RoleModerator: {
+		PermPostCreate, PermPostReact, PermCommentCreate, PermCommentReact, PermUserFollow, PermMessageSend, PermSkillEndorse,
+		PermPostDeleteAny, PermCommentDeleteAny, PermUserList, PermUserSuspend,
+	},
+	RoleAdmin: {
+		PermPostCreate, PermPostReact, PermCommentCreate, PermCommentReact, PermUserFollow, PermMessageSend, PermSkillEndorse,
+		PermPostDeleteAny, PermCommentDeleteAny, PermUserList, PermUserSuspend,
+		PermPostUpdateAny, PermProfileUpdateAny, PermProfileDeleteAny,
+		PermUserBan, PermUserManageRoles, PermUserDelete,
+	},
+}
+
+// roleRank orders roles so that staff can only act on users below them
+var roleRank = map[Role]int{RoleUser: 0, RoleModerator: 1, RoleAdmin: 2}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Can reports whether the role grants permission p
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks reports whether r is strictly above other in the hierarchy
+func (r Role) Outranks(other Role) bool {
+	return roleRank[r] > roleRank[other]
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// SearchType is a kind of record returned by search
+type SearchType string
+
+const (
+	SearchPost    SearchType = "post"
+	SearchProfile SearchType = "profile"
+	SearchComment SearchType = "comment"
+)
+
+// SearchTypes lists every searchable type
+var SearchTypes = []SearchType{SearchPost, SearchProfile, SearchComment}
+
+// Valid reports whether t is a known search type
+func (t SearchType) Valid() bool {
+	for _, known := range SearchTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchResult is one match of a search. It is read-only and not a table.
+type SearchResult struct {
+	Type      SearchType `json:"type"`
+	ID        uint       `json:"id"`                // ID of the post, profile or comment
+	PostID    *uint      `json:"post_id,omitempty"` // Post a comment belongs to
+	UserID    uint       `json:"user_id"`           // Author, or owner of the profile
+	Username  string     `json:"username"`
+	Rank      float64    `json:"rank"`
+	Snippet   string     `json:"snippet"` // HTML-escaped excerpt with the matched terms wrapped in <mark>
+	CreatedAt time.Time  `json:"created_at"`
+}
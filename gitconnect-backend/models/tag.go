@@ -0,0 +1,85 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxTagLength is the longest hashtag recognized in content, without the "#"
+const MaxTagLength = 50
+
+// Tag is a normalized hashtag: lower case, without the "#"
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostTag links a post to a hashtag in its content. The links are rewritten
+// whenever the content changes.
+type PostTag struct {
+	PostID    uint      `json:"post_id" gorm:"primaryKey;autoIncrement:false"`
+	TagID     uint      `json:"tag_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Post      *Post     `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Tag       *Tag      `json:"-" gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagFollow puts a hashtag's posts in a user's home feed
+type TagFollow struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	TagID     uint      `json:"tag_id" gorm:"primaryKey;autoIncrement:false;index"`
+	User      *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Tag       *Tag      `json:"-" gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TrendingTag is a hashtag with its activity over a time window
+type TrendingTag struct {
+	Name    string `json:"name"`
+	Posts   int64  `json:"posts"`   // Posts tagged within the window
+	Authors int64  `json:"authors"` // Distinct authors of those posts
+}
+
+// hashtagPattern matches "#word" at the start of the content or after a character
+// that cannot be part of a word, a URL fragment or an HTML entity
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+)`)
+
+// NormalizeTag returns the canonical form of a hashtag name, with or without its
+// "#", and whether it is a valid tag: up to MaxTagLength letters, digits and
+// underscores, with at least one letter
+func NormalizeTag(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "#"))
+	if name == "" || len([]rune(name)) > MaxTagLength {
+		return "", false
+	}
+	hasLetter := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r), r == '_':
+		default:
+			return "", false
+		}
+	}
+	return name, hasLetter
+}
+
+// ExtractHashtags returns the distinct normalized hashtags in content, sorted by name
+func ExtractHashtags(content string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		name, ok := NormalizeTag(match[1])
+		if ok && !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	for content, want := range map[string][]string{
+		"#Go and #go, #GIN":                       {"gin", "go"},
+		"(#rust) #über_cool!":                     {"rust", "über_cool"},
+		"issue #42 and #2024":                     {},
+		"#v2 ships":                               {"v2"},
+		"see https://example.com/page#section":    {},
+		"AT&#38;T or C#dev or ##double":           {},
+		"#" + strings.Repeat("a", MaxTagLength+1): {},
+	} {
+		if got := ExtractHashtags(content); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: got %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	for name, want := range map[string]string{
+		"#GoLang":                         "golang",
+		"go_1":                            "go_1",
+		strings.Repeat("é", MaxTagLength): strings.Repeat("é", MaxTagLength),
+		"":                                "",
+		"#":                               "",
+		"123":                             "",
+		"c++":                             "",
+		"two words":                       "",
+	} {
+		got, ok := NormalizeTag(name)
+		if ok != (want != "") || ok && got != want {
+			t.Errorf("%q: got %q (%v), want %q", name, got, ok, want)
+		}
+	}
+}
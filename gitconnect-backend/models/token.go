@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// RefreshToken is a rotating, single-use refresh token.
+// Only the SHA-256 hash of the token is stored. Every token issued from the same
+// login shares a FamilyID so that replaying a rotated token can revoke them all.
+type RefreshToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	FamilyID   string     `json:"-" gorm:"size:64;not null;index"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *uint      `json:"-"` // Set when the token was rotated rather than revoked
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RevokedToken is a denylist entry for an access token that was revoked before it expired.
+// Rows can be purged once ExpiresAt has passed, since the JWT itself is no longer valid.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenPurpose says what a one-time token may be used for
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify_email"
+	PurposeResetPassword TokenPurpose = "reset_password"
+)
+
+// OneTimeToken is a single-use, time-limited token sent to the user by email.
+// Only the SHA-256 hash is stored; UsedAt is set when the token is consumed.
+type OneTimeToken struct {
+	ID        uint         `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint         `json:"user_id" gorm:"not null;index"`
+	User      *User        `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"size:32;not null"`
+	TokenHash string       `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time   `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -4,12 +4,26 @@ import "time"
 
 // User represents a registered user
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Username  string    `json:"username" gorm:"unique;not null"`
-	Email     string    `json:"email" gorm:"unique;not null"`
-	Password  string    `json:"-"` // Exclude password from JSON response
-	Profile   *Profile  `json:"profile,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Use pointer to avoid recursion
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username        string     `json:"username" gorm:"unique;not null"`
+	Email           string     `json:"email" gorm:"unique;not null"`
+	Password        string     `json:"-"`                 // Exclude password from JSON response
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // Nil until the user follows the verification link
+	TOTPSecret      string     `json:"-"`                 // Authenticator secret; pending until TOTPEnabledAt is set
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at"`   // Nil unless two-factor login is on
+	TOTPLastCounter int64      `json:"-"`                 // Last accepted TOTP time step, to reject replayed codes
+	FailedLogins    int        `json:"-"`                 // Consecutive failed sign-ins since the last successful login
+	LockedUntil     *time.Time `json:"-"`                 // Sign-in is refused until then after too many failures
+	Role            Role       `json:"role" gorm:"size:16;not null;default:user"`
+	SuspendedUntil  *time.Time `json:"suspended_until,omitempty"`                                              // Read-only and unable to sign in until then
+	BannedAt        *time.Time `json:"banned_at,omitempty"`                                                    // Permanently unable to sign in
+	ModerationNote  string     `json:"-"`                                                                      // Reason given for the last suspension or ban
+	Profile         *Profile   `json:"profile,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Use pointer to avoid recursion
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// Restricted reports whether the account is banned or currently suspended
+func (u User) Restricted(now time.Time) bool {
+	return u.BannedAt != nil || (u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil))
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Bounds on the length of a username
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 39
+)
+
+// MaxUsernameRedirects is how many previous usernames keep pointing at an
+// account; older ones are released for others to take
+const MaxUsernameRedirects = 5
+
+var (
+	// usernamePattern allows letters, digits, hyphens and underscores, starting
+	// and ending with a letter or digit
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9_-]*[A-Za-z0-9])?$`)
+	// usernameLetter requires a letter, so a username never reads as a user ID
+	usernameLetter = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// reservedUsernames are names nobody can register, since they would pass for
+// the site itself or collide with its pages
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true, "api": true, "auth": true,
+	"blocks": true, "conversations": true, "docs": true, "edit": true, "explore": true, "feed": true,
+	"gitconnect": true, "help": true, "home": true, "login": true, "logout": true, "me": true,
+	"messages": true, "moderator": true, "new": true, "notifications": true, "null": true, "oauth": true,
+	"official": true, "posts": true, "privacy": true, "profile": true, "profiles": true, "register": true,
+	"root": true, "search": true, "security": true, "settings": true, "signin": true, "signup": true,
+	"staff": true, "static": true, "status": true, "stream": true, "support": true, "swagger": true,
+	"system": true, "tags": true, "terms": true, "undefined": true, "user": true, "users": true, "www": true,
+}
+
+var (
+	ErrUsernameLength   = errors.New("username must be 3 to 39 characters long")
+	ErrUsernameFormat   = errors.New("username may only contain letters, digits, hyphens and underscores, and must start and end with a letter or digit")
+	ErrUsernameLetter   = errors.New("username must contain a letter")
+	ErrUsernameReserved = errors.New("username is reserved")
+)
+
+// ValidateUsername checks a username chosen at registration or in a rename.
+// Usernames are unique whatever their case, and keep the case they were chosen in.
+func ValidateUsername(username string) error {
+	switch {
+	case len(username) < MinUsernameLength || len(username) > MaxUsernameLength:
+		return ErrUsernameLength
+	case !usernamePattern.MatchString(username):
+		return ErrUsernameFormat
+	case !usernameLetter.MatchString(username):
+		return ErrUsernameLetter
+	case reservedUsernames[strings.ToLower(username)]:
+		return ErrUsernameReserved
+	}
+	return nil
+}
+
+// UsernameRedirect keeps a previous username of an account pointing at it, so
+// links to the old name keep working and nobody else can take it over
+type UsernameRedirect struct {
+	ID        uint      `gorm:"primaryKey"`
+	Username  string    `gorm:"not null"`
+	UserID    uint      `gorm:"not null"`
+	CreatedAt time.Time // When the account moved off the name
+}
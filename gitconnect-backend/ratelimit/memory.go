@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle, full buckets are dropped from memory
+const sweepInterval = time.Minute
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per replica and reset on restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	// Now returns the current time; replaceable in tests
+	Now func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, Now: time.Now}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+
+	rate := limit.perSecond()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = seconds((float64(limit.Burst) - b.tokens) / rate)
+	return result, nil
+}
+
+// sweep forgets buckets that have refilled completely, since a new bucket is identical
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		refilled := b.tokens + now.Sub(b.updated).Seconds()*b.limit.perSecond()
+		if refilled >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// seconds converts a fractional number of seconds to a Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit is a token bucket: Requests tokens refill evenly over Period, and up to
+// Burst can be spent at once. A zero Limit means unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// perSecond is the refill rate of the bucket
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Unlimited reports whether the limit is disabled
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// String formats the limit the way ParseLimit reads it
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	s := strconv.Itoa(l.Requests) + "/" + l.Period.String()
+	if l.Burst != l.Requests {
+		s += "," + strconv.Itoa(l.Burst)
+	}
+	return s
+}
+
+// ParseLimit reads "<requests>/<period>[,<burst>]", e.g. "10/1m" or "100/1h,20".
+// The period may omit its count ("10/m"). "off" disables the limit.
+// Burst defaults to requests.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(value, ",")
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+
+	period = strings.TrimSpace(period)
+	if period != "" && strings.IndexAny(period[:1], "0123456789") < 0 {
+		period = "1" + period
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in rate limit %q", value)
+	}
+
+	limit := Limit{Requests: requests, Period: duration, Burst: requests}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("invalid burst in rate limit %q", value)
+		}
+	}
+	return limit, nil
+}
+
+// Result is the outcome of taking one token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	RetryAfter time.Duration // How long until a request would be allowed; zero when Allowed
+	ResetAfter time.Duration // How long until the bucket is full again
+}
+
+// SetHeaders writes the RateLimit-* headers from the IETF httpapi draft, plus
+// Retry-After when the request was refused
+func (r Result) SetHeaders(h http.Header) {
+	if r.Limit.Unlimited() {
+		return
+	}
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", r.Limit.Burst, int(math.Ceil(r.Limit.Period.Seconds()))))
+	h.Set("RateLimit-Limit", strconv.Itoa(r.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(r.ResetAfter)))
+	if !r.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(r.RetryAfter)))
+	}
+}
+
+// ceilSeconds rounds up so clients never retry too early
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies the limit configured for a named group to keys within it
+type Limiter struct {
+	Store  Store
+	Limits map[string]Limit
+}
+
+// Allow takes a token for key from the bucket of group. Groups without a
+// configured limit are unlimited.
+func (l *Limiter) Allow(ctx context.Context, group, key string) (Result, error) {
+	limit := l.Limits[group]
+	if limit.Unlimited() {
+		return Result{Allowed: true, Limit: limit}, nil
+	}
+	return l.Store.Take(ctx, group+":"+key, limit)
+}
+
+// Group names shared by the routes and the controllers
+const (
+	GroupAuth    = "auth"    // Public auth endpoints, per client IP
+	GroupLogin   = "login"   // Password and second-factor attempts, per account
+	GroupAccount = "account" // Account management, per user
+	GroupWrite   = "write"   // Creating and changing content, per user
+	GroupAdmin   = "admin"   // Staff API, per user
+	GroupSearch  = "search"  // Full-text search, per user or client IP
+	GroupGithub  = "github"  // Public reads that may fetch from the GitHub API, per user or client IP
+)
+
+// DefaultLimits apply unless overridden by RATE_LIMIT_<GROUP>
+var DefaultLimits = map[string]Limit{
+	GroupAuth:    {Requests: 20, Period: time.Minute, Burst: 20},
+	GroupLogin:   {Requests: 5, Period: time.Minute, Burst: 5},
+	GroupAccount: {Requests: 30, Period: time.Minute, Burst: 30},
+	GroupWrite:   {Requests: 60, Period: time.Minute, Burst: 60},
+	GroupAdmin:   {Requests: 120, Period: time.Minute, Burst: 120},
+	GroupSearch:  {Requests: 30, Period: time.Minute, Burst: 30},
+	GroupGithub:  {Requests: 20, Period: time.Minute, Burst: 20},
+}
+
+// Configure builds the limiter from the environment:
+//
+//	RATE_LIMIT_STORE    memory (default) or redis; use redis when running several replicas
+//	REDIS_URL           redis://[user:password@]host:port/db for the redis store
+//	RATE_LIMIT_<GROUP>  override a group's limit, e.g. RATE_LIMIT_LOGIN=5/1m or "off"
+func Configure() (*Limiter, error) {
+	limits := make(map[string]Limit, len(DefaultLimits))
+	for group, limit := range DefaultLimits {
+		if value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(group)); value != "" {
+			var err error
+			if limit, err = ParseLimit(value); err != nil {
+				return nil, fmt.Errorf("RATE_LIMIT_%s: %w", strings.ToUpper(group), err)
+			}
+		}
+		limits[group] = limit
+	}
+
+	var store Store
+	switch driver := os.Getenv("RATE_LIMIT_STORE"); driver {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "redis":
+		redisURL := os.Getenv("REDIS_URL")
+		if redisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is not set")
+		}
+		options, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		client := redis.NewClient(options)
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			return nil, fmt.Errorf("redis is unreachable: %w", err)
+		}
+		store = NewRedisStore(client)
+	default:
+		return nil, fmt.Errorf("unsupported RATE_LIMIT_STORE %q", driver)
+	}
+
+	log.Printf("🚦 Rate limiting with %T", store)
+	return &Limiter{Store: store, Limits: limits}, nil
+}
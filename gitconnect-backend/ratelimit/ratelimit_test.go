@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Limit
+	}{
+		{"10/1m", Limit{Requests: 10, Period: time.Minute, Burst: 10}},
+		{"10/m", Limit{Requests: 10, Period: time.Minute, Burst: 10}},
+		{" 100/1h,20 ", Limit{Requests: 100, Period: time.Hour, Burst: 20}},
+		{"off", Limit{}},
+		{"0", Limit{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "10", "x/1m", "0/1m", "10/0s", "10/fortnight", "10/1m,0", "10/1m,x"} {
+		if _, err := ParseLimit(value); err == nil {
+			t.Errorf("ParseLimit(%q) accepted an invalid limit", value)
+		}
+	}
+}
+
+// clock is a manually advanced time source
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// stores returns each store with a way to move its clock forward
+func stores(t *testing.T) map[string]struct {
+	store   Store
+	advance func(time.Duration)
+} {
+	t.Helper()
+
+	memoryClock := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	memory := NewMemoryStore()
+	memory.Now = memoryClock.Now
+
+	server := miniredis.RunT(t)
+	redisClock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(redisClock)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return map[string]struct {
+		store   Store
+		advance func(time.Duration)
+	}{
+		"memory": {memory, memoryClock.Advance},
+		"redis": {NewRedisStore(client), func(d time.Duration) {
+			redisClock = redisClock.Add(d)
+			server.SetTime(redisClock)
+			server.FastForward(d)
+		}},
+	}
+}
+
+func TestStoresSpendAndRefillBuckets(t *testing.T) {
+	limit := Limit{Requests: 6, Period: time.Minute, Burst: 3} // One token every 10s
+	ctx := context.Background()
+
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 2; i >= 0; i-- {
+				result, err := s.store.Take(ctx, "login:octocat", limit)
+				if err != nil || !result.Allowed || result.Remaining != i {
+					t.Fatalf("take %d: got %+v, %v", 3-i, result, err)
+				}
+			}
+
+			refused, err := s.store.Take(ctx, "login:octocat", limit)
+			if err != nil || refused.Allowed {
+				t.Fatalf("took past the burst: %+v, %v", refused, err)
+			}
+			if refused.RetryAfter <= 0 || refused.RetryAfter > 10*time.Second {
+				t.Errorf("got RetryAfter %v, want at most one refill interval", refused.RetryAfter)
+			}
+			if refused.ResetAfter < 29*time.Second || refused.ResetAfter > 30*time.Second {
+				t.Errorf("got ResetAfter %v, want about three refill intervals", refused.ResetAfter)
+			}
+
+			// Other keys have buckets of their own
+			if other, _ := s.store.Take(ctx, "login:hubot", limit); !other.Allowed {
+				t.Error("another key shares the bucket")
+			}
+
+			// One interval refills exactly one token
+			s.advance(10 * time.Second)
+			if result, _ := s.store.Take(ctx, "login:octocat", limit); !result.Allowed {
+				t.Fatalf("no token after one refill interval: %+v", result)
+			}
+			if result, _ := s.store.Take(ctx, "login:octocat", limit); result.Allowed {
+				t.Fatalf("more than one token after one refill interval: %+v", result)
+			}
+
+			// Refilling never goes past the burst
+			s.advance(time.Hour)
+			for i := 0; i < 3; i++ {
+				if result, _ := s.store.Take(ctx, "login:octocat", limit); !result.Allowed {
+					t.Fatalf("take %d after a full refill was refused", i+1)
+				}
+			}
+			if result, _ := s.store.Take(ctx, "login:octocat", limit); result.Allowed {
+				t.Fatal("bucket refilled past its burst")
+			}
+		})
+	}
+}
+
+func TestRedisStoreExpiresIdleBuckets(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	store := NewRedisStore(client)
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 10}
+	if _, err := store.Take(context.Background(), "write:1", limit); err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !server.Exists(keyPrefix + "write:1") {
+		t.Fatal("bucket was not stored under the key prefix")
+	}
+
+	// A bucket is kept only until it would have refilled, plus a second
+	server.FastForward(3 * time.Second)
+	if server.Exists(keyPrefix + "write:1") {
+		t.Fatal("idle bucket was not expired")
+	}
+}
+
+func TestRedisStoreReportsUnavailableServer(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	server.Close()
+
+	if _, err := NewRedisStore(client).Take(context.Background(), "login:octocat", DefaultLimits[GroupLogin]); err == nil {
+		t.Fatal("Take succeeded without a server")
+	}
+}
+
+func TestLimiterGroups(t *testing.T) {
+	limiter := &Limiter{Store: NewMemoryStore(), Limits: map[string]Limit{
+		GroupLogin: {Requests: 1, Period: time.Minute, Burst: 1},
+	}}
+	ctx := context.Background()
+
+	if result, _ := limiter.Allow(ctx, GroupLogin, "octocat"); !result.Allowed {
+		t.Fatal("first login attempt was refused")
+	}
+	if result, _ := limiter.Allow(ctx, GroupLogin, "octocat"); result.Allowed {
+		t.Fatal("second login attempt was allowed")
+	}
+
+	// Groups are separate buckets, and groups without a limit are unlimited
+	for i := 0; i < 5; i++ {
+		if result, _ := limiter.Allow(ctx, GroupWrite, "octocat"); !result.Allowed {
+			t.Fatal("unlimited group refused a request")
+		}
+	}
+}
+
+func TestResultHeaders(t *testing.T) {
+	h := http.Header{}
+	Result{Allowed: false, Limit: Limit{Requests: 5, Period: time.Minute, Burst: 5}, RetryAfter: 1500 * time.Millisecond, ResetAfter: 59 * time.Second}.SetHeaders(h)
+
+	want := map[string]string{
+		"RateLimit-Policy":    "5;w=60",
+		"RateLimit-Limit":     "5",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "59",
+		"Retry-After":         "2",
+	}
+	for name, value := range want {
+		if got := h.Get(name); got != value {
+			t.Errorf("%s: got %q, want %q", name, got, value)
+		}
+	}
+
+	h = http.Header{}
+	Result{Allowed: true}.SetHeaders(h)
+	if len(h) != 0 {
+		t.Errorf("unlimited result set headers %v", h)
+	}
+}
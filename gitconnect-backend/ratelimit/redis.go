@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces rate limit buckets in a shared Redis
+const keyPrefix = "ratelimit:"
+
+// takeScript refills and takes from a bucket atomically, using the server clock so
+// replicas with drifting clocks agree. It returns {allowed, tokens left}; tokens are
+// returned as a string because Redis truncates Lua numbers to integers.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis, or anything that speaks its protocol and runs
+// Lua scripts, so limits are shared by every replica. Tests can pass an in-process
+// fake such as miniredis through a regular client.
+type RedisStore struct {
+	client redis.Scripter
+}
+
+// NewRedisStore returns a store backed by client
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	rate := limit.perSecond()
+	reply, err := takeScript.Run(ctx, s.client, []string{keyPrefix + key}, limit.Burst, rate).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensText, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	result := Result{Allowed: allowed == 1, Limit: limit, Remaining: int(tokens)}
+	if !result.Allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.ResetAfter = seconds((float64(limit.Burst) - tokens) / rate)
+	return result, nil
+}
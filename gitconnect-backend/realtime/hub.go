@@ -0,0 +1,164 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// subscriptionBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriptionBuffer = 64
+
+// Hub delivers the events relayed by its broker to the subscribers in this process
+type Hub struct {
+	broker Broker
+
+	mu     sync.Mutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+// NewHub starts a hub that relays events through broker
+func NewHub(broker Broker) (*Hub, error) {
+	hub := &Hub{broker: broker, topics: map[string]map[*Subscription]struct{}{}}
+	if err := broker.Start(hub.deliver); err != nil {
+		return nil, err
+	}
+	return hub, nil
+}
+
+// NewLocalHub returns a hub whose events stay within this process
+func NewLocalHub() *Hub {
+	hub, _ := NewHub(NewMemoryBroker())
+	return hub
+}
+
+// Publish sends an event about the resource with id to the subscribers of any
+// of topics, on every replica. Failing to publish is logged, since the change
+// the event reports has already been made.
+func (h *Hub) Publish(ctx context.Context, eventType string, id uint, data any, topics ...string) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Println("❌ Failed to encode event:", err)
+		return
+	}
+	event := Event{Type: eventType, ID: id, Data: raw, Topics: topics}
+	if err := h.broker.Publish(ctx, event); err != nil {
+		log.Println("❌ Failed to publish event:", err)
+	}
+}
+
+// Disconnect closes every subscription to topic, on every replica, ending the
+// streams that carry it
+func (h *Hub) Disconnect(ctx context.Context, topic string) {
+	if err := h.broker.Publish(ctx, Event{Type: eventDisconnect, Topics: []string{topic}}); err != nil {
+		log.Println("❌ Failed to publish disconnect:", err)
+	}
+}
+
+// deliver hands an event to each subscriber of its topics once. A subscriber
+// whose buffer is full is dropped rather than holding up the others.
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.Type == eventDisconnect {
+		for _, topic := range event.Topics {
+			for sub := range h.topics[topic] {
+				h.close(sub)
+			}
+		}
+		return
+	}
+
+	delivered := map[*Subscription]bool{}
+	for _, topic := range event.Topics {
+		for sub := range h.topics[topic] {
+			if delivered[sub] {
+				continue
+			}
+			delivered[sub] = true
+			select {
+			case sub.events <- event:
+			default:
+				h.close(sub)
+			}
+		}
+	}
+}
+
+// Subscribe starts receiving the events of topics
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{hub: h, events: make(chan Event, subscriptionBuffer), topics: map[string]bool{}}
+	for _, topic := range topics {
+		sub.Watch(topic)
+	}
+	return sub
+}
+
+// close removes sub from every topic and closes its channel. The caller holds h.mu.
+func (h *Hub) close(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	for topic := range sub.topics {
+		delete(h.topics[topic], sub)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+	sub.closed = true
+	close(sub.events)
+}
+
+// Subscription receives the events of the topics it watches
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+	topics map[string]bool // Guarded by hub.mu, like closed
+	closed bool
+}
+
+// Events returns the subscription's events. The channel is closed when the
+// subscription is closed or falls too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Watch adds a topic to the subscription
+func (s *Subscription) Watch(topic string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	if s.closed || s.topics[topic] {
+		return
+	}
+	s.topics[topic] = true
+	if s.hub.topics[topic] == nil {
+		s.hub.topics[topic] = map[*Subscription]struct{}{}
+	}
+	s.hub.topics[topic][s] = struct{}{}
+}
+
+// Unwatch removes a topic from the subscription
+func (s *Subscription) Unwatch(topic string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	if !s.topics[topic] {
+		return
+	}
+	delete(s.topics, topic)
+	delete(s.hub.topics[topic], s)
+	if len(s.hub.topics[topic]) == 0 {
+		delete(s.hub.topics, topic)
+	}
+}
+
+// Close stops the subscription. Closing it again is a no-op.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.close(s)
+}
@@ -0,0 +1,106 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+)
+
+// received drains the events waiting on sub, reporting whether its channel is closed
+func received(sub *Subscription) ([]Event, bool) {
+	var events []Event
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events, true
+			}
+			events = append(events, event)
+		default:
+			return events, false
+		}
+	}
+}
+
+func TestHubDeliversToWatchedTopics(t *testing.T) {
+	hub := NewLocalHub()
+	ctx := context.Background()
+	both := hub.Subscribe(PostTopic(1), UserTopic(1))
+	other := hub.Subscribe(PostTopic(2))
+
+	// An event on several topics a subscriber watches arrives once
+	hub.Publish(ctx, EventCommentCreated, 7, map[string]string{"content": "hi"}, PostTopic(1), UserTopic(1))
+	events, closed := received(both)
+	if closed || len(events) != 1 || events[0].ID != 7 || string(events[0].Data) != `{"content":"hi"}` {
+		t.Fatalf("got %+v (closed %v), want the event once", events, closed)
+	}
+	if events, _ := received(other); len(events) != 0 {
+		t.Fatalf("a subscriber of another topic got %+v", events)
+	}
+
+	both.Unwatch(PostTopic(1))
+	other.Watch(PostTopic(1))
+	hub.Publish(ctx, EventCommentCreated, 8, nil, PostTopic(1))
+	if events, _ := received(both); len(events) != 0 {
+		t.Fatalf("got %+v after unwatching", events)
+	}
+	if events, _ := received(other); len(events) != 1 {
+		t.Fatalf("got %+v after watching, want the event", events)
+	}
+
+	// Closing twice is a no-op, and a closed subscription watches nothing
+	both.Close()
+	both.Close()
+	both.Watch(PostTopic(1))
+	if _, closed := received(both); !closed || len(hub.topics[UserTopic(1)]) != 0 || len(hub.topics[PostTopic(1)]) != 1 {
+		t.Fatalf("closing left topics %v", hub.topics)
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewLocalHub()
+	ctx := context.Background()
+	slow := hub.Subscribe(PostTopic(1))
+	fast := hub.Subscribe(PostTopic(1))
+
+	// A subscriber may fall a whole buffer behind
+	for i := 0; i < subscriptionBuffer; i++ {
+		hub.Publish(ctx, EventReactionsUpdated, uint(i), nil, PostTopic(1))
+		if events, closed := received(fast); closed || len(events) != 1 {
+			t.Fatalf("event %d: the fast subscriber got %d events (closed %v)", i, len(events), closed)
+		}
+	}
+	if len(slow.Events()) != subscriptionBuffer {
+		t.Fatalf("the slow subscriber holds %d events, want %d", len(slow.Events()), subscriptionBuffer)
+	}
+
+	// One more drops it without holding up the others, and it still gets what it was sent
+	hub.Publish(ctx, EventReactionsUpdated, subscriptionBuffer, nil, PostTopic(1))
+	if events, closed := received(fast); closed || len(events) != 1 {
+		t.Fatalf("after the slow subscriber fell behind, the fast one got %d events (closed %v)", len(events), closed)
+	}
+	events, closed := received(slow)
+	if !closed || len(events) != subscriptionBuffer || events[len(events)-1].ID != subscriptionBuffer-1 {
+		t.Fatalf("the slow subscriber got %d events (closed %v), want the first %d and then a closed channel", len(events), closed, subscriptionBuffer)
+	}
+	if _, ok := hub.topics[PostTopic(1)][slow]; ok || len(hub.topics[PostTopic(1)]) != 1 {
+		t.Fatal("the dropped subscriber is still subscribed")
+	}
+	slow.Close()
+}
+
+func TestHubDisconnect(t *testing.T) {
+	hub := NewLocalHub()
+	ctx := context.Background()
+	session := hub.Subscribe(UserTopic(1), PostTopic(1))
+	other := hub.Subscribe(UserTopic(2), PostTopic(1))
+
+	// Disconnecting a topic ends every subscription that watches it, whatever else it watches
+	hub.Disconnect(ctx, UserTopic(1))
+	if events, closed := received(session); !closed || len(events) != 0 {
+		t.Fatalf("got %+v (closed %v), want a closed subscription and no event", events, closed)
+	}
+	hub.Publish(ctx, EventCommentCreated, 1, nil, PostTopic(1))
+	if events, closed := received(other); closed || len(events) != 1 {
+		t.Fatalf("another subscriber got %+v (closed %v), want the event", events, closed)
+	}
+}
@@ -0,0 +1,39 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker passes events straight to the hub of this process. Clients
+// connected to other replicas do not see them.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	deliver func(Event)
+}
+
+// NewMemoryBroker returns a broker for a single replica
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Publish implements Broker
+func (b *MemoryBroker) Publish(_ context.Context, event Event) error {
+	b.mu.Lock()
+	deliver := b.deliver
+	b.mu.Unlock()
+
+	if deliver != nil {
+		deliver(event)
+	}
+	return nil
+}
+
+// Start implements Broker
+func (b *MemoryBroker) Start(deliver func(Event)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.deliver = deliver
+	return nil
+}
@@ -0,0 +1,105 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresChannel is the NOTIFY channel events travel on
+const postgresChannel = "gitconnect_events"
+
+// maxNotifyPayload keeps payloads under the 8000-byte limit of NOTIFY
+const maxNotifyPayload = 7900
+
+// maxReconnectDelay caps the wait between attempts to listen again
+const maxReconnectDelay = 30 * time.Second
+
+// PostgresBroker relays events between replicas with LISTEN/NOTIFY. Events too
+// large for a notification are relayed without their Data and marked Truncated.
+// Events published while the listening connection is being reopened are missed.
+type PostgresBroker struct {
+	DB  *sql.DB // Publishes with pg_notify
+	DSN string  // Opens the connection that listens; it is kept out of the pool
+}
+
+// Publish implements Broker
+func (b *PostgresBroker) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		event.Data, event.Truncated = nil, true
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", postgresChannel, string(payload))
+	return err
+}
+
+// Start implements Broker. The first connection is opened before returning, so a
+// broker that cannot listen is reported at startup; later failures reconnect.
+func (b *PostgresBroker) Start(deliver func(Event)) error {
+	conn, err := b.listen(context.Background())
+	if err != nil {
+		return err
+	}
+	go b.relay(conn, deliver)
+	return nil
+}
+
+// listen opens a connection that listens on postgresChannel
+func (b *PostgresBroker) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, b.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// relay passes notifications to deliver for as long as the process runs
+func (b *PostgresBroker) relay(conn *pgx.Conn, deliver func(Event)) {
+	ctx := context.Background()
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			log.Println("❌ Lost the realtime listener connection:", err)
+			conn.Close(ctx)
+			conn = b.reconnect(ctx)
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Println("❌ Failed to decode realtime event:", err)
+			continue
+		}
+		deliver(event)
+	}
+}
+
+// reconnect listens again, backing off between failed attempts
+func (b *PostgresBroker) reconnect(ctx context.Context) *pgx.Conn {
+	delay := time.Second
+	for {
+		time.Sleep(delay)
+		conn, err := b.listen(ctx)
+		if err == nil {
+			log.Println("✅ Realtime listener reconnected")
+			return conn
+		}
+		log.Println("❌ Failed to reconnect the realtime listener:", err)
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
@@ -0,0 +1,112 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// notifyRecorder is a database connector that records the payloads passed to pg_notify
+type notifyRecorder struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (r *notifyRecorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *notifyRecorder) Driver() driver.Driver                        { return r }
+func (r *notifyRecorder) Open(string) (driver.Conn, error)             { return recorderConn{r}, nil }
+
+type recorderConn struct{ recorder *notifyRecorder }
+
+func (c recorderConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c recorderConn) Close() error                        { return nil }
+func (c recorderConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c recorderConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	c.recorder.mu.Lock()
+	defer c.recorder.mu.Unlock()
+	c.recorder.payloads = append(c.recorder.payloads, args[1].Value.(string))
+	return driver.RowsAffected(0), nil
+}
+
+func TestPostgresBrokerTruncatesLargeEvents(t *testing.T) {
+	recorder := &notifyRecorder{}
+	broker := &PostgresBroker{DB: sql.OpenDB(recorder)}
+	hub := &Hub{broker: broker, topics: map[string]map[*Subscription]struct{}{}}
+	ctx := context.Background()
+
+	small := strings.Repeat("a", 1000)
+	large := strings.Repeat("a", maxNotifyPayload)
+	hub.Publish(ctx, EventPostCreated, 1, map[string]string{"content": small}, AuthorTopic(1))
+	hub.Publish(ctx, EventPostCreated, 2, map[string]string{"content": large}, AuthorTopic(1))
+
+	if len(recorder.payloads) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(recorder.payloads))
+	}
+	var events [2]Event
+	for i, payload := range recorder.payloads {
+		if len(payload) > maxNotifyPayload {
+			t.Fatalf("event %d has a payload of %d bytes", i+1, len(payload))
+		}
+		if err := json.Unmarshal([]byte(payload), &events[i]); err != nil {
+			t.Fatalf("decoding event %d: %v", i+1, err)
+		}
+	}
+	if events[0].Truncated || !strings.Contains(string(events[0].Data), small) {
+		t.Fatalf("the small event was relayed as %+v", events[0])
+	}
+	// The large one keeps everything but its data, so clients can fetch the resource
+	if !events[1].Truncated || events[1].Data != nil || events[1].ID != 2 || events[1].Type != EventPostCreated || events[1].Topics[0] != AuthorTopic(1) {
+		t.Fatalf("the large event was relayed as %+v, want it truncated", events[1])
+	}
+}
+
+func TestPostgresBrokerRelaysBetweenReplicas(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Two replicas, each with its own listening connection
+	var hubs [2]*Hub
+	for i := range hubs {
+		if hubs[i], err = NewHub(&PostgresBroker{DB: db, DSN: databaseURL}); err != nil {
+			t.Fatalf("starting replica %d: %v", i+1, err)
+		}
+	}
+	sub := hubs[1].Subscribe(PostTopic(1))
+	defer sub.Close()
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case event := <-sub.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event arrived")
+		}
+		return Event{}
+	}
+	ctx := context.Background()
+	hubs[0].Publish(ctx, EventCommentCreated, 1, map[string]string{"content": "hi"}, PostTopic(1))
+	if event := next(); event.ID != 1 || event.Truncated || string(event.Data) != `{"content":"hi"}` {
+		t.Fatalf("got %+v, want the comment", event)
+	}
+	hubs[0].Publish(ctx, EventCommentCreated, 2, map[string]string{"content": strings.Repeat("a", maxNotifyPayload)}, PostTopic(1))
+	if event := next(); event.ID != 2 || !event.Truncated || event.Data != nil {
+		t.Fatalf("got %+v, want the large comment truncated", event)
+	}
+}
@@ -0,0 +1,109 @@
+// Package realtime fans events out to the clients streaming them. Controllers
+// publish events to topics through a Hub; the Hub hands them to a Broker, which
+// relays them to the Hub of every replica, and each Hub delivers them to its
+// subscribers of those topics.
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// Event types pushed to clients
+const (
+	EventPostCreated      = "post.created"         // A post in the subscriber's feed; Data is the post
+	EventCommentCreated   = "comment.created"      // A comment on a watched post; Data is the comment
+	EventReactionsUpdated = "reactions.updated"    // New counters of a watched post or one of its comments; Data is ReactionCounts
+	EventNotification     = "notification.created" // A new or updated notification of the subscriber; Data is the notification
+	EventMessageCreated   = "message.created"      // A message in one of the subscriber's conversations; Data is the message
+	EventConversationRead = "conversation.read"    // A member's read receipt moved; Data is the receipt
+)
+
+// eventDisconnect is never delivered: it closes the subscriptions to its topics
+const eventDisconnect = "disconnect"
+
+// Event is something that happened to the resource with ID. Topics decide who
+// receives it and are not sent to clients.
+type Event struct {
+	Type      string          `json:"type"`
+	ID        uint            `json:"id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"` // Data was too large to relay; fetch the resource by ID instead
+	Topics    []string        `json:"topics,omitempty"`
+}
+
+// ReactionCounts is the Data of a reactions.updated event
+type ReactionCounts struct {
+	PostID    uint  `json:"post_id"`
+	CommentID *uint `json:"comment_id,omitempty"` // Set when the counters are a comment's
+	Likes     int   `json:"likes"`
+	Dislikes  int   `json:"dislikes"`
+}
+
+// UserTopic carries events for one user, such as their notifications
+func UserTopic(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// InboxTopic carries the messages and read receipts of one user's conversations
+func InboxTopic(userID uint) string {
+	return "inbox:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// AuthorTopic carries the new posts of one author
+func AuthorTopic(userID uint) string {
+	return "author:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// TagTopic carries the new posts with one hashtag
+func TagTopic(name string) string {
+	return "tag:" + name
+}
+
+// PostTopic carries the new comments and reaction counts of one post
+func PostTopic(postID uint) string {
+	return "post:" + strconv.FormatUint(uint64(postID), 10)
+}
+
+// Broker relays events between the hubs of every replica. Implementations must
+// be safe for concurrent use.
+type Broker interface {
+	// Publish sends the event to every hub, this replica's included
+	Publish(ctx context.Context, event Event) error
+	// Start begins passing the events published by any replica to deliver
+	Start(deliver func(Event)) error
+}
+
+// Configure builds the hub from the environment:
+//
+//	REALTIME_BROKER  memory (default) or postgres; use postgres when running several replicas
+//	DATABASE_URL     the database the postgres broker listens on with LISTEN/NOTIFY
+//
+// db is the pool the postgres broker publishes through.
+func Configure(db *sql.DB) (*Hub, error) {
+	var broker Broker
+	switch driver := os.Getenv("REALTIME_BROKER"); driver {
+	case "", "memory":
+		broker = NewMemoryBroker()
+	case "postgres":
+		databaseURL := os.Getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("DATABASE_URL is not set")
+		}
+		broker = &PostgresBroker{DB: db, DSN: databaseURL}
+	default:
+		return nil, fmt.Errorf("unsupported REALTIME_BROKER %q", driver)
+	}
+
+	hub, err := NewHub(broker)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("📡 Realtime events with %T", broker)
+	return hub, nil
+}
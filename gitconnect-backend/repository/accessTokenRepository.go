@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+// AccessTokenRepository stores personal access tokens
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	// List returns the user's tokens, newest first
+	List(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error)
+	// FindByHash returns the token with the hash, whether or not it is still valid
+	FindByHash(ctx context.Context, tokenHash string) (models.PersonalAccessToken, error)
+	// Revoke revokes one of the user's tokens. Other users' tokens are not found;
+	// revoking a token again is a no-op.
+	Revoke(ctx context.Context, userID, id uint) error
+	// Touch records that the token was used. To spare a write on every request,
+	// a use within a minute of the last recorded one is not recorded.
+	Touch(ctx context.Context, id uint, at time.Time) error
+}
+
+type gormAccessTokens struct {
+	db *gorm.DB
+}
+
+func (r *gormAccessTokens) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	return translate(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *gormAccessTokens) List(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *gormAccessTokens) FindByHash(ctx context.Context, tokenHash string) (models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(&token).Error
+	return token, translate(err)
+}
+
+func (r *gormAccessTokens) Revoke(ctx context.Context, userID, id uint) error {
+	db := r.db.WithContext(ctx)
+	var token models.PersonalAccessToken
+	if err := db.Select("id").Where("id = ? AND user_id = ?", id, userID).Take(&token).Error; err != nil {
+		return translate(err)
+	}
+	return db.Model(&token).Where("revoked_at IS NULL").Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormAccessTokens) Touch(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-time.Minute)).
+		Update("last_used_at", at).Error
+}
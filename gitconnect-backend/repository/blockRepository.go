@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockRepository stores which users have blocked whom
+type BlockRepository interface {
+	// Block makes blockerID block blockedID. Blocking again is a no-op;
+	// ErrNotFound is returned if the blocked user does not exist.
+	Block(ctx context.Context, blockerID, blockedID uint) error
+	// Unblock removes the block, if there is one
+	Unblock(ctx context.Context, blockerID, blockedID uint) error
+	// List returns the users blockerID has blocked, most recent first, each with Blocked loaded
+	List(ctx context.Context, blockerID uint) ([]models.Block, error)
+	// BlockersOf reports which of the given users have blocked blockedID
+	BlockersOf(ctx context.Context, blockedID uint, userIDs []uint) (map[uint]bool, error)
+	// BlockedEitherWay reports whether userID has blocked, or been blocked by, any of others
+	BlockedEitherWay(ctx context.Context, userID uint, others []uint) (bool, error)
+}
+
+type gormBlocks struct {
+	db *gorm.DB
+}
+
+func (r *gormBlocks) Block(ctx context.Context, blockerID, blockedID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Select("id").Take(&models.User{}, blockedID).Error; err != nil {
+		return translate(err)
+	}
+
+	block := models.Block{BlockerID: blockerID, BlockedID: blockedID}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error
+}
+
+func (r *gormBlocks) Unblock(ctx context.Context, blockerID, blockedID uint) error {
+	return r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&models.Block{}).Error
+}
+
+func (r *gormBlocks) List(ctx context.Context, blockerID uint) ([]models.Block, error) {
+	blocks := []models.Block{}
+	err := r.db.WithContext(ctx).
+		Where("blocker_id = ?", blockerID).
+		Order("created_at DESC").Order("blocked_id DESC").
+		Preload("Blocked").
+		Find(&blocks).Error
+	return blocks, err
+}
+
+func (r *gormBlocks) BlockersOf(ctx context.Context, blockedID uint, userIDs []uint) (map[uint]bool, error) {
+	blockers := make(map[uint]bool)
+	if len(userIDs) == 0 {
+		return blockers, nil
+	}
+
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Block{}).
+		Where("blocked_id = ? AND blocker_id IN ?", blockedID, userIDs).
+		Pluck("blocker_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		blockers[id] = true
+	}
+	return blockers, nil
+}
+
+func (r *gormBlocks) BlockedEitherWay(ctx context.Context, userID uint, others []uint) (bool, error) {
+	if len(others) == 0 {
+		return false, nil
+	}
+
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Block{}).
+		Where("(blocker_id = ? AND blocked_id IN ?) OR (blocked_id = ? AND blocker_id IN ?)", userID, others, userID, others).
+		Limit(1).
+		Pluck("blocker_id", &ids).Error
+	return len(ids) > 0, err
+}
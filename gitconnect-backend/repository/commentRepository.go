@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CommentRepository stores comments on posts, their replies, mentions and the
+// reactions on them. Mentions are extracted from the content whenever it is written.
+type CommentRepository interface {
+	// Create stores a comment and fills its Mentions. Replies carry ParentID,
+	// RootID and Depth, derived from the parent by the caller.
+	Create(ctx context.Context, comment *models.Comment) error
+	// FindByID returns the comment with its author and mentions
+	FindByID(ctx context.Context, id uint) (models.Comment, error)
+	// ListForPost returns one page of the post's top-level comments with their
+	// authors and mentions, and the cursor of the next page
+	ListForPost(ctx context.Context, postID uint, filter CommentFilter) ([]models.Comment, *Cursor, error)
+	// Replies returns every reply in the threads under the given top-level
+	// comments, oldest first, with their authors and mentions
+	Replies(ctx context.Context, rootIDs []uint) ([]models.Comment, error)
+	// UpdateContent replaces the content, extracts its mentions again and marks
+	// the comment as edited at the given time
+	UpdateContent(ctx context.Context, id uint, content string, at time.Time) error
+	// Delete removes the comment. A comment with replies becomes a tombstone
+	// instead, so the replies keep their place in the thread; tombstones left
+	// without replies are removed.
+	Delete(ctx context.Context, id uint) error
+	// OwnerID returns the ID of the comment's author, or 0 for a tombstone
+	OwnerID(ctx context.Context, id uint) (uint, error)
+	// SetReaction moves the user's reaction on the comment to kind (an empty kind
+	// removes it) and returns the comment's post, author, fresh counters and MyReaction
+	SetReaction(ctx context.Context, commentID, userID uint, kind models.ReactionKind) (models.Comment, error)
+	// ReactionsBy returns the user's reaction on each of the given comments that has one
+	ReactionsBy(ctx context.Context, userID uint, commentIDs []uint) (map[uint]models.ReactionKind, error)
+}
+
+type gormComments struct {
+	db *gorm.DB
+}
+
+func (r *gormComments) Create(ctx context.Context, comment *models.Comment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		var err error
+		comment.Mentions, err = syncMentions(tx, "comment_id", comment.ID, comment.Content)
+		return err
+	})
+	return translate(err)
+}
+
+func (r *gormComments) FindByID(ctx context.Context, id uint) (models.Comment, error) {
+	var comment models.Comment
+	db := r.db.WithContext(ctx)
+	if err := db.Preload("User").First(&comment, id).Error; err != nil {
+		return comment, translate(err)
+	}
+	comments := []models.Comment{comment}
+	err := loadCommentMentions(db, comments)
+	return comments[0], err
+}
+
+func (r *gormComments) ListForPost(ctx context.Context, postID uint, filter CommentFilter) ([]models.Comment, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Comment{}).
+		Where("comments.post_id = ? AND comments.parent_id IS NULL", postID).
+		Preload("User")
+	if filter.AuthorID != 0 {
+		query = query.Where("comments.user_id = ?", filter.AuthorID)
+	}
+
+	var comments []models.Comment
+	if err := paginate(query, "comments.created_at", "comments.id", filter.Created, filter.Page).Find(&comments).Error; err != nil {
+		return nil, nil, err
+	}
+	comments, next := trimPage(comments, filter.Page, commentCursor)
+	return comments, next, loadCommentMentions(r.db.WithContext(ctx), comments)
+}
+
+func (r *gormComments) Replies(ctx context.Context, rootIDs []uint) ([]models.Comment, error) {
+	var replies []models.Comment
+	if len(rootIDs) == 0 {
+		return replies, nil
+	}
+	db := r.db.WithContext(ctx)
+	err := db.Where("root_id IN ?", rootIDs).
+		Order("created_at").Order("id").
+		Preload("User").
+		Find(&replies).Error
+	if err != nil {
+		return nil, err
+	}
+	return replies, loadCommentMentions(db, replies)
+}
+
+func (r *gormComments) UpdateContent(ctx context.Context, id uint, content string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Comment{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"content": content, "edited_at": at})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		_, err := syncMentions(tx, "comment_id", id, content)
+		return err
+	})
+}
+
+func (r *gormComments) Delete(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the row blocks new replies to it until this commits
+		var comment models.Comment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "parent_id").First(&comment, id).Error; err != nil {
+			return err
+		}
+
+		for {
+			var replies int64
+			if err := tx.Model(&models.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+				return err
+			}
+			if replies > 0 {
+				return tombstoneComments(tx, "id = ?", comment.ID)
+			}
+			if err := tx.Delete(&models.Comment{}, comment.ID).Error; err != nil {
+				return err
+			}
+
+			// Walk up through tombstones that were only kept for this reply
+			if comment.ParentID == nil {
+				return nil
+			}
+			var parent models.Comment
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id", "parent_id").
+				Where("id = ? AND deleted_at IS NOT NULL", *comment.ParentID).
+				Take(&parent).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			comment = parent
+		}
+	})
+	return translate(err)
+}
+
+func (r *gormComments) OwnerID(ctx context.Context, id uint) (uint, error) {
+	var owner struct{ UserID *uint }
+	err := r.db.WithContext(ctx).Model(&models.Comment{}).Select("user_id").Where("id = ?", id).Take(&owner).Error
+	if err != nil || owner.UserID == nil {
+		return 0, translate(err)
+	}
+	return *owner.UserID, nil
+}
+
+// SetReaction applies a reaction transition the same way gormPosts.SetReaction
+// does. Tombstones cannot be reacted to.
+func (r *gormComments) SetReaction(ctx context.Context, commentID, userID uint, kind models.ReactionKind) (models.Comment, error) {
+	var comment models.Comment
+	db := r.db.WithContext(ctx)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "deleted_at").First(&comment, commentID).Error; err != nil {
+			return err
+		}
+		if comment.Deleted() {
+			return ErrNotFound
+		}
+
+		var current models.CommentReaction
+		found, err := lockReaction(tx, "comment_id", commentID, userID, &current)
+		if err != nil {
+			return err
+		}
+
+		if !found && kind != "" {
+			reaction := models.CommentReaction{CommentID: commentID, UserID: userID, Kind: kind}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				return bumpReactionCounter(tx, &models.Comment{}, commentID, kind, 1)
+			}
+
+			// A concurrent request inserted first; continue from its row
+			if found, err = lockReaction(tx, "comment_id", commentID, userID, &current); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case !found, current.Kind == kind:
+			return nil
+		case kind == "":
+			if err := tx.Delete(&current).Error; err != nil {
+				return err
+			}
+			return bumpReactionCounter(tx, &models.Comment{}, commentID, current.Kind, -1)
+		default:
+			if err := tx.Model(&current).Update("kind", kind).Error; err != nil {
+				return err
+			}
+			if err := bumpReactionCounter(tx, &models.Comment{}, commentID, current.Kind, -1); err != nil {
+				return err
+			}
+			return bumpReactionCounter(tx, &models.Comment{}, commentID, kind, 1)
+		}
+	})
+	if err != nil {
+		return comment, translate(err)
+	}
+
+	if err := db.Select("id", "post_id", "user_id", "likes", "dislikes").First(&comment, commentID).Error; err != nil {
+		return comment, translate(err)
+	}
+	comment.MyReaction = kind
+	return comment, nil
+}
+
+func (r *gormComments) ReactionsBy(ctx context.Context, userID uint, commentIDs []uint) (map[uint]models.ReactionKind, error) {
+	kinds := make(map[uint]models.ReactionKind)
+	if len(commentIDs) == 0 {
+		return kinds, nil
+	}
+
+	var reactions []models.CommentReaction
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND comment_id IN ?", userID, commentIDs).Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		kinds[reaction.CommentID] = reaction.Kind
+	}
+	return kinds, nil
+}
+
+// tombstoneComments clears the content, mentions and author of the matching
+// comments while keeping them in their threads
+func tombstoneComments(tx *gorm.DB, query interface{}, args ...interface{}) error {
+	matching := tx.Model(&models.Comment{}).Select("id").Where(query, args...)
+	if err := tx.Where("comment_id IN (?)", matching).Delete(&models.Mention{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Comment{}).Where(query, args...).Updates(map[string]interface{}{
+		"content":    "",
+		"user_id":    nil,
+		"deleted_at": time.Now(),
+	}).Error
+}
+
+func commentCursor(comment models.Comment) Cursor {
+	return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EndorsementRepository stores the endorsements users give the skills on each other's profiles
+type EndorsementRepository interface {
+	// Endorse records endorserID's endorsement of a skill and reports whether it is
+	// new. Endorsing again is a no-op; ErrNotFound is returned if the skill does not exist.
+	Endorse(ctx context.Context, skillID, endorserID uint) (bool, error)
+	// Unendorse withdraws endorserID's endorsement of a skill, if there is one
+	Unendorse(ctx context.Context, skillID, endorserID uint) error
+	// Clear withdraws every endorsement of a skill
+	Clear(ctx context.Context, skillID uint) error
+	// List returns one page of a skill's endorsements, each with Endorser loaded,
+	// and the cursor of the next page
+	List(ctx context.Context, skillID uint, page Page) ([]models.Endorsement, *Cursor, error)
+	// Summaries returns the endorsement summary of each of the given skills as seen
+	// by viewerID, which is 0 for an anonymous viewer. Skills nobody endorsed are left out.
+	Summaries(ctx context.Context, skillIDs []uint, viewerID uint) (map[uint]models.EndorsementSummary, error)
+}
+
+type gormEndorsements struct {
+	db *gorm.DB
+}
+
+func (r *gormEndorsements) Endorse(ctx context.Context, skillID, endorserID uint) (bool, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Select("id").Take(&models.Skill{}, skillID).Error; err != nil {
+		return false, translate(err)
+	}
+
+	endorsement := models.Endorsement{SkillID: skillID, EndorserID: endorserID}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&endorsement)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormEndorsements) Unendorse(ctx context.Context, skillID, endorserID uint) error {
+	return r.db.WithContext(ctx).
+		Where("skill_id = ? AND endorser_id = ?", skillID, endorserID).
+		Delete(&models.Endorsement{}).Error
+}
+
+func (r *gormEndorsements) Clear(ctx context.Context, skillID uint) error {
+	return r.db.WithContext(ctx).Where("skill_id = ?", skillID).Delete(&models.Endorsement{}).Error
+}
+
+func (r *gormEndorsements) List(ctx context.Context, skillID uint, page Page) ([]models.Endorsement, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Endorsement{}).Where("endorsements.skill_id = ?", skillID).Preload("Endorser")
+
+	var endorsements []models.Endorsement
+	if err := paginate(query, "endorsements.created_at", "endorsements.endorser_id", TimeRange{}, page).Find(&endorsements).Error; err != nil {
+		return nil, nil, err
+	}
+	endorsements, next := trimPage(endorsements, page, endorsementCursor)
+	return endorsements, next, nil
+}
+
+func endorsementCursor(endorsement models.Endorsement) Cursor {
+	return Cursor{CreatedAt: endorsement.CreatedAt, ID: endorsement.EndorserID}
+}
+
+func (r *gormEndorsements) Summaries(ctx context.Context, skillIDs []uint, viewerID uint) (map[uint]models.EndorsementSummary, error) {
+	summaries := make(map[uint]models.EndorsementSummary, len(skillIDs))
+	if len(skillIDs) == 0 {
+		return summaries, nil
+	}
+	db := r.db.WithContext(ctx)
+	followed := db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", viewerID)
+
+	var counts []struct {
+		SkillID       uint
+		Count         int64
+		FollowedCount int64
+		Endorsed      bool
+	}
+	err := db.Model(&models.Endorsement{}).
+		Select("skill_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE endorser_id IN (?)) AS followed_count, BOOL_OR(endorser_id = ?) AS endorsed", followed, viewerID).
+		Where("skill_id IN ?", skillIDs).
+		Group("skill_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	anyFollowed := false
+	for _, count := range counts {
+		summaries[count.SkillID] = models.EndorsementSummary{Count: count.Count, Endorsed: count.Endorsed, FollowedCount: count.FollowedCount}
+		anyFollowed = anyFollowed || count.FollowedCount > 0
+	}
+	if !anyFollowed {
+		return summaries, nil
+	}
+
+	// The latest few endorsers the viewer follows, per skill
+	ranked := db.Model(&models.Endorsement{}).
+		Select("endorsements.*, ROW_NUMBER() OVER (PARTITION BY skill_id ORDER BY created_at DESC, endorser_id DESC) AS nth").
+		Where("skill_id IN ? AND endorser_id IN (?)", skillIDs, followed)
+	var latest []models.Endorsement
+	err = db.Table("(?) AS endorsements", ranked).
+		Where("nth <= ?", models.EndorsementPreviewSize).
+		Order("skill_id, nth").
+		Preload("Endorser").
+		Find(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, endorsement := range latest {
+		summary := summaries[endorsement.SkillID]
+		summary.Followed = append(summary.Followed, endorserRef(endorsement))
+		summaries[endorsement.SkillID] = summary
+	}
+	return summaries, nil
+}
+
+// endorserRef names the endorser of an endorsement, which must have Endorser loaded
+func endorserRef(endorsement models.Endorsement) models.EndorserRef {
+	ref := models.EndorserRef{ID: endorsement.EndorserID}
+	if endorsement.Endorser != nil {
+		ref.Username = endorsement.Endorser.Username
+	}
+	return ref
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+// FeedRepository builds users' home timelines: their own posts and the posts of
+// everyone and every hashtag they follow, newest first by default
+type FeedRepository interface {
+	// Home returns one page of the user's timeline with post authors, tags and
+	// mentions, and the cursor of the next page
+	Home(ctx context.Context, userID uint, page Page) ([]models.Post, *Cursor, error)
+}
+
+// gormFeed fans out on read: each page is merged from the followees' posts at
+// query time, so following someone shows their history immediately and writes
+// cost nothing extra. Once follow counts make this too slow, a store of
+// precomputed timelines can implement FeedRepository instead.
+type gormFeed struct {
+	db *gorm.DB
+}
+
+func (r *gormFeed) Home(ctx context.Context, userID uint, page Page) ([]models.Post, *Cursor, error) {
+	db := r.db.WithContext(ctx)
+	followees := db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userID)
+	followedTags := db.Model(&models.TagFollow{}).Select("tag_id").Where("user_id = ?", userID)
+	tagged := db.Model(&models.PostTag{}).Select("post_id").Where("tag_id IN (?)", followedTags)
+	query := db.Model(&models.Post{}).
+		Where("posts.user_id = ? OR posts.user_id IN (?) OR posts.id IN (?)", userID, followees, tagged).
+		Preload("User")
+
+	var posts []models.Post
+	if err := paginate(query, "posts.created_at", "posts.id", TimeRange{}, page).Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	posts, next := trimPage(posts, page, postCursor)
+	return posts, next, loadPostDetails(db, posts)
+}
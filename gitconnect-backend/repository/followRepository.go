@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FollowRepository stores the follow graph between users
+type FollowRepository interface {
+	// Follow makes followerID follow followeeID and reports whether the follow is
+	// new. Following again is a no-op; ErrNotFound is returned if the followee does not exist.
+	Follow(ctx context.Context, followerID, followeeID uint) (bool, error)
+	// Unfollow removes the edge, if there is one
+	Unfollow(ctx context.Context, followerID, followeeID uint) error
+	// Followers returns one page of the users following userID, each with Follower
+	// loaded, and the cursor of the next page
+	Followers(ctx context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error)
+	// Following returns one page of the users userID follows, each with Followee
+	// loaded, and the cursor of the next page
+	Following(ctx context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error)
+	// Counts returns the follower and following counts of each of the given users
+	Counts(ctx context.Context, userIDs []uint) (map[uint]models.FollowCounts, error)
+	// FollowersAmong reports which of the given users follow followeeID
+	FollowersAmong(ctx context.Context, followeeID uint, userIDs []uint) (map[uint]bool, error)
+}
+
+type gormFollows struct {
+	db *gorm.DB
+}
+
+func (r *gormFollows) Follow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Select("id").Take(&models.User{}, followeeID).Error; err != nil {
+		return false, translate(err)
+	}
+
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormFollows) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	return r.db.WithContext(ctx).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&models.Follow{}).Error
+}
+
+func (r *gormFollows) Followers(ctx context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Follow{}).Where("follows.followee_id = ?", userID).Preload("Follower")
+
+	var follows []models.Follow
+	if err := paginate(query, "follows.created_at", "follows.follower_id", TimeRange{}, page).Find(&follows).Error; err != nil {
+		return nil, nil, err
+	}
+	follows, next := trimPage(follows, page, followerCursor)
+	return follows, next, nil
+}
+
+func (r *gormFollows) Following(ctx context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Follow{}).Where("follows.follower_id = ?", userID).Preload("Followee")
+
+	var follows []models.Follow
+	if err := paginate(query, "follows.created_at", "follows.followee_id", TimeRange{}, page).Find(&follows).Error; err != nil {
+		return nil, nil, err
+	}
+	follows, next := trimPage(follows, page, followeeCursor)
+	return follows, next, nil
+}
+
+func (r *gormFollows) Counts(ctx context.Context, userIDs []uint) (map[uint]models.FollowCounts, error) {
+	counts := make(map[uint]models.FollowCounts, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+
+	type count struct {
+		UserID uint
+		Total  int64
+	}
+	var followers, following []count
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.Follow{}).Select("followee_id AS user_id, COUNT(*) AS total").Where("followee_id IN ?", userIDs).Group("followee_id").Scan(&followers).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Follow{}).Select("follower_id AS user_id, COUNT(*) AS total").Where("follower_id IN ?", userIDs).Group("follower_id").Scan(&following).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range followers {
+		entry := counts[row.UserID]
+		entry.Followers = row.Total
+		counts[row.UserID] = entry
+	}
+	for _, row := range following {
+		entry := counts[row.UserID]
+		entry.Following = row.Total
+		counts[row.UserID] = entry
+	}
+	return counts, nil
+}
+
+func (r *gormFollows) FollowersAmong(ctx context.Context, followeeID uint, userIDs []uint) (map[uint]bool, error) {
+	followers := make(map[uint]bool)
+	if len(userIDs) == 0 {
+		return followers, nil
+	}
+
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).
+		Where("followee_id = ? AND follower_id IN ?", followeeID, userIDs).
+		Pluck("follower_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		followers[id] = true
+	}
+	return followers, nil
+}
+
+func followerCursor(follow models.Follow) Cursor {
+	return Cursor{CreatedAt: follow.CreatedAt, ID: follow.FollowerID}
+}
+
+func followeeCursor(follow models.Follow) Cursor {
+	return Cursor{CreatedAt: follow.CreatedAt, ID: follow.FolloweeID}
+}
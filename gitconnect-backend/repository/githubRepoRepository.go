@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RepoSort orders a listing of GitHub repositories
+type RepoSort string
+
+const (
+	RepoSortPushed RepoSort = "pushed" // Most recently pushed first
+	RepoSortStars  RepoSort = "stars"  // Most starred first
+)
+
+// Valid reports whether s is a known order
+func (s RepoSort) Valid() bool {
+	return s == RepoSortPushed || s == RepoSortStars
+}
+
+// GithubRepoFilter narrows a listing of cached GitHub repositories
+type GithubRepoFilter struct {
+	ProfileIDs []uint
+	Pinned     bool     // Only pinned repositories, in pin order
+	Sort       RepoSort // Order of the others; RepoSortPushed if empty
+}
+
+// GithubRepoRepository caches the public GitHub repositories of profiles
+type GithubRepoRepository interface {
+	// List returns the cached repositories of the profiles
+	List(ctx context.Context, filter GithubRepoFilter) ([]models.GithubRepo, error)
+	// SyncState returns when the profile's repositories were last fetched, or
+	// ErrNotFound if they never were
+	SyncState(ctx context.Context, profileID uint) (models.GithubRepoSync, error)
+	// Replace stores the repositories just fetched for a profile, and the state of
+	// the fetch. Repositories missing from the fetch are removed unless pinned, and
+	// every repository is removed if the login changed.
+	Replace(ctx context.Context, state models.GithubRepoSync, repos []models.GithubRepo) error
+	// Touch records that a fetch found the repositories unchanged
+	Touch(ctx context.Context, profileID uint, syncedAt time.Time) error
+	// RecordFailure records a failed fetch and why it failed. The cached
+	// repositories and their login are kept; a profile never fetched before gets a
+	// state for login without SyncedAt.
+	RecordFailure(ctx context.Context, profileID uint, login string, checkedAt time.Time, reason string) error
+	// Pin pins the profile's repositories in ids, in that order, and unpins the
+	// others. ErrNotFound is returned if an ID is not one of the profile's.
+	Pin(ctx context.Context, profileID uint, ids []uint) error
+}
+
+type gormGithubRepos struct {
+	db *gorm.DB
+}
+
+func (r *gormGithubRepos) List(ctx context.Context, filter GithubRepoFilter) ([]models.GithubRepo, error) {
+	query := r.db.WithContext(ctx).Where("profile_id IN ?", filter.ProfileIDs)
+	switch {
+	case filter.Pinned:
+		query = query.Where("pin_position IS NOT NULL").Order("profile_id, pin_position")
+	case filter.Sort == RepoSortStars:
+		query = query.Order("profile_id, stars DESC, pushed_at DESC NULLS LAST, id")
+	default:
+		query = query.Order("profile_id, pushed_at DESC NULLS LAST, id")
+	}
+
+	var repos []models.GithubRepo
+	err := query.Find(&repos).Error
+	return repos, err
+}
+
+func (r *gormGithubRepos) SyncState(ctx context.Context, profileID uint) (models.GithubRepoSync, error) {
+	var state models.GithubRepoSync
+	err := r.db.WithContext(ctx).Where("profile_id = ?", profileID).Take(&state).Error
+	return state, translate(err)
+}
+
+func (r *gormGithubRepos) Replace(ctx context.Context, state models.GithubRepoSync, repos []models.GithubRepo) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous models.GithubRepoSync
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("profile_id = ?", state.ProfileID).Take(&previous).Error
+		if err != nil && translate(err) != ErrNotFound {
+			return err
+		}
+
+		// Pins and repositories of another account do not carry over
+		stale := tx.Where("profile_id = ?", state.ProfileID)
+		if previous.Login == "" || strings.EqualFold(previous.Login, state.Login) {
+			stale = stale.Where("pin_position IS NULL")
+			if len(repos) > 0 {
+				githubIDs := make([]int64, len(repos))
+				for i, repo := range repos {
+					githubIDs[i] = repo.GithubID
+				}
+				stale = stale.Where("github_id NOT IN ?", githubIDs)
+			}
+		}
+		if err := stale.Delete(&models.GithubRepo{}).Error; err != nil {
+			return err
+		}
+
+		if len(repos) > 0 {
+			for i := range repos {
+				repos[i].ProfileID = state.ProfileID
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "profile_id"}, {Name: "github_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "full_name", "description", "url", "homepage", "language",
+					"languages", "topics", "stars", "forks", "fork", "archived", "pushed_at", "updated_at"}),
+			}).Create(&repos).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&state).Error
+	})
+}
+
+func (r *gormGithubRepos) Touch(ctx context.Context, profileID uint, syncedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.GithubRepoSync{}).Where("profile_id = ?", profileID).
+		UpdateColumns(map[string]interface{}{"synced_at": syncedAt, "checked_at": syncedAt, "failures": 0, "last_error": ""}).Error
+}
+
+func (r *gormGithubRepos) RecordFailure(ctx context.Context, profileID uint, login string, checkedAt time.Time, reason string) error {
+	state := models.GithubRepoSync{ProfileID: profileID, Login: login, CheckedAt: &checkedAt, Failures: 1, LastError: reason}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "profile_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"checked_at": checkedAt,
+			"failures":   gorm.Expr("github_repo_syncs.failures + 1"),
+			"last_error": reason,
+		}),
+	}).Create(&state).Error
+	return translate(err)
+}
+
+func (r *gormGithubRepos) Pin(ctx context.Context, profileID uint, ids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(ids) > 0 {
+			var owned int64
+			if err := tx.Model(&models.GithubRepo{}).Where("profile_id = ? AND id IN ?", profileID, ids).Count(&owned).Error; err != nil {
+				return err
+			}
+			if int(owned) != len(ids) {
+				return ErrNotFound
+			}
+		}
+
+		err := tx.Model(&models.GithubRepo{}).Where("profile_id = ? AND pin_position IS NOT NULL", profileID).
+			UpdateColumn("pin_position", nil).Error
+		if err != nil {
+			return err
+		}
+		for position, id := range ids {
+			if err := tx.Model(&models.GithubRepo{}).Where("id = ?", id).UpdateColumn("pin_position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,2705 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gitconnect-backend/models"
+)
+
+// NewMemory returns empty repositories that keep everything in process memory,
+// so handlers can be exercised with httptest and no database. The behaviour
+// mirrors the GORM implementation, including cascading deletes.
+func NewMemory() Repositories {
+	m := &memory{
+		users:                   map[uint]models.User{},
+		refreshTokens:           map[uint]models.RefreshToken{},
+		revokedTokens:           map[string]time.Time{},
+		accessTokens:            map[uint]models.PersonalAccessToken{},
+		oneTimeTokens:           map[uint]models.OneTimeToken{},
+		recoveryCodes:           map[uint]models.RecoveryCode{},
+		oauthStates:             map[uint]models.OAuthState{},
+		profiles:                map[uint]models.Profile{},
+		posts:                   map[uint]models.Post{},
+		comments:                map[uint]models.Comment{},
+		reactions:               map[reactionKey]models.ReactionKind{},
+		commentReactions:        map[commentReactionKey]models.ReactionKind{},
+		follows:                 map[followKey]time.Time{},
+		tags:                    map[uint]models.Tag{},
+		postTags:                map[postTagKey]time.Time{},
+		tagFollows:              map[tagFollowKey]time.Time{},
+		mentions:                map[uint]models.Mention{},
+		blocks:                  map[blockKey]time.Time{},
+		notifications:           map[uint]models.Notification{},
+		notificationActors:      map[notificationActorKey]bool{},
+		notificationPreferences: map[notificationPreferenceKey]bool{},
+		conversations:           map[uint]models.Conversation{},
+		conversationMembers:     map[conversationMemberKey]models.ConversationMember{},
+		messages:                map[uint]models.Message{},
+		skills:                  map[uint]models.Skill{},
+		experiences:             map[uint]models.Experience{},
+		educations:              map[uint]models.Education{},
+		profileLinks:            map[uint]models.ProfileLink{},
+		githubRepos:             map[uint]models.GithubRepo{},
+		githubRepoSyncs:         map[uint]models.GithubRepoSync{},
+		endorsements:            map[endorsementKey]time.Time{},
+		usernameRedirects:       map[uint]models.UsernameRedirect{},
+		nextID:                  map[string]uint{},
+	}
+	return Repositories{
+		Users:         &memoryUsers{m},
+		Sessions:      &memorySessions{m},
+		AccessTokens:  &memoryAccessTokens{m},
+		OneTimeTokens: &memoryOneTimeTokens{m},
+		MFA:           &memoryMFA{m},
+		OAuthStates:   &memoryOAuthStates{m},
+		Profiles:      &memoryProfiles{m},
+		Posts:         &memoryPosts{m},
+		Comments:      &memoryComments{m},
+		Follows:       &memoryFollows{m},
+		Feed:          &memoryFeed{m},
+		Search:        &memorySearch{m},
+		Tags:          &memoryTags{m},
+		Blocks:        &memoryBlocks{m},
+		Notifications: &memoryNotifications{m},
+		Messages:      &memoryMessages{m},
+		Skills: &memorySection[models.Skill, *models.Skill]{memory: m, name: "skills", limit: models.MaxSkills,
+			table:   func(m *memory) map[uint]models.Skill { return m.skills },
+			same:    func(a, b *models.Skill) bool { return strings.EqualFold(a.Name, b.Name) },
+			cascade: (*memory).deleteEndorsements},
+		Experiences: &memorySection[models.Experience, *models.Experience]{memory: m, name: "experiences", limit: models.MaxExperiences,
+			table: func(m *memory) map[uint]models.Experience { return m.experiences }},
+		Educations: &memorySection[models.Education, *models.Education]{memory: m, name: "educations", limit: models.MaxEducations,
+			table: func(m *memory) map[uint]models.Education { return m.educations }},
+		Links: &memorySection[models.ProfileLink, *models.ProfileLink]{memory: m, name: "profile_links", limit: models.MaxLinks,
+			table: func(m *memory) map[uint]models.ProfileLink { return m.profileLinks }},
+		GithubRepos:  &memoryGithubRepos{m},
+		Endorsements: &memoryEndorsements{m},
+	}
+}
+
+type reactionKey struct{ postID, userID uint }
+
+type commentReactionKey struct{ commentID, userID uint }
+
+type followKey struct{ followerID, followeeID uint }
+
+type postTagKey struct{ postID, tagID uint }
+
+type tagFollowKey struct{ userID, tagID uint }
+
+type blockKey struct{ blockerID, blockedID uint }
+
+type notificationActorKey struct{ notificationID, actorID uint }
+
+type conversationMemberKey struct{ conversationID, userID uint }
+
+type endorsementKey struct{ skillID, endorserID uint }
+
+type notificationPreferenceKey struct {
+	userID           uint
+	notificationType models.NotificationType
+}
+
+// memory is the shared state behind the in-memory repositories
+type memory struct {
+	mu                      sync.Mutex
+	users                   map[uint]models.User
+	refreshTokens           map[uint]models.RefreshToken
+	revokedTokens           map[string]time.Time // Expiry by jti
+	accessTokens            map[uint]models.PersonalAccessToken
+	oneTimeTokens           map[uint]models.OneTimeToken
+	recoveryCodes           map[uint]models.RecoveryCode
+	oauthStates             map[uint]models.OAuthState
+	profiles                map[uint]models.Profile
+	posts                   map[uint]models.Post
+	comments                map[uint]models.Comment
+	reactions               map[reactionKey]models.ReactionKind
+	commentReactions        map[commentReactionKey]models.ReactionKind
+	follows                 map[followKey]time.Time
+	tags                    map[uint]models.Tag
+	postTags                map[postTagKey]time.Time
+	tagFollows              map[tagFollowKey]time.Time
+	mentions                map[uint]models.Mention
+	blocks                  map[blockKey]time.Time
+	notifications           map[uint]models.Notification
+	notificationActors      map[notificationActorKey]bool
+	notificationPreferences map[notificationPreferenceKey]bool // Only types the user changed
+	conversations           map[uint]models.Conversation       // Without their members, last message or unread count
+	conversationMembers     map[conversationMemberKey]models.ConversationMember
+	messages                map[uint]models.Message
+	skills                  map[uint]models.Skill
+	experiences             map[uint]models.Experience
+	educations              map[uint]models.Education
+	profileLinks            map[uint]models.ProfileLink
+	githubRepos             map[uint]models.GithubRepo
+	githubRepoSyncs         map[uint]models.GithubRepoSync // By profile ID
+	endorsements            map[endorsementKey]time.Time
+	usernameRedirects       map[uint]models.UsernameRedirect
+	nextID                  map[string]uint
+}
+
+// id hands out auto-increment IDs per table
+func (m *memory) id(table string) uint {
+	m.nextID[table]++
+	return m.nextID[table]
+}
+
+// sortedIDs returns the keys of a table in insertion order
+func sortedIDs[T any](table map[uint]T) []uint {
+	ids := make([]uint, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// memoryPage sorts the matching rows in the page's order and cuts them to one page
+func memoryPage[T any](rows []T, page Page, key func(T) Cursor) ([]T, *Cursor) {
+	sort.Slice(rows, func(i, j int) bool {
+		return page.less(key(rows[i]), key(rows[j]))
+	})
+	return trimPage(rows, page, key)
+}
+
+type memoryUsers struct{ *memory }
+
+func (r *memoryUsers) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Username, user.Username) || existing.Email == user.Email {
+			return ErrConflict
+		}
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	user.ID = r.id("users")
+	user.CreatedAt, user.UpdatedAt = time.Now(), time.Now()
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUsers) FindByID(_ context.Context, id uint) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return user, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUsers) FindByEmail(_ context.Context, email string) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (r *memoryUsers) FindByUsername(_ context.Context, username string) (models.User, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var current *models.User
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, false, nil
+		}
+		if strings.EqualFold(user.Username, username) {
+			current = &user
+		}
+	}
+
+	// An exact previous username, then the current one in another case, then a
+	// previous one in another case, of the oldest account
+	var previous *models.UsernameRedirect
+	for _, redirectID := range sortedIDs(r.usernameRedirects) {
+		redirect := r.usernameRedirects[redirectID]
+		if redirect.Username == username {
+			return r.users[redirect.UserID], true, nil
+		}
+		if strings.EqualFold(redirect.Username, username) && (previous == nil || redirect.UserID < previous.UserID) {
+			previous = &redirect
+		}
+	}
+	switch {
+	case current != nil:
+		return *current, false, nil
+	case previous != nil:
+		return r.users[previous.UserID], true, nil
+	}
+	return models.User{}, false, ErrNotFound
+}
+
+func (r *memoryUsers) Usernames(_ context.Context, ids []uint) (map[uint]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usernames := make(map[uint]string, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			usernames[id] = user.Username
+		}
+	}
+	return usernames, nil
+}
+
+func (r *memoryUsers) Rename(_ context.Context, id uint, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if user.Username == username {
+		return nil
+	}
+	for _, other := range r.users {
+		if other.ID != id && strings.EqualFold(other.Username, username) {
+			return ErrConflict
+		}
+	}
+	// Another account's previous username is taken too, unless this account also
+	// held the name in another case (as legacy accounts may)
+	var own, others []uint
+	exactOther := false
+	for redirectID, redirect := range r.usernameRedirects {
+		switch {
+		case !strings.EqualFold(redirect.Username, username):
+		case redirect.UserID == id:
+			own = append(own, redirectID)
+		default:
+			others = append(others, redirectID)
+			exactOther = exactOther || redirect.Username == username
+		}
+	}
+	if exactOther || (len(others) > 0 && len(own) == 0) {
+		return ErrConflict
+	}
+	for _, redirectID := range own {
+		delete(r.usernameRedirects, redirectID)
+	}
+
+	if !strings.EqualFold(user.Username, username) {
+		redirect := models.UsernameRedirect{ID: r.id("username_redirects"), Username: user.Username, UserID: id, CreatedAt: time.Now()}
+		r.usernameRedirects[redirect.ID] = redirect
+		var kept []uint
+		for _, redirectID := range sortedIDs(r.usernameRedirects) {
+			if r.usernameRedirects[redirectID].UserID == id {
+				kept = append(kept, redirectID)
+			}
+		}
+		for _, redirectID := range kept[:max(len(kept)-models.MaxUsernameRedirects, 0)] {
+			delete(r.usernameRedirects, redirectID)
+		}
+	}
+	user.Username, user.UpdatedAt = username, time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUsers) List(_ context.Context, filter UserFilter) ([]models.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	query := strings.ToLower(filter.Query)
+	var matches []models.User
+	for _, id := range sortedIDs(r.users) {
+		user := r.users[id]
+		if query != "" && !strings.Contains(strings.ToLower(user.Username), query) && !strings.Contains(strings.ToLower(user.Email), query) {
+			continue
+		}
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		matches = append(matches, user)
+	}
+
+	total := int64(len(matches))
+	matches = matches[min(filter.Offset, len(matches)):]
+	if filter.Limit > 0 {
+		matches = matches[:min(filter.Limit, len(matches))]
+	}
+	return matches, total, nil
+}
+
+func (r *memoryUsers) SetRole(_ context.Context, id uint, role models.Role) error {
+	return r.update(id, func(user *models.User) { user.Role = role })
+}
+
+func (r *memoryUsers) Suspend(_ context.Context, id uint, until time.Time, note string) error {
+	return r.update(id, func(user *models.User) {
+		user.SuspendedUntil, user.ModerationNote = &until, note
+		r.revokeCredentials(id)
+	})
+}
+
+func (r *memoryUsers) Unsuspend(_ context.Context, id uint) error {
+	return r.update(id, func(user *models.User) { user.SuspendedUntil = nil })
+}
+
+func (r *memoryUsers) Ban(_ context.Context, id uint, at time.Time, note string) error {
+	return r.update(id, func(user *models.User) {
+		user.BannedAt, user.ModerationNote = &at, note
+		r.revokeCredentials(id)
+	})
+}
+
+func (r *memoryUsers) Unban(_ context.Context, id uint) error {
+	return r.update(id, func(user *models.User) { user.BannedAt = nil })
+}
+
+func (r *memoryUsers) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+
+	for key, kind := range r.reactions {
+		if key.userID == id {
+			r.bumpCounter(key.postID, kind, -1)
+			delete(r.reactions, key)
+		}
+	}
+	deleteOwned(r.refreshTokens, id, func(token models.RefreshToken) uint { return token.UserID })
+	deleteOwned(r.accessTokens, id, func(token models.PersonalAccessToken) uint { return token.UserID })
+	deleteOwned(r.oneTimeTokens, id, func(token models.OneTimeToken) uint { return token.UserID })
+	deleteOwned(r.recoveryCodes, id, func(code models.RecoveryCode) uint { return code.UserID })
+	for stateID, state := range r.oauthStates {
+		if state.UserID != nil && *state.UserID == id {
+			delete(r.oauthStates, stateID)
+		}
+	}
+	for key, kind := range r.commentReactions {
+		if key.userID == id {
+			r.bumpCommentCounter(key.commentID, kind, -1)
+			delete(r.commentReactions, key)
+		}
+	}
+	var replied []uint
+	for commentID, comment := range r.comments {
+		if comment.UserID == id && r.hasReplies(commentID) {
+			replied = append(replied, commentID)
+		}
+	}
+	for _, commentID := range replied {
+		r.tombstone(commentID)
+	}
+	for commentID, comment := range r.comments {
+		if comment.UserID == id {
+			r.deleteComment(commentID)
+		}
+	}
+	for profileID, profile := range r.profiles {
+		if profile.UserID == id {
+			r.deleteProfile(profileID)
+		}
+	}
+	for key := range r.follows {
+		if key.followerID == id || key.followeeID == id {
+			delete(r.follows, key)
+		}
+	}
+	for key := range r.endorsements {
+		if key.endorserID == id {
+			delete(r.endorsements, key)
+		}
+	}
+	for redirectID, redirect := range r.usernameRedirects {
+		if redirect.UserID == id {
+			delete(r.usernameRedirects, redirectID)
+		}
+	}
+	for key := range r.tagFollows {
+		if key.userID == id {
+			delete(r.tagFollows, key)
+		}
+	}
+	for key := range r.blocks {
+		if key.blockerID == id || key.blockedID == id {
+			delete(r.blocks, key)
+		}
+	}
+	for mentionID, mention := range r.mentions {
+		if mention.UserID == id {
+			delete(r.mentions, mentionID)
+		}
+	}
+	for notificationID, notification := range r.notifications {
+		if notification.UserID == id || notification.ActorID == id {
+			r.deleteNotification(notificationID)
+		}
+	}
+	for key := range r.notificationActors {
+		if key.actorID == id {
+			delete(r.notificationActors, key)
+		}
+	}
+	for key := range r.notificationPreferences {
+		if key.userID == id {
+			delete(r.notificationPreferences, key)
+		}
+	}
+	for key := range r.conversationMembers {
+		if key.userID == id {
+			delete(r.conversationMembers, key)
+		}
+	}
+	for messageID, message := range r.messages {
+		if message.SenderID == id {
+			delete(r.messages, messageID)
+		}
+	}
+	for conversationID, conversation := range r.conversations {
+		if conversation.CreatorID != nil && *conversation.CreatorID == id {
+			conversation.CreatorID = nil
+			r.conversations[conversationID] = conversation
+		}
+	}
+	for postID, post := range r.posts {
+		if post.UserID == id {
+			r.deletePost(postID)
+		}
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memoryUsers) RecordFailedLogin(_ context.Context, id uint) (int, error) {
+	failures := 0
+	err := r.update(id, func(user *models.User) {
+		user.FailedLogins++
+		failures = user.FailedLogins
+	})
+	return failures, err
+}
+
+func (r *memoryUsers) LockLogin(_ context.Context, id uint, until time.Time) error {
+	return r.update(id, func(user *models.User) { user.LockedUntil = &until })
+}
+
+func (r *memoryUsers) ResetFailedLogins(_ context.Context, id uint) error {
+	return r.update(id, func(user *models.User) { user.FailedLogins, user.LockedUntil = 0, nil })
+}
+
+// update applies change to a stored user. change runs with the lock held.
+func (r *memoryUsers) update(id uint, change func(user *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	change(&user)
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+// revokeCredentials revokes the user's refresh tokens and personal access tokens. The caller holds the lock.
+func (m *memory) revokeCredentials(userID uint) {
+	now := time.Now()
+	for id, token := range m.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			m.refreshTokens[id] = token
+		}
+	}
+	for id, token := range m.accessTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			m.accessTokens[id] = token
+		}
+	}
+}
+
+// deleteOwned removes a user's rows from a table
+func deleteOwned[T any](table map[uint]T, userID uint, owner func(T) uint) {
+	for id, row := range table {
+		if owner(row) == userID {
+			delete(table, id)
+		}
+	}
+}
+
+type memorySessions struct{ *memory }
+
+func (r *memorySessions) Create(_ context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.refreshTokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrConflict
+		}
+	}
+	token.ID = r.id("refresh_tokens")
+	token.CreatedAt = time.Now()
+	r.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memorySessions) Find(_ context.Context, tokenHash string) (models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, ErrNotFound
+}
+
+func (r *memorySessions) Rotate(ctx context.Context, tokenHash string, next *models.RefreshToken) error {
+	stored, err := r.Find(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored = r.refreshTokens[stored.ID]
+	if stored.RevokedAt != nil {
+		r.revokeFamily(stored.FamilyID)
+		return ErrReused
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return ErrExpired
+	}
+
+	next.ID = r.id("refresh_tokens")
+	next.UserID, next.FamilyID, next.CreatedAt = stored.UserID, stored.FamilyID, time.Now()
+	r.refreshTokens[next.ID] = *next
+	now := time.Now()
+	stored.RevokedAt, stored.ReplacedBy = &now, &next.ID
+	r.refreshTokens[stored.ID] = stored
+	return nil
+}
+
+func (r *memorySessions) RevokeFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokeFamily(familyID)
+	return nil
+}
+
+// revokeFamily revokes every still-active refresh token in a family. The caller holds the lock.
+func (m *memory) revokeFamily(familyID string) {
+	now := time.Now()
+	for id, token := range m.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			m.refreshTokens[id] = token
+		}
+	}
+}
+
+func (r *memorySessions) RevokeAccessToken(_ context.Context, jti string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.revokedTokens[jti]; ok {
+		return false, nil
+	}
+	r.revokedTokens[jti] = expiresAt
+	return true, nil
+}
+
+func (r *memorySessions) AccessTokenRevoked(_ context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.revokedTokens[jti]
+	return ok, nil
+}
+
+func (r *memorySessions) PurgeRevoked(_ context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for jti, expiresAt := range r.revokedTokens {
+		if expiresAt.Before(now) {
+			delete(r.revokedTokens, jti)
+		}
+	}
+	return nil
+}
+
+type memoryAccessTokens struct{ *memory }
+
+func (r *memoryAccessTokens) Create(_ context.Context, token *models.PersonalAccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.accessTokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrConflict
+		}
+	}
+	token.ID = r.id("personal_access_tokens")
+	token.CreatedAt = time.Now()
+	r.accessTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryAccessTokens) List(_ context.Context, userID uint) ([]models.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []models.PersonalAccessToken
+	ids := sortedIDs(r.accessTokens)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if token := r.accessTokens[ids[i]]; token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryAccessTokens) FindByHash(_ context.Context, tokenHash string) (models.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.accessTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.PersonalAccessToken{}, ErrNotFound
+}
+
+func (r *memoryAccessTokens) Revoke(_ context.Context, userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.accessTokens[id]
+	if !ok || token.UserID != userID {
+		return ErrNotFound
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		r.accessTokens[id] = token
+	}
+	return nil
+}
+
+func (r *memoryAccessTokens) Touch(_ context.Context, id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.accessTokens[id]
+	if ok && (token.LastUsedAt == nil || token.LastUsedAt.Before(at.Add(-time.Minute))) {
+		token.LastUsedAt = &at
+		r.accessTokens[id] = token
+	}
+	return nil
+}
+
+type memoryOneTimeTokens struct{ *memory }
+
+func (r *memoryOneTimeTokens) Create(_ context.Context, token *models.OneTimeToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, existing := range r.oneTimeTokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrConflict
+		}
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			existing.UsedAt = &now
+			r.oneTimeTokens[id] = existing
+		}
+	}
+	token.ID = r.id("one_time_tokens")
+	token.CreatedAt = now
+	r.oneTimeTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryOneTimeTokens) VerifyEmail(_ context.Context, tokenHash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, err := r.spendOneTimeToken(tokenHash, models.PurposeVerifyEmail, at)
+	if err != nil {
+		return err
+	}
+	if user, ok := r.users[token.UserID]; ok && user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &at
+		r.users[user.ID] = user
+	}
+	return nil
+}
+
+func (r *memoryOneTimeTokens) ResetPassword(_ context.Context, tokenHash, passwordHash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, err := r.spendOneTimeToken(tokenHash, models.PurposeResetPassword, at)
+	if err != nil {
+		return err
+	}
+	if user, ok := r.users[token.UserID]; ok {
+		user.Password, user.FailedLogins, user.LockedUntil = passwordHash, 0, nil
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &at
+		}
+		r.users[user.ID] = user
+	}
+	r.revokeCredentials(token.UserID)
+	return nil
+}
+
+// spendOneTimeToken marks a token as used, returning ErrNotFound if it is unknown,
+// meant for another purpose, already used or expired. The caller holds the lock.
+func (m *memory) spendOneTimeToken(tokenHash string, purpose models.TokenPurpose, at time.Time) (models.OneTimeToken, error) {
+	for id, token := range m.oneTimeTokens {
+		if token.TokenHash != tokenHash || token.Purpose != purpose {
+			continue
+		}
+		if token.UsedAt != nil || at.After(token.ExpiresAt) {
+			return token, ErrNotFound
+		}
+		token.UsedAt = &at
+		m.oneTimeTokens[id] = token
+		return token, nil
+	}
+	return models.OneTimeToken{}, ErrNotFound
+}
+
+type memoryMFA struct{ *memory }
+
+func (r *memoryMFA) SetSecret(ctx context.Context, userID uint, secret string) error {
+	return (&memoryUsers{r.memory}).update(userID, func(user *models.User) { user.TOTPSecret = secret })
+}
+
+func (r *memoryMFA) Enable(ctx context.Context, userID uint, counter int64, codeHashes []string) error {
+	return (&memoryUsers{r.memory}).update(userID, func(user *models.User) {
+		now := time.Now()
+		user.TOTPEnabledAt, user.TOTPLastCounter = &now, counter
+		r.replaceRecoveryCodes(userID, codeHashes)
+	})
+}
+
+func (r *memoryMFA) Disable(ctx context.Context, userID uint) error {
+	return (&memoryUsers{r.memory}).update(userID, func(user *models.User) {
+		user.TOTPSecret, user.TOTPEnabledAt, user.TOTPLastCounter = "", nil, 0
+		r.replaceRecoveryCodes(userID, nil)
+	})
+}
+
+func (r *memoryMFA) ReplaceRecoveryCodes(_ context.Context, userID uint, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores codeHashes instead. The caller holds the lock.
+func (m *memory) replaceRecoveryCodes(userID uint, codeHashes []string) {
+	deleteOwned(m.recoveryCodes, userID, func(code models.RecoveryCode) uint { return code.UserID })
+	for _, hash := range codeHashes {
+		code := models.RecoveryCode{ID: m.id("recovery_codes"), UserID: userID, CodeHash: hash, CreatedAt: time.Now()}
+		m.recoveryCodes[code.ID] = code
+	}
+}
+
+func (r *memoryMFA) UseTOTP(_ context.Context, userID uint, counter int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok || user.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	user.TOTPLastCounter = counter
+	r.users[userID] = user
+	return true, nil
+}
+
+func (r *memoryMFA) UseRecoveryCode(_ context.Context, userID uint, codeHash string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used := false
+	for id, code := range r.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &at
+			r.recoveryCodes[id] = code
+			used = true
+		}
+	}
+	return used, nil
+}
+
+type memoryOAuthStates struct{ *memory }
+
+func (r *memoryOAuthStates) Create(_ context.Context, state *models.OAuthState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, existing := range r.oauthStates {
+		if existing.StateHash == state.StateHash {
+			return ErrConflict
+		}
+		if existing.ExpiresAt.Before(now) {
+			delete(r.oauthStates, id)
+		}
+	}
+	state.ID = r.id("oauth_states")
+	state.CreatedAt = now
+	r.oauthStates[state.ID] = *state
+	return nil
+}
+
+func (r *memoryOAuthStates) Claim(_ context.Context, stateHash string, now time.Time) (models.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, state := range r.oauthStates {
+		if state.StateHash != stateHash {
+			continue
+		}
+		delete(r.oauthStates, id)
+		if now.After(state.ExpiresAt) {
+			return state, ErrNotFound
+		}
+		return state, nil
+	}
+	return models.OAuthState{}, ErrNotFound
+}
+
+type memoryProfiles struct{ *memory }
+
+func (r *memoryProfiles) Create(_ context.Context, profile *models.Profile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.profiles {
+		if existing.UserID == profile.UserID {
+			return ErrConflict
+		}
+	}
+	profile.ID = r.id("profiles")
+	profile.CreatedAt, profile.UpdatedAt = time.Now(), time.Now()
+	r.profiles[profile.ID] = *profile
+	return nil
+}
+
+func (r *memoryProfiles) FindByID(_ context.Context, id uint) (models.Profile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.profiles[id]
+	if !ok {
+		return profile, ErrNotFound
+	}
+	return profile, nil
+}
+
+func (r *memoryProfiles) FindByGithubID(_ context.Context, githubID int64) (models.Profile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, profile := range r.profiles {
+		if profile.GithubID != nil && *profile.GithubID == githubID {
+			return profile, nil
+		}
+	}
+	return models.Profile{}, ErrNotFound
+}
+
+func (r *memoryProfiles) List(_ context.Context, filter ProfileFilter) ([]models.Profile, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profiles := []models.Profile{}
+	for _, profile := range r.profiles {
+		if filter.UserID != 0 && profile.UserID != filter.UserID {
+			continue
+		}
+		if filter.Created.Contains(profile.CreatedAt) && filter.Page.after(profileCursor(profile)) {
+			profiles = append(profiles, profile)
+		}
+	}
+	profiles, next := memoryPage(profiles, filter.Page, profileCursor)
+	return profiles, next, nil
+}
+
+func (r *memoryProfiles) Update(_ context.Context, profile *models.Profile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.profiles[profile.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	profile.PictureID = stored.PictureID
+	profile.UpdatedAt = time.Now()
+	r.profiles[profile.ID] = *profile
+	return nil
+}
+
+func (r *memoryProfiles) SetPicture(_ context.Context, id uint, pictureID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.profiles[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	previous := profile.PictureID
+	profile.PictureID, profile.UpdatedAt = pictureID, time.Now()
+	r.profiles[id] = profile
+	return previous, nil
+}
+
+func (r *memoryProfiles) PictureInUse(_ context.Context, pictureID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, profile := range r.profiles {
+		if profile.PictureID == pictureID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryProfiles) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[id]; !ok {
+		return ErrNotFound
+	}
+	r.deleteProfile(id)
+	return nil
+}
+
+// deleteProfile removes a profile with the entries of its sections. The caller holds the lock.
+func (m *memory) deleteProfile(id uint) {
+	for skillID, skill := range m.skills {
+		if skill.ProfileID == id {
+			m.deleteEndorsements(skillID)
+		}
+	}
+	deleteEntries(m.skills, id)
+	deleteEntries(m.experiences, id)
+	deleteEntries(m.educations, id)
+	deleteEntries(m.profileLinks, id)
+	for repoID, repo := range m.githubRepos {
+		if repo.ProfileID == id {
+			delete(m.githubRepos, repoID)
+		}
+	}
+	delete(m.githubRepoSyncs, id)
+	delete(m.profiles, id)
+}
+
+// deleteEntries removes a profile's entries from a section's table
+func deleteEntries[T any, P models.ProfileEntry[T]](table map[uint]T, profileID uint) {
+	for id, entry := range table {
+		if P(&entry).Item().ProfileID == profileID {
+			delete(table, id)
+		}
+	}
+}
+
+func (r *memoryProfiles) OwnerID(_ context.Context, id uint) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.profiles[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return profile.UserID, nil
+}
+
+func (r *memoryProfiles) LinkGithub(_ context.Context, userID uint, githubID int64, login string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var profile *models.Profile
+	for _, existing := range r.profiles {
+		if existing.GithubID != nil && *existing.GithubID == githubID && existing.UserID != userID {
+			return ErrConflict
+		}
+		if existing.UserID == userID {
+			profile = &existing
+		}
+	}
+	if profile == nil {
+		profile = &models.Profile{ID: r.id("profiles"), UserID: userID, CreatedAt: at}
+	}
+	profile.GithubID, profile.GithubLogin, profile.Github, profile.GithubVerifiedAt = &githubID, login, login, &at
+	profile.UpdatedAt = at
+	r.profiles[profile.ID] = *profile
+	return nil
+}
+
+// memorySection stores one section of profiles in the table its table func returns
+type memorySection[T any, P models.ProfileEntry[T]] struct {
+	*memory
+	table   func(m *memory) map[uint]T
+	name    string // Table name, for IDs
+	limit   int
+	same    func(a, b *T) bool       // Whether two entries repeat each other, if a section lists entries once
+	cascade func(m *memory, id uint) // Removes what refers to an entry being deleted, if anything does
+}
+
+func (r *memorySection[T, P]) List(_ context.Context, profileIDs ...uint) ([]T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []T{}
+	for _, profileID := range profileIDs {
+		entries = append(entries, r.entries(profileID)...)
+	}
+	return entries, nil
+}
+
+// entries returns a profile's entries in position order. The caller holds the lock.
+func (r *memorySection[T, P]) entries(profileID uint) []T {
+	entries := []T{}
+	for _, id := range sortedIDs(r.table(r.memory)) {
+		if entry := r.table(r.memory)[id]; P(&entry).Item().ProfileID == profileID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return P(&entries[i]).Item().Position < P(&entries[j]).Item().Position })
+	return entries
+}
+
+func (r *memorySection[T, P]) Create(_ context.Context, entry *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := P(entry).Item()
+	if _, ok := r.profiles[item.ProfileID]; !ok {
+		return ErrNotFound
+	}
+	existing := r.entries(item.ProfileID)
+	if len(existing) >= r.limit {
+		return ErrLimit
+	}
+	if r.repeats(entry, existing) {
+		return ErrConflict
+	}
+
+	position := 0
+	if len(existing) > 0 {
+		position = P(&existing[len(existing)-1]).Item().Position + 1
+	}
+	now := time.Now()
+	*item = models.ProfileItem{ID: r.id(r.name), ProfileID: item.ProfileID, Position: position, CreatedAt: now, UpdatedAt: now}
+	r.table(r.memory)[item.ID] = *entry
+	return nil
+}
+
+// repeats reports whether entry repeats one of others, besides itself. The caller holds the lock.
+func (r *memorySection[T, P]) repeats(entry *T, others []T) bool {
+	if r.same == nil {
+		return false
+	}
+	for i := range others {
+		if P(&others[i]).Item().ID != P(entry).Item().ID && r.same(entry, &others[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memorySection[T, P]) Update(_ context.Context, entry *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := P(entry).Item()
+	stored, ok := r.table(r.memory)[item.ID]
+	if !ok || P(&stored).Item().ProfileID != item.ProfileID {
+		return ErrNotFound
+	}
+	if r.repeats(entry, r.entries(item.ProfileID)) {
+		return ErrConflict
+	}
+
+	storedItem := P(&stored).Item()
+	item.Position, item.CreatedAt, item.UpdatedAt = storedItem.Position, storedItem.CreatedAt, time.Now()
+	r.table(r.memory)[item.ID] = *entry
+	return nil
+}
+
+func (r *memorySection[T, P]) Delete(_ context.Context, profileID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.table(r.memory)[id]
+	if !ok || P(&stored).Item().ProfileID != profileID {
+		return ErrNotFound
+	}
+	if r.cascade != nil {
+		r.cascade(r.memory, id)
+	}
+	delete(r.table(r.memory), id)
+	return nil
+}
+
+func (r *memorySection[T, P]) Reorder(_ context.Context, profileID uint, ids []uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries(profileID)
+	current := make([]uint, len(entries))
+	for i := range entries {
+		current[i] = P(&entries[i]).Item().ID
+	}
+	order, err := reorder(current, ids)
+	if err != nil {
+		return err
+	}
+	table := r.table(r.memory)
+	for position, id := range order {
+		entry := table[id]
+		P(&entry).Item().Position = position
+		table[id] = entry
+	}
+	return nil
+}
+
+type memoryGithubRepos struct{ *memory }
+
+func (r *memoryGithubRepos) List(_ context.Context, filter GithubRepoFilter) ([]models.GithubRepo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profiles := make(map[uint]bool, len(filter.ProfileIDs))
+	for _, id := range filter.ProfileIDs {
+		profiles[id] = true
+	}
+	repos := []models.GithubRepo{}
+	for _, repo := range r.githubRepos {
+		if profiles[repo.ProfileID] && (!filter.Pinned || repo.PinPosition != nil) {
+			repos = append(repos, repo)
+		}
+	}
+
+	pushed := func(repo models.GithubRepo) time.Time {
+		if repo.PushedAt == nil {
+			return time.Time{}
+		}
+		return *repo.PushedAt
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		a, b := repos[i], repos[j]
+		switch {
+		case a.ProfileID != b.ProfileID:
+			return a.ProfileID < b.ProfileID
+		case filter.Pinned:
+			return *a.PinPosition < *b.PinPosition
+		case filter.Sort == RepoSortStars && a.Stars != b.Stars:
+			return a.Stars > b.Stars
+		case !pushed(a).Equal(pushed(b)):
+			return pushed(a).After(pushed(b))
+		}
+		return a.ID < b.ID
+	})
+	return repos, nil
+}
+
+func (r *memoryGithubRepos) SyncState(_ context.Context, profileID uint) (models.GithubRepoSync, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.githubRepoSyncs[profileID]
+	if !ok {
+		return state, ErrNotFound
+	}
+	return state, nil
+}
+
+func (r *memoryGithubRepos) Replace(_ context.Context, state models.GithubRepoSync, repos []models.GithubRepo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[state.ProfileID]; !ok {
+		return ErrNotFound
+	}
+	previous := r.githubRepoSyncs[state.ProfileID]
+	sameAccount := previous.Login == "" || strings.EqualFold(previous.Login, state.Login)
+	fetched := make(map[int64]bool, len(repos))
+	for _, repo := range repos {
+		fetched[repo.GithubID] = true
+	}
+
+	existing := map[int64]models.GithubRepo{}
+	for id, repo := range r.githubRepos {
+		if repo.ProfileID != state.ProfileID {
+			continue
+		}
+		if !sameAccount || (repo.PinPosition == nil && !fetched[repo.GithubID]) {
+			delete(r.githubRepos, id)
+			continue
+		}
+		existing[repo.GithubID] = repo
+	}
+
+	now := time.Now()
+	for i := range repos {
+		repo := &repos[i]
+		repo.ProfileID, repo.UpdatedAt = state.ProfileID, now
+		if stored, ok := existing[repo.GithubID]; ok {
+			repo.ID, repo.PinPosition, repo.CreatedAt = stored.ID, stored.PinPosition, stored.CreatedAt
+		} else {
+			repo.ID, repo.PinPosition, repo.CreatedAt = r.id("github_repos"), nil, now
+		}
+		r.githubRepos[repo.ID] = *repo
+	}
+	r.githubRepoSyncs[state.ProfileID] = state
+	return nil
+}
+
+func (r *memoryGithubRepos) Touch(_ context.Context, profileID uint, syncedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state, ok := r.githubRepoSyncs[profileID]; ok {
+		state.SyncedAt, state.CheckedAt = &syncedAt, &syncedAt
+		state.Failures, state.LastError = 0, ""
+		r.githubRepoSyncs[profileID] = state
+	}
+	return nil
+}
+
+func (r *memoryGithubRepos) RecordFailure(_ context.Context, profileID uint, login string, checkedAt time.Time, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[profileID]; !ok {
+		return ErrNotFound
+	}
+	state, ok := r.githubRepoSyncs[profileID]
+	if !ok {
+		state = models.GithubRepoSync{ProfileID: profileID, Login: login}
+	}
+	state.CheckedAt = &checkedAt
+	state.Failures++
+	state.LastError = reason
+	r.githubRepoSyncs[profileID] = state
+	return nil
+}
+
+func (r *memoryGithubRepos) Pin(_ context.Context, profileID uint, ids []uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		if repo, ok := r.githubRepos[id]; !ok || repo.ProfileID != profileID {
+			return ErrNotFound
+		}
+	}
+	for id, repo := range r.githubRepos {
+		if repo.ProfileID == profileID {
+			repo.PinPosition = nil
+			r.githubRepos[id] = repo
+		}
+	}
+	for position, id := range ids {
+		repo := r.githubRepos[id]
+		repo.PinPosition = &position
+		r.githubRepos[id] = repo
+	}
+	return nil
+}
+
+type memoryPosts struct{ *memory }
+
+func (r *memoryPosts) Create(_ context.Context, post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.ID = r.id("posts")
+	post.CreatedAt, post.UpdatedAt = time.Now(), time.Now()
+	post.Tags = models.ExtractHashtags(post.Content)
+	stored := *post
+	stored.User, stored.Comments, stored.Tags, stored.Mentions = models.User{}, nil, nil, nil
+	r.posts[post.ID] = stored
+	r.syncPostTags(post.ID, post.Tags)
+	post.Mentions = r.syncMentions(&post.ID, nil, post.Content)
+	return nil
+}
+
+func (r *memoryPosts) FindByID(_ context.Context, id uint) (models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return post, ErrNotFound
+	}
+	post.User = r.users[post.UserID]
+	post.Tags, post.Mentions = r.postTagNames(id), r.mentionsIn(&id, nil)
+	return post, nil
+}
+
+func (r *memoryPosts) List(_ context.Context, filter PostFilter) ([]models.Post, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commented := map[uint]bool{}
+	for _, comment := range r.comments {
+		commented[comment.PostID] = true
+	}
+
+	posts := []models.Post{}
+	for _, post := range r.posts {
+		if filter.AuthorID != 0 && post.UserID != filter.AuthorID {
+			continue
+		}
+		if filter.HasComments != nil && commented[post.ID] != *filter.HasComments {
+			continue
+		}
+		post.Tags = r.postTagNames(post.ID)
+		if filter.Tag != "" && !slices.Contains(post.Tags, filter.Tag) {
+			continue
+		}
+		if filter.Created.Contains(post.CreatedAt) && filter.Page.after(postCursor(post)) {
+			post.User, post.Mentions = r.users[post.UserID], r.mentionsIn(&post.ID, nil)
+			posts = append(posts, post)
+		}
+	}
+	posts, next := memoryPage(posts, filter.Page, postCursor)
+	return posts, next, nil
+}
+
+func (r *memoryPosts) UpdateContent(_ context.Context, id uint, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return ErrNotFound
+	}
+	post.Content, post.UpdatedAt = content, time.Now()
+	r.posts[id] = post
+	r.syncPostTags(id, models.ExtractHashtags(content))
+	r.syncMentions(&id, nil, content)
+	return nil
+}
+
+func (r *memoryPosts) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.posts[id]; !ok {
+		return ErrNotFound
+	}
+	r.deletePost(id)
+	return nil
+}
+
+func (r *memoryPosts) OwnerID(_ context.Context, id uint) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return post.UserID, nil
+}
+
+func (r *memoryPosts) SetReaction(_ context.Context, postID, userID uint, kind models.ReactionKind) (models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.posts[postID]; !ok {
+		return models.Post{}, ErrNotFound
+	}
+
+	key := reactionKey{postID, userID}
+	if current, found := r.reactions[key]; found {
+		r.bumpCounter(postID, current, -1)
+		delete(r.reactions, key)
+	}
+	if kind != "" {
+		r.reactions[key] = kind
+		r.bumpCounter(postID, kind, 1)
+	}
+
+	stored := r.posts[postID]
+	return models.Post{ID: postID, UserID: stored.UserID, Likes: stored.Likes, Dislikes: stored.Dislikes, MyReaction: kind}, nil
+}
+
+func (r *memoryPosts) ReactionsBy(_ context.Context, userID uint, postIDs []uint) (map[uint]models.ReactionKind, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kinds := make(map[uint]models.ReactionKind)
+	for _, postID := range postIDs {
+		if kind, ok := r.reactions[reactionKey{postID, userID}]; ok {
+			kinds[postID] = kind
+		}
+	}
+	return kinds, nil
+}
+
+// bumpCounter adds delta to the post's counter for kind. The caller holds the lock.
+func (m *memory) bumpCounter(postID uint, kind models.ReactionKind, delta int) {
+	post, ok := m.posts[postID]
+	if !ok {
+		return
+	}
+	if kind == models.ReactionLike {
+		post.Likes += delta
+	} else {
+		post.Dislikes += delta
+	}
+	m.posts[postID] = post
+}
+
+// deletePost removes a post with its comments, reactions, tag links, mentions and
+// notifications. The caller holds the lock.
+func (m *memory) deletePost(id uint) {
+	for key := range m.reactions {
+		if key.postID == id {
+			delete(m.reactions, key)
+		}
+	}
+	for key := range m.postTags {
+		if key.postID == id {
+			delete(m.postTags, key)
+		}
+	}
+	for commentID, comment := range m.comments {
+		if comment.PostID == id {
+			m.deleteComment(commentID)
+		}
+	}
+	for mentionID, mention := range m.mentions {
+		if mention.PostID != nil && *mention.PostID == id {
+			delete(m.mentions, mentionID)
+		}
+	}
+	for notificationID, notification := range m.notifications {
+		if notification.PostID != nil && *notification.PostID == id {
+			m.deleteNotification(notificationID)
+		}
+	}
+	delete(m.posts, id)
+}
+
+type memoryComments struct{ *memory }
+
+func (r *memoryComments) Create(_ context.Context, comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = r.id("comments")
+	comment.CreatedAt, comment.UpdatedAt = time.Now(), time.Now()
+	stored := *comment
+	stored.User, stored.Replies, stored.Mentions = models.User{}, nil, nil
+	r.comments[comment.ID] = stored
+	comment.Mentions = r.syncMentions(nil, &comment.ID, comment.Content)
+	return nil
+}
+
+func (r *memoryComments) FindByID(_ context.Context, id uint) (models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return comment, ErrNotFound
+	}
+	comment.User, comment.Mentions = r.users[comment.UserID], r.mentionsIn(nil, &id)
+	return comment, nil
+}
+
+func (r *memoryComments) ListForPost(_ context.Context, postID uint, filter CommentFilter) ([]models.Comment, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comments := []models.Comment{}
+	for _, comment := range r.comments {
+		if comment.PostID != postID || comment.ParentID != nil || (filter.AuthorID != 0 && comment.UserID != filter.AuthorID) {
+			continue
+		}
+		if filter.Created.Contains(comment.CreatedAt) && filter.Page.after(commentCursor(comment)) {
+			comment.User, comment.Mentions = r.users[comment.UserID], r.mentionsIn(nil, &comment.ID)
+			comments = append(comments, comment)
+		}
+	}
+	comments, next := memoryPage(comments, filter.Page, commentCursor)
+	return comments, next, nil
+}
+
+func (r *memoryComments) Replies(_ context.Context, rootIDs []uint) ([]models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	roots := map[uint]bool{}
+	for _, id := range rootIDs {
+		roots[id] = true
+	}
+	replies := []models.Comment{}
+	for _, comment := range r.comments {
+		if comment.RootID != nil && roots[*comment.RootID] {
+			comment.User, comment.Mentions = r.users[comment.UserID], r.mentionsIn(nil, &comment.ID)
+			replies = append(replies, comment)
+		}
+	}
+	replies, _ = memoryPage(replies, Page{Sort: SortOldest}, commentCursor)
+	return replies, nil
+}
+
+func (r *memoryComments) UpdateContent(_ context.Context, id uint, content string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok || comment.Deleted() {
+		return ErrNotFound
+	}
+	comment.Content, comment.EditedAt, comment.UpdatedAt = content, &at, time.Now()
+	r.comments[id] = comment
+	r.syncMentions(nil, &id, content)
+	return nil
+}
+
+func (r *memoryComments) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for {
+		if r.hasReplies(comment.ID) {
+			r.tombstone(comment.ID)
+			return nil
+		}
+		r.deleteComment(comment.ID)
+
+		if comment.ParentID == nil {
+			return nil
+		}
+		parent, ok := r.comments[*comment.ParentID]
+		if !ok || !parent.Deleted() {
+			return nil
+		}
+		comment = parent
+	}
+}
+
+func (r *memoryComments) OwnerID(_ context.Context, id uint) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return comment.UserID, nil
+}
+
+func (r *memoryComments) SetReaction(_ context.Context, commentID, userID uint, kind models.ReactionKind) (models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if comment, ok := r.comments[commentID]; !ok || comment.Deleted() {
+		return models.Comment{}, ErrNotFound
+	}
+
+	key := commentReactionKey{commentID, userID}
+	if current, found := r.commentReactions[key]; found {
+		r.bumpCommentCounter(commentID, current, -1)
+		delete(r.commentReactions, key)
+	}
+	if kind != "" {
+		r.commentReactions[key] = kind
+		r.bumpCommentCounter(commentID, kind, 1)
+	}
+
+	stored := r.comments[commentID]
+	return models.Comment{ID: commentID, PostID: stored.PostID, UserID: stored.UserID, Likes: stored.Likes, Dislikes: stored.Dislikes, MyReaction: kind}, nil
+}
+
+func (r *memoryComments) ReactionsBy(_ context.Context, userID uint, commentIDs []uint) (map[uint]models.ReactionKind, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kinds := make(map[uint]models.ReactionKind)
+	for _, commentID := range commentIDs {
+		if kind, ok := r.commentReactions[commentReactionKey{commentID, userID}]; ok {
+			kinds[commentID] = kind
+		}
+	}
+	return kinds, nil
+}
+
+// hasReplies reports whether any comment replies to id. The caller holds the lock.
+func (m *memory) hasReplies(id uint) bool {
+	for _, comment := range m.comments {
+		if comment.ParentID != nil && *comment.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// tombstone clears a comment's content, mentions and author. The caller holds the lock.
+func (m *memory) tombstone(id uint) {
+	comment := m.comments[id]
+	now := time.Now()
+	comment.Content, comment.UserID, comment.DeletedAt, comment.UpdatedAt = "", 0, &now, now
+	m.comments[id] = comment
+	m.syncMentions(nil, &id, "")
+}
+
+// bumpCommentCounter adds delta to the comment's counter for kind. The caller holds the lock.
+func (m *memory) bumpCommentCounter(commentID uint, kind models.ReactionKind, delta int) {
+	comment, ok := m.comments[commentID]
+	if !ok {
+		return
+	}
+	if kind == models.ReactionLike {
+		comment.Likes += delta
+	} else {
+		comment.Dislikes += delta
+	}
+	m.comments[commentID] = comment
+}
+
+// deleteComment removes a comment with its reactions, mentions and notifications.
+// The caller holds the lock.
+func (m *memory) deleteComment(commentID uint) {
+	for key := range m.commentReactions {
+		if key.commentID == commentID {
+			delete(m.commentReactions, key)
+		}
+	}
+	m.syncMentions(nil, &commentID, "")
+	for notificationID, notification := range m.notifications {
+		if notification.CommentID != nil && *notification.CommentID == commentID {
+			m.deleteNotification(notificationID)
+		}
+	}
+	delete(m.comments, commentID)
+}
+
+type memoryFollows struct{ *memory }
+
+func (r *memoryFollows) Follow(_ context.Context, followerID, followeeID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[followeeID]; !ok {
+		return false, ErrNotFound
+	}
+	key := followKey{followerID, followeeID}
+	if _, ok := r.follows[key]; ok {
+		return false, nil
+	}
+	r.follows[key] = time.Now()
+	return true, nil
+}
+
+func (r *memoryFollows) Unfollow(_ context.Context, followerID, followeeID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.follows, followKey{followerID, followeeID})
+	return nil
+}
+
+func (r *memoryFollows) Followers(_ context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	follows := []models.Follow{}
+	for key, at := range r.follows {
+		follow := models.Follow{FollowerID: key.followerID, FolloweeID: key.followeeID, CreatedAt: at}
+		if key.followeeID == userID && page.after(followerCursor(follow)) {
+			follower := r.users[key.followerID]
+			follow.Follower = &follower
+			follows = append(follows, follow)
+		}
+	}
+	follows, next := memoryPage(follows, page, followerCursor)
+	return follows, next, nil
+}
+
+func (r *memoryFollows) Following(_ context.Context, userID uint, page Page) ([]models.Follow, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	follows := []models.Follow{}
+	for key, at := range r.follows {
+		follow := models.Follow{FollowerID: key.followerID, FolloweeID: key.followeeID, CreatedAt: at}
+		if key.followerID == userID && page.after(followeeCursor(follow)) {
+			followee := r.users[key.followeeID]
+			follow.Followee = &followee
+			follows = append(follows, follow)
+		}
+	}
+	follows, next := memoryPage(follows, page, followeeCursor)
+	return follows, next, nil
+}
+
+func (r *memoryFollows) Counts(_ context.Context, userIDs []uint) (map[uint]models.FollowCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+	counts := make(map[uint]models.FollowCounts, len(userIDs))
+	for key := range r.follows {
+		if wanted[key.followeeID] {
+			entry := counts[key.followeeID]
+			entry.Followers++
+			counts[key.followeeID] = entry
+		}
+		if wanted[key.followerID] {
+			entry := counts[key.followerID]
+			entry.Following++
+			counts[key.followerID] = entry
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryFollows) FollowersAmong(_ context.Context, followeeID uint, userIDs []uint) (map[uint]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	followers := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if _, ok := r.follows[followKey{userID, followeeID}]; ok {
+			followers[userID] = true
+		}
+	}
+	return followers, nil
+}
+
+type memoryEndorsements struct{ *memory }
+
+func (r *memoryEndorsements) Endorse(_ context.Context, skillID, endorserID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.skills[skillID]; !ok {
+		return false, ErrNotFound
+	}
+	key := endorsementKey{skillID, endorserID}
+	if _, ok := r.endorsements[key]; ok {
+		return false, nil
+	}
+	r.endorsements[key] = time.Now()
+	return true, nil
+}
+
+func (r *memoryEndorsements) Unendorse(_ context.Context, skillID, endorserID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.endorsements, endorsementKey{skillID, endorserID})
+	return nil
+}
+
+func (r *memoryEndorsements) Clear(_ context.Context, skillID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.endorsements {
+		if key.skillID == skillID {
+			delete(r.endorsements, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryEndorsements) List(_ context.Context, skillID uint, page Page) ([]models.Endorsement, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endorsements := []models.Endorsement{}
+	for key, at := range r.endorsements {
+		endorsement := models.Endorsement{SkillID: key.skillID, EndorserID: key.endorserID, CreatedAt: at}
+		if key.skillID == skillID && page.after(endorsementCursor(endorsement)) {
+			endorser := r.users[key.endorserID]
+			endorsement.Endorser = &endorser
+			endorsements = append(endorsements, endorsement)
+		}
+	}
+	endorsements, next := memoryPage(endorsements, page, endorsementCursor)
+	return endorsements, next, nil
+}
+
+func (r *memoryEndorsements) Summaries(_ context.Context, skillIDs []uint, viewerID uint) (map[uint]models.EndorsementSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[uint]bool, len(skillIDs))
+	for _, id := range skillIDs {
+		wanted[id] = true
+	}
+	summaries := make(map[uint]models.EndorsementSummary, len(skillIDs))
+	followed := map[uint][]models.Endorsement{}
+	for key, at := range r.endorsements {
+		if !wanted[key.skillID] {
+			continue
+		}
+		summary := summaries[key.skillID]
+		summary.Count++
+		summary.Endorsed = summary.Endorsed || key.endorserID == viewerID
+		if _, ok := r.follows[followKey{viewerID, key.endorserID}]; ok {
+			summary.FollowedCount++
+			endorser := r.users[key.endorserID]
+			followed[key.skillID] = append(followed[key.skillID], models.Endorsement{SkillID: key.skillID, EndorserID: key.endorserID, Endorser: &endorser, CreatedAt: at})
+		}
+		summaries[key.skillID] = summary
+	}
+
+	for skillID, endorsements := range followed {
+		latest, _ := memoryPage(endorsements, Page{Limit: models.EndorsementPreviewSize, Sort: SortNewest}, endorsementCursor)
+		summary := summaries[skillID]
+		for _, endorsement := range latest {
+			summary.Followed = append(summary.Followed, endorserRef(endorsement))
+		}
+		summaries[skillID] = summary
+	}
+	return summaries, nil
+}
+
+// deleteEndorsements removes a skill's endorsements and the notifications about
+// them, ahead of the skill itself. The caller holds the lock.
+func (m *memory) deleteEndorsements(skillID uint) {
+	for key := range m.endorsements {
+		if key.skillID == skillID {
+			delete(m.endorsements, key)
+		}
+	}
+	for notificationID, notification := range m.notifications {
+		if notification.SkillID != nil && *notification.SkillID == skillID {
+			m.deleteNotification(notificationID)
+		}
+	}
+}
+
+type memoryFeed struct{ *memory }
+
+func (r *memoryFeed) Home(_ context.Context, userID uint, page Page) ([]models.Post, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	posts := []models.Post{}
+	for _, post := range r.posts {
+		_, following := r.follows[followKey{userID, post.UserID}]
+		followedTag := false
+		for key := range r.postTags {
+			if _, ok := r.tagFollows[tagFollowKey{userID, key.tagID}]; ok && key.postID == post.ID {
+				followedTag = true
+			}
+		}
+		if (post.UserID == userID || following || followedTag) && page.after(postCursor(post)) {
+			post.User = r.users[post.UserID]
+			post.Tags, post.Mentions = r.postTagNames(post.ID), r.mentionsIn(&post.ID, nil)
+			posts = append(posts, post)
+		}
+	}
+	posts, next := memoryPage(posts, page, postCursor)
+	return posts, next, nil
+}
+
+type memoryTags struct{ *memory }
+
+func (r *memoryTags) FindByName(_ context.Context, name string) (models.Tag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tag := range r.tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return models.Tag{}, ErrNotFound
+}
+
+func (r *memoryTags) Trending(_ context.Context, since time.Time, limit int) ([]models.TrendingTag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := map[uint]*models.TrendingTag{}
+	authors := map[uint]map[uint]bool{}
+	for key, at := range r.postTags {
+		if at.Before(since) {
+			continue
+		}
+		if counts[key.tagID] == nil {
+			counts[key.tagID] = &models.TrendingTag{Name: r.tags[key.tagID].Name}
+			authors[key.tagID] = map[uint]bool{}
+		}
+		counts[key.tagID].Posts++
+		authors[key.tagID][r.posts[key.postID].UserID] = true
+	}
+
+	trending := []models.TrendingTag{}
+	for tagID, count := range counts {
+		count.Authors = int64(len(authors[tagID]))
+		trending = append(trending, *count)
+	}
+	sort.Slice(trending, func(i, j int) bool {
+		a, b := trending[i], trending[j]
+		switch {
+		case a.Authors != b.Authors:
+			return a.Authors > b.Authors
+		case a.Posts != b.Posts:
+			return a.Posts > b.Posts
+		}
+		return a.Name < b.Name
+	})
+	return trending[:min(limit, len(trending))], nil
+}
+
+func (r *memoryTags) Follow(_ context.Context, userID uint, name string) (models.Tag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag := r.tag(name)
+	key := tagFollowKey{userID, tag.ID}
+	if _, ok := r.tagFollows[key]; !ok {
+		r.tagFollows[key] = time.Now()
+	}
+	return tag, nil
+}
+
+func (r *memoryTags) Unfollow(_ context.Context, userID uint, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.tagFollows {
+		if key.userID == userID && r.tags[key.tagID].Name == name {
+			delete(r.tagFollows, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTags) Followed(_ context.Context, userID uint) ([]models.Tag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tags := []models.Tag{}
+	for key := range r.tagFollows {
+		if key.userID == userID {
+			tags = append(tags, r.tags[key.tagID])
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// tag returns the tag with the name, creating it if needed. The caller holds the lock.
+func (m *memory) tag(name string) models.Tag {
+	for _, tag := range m.tags {
+		if tag.Name == name {
+			return tag
+		}
+	}
+	tag := models.Tag{ID: m.id("tags"), Name: name, CreatedAt: time.Now()}
+	m.tags[tag.ID] = tag
+	return tag
+}
+
+// syncPostTags links the post to exactly the named tags. The caller holds the lock.
+func (m *memory) syncPostTags(postID uint, names []string) {
+	wanted := map[uint]bool{}
+	for _, name := range names {
+		wanted[m.tag(name).ID] = true
+	}
+	for key := range m.postTags {
+		if key.postID == postID && !wanted[key.tagID] {
+			delete(m.postTags, key)
+		}
+	}
+	for tagID := range wanted {
+		key := postTagKey{postID, tagID}
+		if _, ok := m.postTags[key]; !ok {
+			m.postTags[key] = time.Now()
+		}
+	}
+}
+
+// postTagNames returns the names of the post's tags, sorted. The caller holds the lock.
+func (m *memory) postTagNames(postID uint) []string {
+	names := []string{}
+	for key := range m.postTags {
+		if key.postID == postID {
+			names = append(names, m.tags[key.tagID].Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// syncMentions replaces the mentions stored for a post or a comment with the
+// @usernames in content that name existing users. The caller holds the lock.
+func (m *memory) syncMentions(postID, commentID *uint, content string) []models.Mention {
+	for id, mention := range m.mentions {
+		if sameID(mention.PostID, postID) && sameID(mention.CommentID, commentID) {
+			delete(m.mentions, id)
+		}
+	}
+
+	var users []models.User
+	for _, id := range sortedIDs(m.users) {
+		users = append(users, m.users[id])
+	}
+	mentions := []models.Mention{}
+	for _, match := range models.ExtractMentions(content) {
+		user, ok := resolveMention(users, match.Username)
+		if !ok {
+			continue
+		}
+		mention := models.Mention{
+			ID: m.id("mentions"), PostID: postID, CommentID: commentID, UserID: user.ID,
+			Start: match.Start, End: match.End, CreatedAt: time.Now(),
+		}
+		m.mentions[mention.ID] = mention
+		mention.Username = user.Username
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+// mentionsIn returns the mentions in a post or a comment with the current
+// usernames, in order of appearance. The caller holds the lock.
+func (m *memory) mentionsIn(postID, commentID *uint) []models.Mention {
+	var mentions []models.Mention
+	for _, mention := range m.mentions {
+		if sameID(mention.PostID, postID) && sameID(mention.CommentID, commentID) {
+			mention.Username = m.users[mention.UserID].Username
+			mentions = append(mentions, mention)
+		}
+	}
+	return sortedMentions(mentions)
+}
+
+func sameID(a, b *uint) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
+
+type memoryBlocks struct{ *memory }
+
+func (r *memoryBlocks) Block(_ context.Context, blockerID, blockedID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[blockedID]; !ok {
+		return ErrNotFound
+	}
+	key := blockKey{blockerID, blockedID}
+	if _, ok := r.blocks[key]; !ok {
+		r.blocks[key] = time.Now()
+	}
+	return nil
+}
+
+func (r *memoryBlocks) Unblock(_ context.Context, blockerID, blockedID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.blocks, blockKey{blockerID, blockedID})
+	return nil
+}
+
+func (r *memoryBlocks) List(_ context.Context, blockerID uint) ([]models.Block, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blocks := []models.Block{}
+	for key, at := range r.blocks {
+		if key.blockerID == blockerID {
+			blocked := r.users[key.blockedID]
+			blocks = append(blocks, models.Block{BlockerID: blockerID, BlockedID: key.blockedID, Blocked: &blocked, CreatedAt: at})
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if !blocks[i].CreatedAt.Equal(blocks[j].CreatedAt) {
+			return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+		}
+		return blocks[i].BlockedID > blocks[j].BlockedID
+	})
+	return blocks, nil
+}
+
+func (r *memoryBlocks) BlockersOf(_ context.Context, blockedID uint, userIDs []uint) (map[uint]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blockers := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if _, ok := r.blocks[blockKey{userID, blockedID}]; ok {
+			blockers[userID] = true
+		}
+	}
+	return blockers, nil
+}
+
+func (r *memoryBlocks) BlockedEitherWay(_ context.Context, userID uint, others []uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range others {
+		if _, ok := r.blocks[blockKey{userID, other}]; ok {
+			return true, nil
+		}
+		if _, ok := r.blocks[blockKey{other, userID}]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryNotifications struct{ *memory }
+
+func (r *memoryNotifications) Create(_ context.Context, notifications []models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range notifications {
+		r.createNotification(&notifications[i], time.Now())
+	}
+	return nil
+}
+
+// createNotification stores one notification, or folds it into the burst it
+// belongs to. The caller holds the lock.
+func (m *memory) createNotification(notification *models.Notification, now time.Time) {
+	if notification.Type.Aggregates() {
+		var burst *models.Notification
+		for _, existing := range m.notifications {
+			if existing.UserID == notification.UserID && existing.Type == notification.Type && existing.ReadAt == nil &&
+				!existing.UpdatedAt.Before(now.Add(-models.NotificationBurstWindow)) &&
+				sameID(existing.PostID, notification.PostID) && sameID(existing.CommentID, notification.CommentID) &&
+				sameID(existing.SkillID, notification.SkillID) &&
+				(burst == nil || existing.UpdatedAt.After(burst.UpdatedAt)) {
+				burst = &existing
+			}
+		}
+		if burst != nil {
+			key := notificationActorKey{burst.ID, notification.ActorID}
+			if !m.notificationActors[key] {
+				m.notificationActors[key] = true
+				burst.ActorID, burst.ActorCount, burst.UpdatedAt = notification.ActorID, burst.ActorCount+1, now
+				m.notifications[burst.ID] = *burst
+			}
+			*notification = *burst
+			return
+		}
+	}
+
+	notification.ID = m.id("notifications")
+	notification.ActorCount = 1
+	notification.CreatedAt, notification.UpdatedAt = now, now
+	m.notifications[notification.ID] = *notification
+	m.notificationActors[notificationActorKey{notification.ID, notification.ActorID}] = true
+}
+
+// deleteNotification removes a notification with its actors. The caller holds the lock.
+func (m *memory) deleteNotification(id uint) {
+	for key := range m.notificationActors {
+		if key.notificationID == id {
+			delete(m.notificationActors, key)
+		}
+	}
+	delete(m.notifications, id)
+}
+
+func (r *memoryNotifications) List(_ context.Context, userID uint, filter NotificationFilter) ([]models.Notification, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range r.notifications {
+		if notification.UserID != userID || (filter.UnreadOnly && notification.ReadAt != nil) {
+			continue
+		}
+		if filter.Page.after(notificationCursor(notification)) {
+			actor := r.users[notification.ActorID]
+			notification.Actor = &actor
+			notifications = append(notifications, notification)
+		}
+	}
+	notifications, next := memoryPage(notifications, filter.Page, notificationCursor)
+	return notifications, next, nil
+}
+
+func (r *memoryNotifications) UnreadCount(_ context.Context, userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, notification := range r.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotifications) MarkRead(_ context.Context, userID, id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[id]
+	if !ok || notification.UserID != userID {
+		return ErrNotFound
+	}
+	if notification.ReadAt == nil {
+		notification.ReadAt = &at
+		r.notifications[id] = notification
+	}
+	return nil
+}
+
+func (r *memoryNotifications) MarkAllRead(_ context.Context, userID uint, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, notification := range r.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			notification.ReadAt = &at
+			r.notifications[id] = notification
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotifications) Preferences(_ context.Context, userID uint) (map[models.NotificationType]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preferences := make(map[models.NotificationType]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		enabled, ok := r.notificationPreferences[notificationPreferenceKey{userID, notificationType}]
+		preferences[notificationType] = enabled || !ok
+	}
+	return preferences, nil
+}
+
+func (r *memoryNotifications) SetPreferences(_ context.Context, userID uint, preferences map[models.NotificationType]bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for notificationType, enabled := range preferences {
+		r.notificationPreferences[notificationPreferenceKey{userID, notificationType}] = enabled
+	}
+	return nil
+}
+
+func (r *memoryNotifications) Disabled(_ context.Context, notificationType models.NotificationType, userIDs []uint) (map[uint]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	disabled := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if enabled, ok := r.notificationPreferences[notificationPreferenceKey{userID, notificationType}]; ok && !enabled {
+			disabled[userID] = true
+		}
+	}
+	return disabled, nil
+}
+
+type memoryMessages struct{ *memory }
+
+func (r *memoryMessages) CreateConversation(_ context.Context, conversation *models.Conversation) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, member := range conversation.Members {
+		if _, ok := r.users[member.UserID]; !ok {
+			return false, ErrNotFound
+		}
+	}
+	if conversation.DirectKey != nil {
+		for id, existing := range r.conversations {
+			if existing.DirectKey != nil && *existing.DirectKey == *conversation.DirectKey {
+				conversation.ID = id
+				return false, nil
+			}
+		}
+	}
+
+	now := time.Now()
+	conversation.ID = r.id("conversations")
+	conversation.CreatedAt, conversation.UpdatedAt = now, now
+	for i := range conversation.Members {
+		member := &conversation.Members[i]
+		member.ConversationID, member.CreatedAt = conversation.ID, now
+		stored := *member
+		stored.User = nil
+		r.conversationMembers[conversationMemberKey{conversation.ID, member.UserID}] = stored
+	}
+	stored := *conversation
+	stored.Members = nil
+	r.conversations[conversation.ID] = stored
+	return true, nil
+}
+
+func (r *memoryMessages) FindConversation(_ context.Context, id, userID uint) (models.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[id]
+	_, member := r.conversationMembers[conversationMemberKey{id, userID}]
+	if !ok || !member {
+		return models.Conversation{}, ErrNotFound
+	}
+	return r.decorateConversation(conversation, userID), nil
+}
+
+func (r *memoryMessages) ListConversations(_ context.Context, userID uint, filter ConversationFilter) ([]models.Conversation, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := models.MemberAccepted
+	if filter.Requests {
+		state = models.MemberRequested
+	}
+	conversations := []models.Conversation{}
+	for id, conversation := range r.conversations {
+		member, ok := r.conversationMembers[conversationMemberKey{id, userID}]
+		if ok && member.State == state && filter.Page.after(conversationCursor(conversation)) {
+			conversations = append(conversations, conversation)
+		}
+	}
+	conversations, next := memoryPage(conversations, filter.Page, conversationCursor)
+	for i := range conversations {
+		conversations[i] = r.decorateConversation(conversations[i], userID)
+	}
+	return conversations, next, nil
+}
+
+// decorateConversation loads the members, the last message and userID's unread
+// count of a conversation. The caller holds the lock.
+func (m *memory) decorateConversation(conversation models.Conversation, userID uint) models.Conversation {
+	conversation.Direct = conversation.DirectKey != nil
+	conversation.Members = []models.ConversationMember{}
+	for key, member := range m.conversationMembers {
+		if key.conversationID == conversation.ID {
+			user := m.users[key.userID]
+			member.User = &user
+			conversation.Members = append(conversation.Members, member)
+		}
+	}
+	sort.Slice(conversation.Members, func(i, j int) bool {
+		a, b := conversation.Members[i], conversation.Members[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.UserID < b.UserID
+	})
+
+	conversation.UnreadCount = m.unreadMessages(conversation.ID, userID)
+	for _, message := range m.messages {
+		if message.ConversationID == conversation.ID && (conversation.LastMessage == nil || message.ID > conversation.LastMessage.ID) {
+			sender := m.users[message.SenderID]
+			message.Sender = &sender
+			conversation.LastMessage = &message
+		}
+	}
+	return conversation
+}
+
+// unreadMessages counts the messages from others after userID's read receipt. The caller holds the lock.
+func (m *memory) unreadMessages(conversationID, userID uint) int64 {
+	member := m.conversationMembers[conversationMemberKey{conversationID, userID}]
+	var count int64
+	for _, message := range m.messages {
+		if message.ConversationID == conversationID && message.SenderID != userID &&
+			(member.LastReadMessageID == nil || message.ID > *member.LastReadMessageID) {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *memoryMessages) Unread(_ context.Context, userID uint) (int64, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var conversations, requests int64
+	for key, member := range r.conversationMembers {
+		switch {
+		case key.userID != userID:
+		case member.State == models.MemberRequested:
+			requests++
+		case r.unreadMessages(key.conversationID, userID) > 0:
+			conversations++
+		}
+	}
+	return conversations, requests, nil
+}
+
+func (r *memoryMessages) Accept(_ context.Context, id, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := conversationMemberKey{id, userID}
+	member, ok := r.conversationMembers[key]
+	if !ok {
+		return ErrNotFound
+	}
+	member.State = models.MemberAccepted
+	r.conversationMembers[key] = member
+	return nil
+}
+
+func (r *memoryMessages) Leave(_ context.Context, id, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := conversationMemberKey{id, userID}
+	if _, ok := r.conversationMembers[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.conversationMembers, key)
+
+	if r.conversations[id].DirectKey == nil {
+		for key := range r.conversationMembers {
+			if key.conversationID == id {
+				return nil
+			}
+		}
+	}
+	r.deleteConversation(id)
+	return nil
+}
+
+// deleteConversation removes a conversation with its members and messages. The caller holds the lock.
+func (m *memory) deleteConversation(id uint) {
+	for key := range m.conversationMembers {
+		if key.conversationID == id {
+			delete(m.conversationMembers, key)
+		}
+	}
+	for messageID, message := range m.messages {
+		if message.ConversationID == id {
+			delete(m.messages, messageID)
+		}
+	}
+	delete(m.conversations, id)
+}
+
+func (r *memoryMessages) Send(_ context.Context, message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[message.ConversationID]
+	if !ok {
+		return ErrNotFound
+	}
+	message.ID = r.id("messages")
+	message.CreatedAt = time.Now()
+	stored := *message
+	stored.Sender = nil
+	r.messages[message.ID] = stored
+
+	conversation.UpdatedAt = message.CreatedAt
+	r.conversations[conversation.ID] = conversation
+
+	key := conversationMemberKey{message.ConversationID, message.SenderID}
+	if member, ok := r.conversationMembers[key]; ok {
+		member.State = models.MemberAccepted
+		member.LastReadMessageID, member.LastReadAt = &stored.ID, &stored.CreatedAt
+		r.conversationMembers[key] = member
+	}
+	return nil
+}
+
+func (r *memoryMessages) Messages(_ context.Context, conversationID uint, page Page) ([]models.Message, *Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID && page.after(messageCursor(message)) {
+			sender := r.users[message.SenderID]
+			message.Sender = &sender
+			messages = append(messages, message)
+		}
+	}
+	messages, next := memoryPage(messages, page, messageCursor)
+	return messages, next, nil
+}
+
+func (r *memoryMessages) MarkRead(_ context.Context, conversationID, userID, messageID uint, at time.Time) (models.ConversationMember, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := conversationMemberKey{conversationID, userID}
+	member, ok := r.conversationMembers[key]
+	if !ok {
+		return member, false, ErrNotFound
+	}
+	if messageID == 0 {
+		for id, message := range r.messages {
+			if message.ConversationID == conversationID && id > messageID {
+				messageID = id
+			}
+		}
+		if messageID == 0 {
+			return member, false, nil
+		}
+	} else if message, ok := r.messages[messageID]; !ok || message.ConversationID != conversationID {
+		return member, false, ErrNotFound
+	}
+	if member.LastReadMessageID != nil && *member.LastReadMessageID >= messageID {
+		return member, false, nil
+	}
+
+	member.LastReadMessageID, member.LastReadAt = &messageID, &at
+	r.conversationMembers[key] = member
+	return member, true, nil
+}
+
+// memorySearch approximates the Postgres full-text search with case-insensitive
+// substring matching: every word of the query must appear, and more occurrences rank higher
+type memorySearch struct{ *memory }
+
+func (r *memorySearch) Search(_ context.Context, filter SearchFilter) ([]models.SearchResult, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	words := strings.FieldsFunc(strings.ToLower(filter.Query), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	if len(words) == 0 {
+		return []models.SearchResult{}, 0, nil
+	}
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	wanted := func(searchType models.SearchType) bool {
+		if len(filter.Types) == 0 {
+			return true
+		}
+		for _, t := range filter.Types {
+			if t == searchType {
+				return true
+			}
+		}
+		return false
+	}
+
+	results := []models.SearchResult{}
+	match := func(result models.SearchResult, body string) {
+		lower := strings.ToLower(body)
+		for _, word := range words {
+			if !strings.Contains(lower, word) {
+				return
+			}
+		}
+		found := pattern.FindAllStringIndex(body, -1)
+		result.Rank = float64(len(found))
+		result.Username = r.users[result.UserID].Username
+
+		var snippet strings.Builder
+		last := 0
+		for _, at := range found {
+			snippet.WriteString(escapeHTML(body[last:at[0]]))
+			snippet.WriteString("<mark>" + escapeHTML(body[at[0]:at[1]]) + "</mark>")
+			last = at[1]
+		}
+		snippet.WriteString(escapeHTML(body[last:]))
+		result.Snippet = snippet.String()
+		results = append(results, result)
+	}
+
+	if wanted(models.SearchPost) {
+		for _, post := range r.posts {
+			match(models.SearchResult{Type: models.SearchPost, ID: post.ID, UserID: post.UserID, CreatedAt: post.CreatedAt}, post.Content)
+		}
+	}
+	if wanted(models.SearchProfile) {
+		for _, profile := range r.profiles {
+			var fields []string
+			for _, field := range []string{profile.FullName, profile.Github, profile.Bio} {
+				if field != "" {
+					fields = append(fields, field)
+				}
+			}
+			body := strings.Join(fields, " · ")
+			match(models.SearchResult{Type: models.SearchProfile, ID: profile.ID, UserID: profile.UserID, CreatedAt: profile.CreatedAt}, body)
+		}
+	}
+	if wanted(models.SearchComment) {
+		for _, comment := range r.comments {
+			if comment.Deleted() {
+				continue
+			}
+			postID := comment.PostID
+			match(models.SearchResult{Type: models.SearchComment, ID: comment.ID, PostID: &postID, UserID: comment.UserID, CreatedAt: comment.CreatedAt}, comment.Content)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.Rank != b.Rank:
+			return a.Rank > b.Rank
+		case !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.After(b.CreatedAt)
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+
+	total := int64(len(results))
+	results = results[min(filter.Offset, len(results)):]
+	if filter.Limit > 0 {
+		results = results[:min(filter.Limit, len(results))]
+	}
+	return results, total, nil
+}
+
+// escapeHTML escapes the same characters as the Postgres search headlines
+var escapeHTML = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+// syncMentions replaces the mentions stored for a post or a comment (keyColumn is
+// post_id or comment_id) with the @usernames in content that name existing users,
+// and returns them
+func syncMentions(tx *gorm.DB, keyColumn string, id uint, content string) ([]models.Mention, error) {
+	if err := tx.Where(keyColumn+" = ?", id).Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	matches := models.ExtractMentions(content)
+	mentions := []models.Mention{}
+	if len(matches) == 0 {
+		return mentions, nil
+	}
+
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, strings.ToLower(match.Username))
+	}
+	var users []models.User
+	if err := tx.Select("id", "username").Where("LOWER(username) IN ?", names).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		user, ok := resolveMention(users, match.Username)
+		if !ok {
+			continue
+		}
+		mention := models.Mention{UserID: user.ID, Username: user.Username, Start: match.Start, End: match.End}
+		if keyColumn == "post_id" {
+			mention.PostID = &id
+		} else {
+			mention.CommentID = &id
+		}
+		mentions = append(mentions, mention)
+	}
+	if len(mentions) == 0 {
+		return mentions, nil
+	}
+	return mentions, tx.Create(&mentions).Error
+}
+
+// resolveMention picks the user an @username names. Matching ignores case, but an
+// exact match wins when usernames differ only in case.
+func resolveMention(users []models.User, username string) (models.User, bool) {
+	var found *models.User
+	for i := range users {
+		if users[i].Username == username {
+			return users[i], true
+		}
+		if found == nil && strings.EqualFold(users[i].Username, username) {
+			found = &users[i]
+		}
+	}
+	if found == nil {
+		return models.User{}, false
+	}
+	return *found, true
+}
+
+// loadMentions returns the mentions in each of the given posts or comments
+// (keyColumn is post_id or comment_id) with the current usernames, in order of appearance
+func loadMentions(db *gorm.DB, keyColumn string, ids []uint) (map[uint][]models.Mention, error) {
+	byTarget := make(map[uint][]models.Mention, len(ids))
+	if len(ids) == 0 {
+		return byTarget, nil
+	}
+
+	var rows []struct {
+		models.Mention
+		MentionedUsername string
+	}
+	err := db.Model(&models.Mention{}).
+		Select("mentions.*, users.username AS mentioned_username").
+		Joins("JOIN users ON users.id = mentions.user_id").
+		Where("mentions."+keyColumn+" IN ?", ids).
+		Order("mentions.start_offset").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		mention := row.Mention
+		mention.Username = row.MentionedUsername
+		target := mention.PostID
+		if target == nil {
+			target = mention.CommentID
+		}
+		byTarget[*target] = append(byTarget[*target], mention)
+	}
+	return byTarget, nil
+}
+
+// loadPostMentions fills Mentions on each post
+func loadPostMentions(db *gorm.DB, posts []models.Post) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	mentions, err := loadMentions(db, "post_id", ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Mentions = sortedMentions(mentions[posts[i].ID])
+	}
+	return nil
+}
+
+// loadCommentMentions fills Mentions on each comment
+func loadCommentMentions(db *gorm.DB, comments []models.Comment) error {
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	mentions, err := loadMentions(db, "comment_id", ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Mentions = sortedMentions(mentions[comments[i].ID])
+	}
+	return nil
+}
+
+// sortedMentions orders mentions by their position, returning an empty slice
+// rather than nil so they encode as []
+func sortedMentions(mentions []models.Mention) []models.Mention {
+	if mentions == nil {
+		return []models.Mention{}
+	}
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].Start < mentions[j].Start })
+	return mentions
+}
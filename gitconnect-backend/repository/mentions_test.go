@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"testing"
+
+	"gitconnect-backend/models"
+)
+
+func TestResolveMention(t *testing.T) {
+	users := []models.User{{ID: 1, Username: "Alice"}, {ID: 2, Username: "alice"}, {ID: 3, Username: "Bob"}}
+
+	for username, want := range map[string]uint{
+		"alice": 2, // The exact match wins over an earlier one differing in case
+		"Alice": 1,
+		"ALICE": 1, // Otherwise the first match ignoring case
+		"bob":   3,
+		"carol": 0,
+	} {
+		user, ok := resolveMention(users, username)
+		if ok != (want != 0) || user.ID != want {
+			t.Errorf("@%s resolved to user %d (%v), want %d", username, user.ID, ok, want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConversationFilter narrows a conversation listing
+type ConversationFilter struct {
+	Requests bool // Message requests instead of the inbox
+	Page     Page // Ordered by when the latest message was sent
+}
+
+// MessageRepository stores conversations, their members' read receipts and their messages
+type MessageRepository interface {
+	// CreateConversation stores a conversation with its members and reports whether
+	// it is new. If the direct conversation between the two members already exists,
+	// its ID is set instead. ErrNotFound is returned if a member does not exist.
+	CreateConversation(ctx context.Context, conversation *models.Conversation) (bool, error)
+	// FindConversation returns a conversation of userID with its members, its last
+	// message and userID's unread count. ErrNotFound is returned if it does not
+	// exist or userID is not a member.
+	FindConversation(ctx context.Context, id, userID uint) (models.Conversation, error)
+	// ListConversations returns one page of userID's inbox or message requests, like
+	// FindConversation, and the cursor of the next page
+	ListConversations(ctx context.Context, userID uint, filter ConversationFilter) ([]models.Conversation, *Cursor, error)
+	// Unread returns how many conversations in userID's inbox have unread messages,
+	// and how many message requests userID has
+	Unread(ctx context.Context, userID uint) (conversations, requests int64, err error)
+	// Accept moves a conversation from userID's message requests to their inbox.
+	// ErrNotFound is returned if userID is not a member.
+	Accept(ctx context.Context, id, userID uint) error
+	// Leave removes userID from a conversation. A direct conversation, or a group
+	// nobody is left in, is deleted with its messages. ErrNotFound is returned if
+	// userID is not a member.
+	Leave(ctx context.Context, id, userID uint) error
+	// Send stores a message and moves its conversation to the top of the members'
+	// lists. The conversation moves to the sender's inbox, read up to the message.
+	Send(ctx context.Context, message *models.Message) error
+	// Messages returns one page of a conversation's messages, each with Sender
+	// loaded, and the cursor of the next page
+	Messages(ctx context.Context, conversationID uint, page Page) ([]models.Message, *Cursor, error)
+	// MarkRead moves userID's read receipt up to messageID, or to the latest message
+	// if messageID is 0, and reports whether it moved; it never moves back.
+	// ErrNotFound is returned if userID is not a member or the conversation has no such message.
+	MarkRead(ctx context.Context, conversationID, userID, messageID uint, at time.Time) (models.ConversationMember, bool, error)
+}
+
+type gormMessages struct {
+	db *gorm.DB
+}
+
+func (r *gormMessages) CreateConversation(ctx context.Context, conversation *models.Conversation) (bool, error) {
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&models.User{}).Where("id IN ?", conversation.MemberIDs()).Count(&users).Error; err != nil {
+			return err
+		}
+		if int(users) != len(conversation.Members) {
+			return ErrNotFound
+		}
+
+		// A direct conversation started concurrently by the other member wins
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(conversation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Select("id").Where("direct_key = ?", conversation.DirectKey).Take(conversation).Error
+		}
+
+		for i := range conversation.Members {
+			conversation.Members[i].ConversationID = conversation.ID
+		}
+		created = true
+		return tx.Create(&conversation.Members).Error
+	})
+	return created, translate(err)
+}
+
+func (r *gormMessages) FindConversation(ctx context.Context, id, userID uint) (models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.conversations(ctx, userID).Where("conversations.id = ?", id).Take(&conversation).Error
+	if err != nil {
+		return conversation, translate(err)
+	}
+
+	conversations := []models.Conversation{conversation}
+	if err := r.decorate(ctx, userID, conversations); err != nil {
+		return conversation, err
+	}
+	return conversations[0], nil
+}
+
+func (r *gormMessages) ListConversations(ctx context.Context, userID uint, filter ConversationFilter) ([]models.Conversation, *Cursor, error) {
+	state := models.MemberAccepted
+	if filter.Requests {
+		state = models.MemberRequested
+	}
+	query := r.conversations(ctx, userID).Where("conversation_members.state = ?", state)
+
+	var conversations []models.Conversation
+	if err := paginate(query, "conversations.updated_at", "conversations.id", TimeRange{}, filter.Page).Find(&conversations).Error; err != nil {
+		return nil, nil, err
+	}
+	conversations, next := trimPage(conversations, filter.Page, conversationCursor)
+	if err := r.decorate(ctx, userID, conversations); err != nil {
+		return nil, nil, err
+	}
+	return conversations, next, nil
+}
+
+func conversationCursor(conversation models.Conversation) Cursor {
+	return Cursor{CreatedAt: conversation.UpdatedAt, ID: conversation.ID}
+}
+
+// conversations selects the conversations userID is a member of, with the members loaded
+func (r *gormMessages) conversations(ctx context.Context, userID uint) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Conversation{}).
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = conversations.id AND conversation_members.user_id = ?", userID).
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, user_id") }).
+		Preload("Members.User")
+}
+
+// decorate fills in the last message and userID's unread count of each conversation
+func (r *gormMessages) decorate(ctx context.Context, userID uint, conversations []models.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+	db := r.db.WithContext(ctx)
+
+	var last []models.Message
+	latest := db.Model(&models.Message{}).Select("MAX(id)").Where("conversation_id IN ?", ids).Group("conversation_id")
+	if err := db.Where("id IN (?)", latest).Preload("Sender").Find(&last).Error; err != nil {
+		return err
+	}
+	lastByConversation := make(map[uint]models.Message, len(last))
+	for _, message := range last {
+		lastByConversation[message.ConversationID] = message
+	}
+
+	var counts []struct {
+		ConversationID uint
+		Unread         int64
+	}
+	err := db.Model(&models.Message{}).
+		Select("messages.conversation_id, COUNT(*) AS unread").
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id AND conversation_members.user_id = ?", userID).
+		Where("messages.conversation_id IN ? AND messages.sender_id <> ?", ids, userID).
+		Where("messages.id > COALESCE(conversation_members.last_read_message_id, 0)").
+		Group("messages.conversation_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	unread := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		unread[count.ConversationID] = count.Unread
+	}
+
+	for i := range conversations {
+		conversation := &conversations[i]
+		conversation.Direct = conversation.DirectKey != nil
+		conversation.UnreadCount = unread[conversation.ID]
+		if message, ok := lastByConversation[conversation.ID]; ok {
+			conversation.LastMessage = &message
+		}
+	}
+	return nil
+}
+
+func (r *gormMessages) Unread(ctx context.Context, userID uint) (int64, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var conversations int64
+	err := db.Model(&models.ConversationMember{}).
+		Where("user_id = ? AND state = ?", userID, models.MemberAccepted).
+		Where(`EXISTS (SELECT 1 FROM messages WHERE messages.conversation_id = conversation_members.conversation_id
+			AND messages.sender_id <> ? AND messages.id > COALESCE(conversation_members.last_read_message_id, 0))`, userID).
+		Count(&conversations).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var requests int64
+	err = db.Model(&models.ConversationMember{}).
+		Where("user_id = ? AND state = ?", userID, models.MemberRequested).
+		Count(&requests).Error
+	return conversations, requests, err
+}
+
+func (r *gormMessages) Accept(ctx context.Context, id, userID uint) error {
+	result := r.db.WithContext(ctx).Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", id, userID).
+		UpdateColumn("state", models.MemberAccepted)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormMessages) Leave(ctx context.Context, id, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var conversation models.Conversation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&conversation, id).Error; err != nil {
+			return translate(err)
+		}
+		result := tx.Where("conversation_id = ? AND user_id = ?", id, userID).Delete(&models.ConversationMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if conversation.DirectKey == nil {
+			var remaining int64
+			if err := tx.Model(&models.ConversationMember{}).Where("conversation_id = ?", id).Count(&remaining).Error; err != nil || remaining > 0 {
+				return err
+			}
+		}
+		return tx.Delete(&conversation).Error
+	})
+}
+
+func (r *gormMessages) Send(ctx context.Context, message *models.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(message).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Conversation{}).Where("id = ?", message.ConversationID).
+			UpdateColumn("updated_at", message.CreatedAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", message.ConversationID, message.SenderID).
+			UpdateColumns(map[string]interface{}{
+				"state":                models.MemberAccepted,
+				"last_read_message_id": message.ID,
+				"last_read_at":         message.CreatedAt,
+			}).Error
+	})
+}
+
+func (r *gormMessages) Messages(ctx context.Context, conversationID uint, page Page) ([]models.Message, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Message{}).
+		Where("messages.conversation_id = ?", conversationID).
+		Preload("Sender")
+
+	var messages []models.Message
+	if err := paginate(query, "messages.created_at", "messages.id", TimeRange{}, page).Find(&messages).Error; err != nil {
+		return nil, nil, err
+	}
+	messages, next := trimPage(messages, page, messageCursor)
+	return messages, next, nil
+}
+
+func messageCursor(message models.Message) Cursor {
+	return Cursor{CreatedAt: message.CreatedAt, ID: message.ID}
+}
+
+func (r *gormMessages) MarkRead(ctx context.Context, conversationID, userID, messageID uint, at time.Time) (models.ConversationMember, bool, error) {
+	var member models.ConversationMember
+	moved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ? AND user_id = ?", conversationID, userID).Take(&member).Error; err != nil {
+			return translate(err)
+		}
+
+		if messageID == 0 {
+			err := tx.Model(&models.Message{}).Select("COALESCE(MAX(id), 0)").Where("conversation_id = ?", conversationID).Scan(&messageID).Error
+			if err != nil || messageID == 0 {
+				return err
+			}
+		} else if err := tx.Select("id").Where("id = ? AND conversation_id = ?", messageID, conversationID).Take(&models.Message{}).Error; err != nil {
+			return translate(err)
+		}
+		if member.LastReadMessageID != nil && *member.LastReadMessageID >= messageID {
+			return nil
+		}
+
+		result := tx.Model(&models.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+			Where("last_read_message_id IS NULL OR last_read_message_id < ?", messageID).
+			UpdateColumns(map[string]interface{}{"last_read_message_id": messageID, "last_read_at": at})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		member.LastReadMessageID, member.LastReadAt = &messageID, &at
+		moved = true
+		return nil
+	})
+	return member, moved, err
+}
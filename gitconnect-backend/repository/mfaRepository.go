@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+// MFARepository stores two-factor login state: the TOTP secret and last used
+// time step on the user, and the hashes of the user's recovery codes
+type MFARepository interface {
+	// SetSecret stores a TOTP secret awaiting confirmation, replacing any earlier one
+	SetSecret(ctx context.Context, userID uint, secret string) error
+	// Enable turns two-factor login on, recording the time step of the code that
+	// confirmed it, and replaces the user's recovery codes with codeHashes
+	Enable(ctx context.Context, userID uint, counter int64, codeHashes []string) error
+	// Disable turns two-factor login off, deleting the secret and the recovery codes
+	Disable(ctx context.Context, userID uint) error
+	// ReplaceRecoveryCodes swaps the user's recovery codes for codeHashes
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+	// UseTOTP records counter as the last time step a code was accepted for, and
+	// reports false if it is not newer than the last one, so a code cannot be replayed
+	UseTOTP(ctx context.Context, userID uint, counter int64) (bool, error)
+	// UseRecoveryCode spends one of the user's unused recovery codes, and reports
+	// false if there is none with the hash
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, at time.Time) (bool, error)
+}
+
+type gormMFA struct {
+	db *gorm.DB
+}
+
+func (r *gormMFA) SetSecret(ctx context.Context, userID uint, secret string) error {
+	return (&gormUsers{db: r.db}).update(ctx, userID, map[string]interface{}{"totp_secret": secret})
+}
+
+func (r *gormMFA) Enable(ctx context.Context, userID uint, counter int64, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := (&gormUsers{db: tx}).update(ctx, userID, map[string]interface{}{
+			"totp_enabled_at":   time.Now(),
+			"totp_last_counter": counter,
+		}); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *gormMFA) Disable(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := (&gormUsers{db: tx}).update(ctx, userID, map[string]interface{}{
+			"totp_secret":       "",
+			"totp_enabled_at":   nil,
+			"totp_last_counter": 0,
+		}); err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+func (r *gormMFA) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores codeHashes instead
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+func (r *gormMFA) UseTOTP(ctx context.Context, userID uint, counter int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormMFA) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
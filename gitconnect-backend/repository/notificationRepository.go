@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationFilter narrows a notification listing
+type NotificationFilter struct {
+	UnreadOnly bool
+	Page       Page // Ordered by when the latest actor joined
+}
+
+// NotificationRepository stores the notifications users receive, their read
+// state and which types each user wants
+type NotificationRepository interface {
+	// Create stores the notifications. A notification of a type that aggregates
+	// joins the recipient's unread one of the same type about the same post,
+	// comment or skill, if that was updated within models.NotificationBurstWindow: its
+	// actor becomes the latest actor and is counted once.
+	Create(ctx context.Context, notifications []models.Notification) error
+	// List returns one page of the user's notifications with their latest actors,
+	// and the cursor of the next page
+	List(ctx context.Context, userID uint, filter NotificationFilter) ([]models.Notification, *Cursor, error)
+	// UnreadCount returns how many of the user's notifications are unread
+	UnreadCount(ctx context.Context, userID uint) (int64, error)
+	// MarkRead marks one of the user's notifications as read at the given time,
+	// unless it already is. ErrNotFound is returned if the user has no such notification.
+	MarkRead(ctx context.Context, userID, id uint, at time.Time) error
+	// MarkAllRead marks every unread notification of the user as read and returns how many there were
+	MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error)
+	// Preferences returns whether each notification type is on for the user
+	Preferences(ctx context.Context, userID uint) (map[models.NotificationType]bool, error)
+	// SetPreferences turns the given notification types on or off for the user
+	SetPreferences(ctx context.Context, userID uint, preferences map[models.NotificationType]bool) error
+	// Disabled reports which of the given users turned the notification type off
+	Disabled(ctx context.Context, notificationType models.NotificationType, userIDs []uint) (map[uint]bool, error)
+}
+
+type gormNotifications struct {
+	db *gorm.DB
+}
+
+func (r *gormNotifications) Create(ctx context.Context, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range notifications {
+			if err := createNotification(tx, &notifications[i], time.Now()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createNotification stores one notification, or folds it into the burst it belongs to
+func createNotification(tx *gorm.DB, notification *models.Notification, now time.Time) error {
+	if notification.Type.Aggregates() {
+		var burst models.Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND type = ? AND read_at IS NULL AND updated_at >= ?",
+				notification.UserID, notification.Type, now.Add(-models.NotificationBurstWindow)).
+			Where(map[string]interface{}{"post_id": notification.PostID, "comment_id": notification.CommentID, "skill_id": notification.SkillID}).
+			Order("updated_at DESC").
+			Take(&burst).Error
+		if err == nil {
+			return joinBurst(tx, burst, notification, now)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	notification.ActorCount = 1
+	if err := tx.Create(notification).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.NotificationActor{NotificationID: notification.ID, ActorID: notification.ActorID}).Error
+}
+
+// joinBurst counts the notification's actor in burst, unless they already are
+func joinBurst(tx *gorm.DB, burst models.Notification, notification *models.Notification, now time.Time) error {
+	actor := models.NotificationActor{NotificationID: burst.ID, ActorID: notification.ActorID}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&actor)
+	if result.Error != nil || result.RowsAffected == 0 {
+		*notification = burst
+		return result.Error
+	}
+
+	err := tx.Model(&burst).Updates(map[string]interface{}{
+		"actor_id":    notification.ActorID,
+		"actor_count": gorm.Expr("actor_count + 1"),
+		"updated_at":  now,
+	}).Error
+	burst.ActorID, burst.ActorCount, burst.UpdatedAt = notification.ActorID, burst.ActorCount+1, now
+	*notification = burst
+	return err
+}
+
+func (r *gormNotifications) List(ctx context.Context, userID uint, filter NotificationFilter) ([]models.Notification, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("notifications.user_id = ?", userID).
+		Preload("Actor")
+	if filter.UnreadOnly {
+		query = query.Where("notifications.read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := paginate(query, "notifications.updated_at", "notifications.id", TimeRange{}, filter.Page).Find(&notifications).Error; err != nil {
+		return nil, nil, err
+	}
+	notifications, next := trimPage(notifications, filter.Page, notificationCursor)
+	return notifications, next, nil
+}
+
+func notificationCursor(notification models.Notification) Cursor {
+	return Cursor{CreatedAt: notification.UpdatedAt, ID: notification.ID}
+}
+
+func (r *gormNotifications) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormNotifications) MarkRead(ctx context.Context, userID, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("read_at", gorm.Expr("COALESCE(read_at, ?)", at))
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormNotifications) MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		UpdateColumn("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormNotifications) Preferences(ctx context.Context, userID uint) (map[models.NotificationType]bool, error) {
+	var stored []models.NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[models.NotificationType]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+func (r *gormNotifications) SetPreferences(ctx context.Context, userID uint, preferences map[models.NotificationType]bool) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	rows := make([]models.NotificationPreference, 0, len(preferences))
+	for notificationType, enabled := range preferences {
+		rows = append(rows, models.NotificationPreference{UserID: userID, Type: notificationType, Enabled: enabled})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+func (r *gormNotifications) Disabled(ctx context.Context, notificationType models.NotificationType, userIDs []uint) (map[uint]bool, error) {
+	disabled := make(map[uint]bool)
+	if len(userIDs) == 0 {
+		return disabled, nil
+	}
+
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.NotificationPreference{}).
+		Where("type = ? AND NOT enabled AND user_id IN ?", notificationType, userIDs).
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		disabled[id] = true
+	}
+	return disabled, nil
+}
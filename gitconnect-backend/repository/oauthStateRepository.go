@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OAuthStateRepository stores pending OAuth2 authorization requests
+type OAuthStateRepository interface {
+	// Create stores a request, clearing out abandoned ones that have expired
+	Create(ctx context.Context, state *models.OAuthState) error
+	// Claim deletes and returns the request with the state hash, so each can be
+	// completed once. ErrNotFound is returned if there is none or it has expired.
+	Claim(ctx context.Context, stateHash string, now time.Time) (models.OAuthState, error)
+}
+
+type gormOAuthStates struct {
+	db *gorm.DB
+}
+
+func (r *gormOAuthStates) Create(ctx context.Context, state *models.OAuthState) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Create(state).Error; err != nil {
+		return translate(err)
+	}
+	return db.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{}).Error
+}
+
+func (r *gormOAuthStates) Claim(ctx context.Context, stateHash string, now time.Time) (models.OAuthState, error) {
+	var state models.OAuthState
+	result := r.db.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).
+		Delete(&state)
+	if result.Error != nil {
+		return state, result.Error
+	}
+	if result.RowsAffected == 0 || now.After(state.ExpiresAt) {
+		return state, ErrNotFound
+	}
+	return state, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OneTimeTokenRepository stores the single-use tokens sent to users by email.
+// Spending a token and acting on it happen together, so a token is never used
+// up without its effect.
+type OneTimeTokenRepository interface {
+	// Create stores a token, invalidating the user's earlier unused tokens for the
+	// same purpose so only the latest link works
+	Create(ctx context.Context, token *models.OneTimeToken) error
+	// VerifyEmail spends an email verification token and marks its user's address verified.
+	// ErrNotFound is returned unless the hash is of an unused, unexpired verification token.
+	VerifyEmail(ctx context.Context, tokenHash string, at time.Time) error
+	// ResetPassword spends a password reset token and gives its user the new
+	// password hash. The address counts as verified, any lockout ends and every
+	// refresh token and personal access token is revoked. ErrNotFound is returned
+	// as by VerifyEmail.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) error
+}
+
+type gormOneTimeTokens struct {
+	db *gorm.DB
+}
+
+func (r *gormOneTimeTokens) Create(ctx context.Context, token *models.OneTimeToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return translate(tx.Create(token).Error)
+	})
+}
+
+func (r *gormOneTimeTokens) VerifyEmail(ctx context.Context, tokenHash string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := spendOneTimeToken(tx, tokenHash, models.PurposeVerifyEmail, at)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", at).Error
+	})
+}
+
+func (r *gormOneTimeTokens) ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := spendOneTimeToken(tx, tokenHash, models.PurposeResetPassword, at)
+		if err != nil {
+			return err
+		}
+
+		// Following the emailed link also proves ownership of the address, and a new
+		// password ends any lockout from guesses at the old one
+		if err := tx.Model(&models.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password":          passwordHash,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", at),
+			"failed_logins":     0,
+			"locked_until":      nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Sign out every device and script: outstanding refresh tokens can no longer
+		// be exchanged, and personal access tokens stop working
+		return revokeCredentials(tx, token.UserID)
+	})
+}
+
+// spendOneTimeToken marks a token as used, returning ErrNotFound if it is
+// unknown, meant for another purpose, already used or expired
+func spendOneTimeToken(tx *gorm.DB, tokenHash string, purpose models.TokenPurpose, at time.Time) (models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", tokenHash, purpose).
+		Take(&token).Error
+	if err != nil {
+		return token, translate(err)
+	}
+	if token.UsedAt != nil || at.After(token.ExpiresAt) {
+		return token, ErrNotFound
+	}
+	return token, tx.Model(&token).Update("used_at", at).Error
+}
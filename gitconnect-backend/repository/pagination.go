@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor for a cursor this API did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sort orders a keyset page by (created_at, id)
+type Sort string
+
+const (
+	SortNewest Sort = "newest"
+	SortOldest Sort = "oldest"
+)
+
+// Valid reports whether s is a known sort order
+func (s Sort) Valid() bool {
+	return s == SortNewest || s == SortOldest
+}
+
+// Cursor is the keyset position of the last row of a page
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor produced by Encode
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(raw, &cursor) != nil || cursor.ID == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Page selects one page of a list ordered by (created_at, id)
+type Page struct {
+	Limit int
+	Sort  Sort
+	// After continues from the last row of the previous page; nil starts at the top
+	After *Cursor
+}
+
+// TimeRange restricts a list to rows created within [Since, Until)
+type TimeRange struct {
+	Since *time.Time
+	Until *time.Time
+}
+
+// Contains reports whether t falls inside the range
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.Since == nil || !t.Before(*r.Since)) && (r.Until == nil || t.Before(*r.Until))
+}
+
+// PostFilter narrows a post listing
+type PostFilter struct {
+	AuthorID    uint
+	Created     TimeRange
+	HasComments *bool
+	Tag         string // Only posts with this normalized hashtag, if set
+	Page        Page
+}
+
+// ProfileFilter narrows a profile listing
+type ProfileFilter struct {
+	UserID  uint
+	Created TimeRange
+	Page    Page
+}
+
+// CommentFilter narrows the comments on a post
+type CommentFilter struct {
+	AuthorID uint
+	Created  TimeRange
+	Page     Page
+}
+
+// paginate applies the time range, keyset position, order and limit to a query
+// keyed on the timeColumn and idColumn pair. A zero Limit returns every row;
+// otherwise one extra row is fetched so the caller can tell whether a next page exists.
+func paginate(query *gorm.DB, timeColumn, idColumn string, created TimeRange, page Page) *gorm.DB {
+	if created.Since != nil {
+		query = query.Where(timeColumn+" >= ?", *created.Since)
+	}
+	if created.Until != nil {
+		query = query.Where(timeColumn+" < ?", *created.Until)
+	}
+
+	direction, compare := "DESC", "<"
+	if page.Sort == SortOldest {
+		direction, compare = "ASC", ">"
+	}
+	if page.After != nil {
+		query = query.Where("("+timeColumn+", "+idColumn+") "+compare+" (?, ?)", page.After.CreatedAt, page.After.ID)
+	}
+	query = query.Order(timeColumn + " " + direction).Order(idColumn + " " + direction)
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit + 1)
+	}
+	return query
+}
+
+// trimPage cuts the extra row fetched by paginate and returns the cursor of the
+// next page, or nil on the last page. key extracts a row's keyset position.
+func trimPage[T any](rows []T, page Page, key func(T) Cursor) ([]T, *Cursor) {
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, nil
+	}
+	rows = rows[:page.Limit]
+	next := key(rows[len(rows)-1])
+	return rows, &next
+}
+
+// after reports whether the row at position c comes after the page's cursor in
+// its sort order. The in-memory repositories use it to mirror paginate.
+func (p Page) after(c Cursor) bool {
+	return p.After == nil || p.less(*p.After, c)
+}
+
+// less orders two keyset positions by the page's sort order
+func (p Page) less(a, b Cursor) bool {
+	if p.Sort != SortOldest {
+		a, b = b, a
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
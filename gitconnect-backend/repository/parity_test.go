@@ -0,0 +1,439 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitconnect-backend/migrations"
+	"gitconnect-backend/models"
+	"gitconnect-backend/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scenario drives a backend through a sequence of calls and returns a transcript
+// of everything observable, which must be the same for every backend
+type scenario func(t *testing.T, ctx context.Context, repos repository.Repositories) []string
+
+// TestMemoryMatchesGorm runs each scenario against the in-memory repositories and,
+// when TEST_DATABASE_URL points at a scratch Postgres database, against GORM on a
+// freshly migrated schema. The database is wiped by every run.
+func TestMemoryMatchesGorm(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	scenarios := map[string]scenario{
+		"users":     usersScenario,
+		"posts":     postsScenario,
+		"comments":  commentsScenario,
+		"sessions":  sessionsScenario,
+		"tokens":    tokensScenario,
+		"follows":   followsScenario,
+		"blocks":    blocksScenario,
+		"tags":      tagsScenario,
+		"usernames": usernamesScenario,
+		"github":    githubReposScenario,
+	}
+	for name, run := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			want := run(t, ctx, repository.NewMemory())
+			got := run(t, ctx, freshGorm(t, databaseURL))
+			if !reflect.DeepEqual(got, want) {
+				for i := range max(len(got), len(want)) {
+					var g, w string
+					if i < len(got) {
+						g = got[i]
+					}
+					if i < len(want) {
+						w = want[i]
+					}
+					if g != w {
+						t.Errorf("step %d: gorm %q, memory %q", i, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+// freshGorm migrates the database down to nothing and back up, returning GORM repositories on it
+func freshGorm(t *testing.T, databaseURL string) repository.Repositories {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("migrating down: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating up: %v", err)
+	}
+	return repository.NewGorm(db)
+}
+
+// outcome names a repository error for the transcript
+func outcome(err error) string {
+	for _, known := range []error{repository.ErrNotFound, repository.ErrConflict, repository.ErrLimit, repository.ErrExpired, repository.ErrReused} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return "ok"
+}
+
+func mustCreateUser(t *testing.T, ctx context.Context, repos repository.Repositories, username string) models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "hash"}
+	if err := repos.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("creating %s: %v", username, err)
+	}
+	return user
+}
+
+func usersScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "Alice")
+
+	duplicate := models.User{Username: "alice", Email: "other@example.com"}
+	log = append(log, "duplicate username: "+outcome(repos.Users.Create(ctx, &duplicate)))
+	duplicate = models.User{Username: "someone", Email: "Alice@example.com"}
+	log = append(log, "duplicate email: "+outcome(repos.Users.Create(ctx, &duplicate)))
+
+	found, err := repos.Users.FindByEmail(ctx, "Alice@example.com")
+	log = append(log, fmt.Sprintf("by email: %s %s %s", found.Username, found.Role, outcome(err)))
+	_, err = repos.Users.FindByID(ctx, alice.ID+100)
+	log = append(log, "missing id: "+outcome(err))
+
+	for i := 0; i < 3; i++ {
+		failures, err := repos.Users.RecordFailedLogin(ctx, alice.ID)
+		log = append(log, fmt.Sprintf("failure %d %s", failures, outcome(err)))
+	}
+	_, err = repos.Users.RecordFailedLogin(ctx, alice.ID+100)
+	log = append(log, "failure on missing user: "+outcome(err))
+	log = append(log, "lock: "+outcome(repos.Users.LockLogin(ctx, alice.ID, time.Now().Add(time.Hour))))
+	found, _ = repos.Users.FindByID(ctx, alice.ID)
+	log = append(log, fmt.Sprintf("locked: %d %v", found.FailedLogins, found.LockedUntil != nil))
+	log = append(log, "reset: "+outcome(repos.Users.ResetFailedLogins(ctx, alice.ID)))
+	found, _ = repos.Users.FindByID(ctx, alice.ID)
+	log = append(log, fmt.Sprintf("reset: %d %v", found.FailedLogins, found.LockedUntil != nil))
+
+	return log
+}
+
+func usernamesScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "alice")
+	mustCreateUser(t, ctx, repos, "bob")
+
+	log = append(log, "rename: "+outcome(repos.Users.Rename(ctx, alice.ID, "alicia")))
+	log = append(log, "rename onto bob: "+outcome(repos.Users.Rename(ctx, alice.ID, "BOB")))
+	log = append(log, "case change: "+outcome(repos.Users.Rename(ctx, alice.ID, "Alicia")))
+
+	for _, name := range []string{"ALICE", "alicia", "Bob", "carol"} {
+		user, renamed, err := repos.Users.FindByUsername(ctx, name)
+		log = append(log, fmt.Sprintf("%s: %s %v %s", name, user.Username, renamed, outcome(err)))
+	}
+
+	bob, _, _ := repos.Users.FindByUsername(ctx, "bob")
+	log = append(log, "take redirect: "+outcome(repos.Users.Rename(ctx, bob.ID, "alice")))
+	return log
+}
+
+func postsScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	author := mustCreateUser(t, ctx, repos, "author")
+	reader := mustCreateUser(t, ctx, repos, "reader")
+
+	post := models.Post{UserID: author.ID, Content: "Hello #Go and #gin, @reader"}
+	log = append(log, "create: "+outcome(repos.Posts.Create(ctx, &post)))
+	log = append(log, fmt.Sprintf("tags %v mentions %d", post.Tags, len(post.Mentions)))
+
+	for _, kind := range []models.ReactionKind{models.ReactionLike, models.ReactionLike, models.ReactionDislike, ""} {
+		reacted, err := repos.Posts.SetReaction(ctx, post.ID, reader.ID, kind)
+		log = append(log, fmt.Sprintf("react %q: %d/%d %q %d %s", kind, reacted.Likes, reacted.Dislikes, reacted.MyReaction, reacted.UserID, outcome(err)))
+	}
+	_, err := repos.Posts.SetReaction(ctx, post.ID+100, reader.ID, models.ReactionLike)
+	log = append(log, "react on missing post: "+outcome(err))
+
+	log = append(log, "update: "+outcome(repos.Posts.UpdateContent(ctx, post.ID, "Now about #rust")))
+	found, err := repos.Posts.FindByID(ctx, post.ID)
+	log = append(log, fmt.Sprintf("found: %q %v %s %s", found.Content, found.Tags, found.User.Username, outcome(err)))
+
+	tagged, _, err := repos.Posts.List(ctx, repository.PostFilter{Tag: "go"})
+	log = append(log, fmt.Sprintf("tagged go: %d %s", len(tagged), outcome(err)))
+	tagged, _, err = repos.Posts.List(ctx, repository.PostFilter{Tag: "rust"})
+	log = append(log, fmt.Sprintf("tagged rust: %d %s", len(tagged), outcome(err)))
+
+	log = append(log, "delete: "+outcome(repos.Posts.Delete(ctx, post.ID)))
+	_, err = repos.Posts.FindByID(ctx, post.ID)
+	log = append(log, "after delete: "+outcome(err))
+	return log
+}
+
+func commentsScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	author := mustCreateUser(t, ctx, repos, "author")
+	replier := mustCreateUser(t, ctx, repos, "replier")
+	post := models.Post{UserID: author.ID, Content: "hello"}
+	if err := repos.Posts.Create(ctx, &post); err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+
+	root := models.Comment{PostID: post.ID, UserID: author.ID, Content: "first"}
+	log = append(log, "root: "+outcome(repos.Comments.Create(ctx, &root)))
+	reply := models.Comment{PostID: post.ID, UserID: replier.ID, Content: "reply", ParentID: &root.ID, RootID: &root.ID, Depth: 1}
+	log = append(log, "reply: "+outcome(repos.Comments.Create(ctx, &reply)))
+
+	reacted, err := repos.Comments.SetReaction(ctx, root.ID, replier.ID, models.ReactionDislike)
+	log = append(log, fmt.Sprintf("react: %d/%d %q %d %s", reacted.Likes, reacted.Dislikes, reacted.MyReaction, reacted.PostID, outcome(err)))
+
+	log = append(log, "edit: "+outcome(repos.Comments.UpdateContent(ctx, root.ID, "edited", time.Now())))
+	log = append(log, "delete root: "+outcome(repos.Comments.Delete(ctx, root.ID)))
+	owner, err := repos.Comments.OwnerID(ctx, root.ID)
+	log = append(log, fmt.Sprintf("tombstone owner: %d %s", owner, outcome(err)))
+
+	roots, _, err := repos.Comments.ListForPost(ctx, post.ID, repository.CommentFilter{})
+	log = append(log, fmt.Sprintf("roots: %d %s", len(roots), outcome(err)))
+	for _, comment := range roots {
+		log = append(log, fmt.Sprintf("root: %q deleted %v", comment.Content, comment.Deleted()))
+	}
+	replies, err := repos.Comments.Replies(ctx, []uint{root.ID})
+	log = append(log, fmt.Sprintf("replies: %d %s", len(replies), outcome(err)))
+
+	// Removing the last reply removes the tombstone with it
+	log = append(log, "delete reply: "+outcome(repos.Comments.Delete(ctx, reply.ID)))
+	_, err = repos.Comments.FindByID(ctx, root.ID)
+	log = append(log, "tombstone after last reply: "+outcome(err))
+	return log
+}
+
+func sessionsScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	user := mustCreateUser(t, ctx, repos, "user")
+
+	first := models.RefreshToken{UserID: user.ID, FamilyID: "family", TokenHash: "first", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "create: "+outcome(repos.Sessions.Create(ctx, &first)))
+	second := models.RefreshToken{TokenHash: "second", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "rotate: "+outcome(repos.Sessions.Rotate(ctx, "first", &second)))
+	log = append(log, fmt.Sprintf("successor: %v %v", second.UserID == user.ID, second.FamilyID))
+
+	third := models.RefreshToken{TokenHash: "third", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "replay: "+outcome(repos.Sessions.Rotate(ctx, "first", &third)))
+	stored, err := repos.Sessions.Find(ctx, "second")
+	log = append(log, fmt.Sprintf("successor revoked: %v %s", stored.RevokedAt != nil, outcome(err)))
+	log = append(log, "missing: "+outcome(repos.Sessions.Rotate(ctx, "unknown", &third)))
+
+	expired := models.RefreshToken{UserID: user.ID, FamilyID: "other", TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	log = append(log, "create expired: "+outcome(repos.Sessions.Create(ctx, &expired)))
+	log = append(log, "rotate expired: "+outcome(repos.Sessions.Rotate(ctx, "expired", &third)))
+
+	for _, jti := range []string{"jti", "jti"} {
+		added, err := repos.Sessions.RevokeAccessToken(ctx, jti, time.Now().Add(-time.Second))
+		log = append(log, fmt.Sprintf("revoke %s: %v %s", jti, added, outcome(err)))
+	}
+	revoked, err := repos.Sessions.AccessTokenRevoked(ctx, "jti")
+	log = append(log, fmt.Sprintf("revoked: %v %s", revoked, outcome(err)))
+	log = append(log, "purge: "+outcome(repos.Sessions.PurgeRevoked(ctx, time.Now())))
+	revoked, err = repos.Sessions.AccessTokenRevoked(ctx, "jti")
+	log = append(log, fmt.Sprintf("after purge: %v %s", revoked, outcome(err)))
+	return log
+}
+
+func tokensScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	user := mustCreateUser(t, ctx, repos, "user")
+	other := mustCreateUser(t, ctx, repos, "other")
+
+	for _, name := range []string{"older", "newer"} {
+		token := models.PersonalAccessToken{UserID: user.ID, Name: name, Prefix: name, TokenHash: name, Scopes: "posts:read", ExpiresAt: time.Now().Add(time.Hour)}
+		log = append(log, "create "+name+": "+outcome(repos.AccessTokens.Create(ctx, &token)))
+	}
+	tokens, err := repos.AccessTokens.List(ctx, user.ID)
+	log = append(log, fmt.Sprintf("list: %d %s", len(tokens), outcome(err)))
+	for _, token := range tokens {
+		log = append(log, "listed "+token.Name)
+	}
+
+	newer, _ := repos.AccessTokens.FindByHash(ctx, "newer")
+	log = append(log, "revoke other's: "+outcome(repos.AccessTokens.Revoke(ctx, other.ID, newer.ID)))
+	log = append(log, "revoke: "+outcome(repos.AccessTokens.Revoke(ctx, user.ID, newer.ID)))
+	log = append(log, "revoke again: "+outcome(repos.AccessTokens.Revoke(ctx, user.ID, newer.ID)))
+	log = append(log, "touch: "+outcome(repos.AccessTokens.Touch(ctx, newer.ID, time.Now())))
+	newer, err = repos.AccessTokens.FindByHash(ctx, "newer")
+	log = append(log, fmt.Sprintf("after: revoked %v used %v %s", newer.RevokedAt != nil, newer.LastUsedAt != nil, outcome(err)))
+
+	verify := models.OneTimeToken{UserID: user.ID, Purpose: models.PurposeVerifyEmail, TokenHash: "verify-1", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "one-time: "+outcome(repos.OneTimeTokens.Create(ctx, &verify)))
+	verify = models.OneTimeToken{UserID: user.ID, Purpose: models.PurposeVerifyEmail, TokenHash: "verify-2", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "one-time again: "+outcome(repos.OneTimeTokens.Create(ctx, &verify)))
+	log = append(log, "superseded: "+outcome(repos.OneTimeTokens.VerifyEmail(ctx, "verify-1", time.Now())))
+	log = append(log, "wrong purpose: "+outcome(repos.OneTimeTokens.ResetPassword(ctx, "verify-2", "new", time.Now())))
+	log = append(log, "verify: "+outcome(repos.OneTimeTokens.VerifyEmail(ctx, "verify-2", time.Now())))
+	log = append(log, "spent: "+outcome(repos.OneTimeTokens.VerifyEmail(ctx, "verify-2", time.Now())))
+	found, _ := repos.Users.FindByID(ctx, user.ID)
+	log = append(log, fmt.Sprintf("verified: %v", found.EmailVerifiedAt != nil))
+
+	// A password reset revokes the user's remaining access tokens
+	reset := models.OneTimeToken{UserID: user.ID, Purpose: models.PurposeResetPassword, TokenHash: "reset", ExpiresAt: time.Now().Add(time.Hour)}
+	log = append(log, "reset token: "+outcome(repos.OneTimeTokens.Create(ctx, &reset)))
+	log = append(log, "reset: "+outcome(repos.OneTimeTokens.ResetPassword(ctx, "reset", "new", time.Now())))
+	older, err := repos.AccessTokens.FindByHash(ctx, "older")
+	log = append(log, fmt.Sprintf("after reset: revoked %v %s", older.RevokedAt != nil, outcome(err)))
+	return log
+}
+
+func followsScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "alice")
+	bob := mustCreateUser(t, ctx, repos, "bob")
+
+	for i := 0; i < 2; i++ {
+		created, err := repos.Follows.Follow(ctx, alice.ID, bob.ID)
+		log = append(log, fmt.Sprintf("follow: %v %s", created, outcome(err)))
+	}
+	_, err := repos.Follows.Follow(ctx, alice.ID, bob.ID+100)
+	log = append(log, "follow missing: "+outcome(err))
+
+	counts, err := repos.Follows.Counts(ctx, []uint{alice.ID, bob.ID})
+	log = append(log, fmt.Sprintf("counts: %+v %+v %s", counts[alice.ID], counts[bob.ID], outcome(err)))
+	among, err := repos.Follows.FollowersAmong(ctx, bob.ID, []uint{alice.ID, bob.ID})
+	log = append(log, fmt.Sprintf("among: %v %v %s", among[alice.ID], among[bob.ID], outcome(err)))
+
+	log = append(log, "unfollow: "+outcome(repos.Follows.Unfollow(ctx, alice.ID, bob.ID)))
+	counts, _ = repos.Follows.Counts(ctx, []uint{bob.ID})
+	log = append(log, fmt.Sprintf("after: %+v", counts[bob.ID]))
+	return log
+}
+
+func blocksScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "alice")
+	bob := mustCreateUser(t, ctx, repos, "bob")
+	carol := mustCreateUser(t, ctx, repos, "carol")
+
+	log = append(log, "block: "+outcome(repos.Blocks.Block(ctx, bob.ID, alice.ID)))
+	log = append(log, "block again: "+outcome(repos.Blocks.Block(ctx, bob.ID, alice.ID)))
+	log = append(log, "block missing: "+outcome(repos.Blocks.Block(ctx, bob.ID, carol.ID+100)))
+
+	blockers, err := repos.Blocks.BlockersOf(ctx, alice.ID, []uint{bob.ID, carol.ID})
+	log = append(log, fmt.Sprintf("blockers: %v %s", blockers, outcome(err)))
+	for _, check := range []struct {
+		userID uint
+		others []uint
+	}{{alice.ID, []uint{bob.ID}}, {bob.ID, []uint{alice.ID}}, {alice.ID, []uint{carol.ID, bob.ID}}, {carol.ID, []uint{alice.ID, bob.ID}}, {alice.ID, nil}} {
+		blocked, err := repos.Blocks.BlockedEitherWay(ctx, check.userID, check.others)
+		log = append(log, fmt.Sprintf("either way: %v %s", blocked, outcome(err)))
+	}
+
+	log = append(log, "unblock: "+outcome(repos.Blocks.Unblock(ctx, bob.ID, alice.ID)))
+	blocked, err := repos.Blocks.BlockedEitherWay(ctx, alice.ID, []uint{bob.ID})
+	log = append(log, fmt.Sprintf("after: %v %s", blocked, outcome(err)))
+	return log
+}
+
+func tagsScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "alice")
+	bob := mustCreateUser(t, ctx, repos, "bob")
+
+	old := models.Post{UserID: alice.ID, Content: "#old #shared"}
+	log = append(log, "create old: "+outcome(repos.Posts.Create(ctx, &old)))
+	time.Sleep(10 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	for _, post := range []models.Post{{UserID: alice.ID, Content: "#shared #solo"}, {UserID: alice.ID, Content: "#solo again"}, {UserID: bob.ID, Content: "#shared"}} {
+		log = append(log, "create: "+outcome(repos.Posts.Create(ctx, &post)))
+	}
+
+	// Only tags added within the window count, and an edit only adds the new ones
+	trending, err := repos.Tags.Trending(ctx, since, 10)
+	log = append(log, fmt.Sprintf("trending: %+v %s", trending, outcome(err)))
+	log = append(log, "retag old: "+outcome(repos.Posts.UpdateContent(ctx, old.ID, "#old #shared #fresh")))
+	trending, err = repos.Tags.Trending(ctx, since, 10)
+	log = append(log, fmt.Sprintf("retagged: %+v %s", trending, outcome(err)))
+	trending, err = repos.Tags.Trending(ctx, time.Now().Add(time.Minute), 10)
+	log = append(log, fmt.Sprintf("future: %+v %s", trending, outcome(err)))
+
+	for i := 0; i < 2; i++ {
+		tag, err := repos.Tags.Follow(ctx, bob.ID, "unused")
+		log = append(log, fmt.Sprintf("follow: %s %s", tag.Name, outcome(err)))
+	}
+	_, err = repos.Tags.FindByName(ctx, "unused")
+	log = append(log, "find unused: "+outcome(err))
+	_, err = repos.Tags.FindByName(ctx, "missing")
+	log = append(log, "find missing: "+outcome(err))
+	_, err = repos.Tags.Follow(ctx, bob.ID, "shared")
+	log = append(log, "follow shared: "+outcome(err))
+	followed, err := repos.Tags.Followed(ctx, bob.ID)
+	log = append(log, fmt.Sprintf("followed: %d %s", len(followed), outcome(err)))
+	log = append(log, "unfollow: "+outcome(repos.Tags.Unfollow(ctx, bob.ID, "unused")))
+	log = append(log, "unfollow again: "+outcome(repos.Tags.Unfollow(ctx, bob.ID, "unused")))
+	followed, _ = repos.Tags.Followed(ctx, bob.ID)
+	for _, tag := range followed {
+		log = append(log, "still followed: "+tag.Name)
+	}
+	return log
+}
+
+func githubReposScenario(t *testing.T, ctx context.Context, repos repository.Repositories) []string {
+	var log []string
+	alice := mustCreateUser(t, ctx, repos, "alice")
+	if err := repos.Profiles.LinkGithub(ctx, alice.ID, 42, "alice", time.Now()); err != nil {
+		t.Fatalf("linking GitHub: %v", err)
+	}
+	profile, err := repos.Profiles.FindByGithubID(ctx, 42)
+	if err != nil {
+		t.Fatalf("finding profile: %v", err)
+	}
+	state := func(step string) {
+		state, err := repos.GithubRepos.SyncState(ctx, profile.ID)
+		log = append(log, fmt.Sprintf("%s: %s synced %v checked %v failures %d %q %s", step, state.Login,
+			state.SyncedAt != nil, state.CheckedAt != nil, state.Failures, state.LastError, outcome(err)))
+	}
+
+	state("never fetched")
+	for i := 0; i < 2; i++ {
+		log = append(log, "fail: "+outcome(repos.GithubRepos.RecordFailure(ctx, profile.ID, "alice", time.Now(), "not_found")))
+	}
+	state("failed")
+
+	now := time.Now()
+	synced := models.GithubRepoSync{ProfileID: profile.ID, Login: "alice", ETag: `"v1"`, SyncedAt: &now, CheckedAt: &now}
+	log = append(log, "replace: "+outcome(repos.GithubRepos.Replace(ctx, synced, []models.GithubRepo{{GithubID: 1, Name: "hello", FullName: "alice/hello"}})))
+	state("fetched")
+
+	// A failure for another login keeps the cache's login
+	log = append(log, "fail: "+outcome(repos.GithubRepos.RecordFailure(ctx, profile.ID, "bob", time.Now(), "unavailable")))
+	state("failed again")
+	log = append(log, "touch: "+outcome(repos.GithubRepos.Touch(ctx, profile.ID, time.Now())))
+	state("touched")
+	return log
+}
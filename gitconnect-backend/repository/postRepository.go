@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostRepository stores posts, their hashtags and mentions, and the reactions on them.
+// Hashtags and mentions are extracted from the content whenever it is written.
+type PostRepository interface {
+	// Create stores the post and fills its Tags and Mentions
+	Create(ctx context.Context, post *models.Post) error
+	// FindByID returns the post with its author, tags and mentions
+	FindByID(ctx context.Context, id uint) (models.Post, error)
+	// List returns one page of posts with their authors, tags and mentions, and the cursor of the next page
+	List(ctx context.Context, filter PostFilter) ([]models.Post, *Cursor, error)
+	// UpdateContent replaces the content and extracts its tags and mentions again
+	UpdateContent(ctx context.Context, id uint, content string) error
+	Delete(ctx context.Context, id uint) error
+	// OwnerID returns the ID of the post's author
+	OwnerID(ctx context.Context, id uint) (uint, error)
+	// SetReaction moves the user's reaction on the post to kind (an empty kind removes
+	// it) and returns the post's author, fresh counters and MyReaction
+	SetReaction(ctx context.Context, postID, userID uint, kind models.ReactionKind) (models.Post, error)
+	// ReactionsBy returns the user's reaction on each of the given posts that has one
+	ReactionsBy(ctx context.Context, userID uint, postIDs []uint) (map[uint]models.ReactionKind, error)
+}
+
+type gormPosts struct {
+	db *gorm.DB
+}
+
+func (r *gormPosts) Create(ctx context.Context, post *models.Post) error {
+	post.Tags = models.ExtractHashtags(post.Content)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := syncPostTags(tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+		var err error
+		post.Mentions, err = syncMentions(tx, "post_id", post.ID, post.Content)
+		return err
+	})
+	return translate(err)
+}
+
+func (r *gormPosts) FindByID(ctx context.Context, id uint) (models.Post, error) {
+	var post models.Post
+	db := r.db.WithContext(ctx)
+	if err := db.Preload("User").First(&post, id).Error; err != nil {
+		return post, translate(err)
+	}
+	posts := []models.Post{post}
+	err := loadPostDetails(db, posts)
+	return posts[0], err
+}
+
+func (r *gormPosts) List(ctx context.Context, filter PostFilter) ([]models.Post, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Post{}).Preload("User")
+	if filter.AuthorID != 0 {
+		query = query.Where("posts.user_id = ?", filter.AuthorID)
+	}
+	if filter.HasComments != nil {
+		exists := "EXISTS (SELECT 1 FROM comments WHERE comments.post_id = posts.id)"
+		if !*filter.HasComments {
+			exists = "NOT " + exists
+		}
+		query = query.Where(exists)
+	}
+	if filter.Tag != "" {
+		query = query.Where("posts.id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.name = ?)", filter.Tag)
+	}
+
+	var posts []models.Post
+	if err := paginate(query, "posts.created_at", "posts.id", filter.Created, filter.Page).Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	posts, next := trimPage(posts, filter.Page, postCursor)
+	return posts, next, loadPostDetails(r.db.WithContext(ctx), posts)
+}
+
+func postCursor(post models.Post) Cursor {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+func (r *gormPosts) UpdateContent(ctx context.Context, id uint, content string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Update the content column only, so concurrent reaction counters are not overwritten
+		result := tx.Model(&models.Post{}).Where("id = ?", id).Update("content", content)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := syncPostTags(tx, id, models.ExtractHashtags(content)); err != nil {
+			return err
+		}
+		_, err := syncMentions(tx, "post_id", id, content)
+		return err
+	})
+}
+
+func (r *gormPosts) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Post{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormPosts) OwnerID(ctx context.Context, id uint) (uint, error) {
+	var owner struct{ UserID uint }
+	err := r.db.WithContext(ctx).Model(&models.Post{}).Select("user_id").Where("id = ?", id).Take(&owner).Error
+	return owner.UserID, translate(err)
+}
+
+// SetReaction applies a reaction transition in a single transaction:
+// none -> kind adds, kind -> other kind switches, kind -> "" removes, and
+// kind -> same kind changes nothing. Counters are adjusted with in-place
+// increments so concurrent reactions never overwrite each other.
+func (r *gormPosts) SetReaction(ctx context.Context, postID, userID uint, kind models.ReactionKind) (models.Post, error) {
+	var post models.Post
+	db := r.db.WithContext(ctx)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&post, postID).Error; err != nil {
+			return err
+		}
+
+		var current models.PostReaction
+		found, err := lockReaction(tx, "post_id", postID, userID, &current)
+		if err != nil {
+			return err
+		}
+
+		if !found && kind != "" {
+			reaction := models.PostReaction{PostID: postID, UserID: userID, Kind: kind}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				return bumpReactionCounter(tx, &models.Post{}, postID, kind, 1)
+			}
+
+			// A concurrent request inserted first; continue from its row
+			if found, err = lockReaction(tx, "post_id", postID, userID, &current); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case !found, current.Kind == kind:
+			return nil
+		case kind == "":
+			if err := tx.Delete(&current).Error; err != nil {
+				return err
+			}
+			return bumpReactionCounter(tx, &models.Post{}, postID, current.Kind, -1)
+		default:
+			if err := tx.Model(&current).Update("kind", kind).Error; err != nil {
+				return err
+			}
+			if err := bumpReactionCounter(tx, &models.Post{}, postID, current.Kind, -1); err != nil {
+				return err
+			}
+			return bumpReactionCounter(tx, &models.Post{}, postID, kind, 1)
+		}
+	})
+	if err != nil {
+		return post, translate(err)
+	}
+
+	if err := db.Select("id", "user_id", "likes", "dislikes").First(&post, postID).Error; err != nil {
+		return post, translate(err)
+	}
+	post.MyReaction = kind
+	return post, nil
+}
+
+func (r *gormPosts) ReactionsBy(ctx context.Context, userID uint, postIDs []uint) (map[uint]models.ReactionKind, error) {
+	kinds := make(map[uint]models.ReactionKind)
+	if len(postIDs) == 0 {
+		return kinds, nil
+	}
+
+	var reactions []models.PostReaction
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND post_id IN ?", userID, postIDs).Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		kinds[reaction.PostID] = reaction.Kind
+	}
+	return kinds, nil
+}
+
+// lockReaction loads the user's reaction row for update, reporting whether it exists.
+// keyColumn names the ledger column pointing at the reacted-to row.
+func lockReaction(tx *gorm.DB, keyColumn string, targetID, userID uint, reaction interface{}) (bool, error) {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(keyColumn+" = ? AND user_id = ?", targetID, userID).
+		Take(reaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// bumpReactionCounter atomically adds delta to the counter for kind on the row of
+// model (a post or a comment) with the given ID
+func bumpReactionCounter(tx *gorm.DB, model interface{}, id uint, kind models.ReactionKind, delta int) error {
+	column := kind.CounterColumn()
+	return tx.Model(model).
+		Where("id = ?", id).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// syncPostTags links the post to exactly the named tags, creating tags that do not exist yet
+func syncPostTags(tx *gorm.DB, postID uint, names []string) error {
+	unlinked := tx.Where("post_id = ?", postID)
+	if len(names) > 0 {
+		tags := make([]models.Tag, len(names))
+		for i, name := range names {
+			tags[i] = models.Tag{Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+		unlinked = unlinked.Where("tag_id NOT IN (?)", tx.Model(&models.Tag{}).Select("id").Where("name IN ?", names))
+	}
+	if err := unlinked.Delete(&models.PostTag{}).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO post_tags (post_id, tag_id, created_at)
+		SELECT ?, id, now() FROM tags WHERE name IN ?
+		ON CONFLICT DO NOTHING`, postID, names).Error
+}
+
+// loadPostDetails fills Tags and Mentions on each post
+func loadPostDetails(db *gorm.DB, posts []models.Post) error {
+	if err := loadPostTags(db, posts); err != nil {
+		return err
+	}
+	return loadPostMentions(db, posts)
+}
+
+// loadPostTags fills Tags on each post
+func loadPostTags(db *gorm.DB, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+		posts[i].Tags = []string{}
+	}
+
+	var links []struct {
+		PostID uint
+		Name   string
+	}
+	err := db.Model(&models.PostTag{}).
+		Select("post_tags.post_id, tags.name").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("post_tags.post_id IN ?", ids).
+		Order("tags.name").
+		Scan(&links).Error
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[uint][]string, len(posts))
+	for _, link := range links {
+		tags[link.PostID] = append(tags[link.PostID], link.Name)
+	}
+	for i := range posts {
+		if names, ok := tags[posts[i].ID]; ok {
+			posts[i].Tags = names
+		}
+	}
+	return nil
+}
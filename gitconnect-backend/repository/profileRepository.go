@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProfileRepository stores user profiles. Each user has at most one.
+type ProfileRepository interface {
+	// Create returns ErrConflict if the user already has a profile
+	Create(ctx context.Context, profile *models.Profile) error
+	FindByID(ctx context.Context, id uint) (models.Profile, error)
+	// FindByGithubID returns the profile the GitHub account is linked to
+	FindByGithubID(ctx context.Context, githubID int64) (models.Profile, error)
+	// List returns one page of profiles and the cursor of the next page
+	List(ctx context.Context, filter ProfileFilter) ([]models.Profile, *Cursor, error)
+	// Update saves every field of the profile but the picture
+	Update(ctx context.Context, profile *models.Profile) error
+	// SetPicture sets the picture of a profile, or clears it if pictureID is empty,
+	// and returns the ID of the picture it replaced
+	SetPicture(ctx context.Context, id uint, pictureID string) (string, error)
+	// PictureInUse reports whether any profile shows the picture
+	PictureInUse(ctx context.Context, pictureID string) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	// OwnerID returns the ID of the user the profile belongs to
+	OwnerID(ctx context.Context, id uint) (uint, error)
+	// LinkGithub records a verified GitHub account on the user's profile, creating
+	// the profile if the user has none. ErrConflict is returned if the account is
+	// linked to another user.
+	LinkGithub(ctx context.Context, userID uint, githubID int64, login string, at time.Time) error
+}
+
+type gormProfiles struct {
+	db *gorm.DB
+}
+
+func (r *gormProfiles) Create(ctx context.Context, profile *models.Profile) error {
+	return translate(r.db.WithContext(ctx).Create(profile).Error)
+}
+
+func (r *gormProfiles) FindByID(ctx context.Context, id uint) (models.Profile, error) {
+	var profile models.Profile
+	err := r.db.WithContext(ctx).First(&profile, id).Error
+	return profile, translate(err)
+}
+
+func (r *gormProfiles) FindByGithubID(ctx context.Context, githubID int64) (models.Profile, error) {
+	var profile models.Profile
+	err := r.db.WithContext(ctx).Where("github_id = ?", githubID).Take(&profile).Error
+	return profile, translate(err)
+}
+
+func (r *gormProfiles) List(ctx context.Context, filter ProfileFilter) ([]models.Profile, *Cursor, error) {
+	query := r.db.WithContext(ctx).Model(&models.Profile{})
+	if filter.UserID != 0 {
+		query = query.Where("profiles.user_id = ?", filter.UserID)
+	}
+
+	var profiles []models.Profile
+	if err := paginate(query, "profiles.created_at", "profiles.id", filter.Created, filter.Page).Find(&profiles).Error; err != nil {
+		return nil, nil, err
+	}
+	profiles, next := trimPage(profiles, filter.Page, profileCursor)
+	return profiles, next, nil
+}
+
+func profileCursor(profile models.Profile) Cursor {
+	return Cursor{CreatedAt: profile.CreatedAt, ID: profile.ID}
+}
+
+func (r *gormProfiles) Update(ctx context.Context, profile *models.Profile) error {
+	return translate(r.db.WithContext(ctx).Omit("picture_id").Save(profile).Error)
+}
+
+func (r *gormProfiles) SetPicture(ctx context.Context, id uint, pictureID string) (string, error) {
+	var previous string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var profile models.Profile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "picture_id").Take(&profile, id).Error; err != nil {
+			return translate(err)
+		}
+		previous = profile.PictureID
+		return tx.Model(&profile).Updates(map[string]interface{}{"picture_id": pictureID, "updated_at": time.Now()}).Error
+	})
+	return previous, err
+}
+
+func (r *gormProfiles) PictureInUse(ctx context.Context, pictureID string) (bool, error) {
+	var profiles int64
+	err := r.db.WithContext(ctx).Model(&models.Profile{}).Where("picture_id = ?", pictureID).Count(&profiles).Error
+	return profiles > 0, err
+}
+
+func (r *gormProfiles) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Profile{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormProfiles) OwnerID(ctx context.Context, id uint) (uint, error) {
+	var owner struct{ UserID uint }
+	err := r.db.WithContext(ctx).Model(&models.Profile{}).Select("user_id").Where("id = ?", id).Take(&owner).Error
+	return owner.UserID, translate(err)
+}
+
+func (r *gormProfiles) LinkGithub(ctx context.Context, userID uint, githubID int64, login string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var linked int64
+		if err := tx.Model(&models.Profile{}).Where("github_id = ? AND user_id <> ?", githubID, userID).Count(&linked).Error; err != nil {
+			return err
+		}
+		if linked > 0 {
+			return ErrConflict
+		}
+
+		var profile models.Profile
+		if err := tx.Where(models.Profile{UserID: userID}).FirstOrCreate(&profile).Error; err != nil {
+			return translate(err)
+		}
+		return translate(tx.Model(&profile).Updates(map[string]interface{}{
+			"github_id":          githubID,
+			"github_login":       login,
+			"github":             login,
+			"github_verified_at": at,
+		}).Error)
+	})
+}
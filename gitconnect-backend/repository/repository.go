@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a record would break a uniqueness rule
+	ErrConflict = errors.New("record already exists")
+	// ErrLimit is returned when a record would exceed a cap on how many there may be
+	ErrLimit = errors.New("too many records")
+	// ErrExpired is returned when a token is past its expiry
+	ErrExpired = errors.New("record has expired")
+	// ErrReused is returned when a single-use token is presented again
+	ErrReused = errors.New("record was already used")
+)
+
+// Repositories groups the repositories the controllers are built from
+type Repositories struct {
+	Users         UserRepository
+	Sessions      SessionRepository
+	AccessTokens  AccessTokenRepository
+	OneTimeTokens OneTimeTokenRepository
+	MFA           MFARepository
+	OAuthStates   OAuthStateRepository
+	Profiles      ProfileRepository
+	Posts         PostRepository
+	Comments      CommentRepository
+	Follows       FollowRepository
+	Feed          FeedRepository
+	Search        SearchRepository
+	Tags          TagRepository
+	Blocks        BlockRepository
+	Notifications NotificationRepository
+	Messages      MessageRepository
+	Skills        SectionRepository[models.Skill]
+	Experiences   SectionRepository[models.Experience]
+	Educations    SectionRepository[models.Education]
+	Links         SectionRepository[models.ProfileLink]
+	GithubRepos   GithubRepoRepository
+	Endorsements  EndorsementRepository
+}
+
+// NewGorm returns repositories backed by db
+func NewGorm(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:         &gormUsers{db: db},
+		Sessions:      &gormSessions{db: db},
+		AccessTokens:  &gormAccessTokens{db: db},
+		OneTimeTokens: &gormOneTimeTokens{db: db},
+		MFA:           &gormMFA{db: db},
+		OAuthStates:   &gormOAuthStates{db: db},
+		Profiles:      &gormProfiles{db: db},
+		Posts:         &gormPosts{db: db},
+		Comments:      &gormComments{db: db},
+		Follows:       &gormFollows{db: db},
+		Feed:          &gormFeed{db: db},
+		Search:        &gormSearch{db: db},
+		Tags:          &gormTags{db: db},
+		Blocks:        &gormBlocks{db: db},
+		Notifications: &gormNotifications{db: db},
+		Messages:      &gormMessages{db: db},
+		Skills:        &gormSection[models.Skill, *models.Skill]{db: db, limit: models.MaxSkills},
+		Experiences:   &gormSection[models.Experience, *models.Experience]{db: db, limit: models.MaxExperiences},
+		Educations:    &gormSection[models.Education, *models.Education]{db: db, limit: models.MaxEducations},
+		Links:         &gormSection[models.ProfileLink, *models.ProfileLink]{db: db, limit: models.MaxLinks},
+		GithubRepos:   &gormGithubRepos{db: db},
+		Endorsements:  &gormEndorsements{db: db},
+	}
+}
+
+// translate maps GORM errors onto the repository errors
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrConflict
+	}
+	return err
+}
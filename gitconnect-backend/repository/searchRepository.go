@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+)
+
+// SearchFilter selects one page of search results
+type SearchFilter struct {
+	Query  string              // Web search syntax: words, "quoted phrases", OR and -excluded words
+	Types  []models.SearchType // Only these types; all types if empty
+	Limit  int
+	Offset int
+}
+
+// SearchRepository finds posts, profiles and comments by their text
+type SearchRepository interface {
+	// Search returns one page of matches, best first, and the total number of matches
+	Search(ctx context.Context, filter SearchFilter) ([]models.SearchResult, int64, error)
+}
+
+// gormSearch queries the search_vector columns that the migrations generate
+// from the searchable text, through their GIN indexes
+type gormSearch struct {
+	db *gorm.DB
+}
+
+// searchSources selects the matches of each type against the query q,
+// with the text a snippet is cut from as body
+var searchSources = map[models.SearchType]string{
+	models.SearchPost: `SELECT 'post' AS type, posts.id, NULL::bigint AS post_id, posts.user_id,
+		ts_rank_cd(posts.search_vector, q.query) AS rank, posts.content AS body, posts.created_at
+		FROM posts, q WHERE posts.search_vector @@ q.query`,
+	models.SearchProfile: `SELECT 'profile' AS type, profiles.id, NULL::bigint AS post_id, profiles.user_id,
+		ts_rank_cd(profiles.search_vector, q.query) AS rank, concat_ws(' · ', nullif(profiles.full_name, ''), nullif(profiles.github, ''), nullif(profiles.bio, '')) AS body, profiles.created_at
+		FROM profiles, q WHERE profiles.search_vector @@ q.query`,
+	models.SearchComment: `SELECT 'comment' AS type, comments.id, comments.post_id, comments.user_id,
+		ts_rank_cd(comments.search_vector, q.query) AS rank, comments.content AS body, comments.created_at
+		FROM comments, q WHERE comments.search_vector @@ q.query AND comments.deleted_at IS NULL`,
+}
+
+// searchHeadline marks the matched terms in an HTML-escaped body
+const searchHeadline = `ts_headline('english',
+	replace(replace(replace(page.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+	q.query, 'StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2')`
+
+func (r *gormSearch) Search(ctx context.Context, filter SearchFilter) ([]models.SearchResult, int64, error) {
+	types := filter.Types
+	if len(types) == 0 {
+		types = models.SearchTypes
+	}
+	sources := make([]string, 0, len(types))
+	for _, searchType := range models.SearchTypes {
+		for _, wanted := range types {
+			if wanted == searchType {
+				sources = append(sources, searchSources[searchType])
+				break
+			}
+		}
+	}
+	matches := `WITH q AS (SELECT websearch_to_tsquery('english', @query) AS query),
+		results AS (` + strings.Join(sources, " UNION ALL ") + `)`
+
+	db := r.db.WithContext(ctx)
+	query := sql.Named("query", filter.Query)
+
+	var total int64
+	if err := db.Raw(matches+` SELECT count(*) FROM results`, query).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Headlines are expensive, so they are only cut for the rows of the page
+	results := []models.SearchResult{}
+	err := db.Raw(matches+`
+		SELECT page.type, page.id, page.post_id, page.user_id, users.username,
+			page.rank, page.created_at, `+searchHeadline+` AS snippet
+		FROM (SELECT * FROM results ORDER BY rank DESC, created_at DESC, type, id LIMIT @limit OFFSET @offset) AS page
+		JOIN users ON users.id = page.user_id
+		CROSS JOIN q
+		ORDER BY page.rank DESC, page.created_at DESC, page.type, page.id`,
+		query, sql.Named("limit", filter.Limit), sql.Named("offset", filter.Offset)).
+		Scan(&results).Error
+	return results, total, err
+}
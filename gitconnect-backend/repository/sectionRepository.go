@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SectionRepository stores the entries of one section of profiles, such as their skills
+type SectionRepository[T any] interface {
+	// List returns the entries of the profiles, each profile's in position order
+	List(ctx context.Context, profileIDs ...uint) ([]T, error)
+	// Create appends an entry to its profile's section. ErrNotFound is returned if
+	// the profile does not exist, ErrLimit if the section is full and ErrConflict
+	// if the section already lists the entry.
+	Create(ctx context.Context, entry *T) error
+	// Update saves the fields of an entry but its position, and reloads it.
+	// ErrNotFound is returned if the profile has no such entry.
+	Update(ctx context.Context, entry *T) error
+	// Delete returns ErrNotFound if the profile has no such entry
+	Delete(ctx context.Context, profileID, id uint) error
+	// Reorder moves the entries in ids to the top of the profile's section, in
+	// that order; the others follow in their current order. ErrNotFound is
+	// returned if an ID is not one of the profile's entries.
+	Reorder(ctx context.Context, profileID uint, ids []uint) error
+}
+
+type gormSection[T any, P models.ProfileEntry[T]] struct {
+	db    *gorm.DB
+	limit int
+}
+
+func (r *gormSection[T, P]) List(ctx context.Context, profileIDs ...uint) ([]T, error) {
+	var entries []T
+	err := r.db.WithContext(ctx).Where("profile_id IN ?", profileIDs).Order("profile_id, position, id").Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormSection[T, P]) Create(ctx context.Context, entry *T) error {
+	item := P(entry).Item()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the profile so concurrent appends count each other
+		var profile models.Profile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Take(&profile, item.ProfileID).Error; err != nil {
+			return err
+		}
+
+		var section struct {
+			Entries int
+			Last    int
+		}
+		err := tx.Model(new(T)).Select("COUNT(*) AS entries, COALESCE(MAX(position), -1) AS last").
+			Where("profile_id = ?", item.ProfileID).Scan(&section).Error
+		if err != nil {
+			return err
+		}
+		if section.Entries >= r.limit {
+			return ErrLimit
+		}
+
+		*item = models.ProfileItem{ProfileID: item.ProfileID, Position: section.Last + 1}
+		return tx.Create(entry).Error
+	})
+	return translate(err)
+}
+
+func (r *gormSection[T, P]) Update(ctx context.Context, entry *T) error {
+	item := P(entry).Item()
+	db := r.db.WithContext(ctx)
+	result := db.Model(entry).Where("profile_id = ?", item.ProfileID).
+		Select("*").Omit("id", "profile_id", "position", "created_at").Updates(entry)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return translate(db.Take(entry, item.ID).Error)
+}
+
+func (r *gormSection[T, P]) Delete(ctx context.Context, profileID, id uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND profile_id = ?", id, profileID).Delete(new(T))
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormSection[T, P]) Reorder(ctx context.Context, profileID uint, ids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		err := tx.Model(new(T)).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("profile_id = ?", profileID).Order("position, id").Pluck("id", &current).Error
+		if err != nil {
+			return err
+		}
+		order, err := reorder(current, ids)
+		if err != nil {
+			return err
+		}
+		for position, id := range order {
+			if err := tx.Model(new(T)).Where("id = ?", id).UpdateColumn("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reorder moves ids to the front of current, keeping the order of the rest
+func reorder(current, ids []uint) ([]uint, error) {
+	listed := make(map[uint]bool, len(current))
+	for _, id := range current {
+		listed[id] = false
+	}
+	order := make([]uint, 0, len(current))
+	for _, id := range ids {
+		moved, ok := listed[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if !moved {
+			listed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+	return order, nil
+}
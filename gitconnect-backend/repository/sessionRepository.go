@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionRepository stores login sessions: the rotating refresh tokens issued at
+// login, and the denylist of access tokens revoked before they expire
+type SessionRepository interface {
+	// Create stores a new refresh token
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// Find returns the refresh token with the hash, whether or not it is still valid
+	Find(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// Rotate spends the refresh token with the hash and stores next in its place,
+	// for the same user and in the same family. ErrExpired is returned for an
+	// expired token. A token that was already spent is taken as stolen: its whole
+	// family is revoked and ErrReused returned.
+	Rotate(ctx context.Context, tokenHash string, next *models.RefreshToken) error
+	// RevokeFamily revokes every active refresh token issued from the same login
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeAccessToken puts the jti of an access token on the denylist until the
+	// token expires, and reports whether it was not there already
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+	// AccessTokenRevoked reports whether the jti is on the denylist
+	AccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// PurgeRevoked drops the denylist entries of tokens that have expired by now
+	PurgeRevoked(ctx context.Context, now time.Time) error
+}
+
+type gormSessions struct {
+	db *gorm.DB
+}
+
+func (r *gormSessions) Create(ctx context.Context, token *models.RefreshToken) error {
+	return translate(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *gormSessions) Find(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(&token).Error
+	return token, translate(err)
+}
+
+func (r *gormSessions) Rotate(ctx context.Context, tokenHash string, next *models.RefreshToken) error {
+	reused := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).Take(&stored).Error
+		if err != nil {
+			return translate(err)
+		}
+
+		// The family is revoked in a committed transaction, then ErrReused reported
+		if stored.RevokedAt != nil {
+			reused = true
+			return revokeFamily(tx, stored.FamilyID)
+		}
+		if time.Now().After(stored.ExpiresAt) {
+			return ErrExpired
+		}
+
+		next.UserID, next.FamilyID = stored.UserID, stored.FamilyID
+		if err := tx.Create(next).Error; err != nil {
+			return translate(err)
+		}
+		return tx.Model(&stored).Updates(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"replaced_by": next.ID,
+		}).Error
+	})
+	if err == nil && reused {
+		return ErrReused
+	}
+	return err
+}
+
+func (r *gormSessions) RevokeFamily(ctx context.Context, familyID string) error {
+	return revokeFamily(r.db.WithContext(ctx), familyID)
+}
+
+// revokeFamily revokes every still-active refresh token in a family
+func revokeFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormSessions) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormSessions) AccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked int64
+	err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&revoked).Error
+	return revoked > 0, err
+}
+
+func (r *gormSessions) PurgeRevoked(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error
+}
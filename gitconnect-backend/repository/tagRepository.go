@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagRepository stores hashtags and who follows them. Posts are linked to their
+// hashtags by PostRepository; PostFilter.Tag lists a hashtag's posts.
+type TagRepository interface {
+	// FindByName returns the tag with the normalized name
+	FindByName(ctx context.Context, name string) (models.Tag, error)
+	// Trending returns up to limit tags used on posts since the given time, ranked
+	// by distinct authors and then by posts, so one account cannot trend a tag alone
+	Trending(ctx context.Context, since time.Time, limit int) ([]models.TrendingTag, error)
+	// Follow adds the tag's posts to the user's home feed, creating the tag if it
+	// has not been used yet. Following again is a no-op.
+	Follow(ctx context.Context, userID uint, name string) (models.Tag, error)
+	// Unfollow removes the tag from the user's home feed, if it was followed
+	Unfollow(ctx context.Context, userID uint, name string) error
+	// Followed returns the tags the user follows, by name
+	Followed(ctx context.Context, userID uint) ([]models.Tag, error)
+}
+
+type gormTags struct {
+	db *gorm.DB
+}
+
+func (r *gormTags) FindByName(ctx context.Context, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Where("name = ?", name).Take(&tag).Error
+	return tag, translate(err)
+}
+
+func (r *gormTags) Trending(ctx context.Context, since time.Time, limit int) ([]models.TrendingTag, error) {
+	trending := []models.TrendingTag{}
+	err := r.db.WithContext(ctx).Model(&models.PostTag{}).
+		Select("tags.name, COUNT(*) AS posts, COUNT(DISTINCT posts.user_id) AS authors").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("post_tags.created_at >= ?", since).
+		Group("tags.name").
+		Order("COUNT(DISTINCT posts.user_id) DESC").Order("COUNT(*) DESC").Order("tags.name").
+		Limit(limit).
+		Scan(&trending).Error
+	return trending, err
+}
+
+func (r *gormTags) Follow(ctx context.Context, userID uint, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Tag{Name: name}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name = ?", name).Take(&tag).Error; err != nil {
+			return err
+		}
+		follow := models.TagFollow{UserID: userID, TagID: tag.ID}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error
+	})
+	return tag, translate(err)
+}
+
+func (r *gormTags) Unfollow(ctx context.Context, userID uint, name string) error {
+	db := r.db.WithContext(ctx)
+	return db.Where("user_id = ? AND tag_id IN (?)", userID, db.Model(&models.Tag{}).Select("id").Where("name = ?", name)).
+		Delete(&models.TagFollow{}).Error
+}
+
+func (r *gormTags) Followed(ctx context.Context, userID uint) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.WithContext(ctx).
+		Joins("JOIN tag_follows ON tag_follows.tag_id = tags.id").
+		Where("tag_follows.user_id = ?", userID).
+		Order("tags.name").
+		Find(&tags).Error
+	return tags, err
+}
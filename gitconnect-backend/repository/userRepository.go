@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserFilter narrows and pages UserRepository.List
+type UserFilter struct {
+	Query  string      // Case-insensitive match on username or email
+	Role   models.Role // Only users with this role, if set
+	Limit  int
+	Offset int
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	// FindByUsername returns the user with the username, whatever its case. If it is
+	// a previous username of an account instead, that account is returned and renamed
+	// is true. Exact matches come first: a previous username in the exact case wins
+	// over a current one in another case.
+	FindByUsername(ctx context.Context, username string) (user models.User, renamed bool, err error)
+	// Usernames returns the username of each of the given users
+	Usernames(ctx context.Context, ids []uint) (map[uint]string, error)
+	// Rename changes a username, keeping the old one as a redirect to the account;
+	// only the latest models.MaxUsernameRedirects redirects are kept. ErrConflict is
+	// returned if another account holds the new name, currently or as a redirect.
+	Rename(ctx context.Context, id uint, username string) error
+	// List returns one page of matching users, ordered by ID, and the total number of matches
+	List(ctx context.Context, filter UserFilter) ([]models.User, int64, error)
+	SetRole(ctx context.Context, id uint, role models.Role) error
+	// Suspend makes the account read-only until the given time and signs it out everywhere
+	Suspend(ctx context.Context, id uint, until time.Time, note string) error
+	Unsuspend(ctx context.Context, id uint) error
+	// Ban blocks the account permanently and signs it out everywhere
+	Ban(ctx context.Context, id uint, at time.Time, note string) error
+	Unban(ctx context.Context, id uint) error
+	// Delete removes the account with everything it owns
+	Delete(ctx context.Context, id uint) error
+	// RecordFailedLogin counts a failed sign-in and returns the consecutive failures so far
+	RecordFailedLogin(ctx context.Context, id uint) (int, error)
+	// LockLogin refuses sign-ins to the account until the given time
+	LockLogin(ctx context.Context, id uint, until time.Time) error
+	// ResetFailedLogins clears the failure count and any lockout
+	ResetFailedLogins(ctx context.Context, id uint) error
+}
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *gormUsers) FindByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	return user, translate(err)
+}
+
+func (r *gormUsers) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("email = ?", email).Take(&user).Error
+	return user, translate(err)
+}
+
+func (r *gormUsers) FindByUsername(ctx context.Context, username string) (models.User, bool, error) {
+	db := r.db.WithContext(ctx)
+	var user models.User
+	err := db.Where("lower(username) = lower(?)", username).Take(&user).Error
+	found := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, false, err
+	}
+	if found && user.Username == username {
+		return user, false, nil
+	}
+
+	// An exact previous username, then the current one in another case, then a
+	// previous one in another case; those are unique but for legacy accounts
+	var redirect models.UsernameRedirect
+	err = db.Where("username = ?", username).Take(&redirect).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if found {
+			return user, false, nil
+		}
+		err = db.Where("lower(username) = lower(?)", username).Order("user_id").Take(&redirect).Error
+	}
+	if err != nil {
+		return models.User{}, false, translate(err)
+	}
+	user, err = r.FindByID(ctx, redirect.UserID)
+	return user, true, err
+}
+
+func (r *gormUsers) Usernames(ctx context.Context, ids []uint) (map[uint]string, error) {
+	usernames := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return usernames, nil
+	}
+
+	var users []models.User
+	if err := r.db.WithContext(ctx).Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	return usernames, nil
+}
+
+func (r *gormUsers) Rename(ctx context.Context, id uint, username string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&user, id).Error; err != nil {
+			return translate(err)
+		}
+		if user.Username == username {
+			return nil
+		}
+
+		var taken int64
+		err := tx.Model(&models.User{}).Where("lower(username) = lower(?) AND id <> ?", username, id).Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken == 0 {
+			// Another account's previous username is taken too, unless this account also
+			// held the name in another case (as legacy accounts may)
+			own := tx.Model(&models.UsernameRedirect{}).Select("1").Where("lower(username) = lower(?) AND user_id = ?", username, id)
+			err = tx.Model(&models.UsernameRedirect{}).Where("lower(username) = lower(?) AND user_id <> ?", username, id).
+				Where("username = ? OR NOT EXISTS (?)", username, own).Count(&taken).Error
+			if err != nil {
+				return err
+			}
+		}
+		if taken > 0 {
+			return ErrConflict
+		}
+
+		// Taking back a previous username drops its redirect; changing only the
+		// case needs none, since lookups ignore it
+		if err := tx.Where("lower(username) = lower(?) AND user_id = ?", username, id).Delete(&models.UsernameRedirect{}).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Username, username) {
+			if err := tx.Create(&models.UsernameRedirect{Username: user.Username, UserID: id}).Error; err != nil {
+				return translate(err)
+			}
+			stale := tx.Model(&models.UsernameRedirect{}).Select("id").Where("user_id = ?", id).
+				Order("created_at DESC, id DESC").Offset(models.MaxUsernameRedirects)
+			if err := tx.Where("id IN (?)", stale).Delete(&models.UsernameRedirect{}).Error; err != nil {
+				return err
+			}
+		}
+		return translate(tx.Model(&user).Updates(map[string]interface{}{"username": username, "updated_at": time.Now()}).Error)
+	})
+}
+
+func (r *gormUsers) List(ctx context.Context, filter UserFilter) ([]models.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.User{})
+	if filter.Query != "" {
+		pattern := "%" + strings.ToLower(filter.Query) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Order("id").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+func (r *gormUsers) SetRole(ctx context.Context, id uint, role models.Role) error {
+	return r.update(ctx, id, map[string]interface{}{"role": role})
+}
+
+func (r *gormUsers) Suspend(ctx context.Context, id uint, until time.Time, note string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := (&gormUsers{db: tx}).update(ctx, id, map[string]interface{}{
+			"suspended_until": until,
+			"moderation_note": note,
+		}); err != nil {
+			return err
+		}
+		return revokeCredentials(tx, id)
+	})
+}
+
+func (r *gormUsers) Unsuspend(ctx context.Context, id uint) error {
+	return r.update(ctx, id, map[string]interface{}{"suspended_until": nil})
+}
+
+func (r *gormUsers) Ban(ctx context.Context, id uint, at time.Time, note string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := (&gormUsers{db: tx}).update(ctx, id, map[string]interface{}{
+			"banned_at":       at,
+			"moderation_note": note,
+		}); err != nil {
+			return err
+		}
+		return revokeCredentials(tx, id)
+	})
+}
+
+func (r *gormUsers) Unban(ctx context.Context, id uint) error {
+	return r.update(ctx, id, map[string]interface{}{"banned_at": nil})
+}
+
+func (r *gormUsers) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Remove the user's reactions first so the counters on other people's posts and comments stay right
+		var reactions []models.PostReaction
+		if err := tx.Where("user_id = ?", id).Find(&reactions).Error; err != nil {
+			return err
+		}
+		for _, reaction := range reactions {
+			if err := bumpReactionCounter(tx, &models.Post{}, reaction.PostID, reaction.Kind, -1); err != nil {
+				return err
+			}
+		}
+		var commentReactions []models.CommentReaction
+		if err := tx.Where("user_id = ?", id).Find(&commentReactions).Error; err != nil {
+			return err
+		}
+		for _, reaction := range commentReactions {
+			if err := bumpReactionCounter(tx, &models.Comment{}, reaction.CommentID, reaction.Kind, -1); err != nil {
+				return err
+			}
+		}
+
+		// The user's comments that others have replied to become tombstones, keeping those threads intact
+		if err := tombstoneComments(tx, "user_id = ? AND EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)", id); err != nil {
+			return err
+		}
+
+		// Posts and comments have no cascading foreign key to users, so everything is removed explicitly
+		owned := []interface{}{
+			&models.PostReaction{}, &models.CommentReaction{}, &models.Comment{}, &models.RefreshToken{}, &models.OneTimeToken{},
+			&models.OAuthState{}, &models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.Profile{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		userPosts := tx.Model(&models.Post{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Where("post_id IN (?)", userPosts).Delete(&models.PostReaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id IN (?)", userPosts).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.Post{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.User{}, id)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return result.Error
+	})
+}
+
+func (r *gormUsers) RecordFailedLogin(ctx context.Context, id uint) (int, error) {
+	// Rows older than migration 0016 may still hold NULL, which NULL + 1 would keep
+	var user models.User
+	result := r.db.WithContext(ctx).Model(&user).Clauses(clause.Returning{}).Where("id = ?", id).
+		Update("failed_logins", gorm.Expr("COALESCE(failed_logins, 0) + 1"))
+	if result.Error == nil && result.RowsAffected == 0 {
+		return 0, ErrNotFound
+	}
+	return user.FailedLogins, result.Error
+}
+
+func (r *gormUsers) LockLogin(ctx context.Context, id uint, until time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"locked_until": until})
+}
+
+func (r *gormUsers) ResetFailedLogins(ctx context.Context, id uint) error {
+	return r.update(ctx, id, map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+}
+
+// update writes columns of one user, reporting ErrNotFound if there is no such user
+func (r *gormUsers) update(ctx context.Context, id uint, columns map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(columns)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+// revokeCredentials signs a user out everywhere by revoking their refresh tokens and
+// personal access tokens. Access tokens already issued expire on their own shortly.
+func revokeCredentials(tx *gorm.DB, userID uint) error {
+	now := time.Now()
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(router *gin.Engine, deps Dependencies) {
+	adminCtrl := controllers.NewAdminController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Staff routes: login sessions only, each gated by a permission from the role matrix
+	admin := router.Group("/api/admin").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.SessionOnly(),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupAdmin, middlewares.ByUser))
+	{
+		// Users
+		admin.GET("/users", access.RequirePermission(models.PermUserList), adminCtrl.ListUsers)
+		admin.PUT("/users/:id/role", access.RequirePermission(models.PermUserManageRoles), adminCtrl.SetUserRole)
+		admin.POST("/users/:id/suspend", access.RequirePermission(models.PermUserSuspend), adminCtrl.SuspendUser)
+		admin.POST("/users/:id/unsuspend", access.RequirePermission(models.PermUserSuspend), adminCtrl.UnsuspendUser)
+		admin.POST("/users/:id/ban", access.RequirePermission(models.PermUserBan), adminCtrl.BanUser)
+		admin.POST("/users/:id/unban", access.RequirePermission(models.PermUserBan), adminCtrl.UnbanUser)
+		admin.DELETE("/users/:id", access.RequirePermission(models.PermUserDelete), adminCtrl.DeleteUser)
+
+		// Content
+		admin.DELETE("/posts/:id", access.RequirePermission(models.PermPostDeleteAny), adminCtrl.AdminDeletePost)
+		admin.DELETE("/comments/:id", access.RequirePermission(models.PermCommentDeleteAny), adminCtrl.AdminDeleteComment)
+	}
+}
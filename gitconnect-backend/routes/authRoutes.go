@@ -2,15 +2,59 @@ package routes
 
 import (
 	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(router *gin.Engine) {
-	auth := router.Group("/api/auth")
+func AuthRoutes(router *gin.Engine, deps Dependencies) {
+	authCtrl := controllers.NewAuthController(deps.Repos, deps.Limiter, deps.Mailer)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Public endpoints are limited per client IP; both login steps also limit attempts per account
+	auth := router.Group("/api/auth").Use(middlewares.RateLimit(deps.Limiter, ratelimit.GroupAuth, middlewares.ByIP))
 	{
-		auth.POST("/register", controllers.Register)
-		auth.POST("/login", controllers.Login)
+		auth.POST("/register", authCtrl.Register)
+		auth.POST("/login", authCtrl.Login)
+		auth.POST("/login/2fa", authCtrl.LoginMFA)
+		auth.POST("/refresh", authCtrl.Refresh)
+		auth.POST("/verify", authCtrl.VerifyEmail)
+		auth.POST("/forgot-password", authCtrl.ForgotPassword)
+		auth.POST("/reset-password", authCtrl.ResetPassword)
+
+		// Sign in with GitHub
+		auth.GET("/github/login", authCtrl.GithubLogin)
+		auth.GET("/github/callback", authCtrl.GithubCallback)
+	}
+
+	// Account management needs a login session; personal access tokens are rejected
+	session := []gin.HandlerFunc{middlewares.AuthMiddleware(deps.Repos), middlewares.SessionOnly(), middlewares.RateLimit(deps.Limiter, ratelimit.GroupAccount, middlewares.ByUser)}
+	signedIn := router.Group("/api/auth").Use(session...)
+	{
+		// Suspended and banned users may still sign out and see their tokens
+		signedIn.POST("/logout", authCtrl.Logout)
+		signedIn.GET("/tokens", authCtrl.ListAccessTokens)
 	}
-}
 
+	// Everything else changes credentials or the account, which restricted users may not do
+	account := router.Group("/api/auth").Use(append(session, access.ActiveAccount())...)
+	{
+		account.POST("/verify/resend", authCtrl.ResendVerification)
+		account.POST("/github/link", authCtrl.GithubLink)
+		account.PUT("/username", authCtrl.ChangeUsername)
+
+		// Two-factor authentication
+		account.POST("/2fa/setup", authCtrl.Setup2FA)
+		account.POST("/2fa/confirm", authCtrl.Confirm2FA)
+		account.POST("/2fa/disable", authCtrl.Disable2FA)
+		account.POST("/2fa/recovery-codes", authCtrl.RegenerateRecoveryCodes)
+
+		// Personal access tokens
+		account.POST("/tokens", authCtrl.CreateAccessToken)
+		account.DELETE("/tokens/:id", authCtrl.RevokeAccessToken)
+	}
+
+	// Public keys for services that verify GitConnect tokens
+	router.GET("/.well-known/jwks.json", controllers.JWKS)
+}
@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitconnect-backend/migrations"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterRejectsTakenUsernames(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp("octocat")
+
+	s.expect(http.StatusConflict, "POST", "/api/auth/register", "", gin.H{
+		"username": "OctoCat", "email": "other@example.com", "password": "correct horse",
+	})
+	s.expect(http.StatusConflict, "POST", "/api/auth/register", "", gin.H{
+		"username": "someone", "email": "octocat@example.com", "password": "correct horse",
+	})
+	s.expect(http.StatusBadRequest, "POST", "/api/auth/register", "", gin.H{
+		"username": "12345", "email": "digits@example.com", "password": "correct horse",
+	})
+}
+
+func TestLoginAndRefreshRotation(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp("octocat")
+
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "wrong password"})
+	login := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	s.expect(http.StatusOK, "GET", "/api/auth/tokens", login["token"].(string), nil)
+
+	first := login["refresh_token"].(string)
+	refreshed := s.expect(http.StatusOK, "POST", "/api/auth/refresh", "", gin.H{"refresh_token": first})
+	second := refreshed["refresh_token"].(string)
+	if second == first {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	s.expect(http.StatusOK, "GET", "/api/auth/tokens", refreshed["token"].(string), nil)
+
+	// Replaying the spent token revokes the whole family, including its successor
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/refresh", "", gin.H{"refresh_token": first})
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/refresh", "", gin.H{"refresh_token": second})
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp("octocat")
+	login := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	token := login["token"].(string)
+
+	s.expect(http.StatusOK, "POST", "/api/auth/logout", token, gin.H{"refresh_token": login["refresh_token"]})
+
+	body := s.expect(http.StatusUnauthorized, "GET", "/api/auth/tokens", token, nil)
+	if body["error"] != "Token has been revoked" {
+		t.Fatalf("got error %q, want the revoked token error", body["error"])
+	}
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/refresh", "", gin.H{"refresh_token": login["refresh_token"]})
+}
+
+func TestFailedLoginsLockTheAccount(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	s.signUp("octocat")
+
+	for i := 0; i < 5; i++ {
+		s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "wrong password"})
+	}
+
+	rec := s.do("POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d with Retry-After %q, want a 429 lockout", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestLoginAttemptsAreLimitedPerAccount(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{Requests: 2, Period: time.Minute, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"email": "nobody@example.com", "password": "guess"})
+	}
+	s.expect(http.StatusTooManyRequests, "POST", "/api/auth/login", "", gin.H{"email": "Nobody@Example.com ", "password": "guess"})
+}
+
+func TestVerifyEmailAndResetPassword(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.signUp("octocat")
+
+	verify := s.mail.lastToken(t, "octocat@example.com")
+	s.expect(http.StatusOK, "POST", "/api/auth/verify", "", gin.H{"token": verify})
+	s.expect(http.StatusBadRequest, "POST", "/api/auth/verify", "", gin.H{"token": verify})
+	s.expect(http.StatusConflict, "POST", "/api/auth/verify/resend", token, nil)
+
+	login := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	created := s.expect(http.StatusCreated, "POST", "/api/auth/tokens", token, gin.H{"name": "ci", "scopes": []string{models.ScopePostsWrite}})
+	script := field(created, "access_token", "token").(string)
+	s.expect(http.StatusCreated, "POST", "/api/posts", script, gin.H{"content": "before the reset"})
+	s.expect(http.StatusOK, "POST", "/api/auth/forgot-password", "", gin.H{"email": "octocat@example.com"})
+	s.expect(http.StatusOK, "POST", "/api/auth/forgot-password", "", gin.H{"email": "nobody@example.com"})
+
+	reset := s.mail.lastToken(t, "octocat@example.com")
+	s.expect(http.StatusOK, "POST", "/api/auth/reset-password", "", gin.H{"token": reset, "password": "battery staple"})
+	s.expect(http.StatusBadRequest, "POST", "/api/auth/reset-password", "", gin.H{"token": reset, "password": "battery staple"})
+
+	// The reset signs out every device, revokes every personal access token and replaces the password
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/refresh", "", gin.H{"refresh_token": login["refresh_token"]})
+	s.expect(http.StatusUnauthorized, "POST", "/api/posts", script, gin.H{"content": "after the reset"})
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "battery staple"})
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	s := newTestServer(t)
+	session, _ := s.signUp("octocat")
+
+	created := s.expect(http.StatusCreated, "POST", "/api/auth/tokens", session, gin.H{
+		"name": "ci", "scopes": []string{models.ScopePostsRead},
+	})
+	readOnly := field(created, "access_token", "token").(string)
+	created = s.expect(http.StatusCreated, "POST", "/api/auth/tokens", session, gin.H{
+		"name": "bot", "scopes": []string{models.ScopePostsWrite},
+	})
+	writer := field(created, "access_token", "token").(string)
+	writerID := field(created, "access_token", "id").(float64)
+
+	s.expect(http.StatusForbidden, "POST", "/api/posts", readOnly, gin.H{"content": "hello"})
+	s.expect(http.StatusCreated, "POST", "/api/posts", writer, gin.H{"content": "hello"})
+
+	// Tokens cannot manage the account, and stop working once revoked
+	s.expect(http.StatusForbidden, "GET", "/api/auth/tokens", writer, nil)
+	list := s.expect(http.StatusOK, "GET", "/api/auth/tokens", session, nil)
+	if tokens := list["access_tokens"].([]any); len(tokens) != 2 {
+		t.Fatalf("listed %d tokens, want 2", len(tokens))
+	}
+	s.expect(http.StatusOK, "DELETE", path("/api/auth/tokens/%d", int(writerID)), session, nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/posts", writer, gin.H{"content": "hello again"})
+
+	// Other users' tokens are not found
+	other, _ := s.signUp("hubot")
+	s.expect(http.StatusNotFound, "DELETE", path("/api/auth/tokens/%d", int(writerID)), other, nil)
+}
+
+func TestFailedLoginsLockLegacyAccounts(t *testing.T) {
+	// Accounts from before migration 0016 have no failure count at all
+	repos, db := postgresRepos(t, 15)
+	s := newTestServerOn(t, repos)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	s.signUp("octocat")
+	if err := db.Exec("UPDATE users SET failed_logins = NULL").Error; err != nil {
+		t.Fatalf("clearing failure counts: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "wrong password"})
+	}
+	s.expect(http.StatusTooManyRequests, "POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+
+	// Migrating counts the remaining legacy accounts from zero
+	s.signUp("hubot")
+	if err := db.Exec("UPDATE users SET failed_logins = NULL WHERE username = 'hubot'").Error; err != nil {
+		t.Fatalf("clearing failure counts: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	migrator, _ := migrations.New(sqlDB)
+	if err := migrator.To(context.Background(), 16); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	var failures []*int
+	db.Raw("SELECT failed_logins FROM users ORDER BY id").Scan(&failures)
+	if len(failures) != 2 || failures[0] == nil || *failures[0] != 5 || failures[1] == nil || *failures[1] != 0 {
+		t.Fatalf("got failure counts %v, want 5 and 0", failures)
+	}
+	if err := db.Exec("UPDATE users SET failed_logins = NULL").Error; err == nil {
+		t.Fatal("failed_logins still accepts NULL")
+	}
+}
+
+func TestSuspendedAccountsCannotIssueCredentials(t *testing.T) {
+	s := newTestServer(t)
+	session, userID := s.signUp("octocat")
+	// The login session outlives the suspension until it expires
+	if err := s.repos.Users.Suspend(context.Background(), userID, time.Now().Add(time.Hour), "spam"); err != nil {
+		t.Fatalf("suspending: %v", err)
+	}
+
+	s.expect(http.StatusForbidden, "POST", "/api/auth/tokens", session, gin.H{"name": "ci", "scopes": []string{models.ScopePostsRead}})
+	s.expect(http.StatusForbidden, "POST", "/api/auth/2fa/setup", session, nil)
+	s.expect(http.StatusForbidden, "POST", "/api/auth/verify/resend", session, nil)
+
+	// Signing out still works
+	s.expect(http.StatusOK, "GET", "/api/auth/tokens", session, nil)
+	s.expect(http.StatusOK, "POST", "/api/auth/logout", session, nil)
+}
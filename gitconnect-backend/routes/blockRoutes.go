@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func BlockRoutes(router *gin.Engine, deps Dependencies) {
+	blockCtrl := controllers.NewBlockController(deps.Repos)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Users the caller has blocked
+	router.GET("/api/blocks", middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopeProfileRead), blockCtrl.GetBlocks)
+
+	// Protected routes
+	protected := router.Group("/api/users").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopeFollowsWrite),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser), access.ActiveAccount())
+	{
+		// Block a user
+		protected.POST("/:id/block", blockCtrl.BlockUser)
+
+		// Unblock a user
+		protected.DELETE("/:id/block", blockCtrl.UnblockUser)
+	}
+}
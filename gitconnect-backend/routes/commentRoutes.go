@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func CommentRoutes(router *gin.Engine, deps Dependencies) {
+	commentCtrl := controllers.NewCommentController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+	commentOwner := middlewares.OwnerOf(deps.Repos.Comments.OwnerID)
+
+	// Protected routes; listing and creating comments live under /api/posts/:id/comments
+	protected := router.Group("/api/comments").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopePostsWrite),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser))
+	{
+		// Edit a comment (author only)
+		protected.PUT("/:id", access.RequireOwner(commentOwner), commentCtrl.UpdateComment)
+
+		// Delete a comment (author only; moderators use /api/admin/comments/:id)
+		protected.DELETE("/:id", access.RequireOwner(commentOwner), commentCtrl.DeleteComment)
+
+		// Like a comment
+		protected.POST("/:id/like", access.RequirePermission(models.PermCommentReact), commentCtrl.LikeComment)
+
+		// Dislike a comment
+		protected.POST("/:id/dislike", access.RequirePermission(models.PermCommentReact), commentCtrl.DislikeComment)
+
+		// Remove the caller's like or dislike
+		protected.DELETE("/:id/reaction", access.RequirePermission(models.PermCommentReact), commentCtrl.RemoveCommentReaction)
+	}
+}
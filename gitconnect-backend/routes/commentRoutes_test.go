@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCommentThreads(t *testing.T) {
+	s := newTestServer(t)
+	author, _ := s.signUp("octocat")
+	replier, _ := s.signUp("hubot")
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", author, gin.H{"content": "hello"})
+	postID := int(field(created, "post", "id").(float64))
+
+	comment := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", postID), author, gin.H{"content": "first"})
+	commentID := field(comment, "comment", "id").(float64)
+	reply := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", postID), replier, gin.H{"content": "a reply", "parent_id": commentID})
+	if depth := field(reply, "comment", "depth"); depth != 1.0 {
+		t.Fatalf("reply has depth %v, want 1", depth)
+	}
+	s.expect(http.StatusNotFound, "POST", path("/api/posts/%d/comments", postID), replier, gin.H{"content": "orphan", "parent_id": 999})
+
+	// The flat view lists each thread depth-first
+	list := s.expect(http.StatusOK, "GET", path("/api/posts/%d/comments", postID), "", nil)
+	comments := list["comments"].([]any)
+	if len(comments) != 2 || comments[0].(map[string]any)["content"] != "first" || comments[1].(map[string]any)["content"] != "a reply" {
+		t.Fatalf("got comments %v", comments)
+	}
+
+	// Only the author edits or deletes a comment
+	s.expect(http.StatusForbidden, "PUT", path("/api/comments/%d", int(commentID)), replier, gin.H{"content": "edited"})
+	edited := s.expect(http.StatusOK, "PUT", path("/api/comments/%d", int(commentID)), author, gin.H{"content": "edited"})
+	if field(edited, "comment", "edited_at") == nil {
+		t.Fatal("edit did not set edited_at")
+	}
+	s.expect(http.StatusForbidden, "DELETE", path("/api/comments/%d", int(commentID)), replier, nil)
+	s.expect(http.StatusOK, "DELETE", path("/api/comments/%d", int(commentID)), author, nil)
+
+	// A deleted comment with replies stays as a tombstone
+	tree := s.expect(http.StatusOK, "GET", path("/api/posts/%d/comments?view=tree", postID), "", nil)
+	roots := tree["comments"].([]any)
+	if len(roots) != 1 {
+		t.Fatalf("got %d threads, want 1", len(roots))
+	}
+	root := roots[0].(map[string]any)
+	if root["deleted_at"] == nil || root["content"] != "" || len(root["replies"].([]any)) != 1 {
+		t.Fatalf("got root %v, want a tombstone with one reply", root)
+	}
+}
+
+func TestCommentReactions(t *testing.T) {
+	s := newTestServer(t)
+	author, _ := s.signUp("octocat")
+	reader, _ := s.signUp("hubot")
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", author, gin.H{"content": "hello"})
+	postID := int(field(created, "post", "id").(float64))
+	comment := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", postID), author, gin.H{"content": "first"})
+	id := int(field(comment, "comment", "id").(float64))
+
+	body := s.expect(http.StatusOK, "POST", path("/api/comments/%d/like", id), reader, nil)
+	if body["likes"] != 1.0 || body["my_reaction"] != "like" {
+		t.Fatalf("after like got %v", body)
+	}
+	body = s.expect(http.StatusOK, "POST", path("/api/comments/%d/dislike", id), reader, nil)
+	if body["likes"] != 0.0 || body["dislikes"] != 1.0 || body["my_reaction"] != "dislike" {
+		t.Fatalf("after dislike got %v", body)
+	}
+
+	list := s.expect(http.StatusOK, "GET", path("/api/posts/%d/comments", postID), reader, nil)
+	if mine := list["comments"].([]any)[0].(map[string]any)["my_reaction"]; mine != "dislike" {
+		t.Fatalf("listed my_reaction %v, want dislike", mine)
+	}
+
+	body = s.expect(http.StatusOK, "DELETE", path("/api/comments/%d/reaction", id), reader, nil)
+	if body["likes"] != 0.0 || body["dislikes"] != 0.0 {
+		t.Fatalf("after removing the reaction got %v", body)
+	}
+	s.expect(http.StatusNotFound, "POST", "/api/comments/999/like", reader, nil)
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func FollowRoutes(router *gin.Engine, deps Dependencies) {
+	followCtrl := controllers.NewFollowController(deps.Repos, deps.Events)
+	postCtrl := controllers.NewPostController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Public routes: follower and following lists
+	router.GET("/api/users/:id/followers", followCtrl.GetFollowers)
+	router.GET("/api/users/:id/following", followCtrl.GetFollowing)
+
+	// Protected routes
+	protected := router.Group("/api/users").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopeFollowsWrite),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser))
+	{
+		// Follow a user
+		protected.POST("/:id/follow", access.RequirePermission(models.PermUserFollow), followCtrl.FollowUser)
+
+		// Unfollow a user
+		protected.DELETE("/:id/follow", access.RequirePermission(models.PermUserFollow), followCtrl.UnfollowUser)
+	}
+
+	// Home feed: the caller's posts and those of everyone they follow
+	router.GET("/api/feed", middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopePostsRead), postCtrl.GetFeed)
+}
@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// post creates a post and returns its ID
+func (s *testServer) post(token, content string) int {
+	s.t.Helper()
+	body := s.expect(http.StatusCreated, "POST", "/api/posts", token, gin.H{"content": content})
+	return int(field(body, "post", "id").(float64))
+}
+
+// followCounts returns the follower and following counts shown for a user
+func (s *testServer) followCounts(userID uint) (float64, float64) {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "GET", path("/api/users/%d", userID), "", nil)
+	return field(body, "counts", "followers").(float64), field(body, "counts", "following").(float64)
+}
+
+// pages follows next_cursor through a paginated list, returning the IDs of the items under key
+func (s *testServer) pages(token, route, key string, limit int) []int {
+	s.t.Helper()
+	target, err := url.Parse(route)
+	if err != nil {
+		s.t.Fatalf("parsing %s: %v", route, err)
+	}
+	query := target.Query()
+	query.Set("limit", fmt.Sprint(limit))
+
+	var ids []int
+	for {
+		target.RawQuery = query.Encode()
+		body := s.expect(http.StatusOK, "GET", target.String(), token, nil)
+		items := body[key].([]any)
+		if len(items) > limit {
+			s.t.Fatalf("got %d items on a page of %d", len(items), limit)
+		}
+		for _, item := range items {
+			ids = append(ids, int(item.(map[string]any)["id"].(float64)))
+		}
+		next, _ := body["next_cursor"].(string)
+		if next == "" {
+			return ids
+		}
+		query.Set("cursor", next)
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+
+	// Following twice is a no-op
+	for i := 0; i < 2; i++ {
+		body := s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", bobID), alice, nil)
+		if body["followers_count"] != 1.0 {
+			t.Fatalf("following again gave %v followers, want 1", body["followers_count"])
+		}
+	}
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", aliceID), bob, nil)
+	if followers, following := s.followCounts(bobID); followers != 1 || following != 1 {
+		t.Fatalf("bob has %v followers and follows %v, want 1 and 1", followers, following)
+	}
+	list := s.expect(http.StatusOK, "GET", path("/api/users/%d/followers", bobID), "", nil)
+	if followers := list["followers"].([]any); len(followers) != 1 || followers[0].(map[string]any)["username"] != "alice" {
+		t.Fatalf("bob's followers %v, want alice", followers)
+	}
+
+	// So is unfollowing twice
+	for i := 0; i < 2; i++ {
+		body := s.expect(http.StatusOK, "DELETE", path("/api/users/%d/follow", bobID), alice, nil)
+		if body["followers_count"] != 0.0 {
+			t.Fatalf("unfollowing gave %v followers, want 0", body["followers_count"])
+		}
+	}
+	if followers, following := s.followCounts(aliceID); followers != 1 || following != 0 {
+		t.Fatalf("alice has %v followers and follows %v, want 1 and 0", followers, following)
+	}
+
+	s.expect(http.StatusBadRequest, "POST", path("/api/users/%d/follow", aliceID), alice, nil)
+	s.expect(http.StatusNotFound, "POST", path("/api/users/%d/follow", bobID+100), alice, nil)
+	s.expect(http.StatusNotFound, "GET", path("/api/users/%d/following", bobID+100), "", nil)
+	if followers, following := s.followCounts(aliceID); followers != 1 || following != 0 {
+		t.Fatalf("rejected follows changed alice's counts to %v and %v", followers, following)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", bobID), alice, nil)
+
+	// The feed holds the caller's posts and those of the users they follow, newest first
+	var want, own []int
+	for i := 0; i < 4; i++ {
+		want = append([]int{s.post(alice, fmt.Sprintf("alice %d", i))}, want...)
+		own = append([]int{want[0]}, own...)
+		want = append([]int{s.post(bob, fmt.Sprintf("bob %d", i))}, want...)
+		s.post(carol, fmt.Sprintf("carol %d", i))
+	}
+	if got := s.pages(alice, "/api/feed", "posts", 100); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("feed %v, want %v", got, want)
+	}
+
+	// Small pages add up to the same feed, in both orders
+	for limit := 1; limit <= 3; limit++ {
+		if got := s.pages(alice, "/api/feed", "posts", limit); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("pages of %d: %v, want %v", limit, got, want)
+		}
+	}
+	oldest := make([]int, len(want))
+	for i, id := range want {
+		oldest[len(want)-1-i] = id
+	}
+	if got := s.pages(alice, "/api/feed?sort=oldest", "posts", 3); fmt.Sprint(got) != fmt.Sprint(oldest) {
+		t.Fatalf("oldest first: %v, want %v", got, oldest)
+	}
+
+	// Posting while paging neither repeats nor skips a post
+	first := s.expect(http.StatusOK, "GET", "/api/feed?limit=3", alice, nil)
+	s.post(bob, "late")
+	rest := s.pages(alice, "/api/feed?cursor="+url.QueryEscape(first["next_cursor"].(string)), "posts", 3)
+	if fmt.Sprint(rest) != fmt.Sprint(want[3:]) {
+		t.Fatalf("after a new post, the rest of the feed is %v, want %v", rest, want[3:])
+	}
+
+	// Unfollowing takes the user's posts out
+	s.expect(http.StatusOK, "DELETE", path("/api/users/%d/follow", bobID), alice, nil)
+	if got := s.pages(alice, "/api/feed", "posts", 100); fmt.Sprint(got) != fmt.Sprint(own) {
+		t.Fatalf("after unfollowing, feed %v, want alice's own %v", got, own)
+	}
+}
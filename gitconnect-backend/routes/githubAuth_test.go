@@ -0,0 +1,248 @@
+package routes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeGithub serves the OAuth token endpoint and the user API. The token
+// endpoint only accepts the code together with the verifier of the last PKCE
+// challenge the test recorded.
+type fakeGithub struct {
+	*httptest.Server
+	mu        sync.Mutex
+	challenge string
+	exchanges int
+	userID    int64
+	login     string
+	email     string
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	t.Helper()
+	gh := &fakeGithub{userID: 42, login: "octocat", email: "octocat@github.test"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("client_id") != "client-id" || r.FormValue("client_secret") != "client-secret" ||
+			r.FormValue("code") != "good-code" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != gh.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		gh.exchanges++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_test", "token_type": "bearer"})
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer gho_test" {
+				http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("GET /user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"id": gh.userID, "login": gh.login, "name": "Octo Cat"})
+	}))
+	mux.HandleFunc("GET /user/emails", authorized(func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "secondary@github.test", "primary": false, "verified": true},
+			{"email": gh.email, "primary": true, "verified": true},
+		})
+	}))
+
+	gh.Server = httptest.NewServer(mux)
+	t.Cleanup(gh.Close)
+
+	t.Setenv("GITHUB_CLIENT_ID", "client-id")
+	t.Setenv("GITHUB_CLIENT_SECRET", "client-secret")
+	t.Setenv("GITHUB_REDIRECT_URL", "http://api.test/api/auth/github/callback")
+	t.Setenv("GITHUB_AUTH_URL", gh.URL+"/login/oauth/authorize")
+	t.Setenv("GITHUB_TOKEN_URL", gh.URL+"/login/oauth/access_token")
+	t.Setenv("GITHUB_API_URL", gh.URL)
+	t.Setenv("APP_BASE_URL", "http://app.test")
+	return gh
+}
+
+// authorize follows a response that redirects to the provider, recording its PKCE
+// challenge, and returns the state and the state cookie
+func (gh *fakeGithub) authorize(t *testing.T, location string, cookies []*http.Cookie) (string, *http.Cookie) {
+	t.Helper()
+
+	authURL, err := url.Parse(location)
+	if err != nil || authURL.Path != "/login/oauth/authorize" {
+		t.Fatalf("redirected to %q, want the provider", location)
+	}
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request has no S256 challenge: %s", location)
+	}
+	gh.mu.Lock()
+	gh.challenge = query.Get("code_challenge")
+	gh.mu.Unlock()
+
+	for _, cookie := range cookies {
+		if cookie.Name == "github_oauth_state" {
+			if cookie.Value != query.Get("state") || !cookie.HttpOnly {
+				t.Fatalf("state cookie %+v does not match state %q", cookie, query.Get("state"))
+			}
+			return query.Get("state"), cookie
+		}
+	}
+	t.Fatal("no state cookie set")
+	return "", nil
+}
+
+// startGithubLogin begins a sign-in and returns the state and its cookie
+func (s *testServer) startGithubLogin(gh *fakeGithub) (string, *http.Cookie) {
+	s.t.Helper()
+	rec := s.do("GET", "/api/auth/github/login", "", nil)
+	if rec.Code != http.StatusFound {
+		s.t.Fatalf("login: got %d: %s", rec.Code, rec.Body.String())
+	}
+	return gh.authorize(s.t, rec.Header().Get("Location"), rec.Result().Cookies())
+}
+
+// githubCallback completes the flow and returns the values in the fragment of the frontend redirect
+func (s *testServer) githubCallback(code, state string, cookie *http.Cookie) url.Values {
+	s.t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/auth/github/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || err != nil || location.Host != "app.test" || location.Path != "/oauth/github" {
+		s.t.Fatalf("callback: got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		s.t.Fatalf("callback fragment %q: %v", location.Fragment, err)
+	}
+	return values
+}
+
+func TestGithubLoginCreatesAccount(t *testing.T) {
+	s := newTestServer(t)
+	gh := newFakeGithub(t)
+
+	state, cookie := s.startGithubLogin(gh)
+	values := s.githubCallback("good-code", state, cookie)
+	if values.Get("error") != "" || values.Get("token") == "" || values.Get("refresh_token") == "" {
+		t.Fatalf("callback returned %v", values)
+	}
+
+	// The state is spent, so the callback cannot be replayed
+	if values := s.githubCallback("good-code", state, cookie); values.Get("error") != "invalid_state" {
+		t.Fatalf("replayed callback returned %v", values)
+	}
+
+	user := s.expect(http.StatusOK, "GET", "/api/users/octocat", "", nil)
+	if field(user, "user", "username") != "octocat" {
+		t.Fatalf("got user %v", user)
+	}
+
+	// Signing in again finds the same account by its GitHub ID, even after a rename on GitHub
+	gh.mu.Lock()
+	gh.login = "octo-renamed"
+	gh.mu.Unlock()
+	state, cookie = s.startGithubLogin(gh)
+	if values := s.githubCallback("good-code", state, cookie); values.Get("token") == "" {
+		t.Fatalf("second login returned %v", values)
+	}
+	if gh.exchanges != 2 {
+		t.Fatalf("got %d code exchanges, want 2", gh.exchanges)
+	}
+	s.expect(http.StatusNotFound, "GET", "/api/users/octo-renamed", "", nil)
+}
+
+func TestGithubCallbackRejectsForgedRequests(t *testing.T) {
+	s := newTestServer(t)
+	gh := newFakeGithub(t)
+
+	state, cookie := s.startGithubLogin(gh)
+	if values := s.githubCallback("good-code", state, nil); values.Get("error") != "invalid_state" {
+		t.Fatalf("callback without the state cookie returned %v", values)
+	}
+	if values := s.githubCallback("good-code", "forged", &http.Cookie{Name: "github_oauth_state", Value: "forged"}); values.Get("error") != "invalid_state" {
+		t.Fatalf("callback with an unknown state returned %v", values)
+	}
+
+	// A code exchanged without the verifier of this request's challenge is refused
+	gh.mu.Lock()
+	gh.challenge = "other-challenge"
+	gh.mu.Unlock()
+	if values := s.githubCallback("good-code", state, cookie); values.Get("error") != "server_error" {
+		t.Fatalf("callback with the wrong verifier returned %v", values)
+	}
+	if gh.exchanges != 0 {
+		t.Fatalf("got %d code exchanges, want none", gh.exchanges)
+	}
+}
+
+func TestGithubLoginDoesNotTakeOverUnverifiedAccounts(t *testing.T) {
+	s := newTestServer(t)
+	gh := newFakeGithub(t)
+	s.expect(http.StatusCreated, "POST", "/api/auth/register", "", gin.H{
+		"username": "squatter", "email": gh.email, "password": "correct horse",
+	})
+
+	state, cookie := s.startGithubLogin(gh)
+	if values := s.githubCallback("good-code", state, cookie); values.Get("error") != "email_in_use" {
+		t.Fatalf("callback returned %v", values)
+	}
+}
+
+func TestGithubLinkAttachesIdentity(t *testing.T) {
+	s := newTestServer(t)
+	gh := newFakeGithub(t)
+	session, userID := s.signUp("hubot")
+
+	rec := s.do("POST", "/api/auth/github/link", session, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("link: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	state, cookie := gh.authorize(t, body["authorization_url"], rec.Result().Cookies())
+
+	if values := s.githubCallback("good-code", state, cookie); values.Get("token") == "" {
+		t.Fatalf("callback returned %v", values)
+	}
+	profile, err := s.repos.Profiles.FindByGithubID(context.Background(), gh.userID)
+	if err != nil || profile.UserID != userID || profile.GithubLogin != "octocat" {
+		t.Fatalf("got profile %+v, %v", profile, err)
+	}
+
+	// The identity cannot then be linked to a second account
+	other, _ := s.signUp("someone")
+	rec = s.do("POST", "/api/auth/github/link", other, nil)
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	state, cookie = gh.authorize(t, body["authorization_url"], rec.Result().Cookies())
+	if values := s.githubCallback("good-code", state, cookie); values.Get("error") != "github_account_in_use" {
+		t.Fatalf("second link returned %v", values)
+	}
+}
@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notifications lists the caller's notifications, newest first
+func (s *testServer) notifications(token string) []map[string]any {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "GET", "/api/notifications", token, nil)
+	var notifications []map[string]any
+	for _, notification := range body["notifications"].([]any) {
+		notifications = append(notifications, notification.(map[string]any))
+	}
+	return notifications
+}
+
+// mentions summarizes the mentions of a post or a comment as username[start:end]
+func mentions(item map[string]any) string {
+	var summary []string
+	for _, mention := range item["mentions"].([]any) {
+		m := mention.(map[string]any)
+		summary = append(summary, fmt.Sprintf("%v[%v:%v]", m["username"], m["start"], m["end"]))
+	}
+	return fmt.Sprint(summary)
+}
+
+// mentionsOf counts the caller's mention notifications
+func (s *testServer) mentionsOf(token string) int {
+	s.t.Helper()
+	count := 0
+	for _, notification := range s.notifications(token) {
+		if notification["type"] == "mention" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPostMentions(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+
+	// Usernames match whatever their case, and unknown ones are left as text
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", bob, gin.H{"content": "thanks @ALICE and @nobody, mail bob@example.com"})
+	post := created["post"].(map[string]any)
+	if got := mentions(post); got != "[alice[7:13]]" {
+		t.Fatalf("got mentions %s, want alice at [7:13]", got)
+	}
+	if got := s.mentionsOf(alice); got != 1 {
+		t.Fatalf("alice has %d mention notifications, want 1", got)
+	}
+	if notification := s.notifications(alice)[0]; notification["post_id"] != post["id"] || field(notification, "actor", "username") != "bob" {
+		t.Fatalf("got notification %v, want bob's post", notification)
+	}
+
+	// Editing only notifies the users it newly mentions
+	edited := s.expect(http.StatusOK, "PUT", path("/api/posts/%d", int(post["id"].(float64))), bob, gin.H{"content": "@carol, thanks @alice"})
+	if got := mentions(edited["post"].(map[string]any)); got != "[carol[0:6] alice[15:21]]" {
+		t.Fatalf("after editing, got mentions %s", got)
+	}
+	if alices, carols := s.mentionsOf(alice), s.mentionsOf(carol); alices != 1 || carols != 1 {
+		t.Fatalf("after editing, alice has %d mention notifications and carol %d, want 1 each", alices, carols)
+	}
+
+	// Mentioning oneself notifies no one
+	s.expect(http.StatusCreated, "POST", "/api/posts", bob, gin.H{"content": "note to @bob"})
+	if got := s.mentionsOf(bob); got != 0 {
+		t.Fatalf("bob has %d mention notifications of himself", got)
+	}
+}
+
+func TestCommentMentions(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	postID := s.post(alice, "hello")
+
+	comment := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", postID), bob, gin.H{"content": "cc @alice"})
+	commentID := field(comment, "comment", "id")
+	if got := mentions(comment["comment"].(map[string]any)); got != "[alice[3:9]]" {
+		t.Fatalf("got mentions %s, want alice at [3:9]", got)
+	}
+	var mentioned map[string]any
+	for _, notification := range s.notifications(alice) {
+		if notification["type"] == "mention" {
+			mentioned = notification
+		}
+	}
+	if mentioned == nil || mentioned["comment_id"] != commentID || mentioned["post_id"] != float64(postID) {
+		t.Fatalf("got notifications %v, want a mention in comment %v", s.notifications(alice), commentID)
+	}
+
+	// Someone who blocked the author is not notified, though the mention is still linked
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/block", bobID), alice, nil)
+	comment = s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", postID), bob, gin.H{"content": "@alice again"})
+	if got := mentions(comment["comment"].(map[string]any)); got != "[alice[0:6]]" {
+		t.Fatalf("got mentions %s, want alice at [0:6]", got)
+	}
+	if got := s.mentionsOf(alice); got != 1 {
+		t.Fatalf("alice has %d mention notifications after blocking bob, want 1", got)
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func MessageRoutes(router *gin.Engine, deps Dependencies) {
+	messageCtrl := controllers.NewMessageController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Protected routes
+	protected := router.Group("/api/conversations").Use(middlewares.AuthMiddleware(deps.Repos))
+	{
+		readScope := middlewares.RequireScope(models.ScopeMessagesRead)
+		writeScope := middlewares.RequireScope(models.ScopeMessagesWrite)
+		writeLimit := middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser)
+		send := access.RequirePermission(models.PermMessageSend)
+		active := access.ActiveAccount()
+
+		// The caller's inbox and message requests
+		protected.GET("", readScope, messageCtrl.GetConversations)
+		protected.GET("/unread-count", readScope, messageCtrl.GetUnreadCount)
+		protected.POST("", writeScope, writeLimit, send, messageCtrl.CreateConversation)
+
+		// One conversation
+		protected.GET("/:id", readScope, messageCtrl.GetConversation)
+		protected.POST("/:id/accept", writeScope, writeLimit, active, messageCtrl.AcceptConversation)
+		protected.DELETE("/:id", writeScope, writeLimit, active, messageCtrl.LeaveConversation)
+
+		// Its messages and the caller's read receipt
+		protected.GET("/:id/messages", readScope, messageCtrl.GetMessages)
+		protected.POST("/:id/messages", writeScope, writeLimit, send, messageCtrl.SendMessage)
+		protected.POST("/:id/read", writeScope, writeLimit, active, messageCtrl.MarkRead)
+	}
+}
@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// startConversation starts a conversation from the caller with users, returning its ID
+func (s *testServer) startConversation(token string, status int, userIDs ...uint) int {
+	s.t.Helper()
+	body := s.expect(status, "POST", "/api/conversations", token, gin.H{"user_ids": userIDs})
+	return int(field(body, "conversation", "id").(float64))
+}
+
+// sendMessage sends content to a conversation, returning the message ID
+func (s *testServer) sendMessage(token string, conversationID int, content string) int {
+	s.t.Helper()
+	body := s.expect(http.StatusCreated, "POST", path("/api/conversations/%d/messages", conversationID), token, gin.H{"content": content})
+	return int(field(body, "data", "id").(float64))
+}
+
+// conversationIDs lists the IDs in the caller's inbox, or in their message requests
+func (s *testServer) conversationIDs(token string, requests bool) []int {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/conversations?requests=%v", requests), token, nil)
+	ids := []int{}
+	for _, conversation := range body["conversations"].([]any) {
+		ids = append(ids, int(conversation.(map[string]any)["id"].(float64)))
+	}
+	return ids
+}
+
+func TestMessageRequests(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+	dave, daveID := s.signUp("dave")
+
+	// Bob does not follow Alice, so her message waits in his requests
+	fromAlice := s.startConversation(alice, http.StatusCreated, bobID)
+	s.sendMessage(alice, fromAlice, "hi bob")
+	if inbox, requests := s.conversationIDs(bob, false), s.conversationIDs(bob, true); len(inbox) != 0 || fmt.Sprint(requests) != fmt.Sprint([]int{fromAlice}) {
+		t.Fatalf("bob's inbox %v and requests %v, want only a request", inbox, requests)
+	}
+	if inbox := s.conversationIDs(alice, false); fmt.Sprint(inbox) != fmt.Sprint([]int{fromAlice}) {
+		t.Fatalf("alice's inbox %v, want the conversation she started", inbox)
+	}
+	counts := s.expect(http.StatusOK, "GET", "/api/conversations/unread-count", bob, nil)
+	if counts["unread"] != 0.0 || counts["requests"] != 1.0 {
+		t.Fatalf("got counts %v, want one request and nothing unread", counts)
+	}
+	s.expect(http.StatusForbidden, "POST", path("/api/conversations/%d/read", fromAlice), bob, nil)
+
+	// Replying accepts it
+	s.sendMessage(bob, fromAlice, "hi alice")
+	if inbox, requests := s.conversationIDs(bob, false), s.conversationIDs(bob, true); fmt.Sprint(inbox) != fmt.Sprint([]int{fromAlice}) || len(requests) != 0 {
+		t.Fatalf("after replying, bob's inbox %v and requests %v", inbox, requests)
+	}
+
+	// So does accepting, which is a no-op for a conversation already in the inbox
+	fromCarol := s.startConversation(carol, http.StatusCreated, bobID)
+	s.expect(http.StatusOK, "POST", path("/api/conversations/%d/accept", fromCarol), bob, nil)
+	s.expect(http.StatusOK, "POST", path("/api/conversations/%d/accept", fromCarol), bob, nil)
+	if requests := s.conversationIDs(bob, true); len(requests) != 0 {
+		t.Fatalf("after accepting, bob's requests %v", requests)
+	}
+	s.expect(http.StatusNotFound, "POST", path("/api/conversations/%d/accept", fromCarol), dave, nil)
+
+	// Messages from someone Bob follows go straight to his inbox
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", daveID), bob, nil)
+	fromDave := s.startConversation(dave, http.StatusCreated, bobID)
+	if requests := s.conversationIDs(bob, true); len(requests) != 0 {
+		t.Fatalf("a followed user's conversation %d landed in requests %v", fromDave, requests)
+	}
+}
+
+func TestBlocksCloseConversations(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	_, carolID := s.signUp("carol")
+	direct := s.startConversation(alice, http.StatusCreated, bobID)
+	group := s.startConversation(alice, http.StatusCreated, bobID, carolID)
+
+	// Alice blocking Bob and Bob blocking Alice close the same doors
+	for _, block := range []struct {
+		token     string
+		blockedID uint
+	}{{alice, bobID}, {bob, aliceID}} {
+		s.expect(http.StatusOK, "POST", path("/api/users/%d/block", block.blockedID), block.token, nil)
+
+		// Neither side can start a conversation with the other, nor add them to a group
+		s.expect(http.StatusForbidden, "POST", "/api/conversations", alice, gin.H{"user_ids": []uint{bobID}})
+		s.expect(http.StatusForbidden, "POST", "/api/conversations", bob, gin.H{"user_ids": []uint{aliceID}})
+		s.expect(http.StatusForbidden, "POST", "/api/conversations", alice, gin.H{"user_ids": []uint{carolID, bobID}})
+
+		// Nor send in their direct conversation, though a shared group stays open
+		s.expect(http.StatusForbidden, "POST", path("/api/conversations/%d/messages", direct), alice, gin.H{"content": "hello?"})
+		s.expect(http.StatusForbidden, "POST", path("/api/conversations/%d/messages", direct), bob, gin.H{"content": "hello?"})
+		s.sendMessage(bob, group, "still here")
+
+		s.expect(http.StatusOK, "DELETE", path("/api/users/%d/block", block.blockedID), block.token, nil)
+		s.sendMessage(alice, direct, "hello again")
+		s.sendMessage(bob, direct, "hello again")
+	}
+}
+
+func TestDirectConversationsAreDeduplicated(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	_, carolID := s.signUp("carol")
+
+	direct := s.startConversation(alice, http.StatusCreated, bobID)
+	if again := s.startConversation(alice, http.StatusOK, bobID); again != direct {
+		t.Fatalf("starting again gave conversation %d, want %d", again, direct)
+	}
+	if reverse := s.startConversation(bob, http.StatusOK, aliceID); reverse != direct {
+		t.Fatalf("starting from the other side gave conversation %d, want %d", reverse, direct)
+	}
+	// The caller and repeated IDs do not count as other members
+	if repeated := s.startConversation(alice, http.StatusOK, bobID, aliceID, bobID); repeated != direct {
+		t.Fatalf("repeated IDs gave conversation %d, want %d", repeated, direct)
+	}
+
+	// Groups are never merged
+	first := s.startConversation(alice, http.StatusCreated, bobID, carolID)
+	if second := s.startConversation(alice, http.StatusCreated, bobID, carolID); second == first {
+		t.Fatal("two groups with the same members were merged")
+	}
+	s.expect(http.StatusBadRequest, "POST", "/api/conversations", alice, gin.H{"user_ids": []uint{aliceID}})
+	s.expect(http.StatusNotFound, "POST", "/api/conversations", alice, gin.H{"user_ids": []uint{carolID + 100}})
+}
+
+func TestUnreadCountsAndReadReceipts(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", aliceID), bob, nil)
+
+	conversation := s.startConversation(alice, http.StatusCreated, bobID)
+	var messages []int
+	for i := 0; i < 3; i++ {
+		messages = append(messages, s.sendMessage(alice, conversation, fmt.Sprintf("message %d", i)))
+	}
+
+	unread := func(token string) float64 {
+		t.Helper()
+		return field(s.expect(http.StatusOK, "GET", path("/api/conversations/%d", conversation), token, nil), "conversation", "unread_count").(float64)
+	}
+	if got := unread(bob); got != 3 {
+		t.Fatalf("bob has %v unread, want 3", got)
+	}
+	if got := unread(alice); got != 0 {
+		t.Fatalf("alice has %v unread of her own messages, want 0", got)
+	}
+	if counts := s.expect(http.StatusOK, "GET", "/api/conversations/unread-count", bob, nil); counts["unread"] != 1.0 {
+		t.Fatalf("got counts %v, want one unread conversation", counts)
+	}
+
+	receipt := s.expect(http.StatusOK, "POST", path("/api/conversations/%d/read", conversation), bob, gin.H{"message_id": messages[1]})
+	if receipt["last_read_message_id"] != float64(messages[1]) || unread(bob) != 1 {
+		t.Fatalf("after reading message 2, got receipt %v and %v unread", receipt, unread(bob))
+	}
+
+	// A receipt never moves back
+	receipt = s.expect(http.StatusOK, "POST", path("/api/conversations/%d/read", conversation), bob, gin.H{"message_id": messages[0]})
+	if receipt["last_read_message_id"] != float64(messages[1]) || unread(bob) != 1 {
+		t.Fatalf("reading an older message moved the receipt to %v", receipt["last_read_message_id"])
+	}
+
+	// With no message ID, everything is read
+	receipt = s.expect(http.StatusOK, "POST", path("/api/conversations/%d/read", conversation), bob, nil)
+	if receipt["last_read_message_id"] != float64(messages[2]) || unread(bob) != 0 {
+		t.Fatalf("reading everything gave receipt %v and %v unread", receipt, unread(bob))
+	}
+	if counts := s.expect(http.StatusOK, "GET", "/api/conversations/unread-count", bob, nil); counts["unread"] != 0.0 {
+		t.Fatalf("got counts %v, want nothing unread", counts)
+	}
+	s.expect(http.StatusNotFound, "POST", path("/api/conversations/%d/read", conversation), bob, gin.H{"message_id": messages[2] + 100})
+}
+
+func TestConversationMemberLimit(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupAuth] = ratelimit.Limit{}
+	alice, _ := s.signUp("alice")
+
+	var others []uint
+	for i := 0; i < models.MaxConversationMembers; i++ {
+		_, userID := s.signUp(fmt.Sprintf("user%c", 'a'+i))
+		others = append(others, userID)
+	}
+
+	// The limit counts the caller
+	s.expect(http.StatusBadRequest, "POST", "/api/conversations", alice, gin.H{"user_ids": others})
+	s.startConversation(alice, http.StatusCreated, others[:models.MaxConversationMembers-1]...)
+}
@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gitconnect-backend/ratelimit"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// enable2FA turns on two-factor login for the user with session, returning the
+// TOTP secret and the recovery codes
+func (s *testServer) enable2FA(session string) (string, []string) {
+	s.t.Helper()
+
+	setup := s.expect(http.StatusOK, "POST", "/api/auth/2fa/setup", session, nil)
+	secret := setup["secret"].(string)
+	confirmed := s.expect(http.StatusOK, "POST", "/api/auth/2fa/confirm", session, gin.H{"code": s.totp(secret, 0)})
+
+	var codes []string
+	for _, code := range confirmed["recovery_codes"].([]any) {
+		codes = append(codes, code.(string))
+	}
+	return secret, codes
+}
+
+// totp returns the code for the time step steps away from now
+func (s *testServer) totp(secret string, steps int) string {
+	s.t.Helper()
+	code, err := utils.TOTPCode(secret, time.Now().Add(time.Duration(steps)*30*time.Second))
+	if err != nil {
+		s.t.Fatalf("computing code: %v", err)
+	}
+	return code
+}
+
+// mfaToken completes the password step for username and returns the pending token
+func (s *testServer) mfaToken(username string) string {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": username + "@example.com", "password": "correct horse"})
+	if body["mfa_required"] != true || body["token"] != nil {
+		s.t.Fatalf("password step returned %v, want only an mfa_token", body)
+	}
+	return body["mfa_token"].(string)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	session, _ := s.signUp("octocat")
+	secret, _ := s.enable2FA(session)
+
+	// The pending token is not an access token
+	pending := s.mfaToken("octocat")
+	s.expect(http.StatusUnauthorized, "GET", "/api/auth/tokens", pending, nil)
+
+	// The code that confirmed enrollment has been used, so it cannot log in
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": pending, "code": s.totp(secret, 0)})
+
+	next := s.totp(secret, 1)
+	login := s.expect(http.StatusOK, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "code": next})
+	s.expect(http.StatusOK, "GET", "/api/auth/tokens", login["token"].(string), nil)
+
+	// Nor can any code replay a step already accepted
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "code": next})
+}
+
+func TestMFATokensAllowOneAttempt(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	session, _ := s.signUp("octocat")
+	secret, _ := s.enable2FA(session)
+
+	// A wrong code spends the token, so the right code cannot follow it
+	pending := s.mfaToken("octocat")
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": pending, "code": "000000"})
+	body := s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": pending, "code": s.totp(secret, 1)})
+	if body["error"] != "Invalid or expired MFA token" {
+		t.Fatalf("got error %q, want the spent token to be refused", body["error"])
+	}
+
+	// So does a successful login
+	pending = s.mfaToken("octocat")
+	s.expect(http.StatusOK, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": pending, "code": s.totp(secret, 1)})
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": pending, "code": s.totp(secret, 1)})
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	session, _ := s.signUp("octocat")
+	_, codes := s.enable2FA(session)
+	if len(codes) != 10 {
+		t.Fatalf("got %d recovery codes, want 10", len(codes))
+	}
+
+	// Codes are accepted however they are typed back, but only once
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	s.expect(http.StatusOK, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "recovery_code": typed})
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "recovery_code": codes[0]})
+	s.expect(http.StatusOK, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "recovery_code": codes[1]})
+}
+
+func TestWrongSecondFactorsLockTheAccount(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupLogin] = ratelimit.Limit{}
+	session, _ := s.signUp("octocat")
+	secret, _ := s.enable2FA(session)
+
+	// Knowing the password does not reset the count between wrong codes
+	early := s.mfaToken("octocat")
+	for i := 0; i < 5; i++ {
+		s.expect(http.StatusUnauthorized, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": s.mfaToken("octocat"), "code": "000000"})
+	}
+	user, err := s.repos.Users.FindByEmail(context.Background(), "octocat@example.com")
+	if err != nil || user.FailedLogins != 5 {
+		t.Fatalf("got %d failed logins (%v), want 5", user.FailedLogins, err)
+	}
+
+	rec := s.do("POST", "/api/auth/login", "", gin.H{"email": "octocat@example.com", "password": "correct horse"})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d with Retry-After %q, want a 429 lockout", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// A token issued before the lockout cannot get around it, even with the right code
+	s.expect(http.StatusTooManyRequests, "POST", "/api/auth/login/2fa", "", gin.H{"mfa_token": early, "code": s.totp(secret, 1)})
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func NotificationRoutes(router *gin.Engine, deps Dependencies) {
+	notificationCtrl := controllers.NewNotificationController(deps.Repos)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Protected routes
+	protected := router.Group("/api/notifications").Use(middlewares.AuthMiddleware(deps.Repos))
+	{
+		readScope := middlewares.RequireScope(models.ScopeNotificationsRead)
+		writeScope := middlewares.RequireScope(models.ScopeNotificationsWrite)
+		writeLimit := middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser)
+		active := access.ActiveAccount()
+
+		// The caller's notifications and how many are unread
+		protected.GET("", readScope, notificationCtrl.GetNotifications)
+		protected.GET("/unread-count", readScope, notificationCtrl.GetUnreadCount)
+
+		// Read state
+		protected.POST("/:id/read", writeScope, writeLimit, active, notificationCtrl.MarkRead)
+		protected.POST("/read-all", writeScope, writeLimit, active, notificationCtrl.MarkAllRead)
+
+		// Which notification types the caller wants
+		protected.GET("/preferences", readScope, notificationCtrl.GetPreferences)
+		protected.PUT("/preferences", writeScope, writeLimit, active, notificationCtrl.UpdatePreferences)
+	}
+}
@@ -0,0 +1,162 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// summaries lists the summaries of the caller's notifications, newest first
+func (s *testServer) summaries(token string) []string {
+	s.t.Helper()
+	summaries := []string{}
+	for _, notification := range s.notifications(token) {
+		summaries = append(summaries, notification["summary"].(string))
+	}
+	return summaries
+}
+
+func TestNotificationsAggregateBursts(t *testing.T) {
+	s := newTestServer(t)
+	s.limits[ratelimit.GroupAuth] = ratelimit.Limit{}
+	alice, aliceID := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+	dave, _ := s.signUp("dave")
+	post := s.post(alice, "hello")
+
+	// Likes on the same post pile up, naming the latest actor and counting each one once
+	for _, token := range []string{bob, carol, bob, dave} {
+		s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", post), token, nil)
+		s.expect(http.StatusOK, "DELETE", path("/api/posts/%d/reaction", post), token, nil)
+		s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", post), token, nil)
+	}
+	notifications := s.notifications(alice)
+	if len(notifications) != 1 || notifications[0]["actor_count"] != 3.0 || notifications[0]["summary"] != "dave and 2 others liked your post" {
+		t.Fatalf("got %v, want one reaction from dave and 2 others", s.summaries(alice))
+	}
+
+	// Other events, and the same event about something else, get their own notifications
+	other := s.post(alice, "again")
+	s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", other), bob, nil)
+	comment := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", post), alice, gin.H{"content": "thanks"})
+	commentID := field(comment, "comment", "id")
+	s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", post), carol, gin.H{"content": "nice", "parent_id": commentID})
+	s.expect(http.StatusOK, "POST", path("/api/comments/%d/like", int(commentID.(float64))), carol, nil)
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", aliceID), bob, nil)
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", aliceID), carol, nil)
+	want := []string{
+		"carol and 1 other followed you",
+		"carol liked your comment",
+		"carol replied to your comment",
+		"bob liked your post",
+		"dave and 2 others liked your post",
+	}
+	if got := s.summaries(alice); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// A read notification absorbs nothing more
+	s.expect(http.StatusOK, "POST", "/api/notifications/read-all", alice, nil)
+	erin, erinID := s.signUp("erin")
+	s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", post), erin, nil)
+	notifications = s.notifications(alice)
+	if notifications[0]["summary"] != "erin liked your post" || notifications[0]["read_at"] != nil || notifications[0]["actor_id"] != float64(erinID) {
+		t.Fatalf("after reading, got %v, want a new unread notification from erin", s.summaries(alice))
+	}
+}
+
+func TestNotificationReadState(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+	s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", s.post(alice, "first")), bob, nil)
+	s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", s.post(alice, "second")), carol, gin.H{"content": "hi"})
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", aliceID), bob, nil)
+
+	unread := func() float64 {
+		t.Helper()
+		return s.expect(http.StatusOK, "GET", "/api/notifications/unread-count", alice, nil)["unread"].(float64)
+	}
+	ids := s.pages(alice, "/api/notifications", "notifications", 1)
+	if len(ids) != 3 || unread() != 3 {
+		t.Fatalf("got notifications %v and %v unread, want 3 of each", ids, unread())
+	}
+
+	// Marking one read twice is a no-op the second time
+	for i := 0; i < 2; i++ {
+		body := s.expect(http.StatusOK, "POST", path("/api/notifications/%d/read", ids[0]), alice, nil)
+		if body["unread"] != 2.0 {
+			t.Fatalf("after marking one read, got %v unread, want 2", body["unread"])
+		}
+	}
+	if got := s.pages(alice, "/api/notifications?unread=true", "notifications", 10); fmt.Sprint(got) != fmt.Sprint(ids[1:]) {
+		t.Fatalf("got unread notifications %v, want %v", got, ids[1:])
+	}
+	if read := s.notifications(alice)[0]; read["read_at"] == nil {
+		t.Fatalf("got %v, want it marked read", read)
+	}
+
+	// Only the recipient can read a notification
+	s.expect(http.StatusNotFound, "POST", path("/api/notifications/%d/read", ids[1]), bob, nil)
+	s.expect(http.StatusNotFound, "POST", path("/api/notifications/%d/read", ids[0]+100), alice, nil)
+	s.expect(http.StatusBadRequest, "POST", "/api/notifications/0/read", alice, nil)
+	s.expect(http.StatusBadRequest, "GET", "/api/notifications?unread=maybe", alice, nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/notifications", "", nil)
+
+	for _, want := range []float64{2, 0} {
+		body := s.expect(http.StatusOK, "POST", "/api/notifications/read-all", alice, nil)
+		if body["marked"] != want || body["unread"] != 0.0 {
+			t.Fatalf("read-all marked %v and left %v unread, want %v and 0", body["marked"], body["unread"], want)
+		}
+	}
+	if got := unread(); got != 0 {
+		t.Fatalf("after reading everything, got %v unread", got)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+	post := s.post(alice, "hello")
+
+	// Every type starts on
+	preferences := s.expect(http.StatusOK, "GET", "/api/notifications/preferences", alice, nil)["preferences"].(map[string]any)
+	if len(preferences) != 6 {
+		t.Fatalf("got preferences %v, want all six types", preferences)
+	}
+	for notificationType, on := range preferences {
+		if on != true {
+			t.Fatalf("%s starts off", notificationType)
+		}
+	}
+
+	// Turning one off leaves the others alone
+	body := s.expect(http.StatusOK, "PUT", "/api/notifications/preferences", alice, gin.H{"reaction": false})
+	if preferences := body["preferences"].(map[string]any); preferences["reaction"] != false || preferences["comment"] != true {
+		t.Fatalf("got preferences %v, want only reaction off", preferences)
+	}
+	s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", post), bob, nil)
+	s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", post), bob, gin.H{"content": "hi"})
+	if got := s.summaries(alice); fmt.Sprint(got) != fmt.Sprint([]string{"bob commented on your post"}) {
+		t.Fatalf("with reactions off, got %q", got)
+	}
+
+	// Turning it back on only affects later events
+	s.expect(http.StatusOK, "PUT", "/api/notifications/preferences", alice, gin.H{"reaction": true})
+	s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", s.post(alice, "another")), bob, nil)
+	if got := s.summaries(alice); len(got) != 2 || got[0] != "bob liked your post" {
+		t.Fatalf("with reactions back on, got %q", got)
+	}
+
+	s.expect(http.StatusBadRequest, "PUT", "/api/notifications/preferences", alice, gin.H{"reaction": true, "shout": false})
+	s.expect(http.StatusBadRequest, "PUT", "/api/notifications/preferences", alice, gin.H{"reaction": "off"})
+	if preferences := s.expect(http.StatusOK, "GET", "/api/notifications/preferences", alice, nil)["preferences"].(map[string]any); preferences["reaction"] != true {
+		t.Fatalf("a rejected update changed the preferences to %v", preferences)
+	}
+}
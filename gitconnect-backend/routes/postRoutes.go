@@ -1,41 +1,50 @@
 package routes
 
 import (
-	"github.com/gin-gonic/gin"
 	"gitconnect-backend/controllers"
 	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
 )
 
-func PostRoutes(router *gin.Engine) {
-	// Public route: Get all posts
-	router.GET("/api/posts", controllers.GetPosts)
+func PostRoutes(router *gin.Engine, deps Dependencies) {
+	postCtrl := controllers.NewPostController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+	postOwner := middlewares.OwnerOf(deps.Repos.Posts.OwnerID)
+
+	// Public route: Get all posts (the caller's reactions are included when authenticated)
+	router.GET("/api/posts", middlewares.OptionalAuthMiddleware(deps.Repos), postCtrl.GetPosts)
 
 	// Protected routes
-	protected := router.Group("/api/posts").Use(middlewares.AuthMiddleware()) // Updated to use the correct middleware
+	protected := router.Group("/api/posts").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopePostsWrite),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser))
 	{
 		// Create a new post
-		protected.POST("", controllers.CreatePost)
+		protected.POST("", access.RequirePermission(models.PermPostCreate), postCtrl.CreatePost)
 
 		// Update a post
-		protected.PUT("/:id", controllers.UpdatePost)
+		protected.PUT("/:id", access.RequireOwnerOr(models.PermPostUpdateAny, postOwner), postCtrl.UpdatePost)
 
 		// Delete a post
-		protected.DELETE("/:id", controllers.DeletePost)
+		protected.DELETE("/:id", access.RequireOwnerOr(models.PermPostDeleteAny, postOwner), postCtrl.DeletePost)
 
 		// Like a post
-		protected.POST("/:id/like", controllers.LikePost)
+		protected.POST("/:id/like", access.RequirePermission(models.PermPostReact), postCtrl.LikePost)
 
 		// Dislike a post
-		protected.POST("/:id/dislike", controllers.DislikePost)
+		protected.POST("/:id/dislike", access.RequirePermission(models.PermPostReact), postCtrl.DislikePost)
+
+		// Remove the caller's like or dislike
+		protected.DELETE("/:id/reaction", access.RequirePermission(models.PermPostReact), postCtrl.RemovePostReaction)
 
 		// Comment on a post
-		protected.POST("/:id/comments", controllers.CommentOnPost)
+		protected.POST("/:id/comments", access.RequirePermission(models.PermCommentCreate), postCtrl.CommentOnPost)
 	}
 
 	// Get a single post
-	router.GET("/api/posts/:id", controllers.GetPost)
+	router.GET("/api/posts/:id", middlewares.OptionalAuthMiddleware(deps.Repos), postCtrl.GetPost)
 
-	// Get comments for a post
-	router.GET("/api/posts/:id/comments", controllers.GetCommentsForPost)
+	// Get comments for a post (the caller's reactions are included when authenticated)
+	router.GET("/api/posts/:id/comments", middlewares.OptionalAuthMiddleware(deps.Repos), postCtrl.GetCommentsForPost)
 }
-
@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	author, authorID := s.signUp("octocat")
+	other, _ := s.signUp("hubot")
+
+	s.expect(http.StatusUnauthorized, "POST", "/api/posts", "", gin.H{"content": "hello"})
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", author, gin.H{"content": "Hello #golang", "likes": 99})
+	id := int(field(created, "post", "id").(float64))
+	if likes := field(created, "post", "likes"); likes != 0.0 {
+		t.Fatalf("new post has %v likes, want 0", likes)
+	}
+	if uint(field(created, "post", "user_id").(float64)) != authorID {
+		t.Fatal("post is not owned by its author")
+	}
+
+	// Only the author can change the post
+	s.expect(http.StatusForbidden, "PUT", path("/api/posts/%d", id), other, gin.H{"content": "hijacked"})
+	s.expect(http.StatusForbidden, "DELETE", path("/api/posts/%d", id), other, nil)
+	s.expect(http.StatusOK, "PUT", path("/api/posts/%d", id), author, gin.H{"content": "Hello #gin"})
+
+	got := s.expect(http.StatusOK, "GET", path("/api/posts/%d", id), "", nil)
+	if content := field(got, "post", "content"); content != "Hello #gin" {
+		t.Fatalf("got content %q after update", content)
+	}
+	list := s.expect(http.StatusOK, "GET", "/api/posts", "", nil)
+	if posts := list["posts"].([]any); len(posts) != 1 {
+		t.Fatalf("listed %d posts, want 1", len(posts))
+	}
+
+	s.expect(http.StatusOK, "DELETE", path("/api/posts/%d", id), author, nil)
+	s.expect(http.StatusNotFound, "GET", path("/api/posts/%d", id), "", nil)
+	s.expect(http.StatusNotFound, "PUT", path("/api/posts/%d", id), author, gin.H{"content": "gone"})
+}
+
+func TestPostReactions(t *testing.T) {
+	s := newTestServer(t)
+	author, _ := s.signUp("octocat")
+	reader, _ := s.signUp("hubot")
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", author, gin.H{"content": "hello"})
+	id := int(field(created, "post", "id").(float64))
+
+	checkCounts := func(body map[string]any, likes, dislikes float64, mine any) {
+		t.Helper()
+		if body["likes"] != likes || body["dislikes"] != dislikes || body["my_reaction"] != mine {
+			t.Fatalf("got likes %v, dislikes %v, my_reaction %v; want %v, %v, %v",
+				body["likes"], body["dislikes"], body["my_reaction"], likes, dislikes, mine)
+		}
+	}
+
+	checkCounts(s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", id), reader, nil), 1, 0, "like")
+	// Liking again is a no-op
+	checkCounts(s.expect(http.StatusOK, "POST", path("/api/posts/%d/like", id), reader, nil), 1, 0, "like")
+	// A dislike replaces the like
+	checkCounts(s.expect(http.StatusOK, "POST", path("/api/posts/%d/dislike", id), reader, nil), 0, 1, "dislike")
+
+	got := s.expect(http.StatusOK, "GET", path("/api/posts/%d", id), reader, nil)
+	checkCounts(got["post"].(map[string]any), 0, 1, "dislike")
+	got = s.expect(http.StatusOK, "GET", path("/api/posts/%d", id), "", nil)
+	checkCounts(got["post"].(map[string]any), 0, 1, nil)
+
+	checkCounts(s.expect(http.StatusOK, "DELETE", path("/api/posts/%d/reaction", id), reader, nil), 0, 0, "")
+	s.expect(http.StatusNotFound, "POST", "/api/posts/999/like", reader, nil)
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"gitconnect-backend/migrations"
+	"gitconnect-backend/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testSchema keeps these tests apart from other packages' tests on the same database
+const testSchema = "routes_test"
+
+// postgresRepos returns GORM repositories on the database at TEST_DATABASE_URL, in a
+// schema migrated from nothing to exactly version, and skips the test if it is not set.
+// The schema is wiped by every call.
+func postgresRepos(t *testing.T, version int64) (repository.Repositories, *gorm.DB) {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	if err := admin.Exec("CREATE SCHEMA IF NOT EXISTS " + testSchema).Error; err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	if sqlDB, err := admin.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(databaseURL, testSchema)), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("migrating down: %v", err)
+	}
+	if err := migrator.To(ctx, version); err != nil {
+		t.Fatalf("migrating to %d: %v", version, err)
+	}
+	return repository.NewGorm(db), db
+}
+
+// withSearchPath sets the schema of every connection made with databaseURL, which
+// is either a postgres:// URL or a list of key=value settings
+func withSearchPath(databaseURL, schema string) string {
+	if !strings.Contains(databaseURL, "://") {
+		return databaseURL + " search_path=" + schema
+	}
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitconnect-backend/ratelimit"
+)
+
+// fakeRepoAPI serves the repositories of octocat, counting the listings it is asked for.
+// The listing answers with status while it is not 200, and waits for release if set.
+type fakeRepoAPI struct {
+	listings atomic.Int32
+	status   atomic.Int32
+	release  chan struct{}
+}
+
+// newFakeRepoAPI points the GitHub API at a stub; call it before newTestServer
+func newFakeRepoAPI(t *testing.T) *fakeRepoAPI {
+	t.Helper()
+	api := &fakeRepoAPI{}
+	api.status.Store(http.StatusOK)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{login}/repos", func(w http.ResponseWriter, r *http.Request) {
+		api.listings.Add(1)
+		if api.release != nil {
+			<-api.release
+		}
+		switch {
+		case r.PathValue("login") != "octocat":
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		case api.status.Load() != http.StatusOK:
+			http.Error(w, `{"message": "Server Error"}`, int(api.status.Load()))
+		default:
+			w.Write([]byte(`[{"id": 1, "name": "hello", "full_name": "octocat/hello", "pushed_at": "2024-01-01T00:00:00Z"}]`))
+		}
+	})
+	mux.HandleFunc("GET /repos/octocat/hello/languages", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Go": 100}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_API_TOKEN", "")
+	return api
+}
+
+// githubProfile signs up a user whose profile shows the GitHub account login, and
+// returns the user's token and the profile's repositories path
+func (s *testServer) githubProfile(username, login string) (string, string) {
+	s.t.Helper()
+	token, userID := s.signUp(username)
+	if err := s.repos.Profiles.LinkGithub(context.Background(), userID, 42, login, time.Now()); err != nil {
+		s.t.Fatalf("linking GitHub: %v", err)
+	}
+	user := s.expect(http.StatusOK, "GET", "/api/users/"+username, "", nil)
+	return token, path("/api/profiles/%d/repos", int(field(user, "profile", "id").(float64)))
+}
+
+func TestProfileReposBackOffAfterFailures(t *testing.T) {
+	api := newFakeRepoAPI(t)
+	api.status.Store(http.StatusInternalServerError)
+	s := newTestServer(t)
+	owner, repos := s.githubProfile("octocat", "octocat")
+
+	s.expect(http.StatusBadGateway, "GET", repos, "", nil)
+	// The failure is remembered, so GitHub is not asked again straight away
+	s.expect(http.StatusBadGateway, "GET", repos, "", nil)
+	if n := api.listings.Load(); n != 1 {
+		t.Fatalf("GitHub was asked %d times, want 1", n)
+	}
+
+	// The owner can still refresh now
+	api.status.Store(http.StatusOK)
+	s.expect(http.StatusOK, "POST", repos+"/sync", owner, nil)
+	body := s.expect(http.StatusOK, "GET", repos, "", nil)
+	if list := body["repos"].([]any); len(list) != 1 || body["synced_at"] == nil {
+		t.Fatalf("got %v", body)
+	}
+	if n := api.listings.Load(); n != 2 {
+		t.Fatalf("GitHub was asked %d times, want 2", n)
+	}
+}
+
+func TestProfileReposRemembersMissingAccounts(t *testing.T) {
+	api := newFakeRepoAPI(t)
+	s := newTestServer(t)
+	_, repos := s.githubProfile("hubot", "no-such-user")
+
+	s.expect(http.StatusNotFound, "GET", repos, "", nil)
+	s.expect(http.StatusNotFound, "GET", repos, "", nil)
+	if n := api.listings.Load(); n != 1 {
+		t.Fatalf("GitHub was asked %d times, want 1", n)
+	}
+}
+
+func TestProfileReposCollapseConcurrentSyncs(t *testing.T) {
+	api := newFakeRepoAPI(t)
+	api.release = make(chan struct{})
+	s := newTestServer(t)
+	_, repos := s.githubProfile("octocat", "octocat")
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest("GET", repos, nil))
+			codes[i] = rec.Code
+		}()
+	}
+	// Let every request reach the sync before GitHub answers
+	time.Sleep(100 * time.Millisecond)
+	close(api.release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("request %d: got %d", i, code)
+		}
+	}
+	if n := api.listings.Load(); n != 1 {
+		t.Fatalf("GitHub was asked %d times, want 1", n)
+	}
+}
+
+func TestProfileReposAreRateLimited(t *testing.T) {
+	newFakeRepoAPI(t)
+	s := newTestServer(t)
+	_, repos := s.githubProfile("octocat", "octocat")
+	s.limits[ratelimit.GroupGithub] = ratelimit.Limit{Requests: 1, Period: time.Minute, Burst: 1}
+
+	s.expect(http.StatusOK, "GET", repos, "", nil)
+	s.expect(http.StatusTooManyRequests, "GET", repos, "", nil)
+}
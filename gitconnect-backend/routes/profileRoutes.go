@@ -3,30 +3,83 @@ package routes
 import (
 	"gitconnect-backend/controllers"
 	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
-func ProfileRoutes(router *gin.Engine) {
-	// Public route: Get all profiles
-	router.GET("/api/profiles", controllers.GetProfiles)
-	
+func ProfileRoutes(router *gin.Engine, deps Dependencies) {
+	profileCtrl := controllers.NewProfileController(deps.Repos, deps.Events, deps.Blobs)
+	access := middlewares.Access{Users: deps.Repos.Users}
+	profileOwner := middlewares.OwnerOf(deps.Repos.Profiles.OwnerID)
+	writeLimit := middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser)
+
+	// Public route: a user's page, by username or user ID
+	router.GET("/api/users/:id", middlewares.OptionalAuthMiddleware(deps.Repos), profileCtrl.GetUser)
+
 	// Public route: Serve profile image
-//	router.GET("/api/profiles/:id/image", controllers.GetProfileImage)
+	router.GET("/api/profiles/:id/image", profileCtrl.GetProfileImage)
+
+	// Public routes: profiles and their sections can be read without logging in.
+	// A token, if sent, tailors the response to the caller (e.g. endorsements).
+	public := router.Group("/api/profiles").Use(middlewares.OptionalAuthMiddleware(deps.Repos))
+	{
+		public.GET("", profileCtrl.GetProfiles)
+		public.GET("/:id", profileCtrl.GetProfile)
+		for path, list := range map[string]gin.HandlerFunc{
+			"skills":     profileCtrl.GetSkills,
+			"experience": profileCtrl.GetExperience,
+			"education":  profileCtrl.GetEducation,
+			"links":      profileCtrl.GetLinks,
+		} {
+			public.GET("/:id/"+path, list)
+		}
+		public.GET("/:id/skills/:entryId/endorsements", profileCtrl.GetSkillEndorsements)
+		public.GET("/:id/repos", middlewares.RateLimit(deps.Limiter, ratelimit.GroupGithub, middlewares.ByUser), profileCtrl.GetProfileRepos)
+	}
 
 	// Protected routes
-	protected := router.Group("/api/profiles").Use(middlewares.AuthMiddleware()) // Apply AuthMiddleware to this group
+	protected := router.Group("/api/profiles").Use(middlewares.AuthMiddleware(deps.Repos)) // Apply AuthMiddleware to this group
 	{
 		// Create a new profile
-		protected.POST("/", controllers.CreateProfile)
-
-		// Get a single profile by ID (protected)
-		protected.GET("/:id", controllers.GetProfile)
+		protected.POST("/", middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.ActiveAccount(), profileCtrl.CreateProfile)
 
 		// Update a profile (protected)
-		protected.PUT("/:id", controllers.UpdateProfile)
+		protected.PUT("/:id", middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.RequireOwnerOr(models.PermProfileUpdateAny, profileOwner), profileCtrl.UpdateProfile)
 
-		// Upload a profile image (protected)
-		//protected.POST("/:id/image", controllers.UploadProfileImage)
+		// Delete a profile (protected)
+		protected.DELETE("/:id", middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.RequireOwnerOr(models.PermProfileDeleteAny, profileOwner), profileCtrl.DeleteProfile)
+
+		// Upload or remove a profile image (protected)
+		protected.POST("/:id/image", middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.RequireOwnerOr(models.PermProfileUpdateAny, profileOwner), profileCtrl.UploadProfileImage)
+		protected.DELETE("/:id/image", middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.RequireOwnerOr(models.PermProfileUpdateAny, profileOwner), profileCtrl.DeleteProfileImage)
+
+		// Profile sections: skills, experience, education and links (protected)
+		write := []gin.HandlerFunc{middlewares.RequireScope(models.ScopeProfileWrite), writeLimit, access.RequireOwnerOr(models.PermProfileUpdateAny, profileOwner)}
+		sections := []struct {
+			path                         string
+			add, update, remove, reorder gin.HandlerFunc
+		}{
+			{"skills", profileCtrl.AddSkill, profileCtrl.UpdateSkill, profileCtrl.DeleteSkill, profileCtrl.ReorderSkills},
+			{"experience", profileCtrl.AddExperience, profileCtrl.UpdateExperience, profileCtrl.DeleteExperience, profileCtrl.ReorderExperience},
+			{"education", profileCtrl.AddEducation, profileCtrl.UpdateEducation, profileCtrl.DeleteEducation, profileCtrl.ReorderEducation},
+			{"links", profileCtrl.AddLink, profileCtrl.UpdateLink, profileCtrl.DeleteLink, profileCtrl.ReorderLinks},
+		}
+		for _, section := range sections {
+			path := "/:id/" + section.path
+			protected.POST(path, append(write, section.add)...)
+			protected.PUT(path+"/order", append(write, section.reorder)...)
+			protected.PUT(path+"/:entryId", append(write, section.update)...)
+			protected.DELETE(path+"/:entryId", append(write, section.remove)...)
+		}
+
+		// Skill endorsements between users who follow each other (protected)
+		endorse := []gin.HandlerFunc{middlewares.RequireScope(models.ScopeEndorsementsWrite), writeLimit, access.RequirePermission(models.PermSkillEndorse)}
+		protected.POST("/:id/skills/:entryId/endorsements", append(endorse, profileCtrl.EndorseSkill)...)
+		protected.DELETE("/:id/skills/:entryId/endorsements", append(endorse, profileCtrl.UnendorseSkill)...)
+
+		// GitHub repository showcase (protected)
+		protected.POST("/:id/repos/sync", append(write, profileCtrl.SyncProfileRepos)...)
+		protected.PUT("/:id/repos/pins", append(write, profileCtrl.PinProfileRepos)...)
 	}
 }
-
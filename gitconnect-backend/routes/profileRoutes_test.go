@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSkillRenameWithdrawsEndorsements(t *testing.T) {
+	s := newTestServer(t)
+	owner, ownerID := s.signUp("octocat")
+	endorser, endorserID := s.signUp("hubot")
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", endorserID), owner, nil)
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", ownerID), endorser, nil)
+
+	user := s.expect(http.StatusOK, "GET", "/api/users/octocat", "", nil)
+	skills := path("/api/profiles/%d/skills", int(field(user, "profile", "id").(float64)))
+	created := s.expect(http.StatusCreated, "POST", skills, owner, gin.H{"name": "Go"})
+	skill := path("%s/%d", skills, int(field(created, "skill", "id").(float64)))
+	s.expect(http.StatusOK, "POST", skill+"/endorsements", endorser, nil)
+
+	endorsements := func() any {
+		t.Helper()
+		list := s.expect(http.StatusOK, "GET", skills, "", nil)["skills"].([]any)
+		return field(list[0].(map[string]any), "endorsements", "count")
+	}
+
+	// Changing the case or the proficiency keeps the endorsements
+	s.expect(http.StatusOK, "PUT", skill, owner, gin.H{"name": "GO", "proficiency": "expert"})
+	if count := endorsements(); count != 1.0 {
+		t.Fatalf("got %v endorsements after a case change, want 1", count)
+	}
+
+	// They vouched for Go, not for whatever the skill is renamed to
+	updated := s.expect(http.StatusOK, "PUT", skill, owner, gin.H{"name": "Rust"})
+	if name := field(updated, "skill", "name"); name != "Rust" {
+		t.Fatalf("got name %v", name)
+	}
+	if count := endorsements(); count != 0.0 {
+		t.Fatalf("got %v endorsements after a rename, want none", count)
+	}
+
+	s.expect(http.StatusNotFound, "PUT", skills+"/999", owner, gin.H{"name": "Go"})
+}
+
+func TestRenamedUsernameRedirects(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.signUp("octocat")
+	s.expect(http.StatusOK, "PUT", "/api/auth/username", token, gin.H{"username": "monalisa"})
+
+	// The redirect is temporary, as the old name may later go to someone else
+	rec := s.do("GET", "/api/users/OctoCat?include=skills", "", nil)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/api/users/monalisa?include=skills" {
+		t.Fatalf("got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	s.expect(http.StatusOK, "GET", "/api/users/monalisa", "", nil)
+}
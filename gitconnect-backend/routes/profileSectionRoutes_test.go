@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// profileID returns the ID of the profile created for a user at sign-up
+func (s *testServer) profileID(userID uint) int {
+	s.t.Helper()
+	user := s.expect(http.StatusOK, "GET", path("/api/users/%d", userID), "", nil)
+	return int(field(user, "profile", "id").(float64))
+}
+
+// entryIDs lists the IDs of a section's entries, in display order
+func entryIDs(entries any) []int {
+	ids := []int{}
+	for _, entry := range entries.([]any) {
+		ids = append(ids, int(entry.(map[string]any)["id"].(float64)))
+	}
+	return ids
+}
+
+// profileSections lists each section with three valid entries and an invalid one
+var profileSections = []struct {
+	key, field string
+	entries    []gin.H
+	invalid    gin.H
+}{
+	{"skills", "skill", []gin.H{{"name": "Go"}, {"name": "Rust", "proficiency": "expert"}, {"name": "SQL"}}, gin.H{"name": "Go", "proficiency": "guru"}},
+	{"experience", "experience", []gin.H{
+		{"title": "Engineer", "company": "Acme", "start_date": "2019-01", "end_date": "2021-06"},
+		{"title": "Lead", "company": "Acme", "start_date": "2021-07"},
+		{"title": "Intern", "company": "Initech", "start_date": "2018-06", "end_date": "2018-09"},
+	}, gin.H{"title": "Engineer", "company": "Acme", "start_date": "2021-06", "end_date": "2019-01"}},
+	{"education", "education", []gin.H{
+		{"school": "MIT", "degree": "BSc"},
+		{"school": "ETH", "field_of_study": "CS", "start_date": "2015-09"},
+		{"school": "Online"},
+	}, gin.H{"school": "  "}},
+	{"links", "link", []gin.H{
+		{"kind": "website", "url": "https://example.com"},
+		{"kind": "mastodon", "label": "Fediverse", "url": "https://hachyderm.io/@alice"},
+		{"kind": "blog", "url": "http://blog.example.com"},
+	}, gin.H{"kind": "website", "url": "javascript:alert(1)"}},
+}
+
+func TestProfileSections(t *testing.T) {
+	for _, section := range profileSections {
+		t.Run(section.key, func(t *testing.T) {
+			s := newTestServer(t)
+			alice, aliceID := s.signUp("alice")
+			bob, bobID := s.signUp("bob")
+			base := path("/api/profiles/%d/%s", s.profileID(aliceID), section.key)
+			list := func() []int {
+				t.Helper()
+				return entryIDs(s.expect(http.StatusOK, "GET", base, "", nil)[section.key])
+			}
+
+			var ids []int
+			for _, entry := range section.entries {
+				created := s.expect(http.StatusCreated, "POST", base, alice, entry)
+				ids = append(ids, int(field(created, section.field, "id").(float64)))
+			}
+			if got := list(); fmt.Sprint(got) != fmt.Sprint(ids) {
+				t.Fatalf("got entries %v, want them in the order added %v", got, ids)
+			}
+			s.expect(http.StatusBadRequest, "POST", base, alice, section.invalid)
+			s.expect(http.StatusBadRequest, "POST", base, alice, gin.H{})
+
+			// Only the owner edits the section
+			s.expect(http.StatusForbidden, "POST", base, bob, section.entries[0])
+			s.expect(http.StatusForbidden, "PUT", path("%s/%d", base, ids[0]), bob, section.entries[0])
+			s.expect(http.StatusForbidden, "DELETE", path("%s/%d", base, ids[0]), bob, nil)
+			s.expect(http.StatusForbidden, "PUT", base+"/order", bob, gin.H{"ids": []int{ids[2]}})
+			s.expect(http.StatusUnauthorized, "POST", base, "", section.entries[0])
+
+			// The listed entries move to the top, the others keep their order
+			reordered := s.expect(http.StatusOK, "PUT", base+"/order", alice, gin.H{"ids": []int{ids[2], ids[0]}})
+			want := []int{ids[2], ids[0], ids[1]}
+			if got := entryIDs(reordered[section.key]); fmt.Sprint(got) != fmt.Sprint(want) || fmt.Sprint(list()) != fmt.Sprint(want) {
+				t.Fatalf("after reordering, got %v, want %v", got, want)
+			}
+
+			// Another profile's entries cannot be reached through this one
+			other := path("/api/profiles/%d/%s", s.profileID(bobID), section.key)
+			otherID := int(field(s.expect(http.StatusCreated, "POST", other, bob, section.entries[0]), section.field, "id").(float64))
+			s.expect(http.StatusBadRequest, "PUT", base+"/order", alice, gin.H{"ids": []int{otherID}})
+			s.expect(http.StatusNotFound, "PUT", path("%s/%d", base, otherID), alice, section.entries[1])
+			s.expect(http.StatusNotFound, "DELETE", path("%s/%d", base, otherID), alice, nil)
+			s.expect(http.StatusBadRequest, "DELETE", base+"/first", alice, nil)
+
+			// Editing keeps the entry's place
+			updated := s.expect(http.StatusOK, "PUT", path("%s/%d", base, ids[1]), alice, section.entries[1])
+			if id := field(updated, section.field, "id"); id != float64(ids[1]) {
+				t.Fatalf("editing returned entry %v, want %d", id, ids[1])
+			}
+			s.expect(http.StatusBadRequest, "PUT", path("%s/%d", base, ids[1]), alice, section.invalid)
+			s.expect(http.StatusOK, "DELETE", path("%s/%d", base, ids[0]), alice, nil)
+			s.expect(http.StatusNotFound, "DELETE", path("%s/%d", base, ids[0]), alice, nil)
+			if got := list(); fmt.Sprint(got) != fmt.Sprint([]int{ids[2], ids[1]}) {
+				t.Fatalf("after editing and deleting, got %v, want %v", got, []int{ids[2], ids[1]})
+			}
+		})
+	}
+}
+
+func TestProfileIncludesSections(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	profile := path("/api/profiles/%d", s.profileID(aliceID))
+	for _, section := range profileSections {
+		s.expect(http.StatusCreated, "POST", profile+"/"+section.key, alice, section.entries[0])
+	}
+
+	// Sections are only filled when asked for
+	for include, want := range map[string][]string{
+		"":                             {},
+		"?include=skills":              {"skills"},
+		"?include=links,%20experience": {"experience", "links"},
+		"?include=skills,experience,education,links": {"education", "experience", "links", "skills"},
+	} {
+		body := s.expect(http.StatusOK, "GET", profile+include, "", nil)
+		var got []string
+		for _, section := range []string{"education", "experience", "links", "skills"} {
+			if entries, ok := body["profile"].(map[string]any)[section]; ok && len(entries.([]any)) == 1 {
+				got = append(got, section)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: got sections %v, want %v", include, got, want)
+		}
+	}
+	s.expect(http.StatusBadRequest, "GET", profile+"?include=posts", "", nil)
+}
+
+func TestProfileSectionRules(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signUp("alice")
+	profile := path("/api/profiles/%d", s.profileID(aliceID))
+
+	// A skill is listed once, whatever its case
+	s.expect(http.StatusCreated, "POST", profile+"/skills", alice, gin.H{"name": "Go"})
+	s.expect(http.StatusConflict, "POST", profile+"/skills", alice, gin.H{"name": " go "})
+
+	// Each section holds a limited number of entries
+	for i := 0; i < 10; i++ {
+		s.expect(http.StatusCreated, "POST", profile+"/links", alice, gin.H{"kind": "other", "url": fmt.Sprintf("https://example.com/%d", i)})
+	}
+	s.expect(http.StatusConflict, "POST", profile+"/links", alice, gin.H{"kind": "other", "url": "https://example.com/more"})
+
+	// Links are web links
+	for _, url := range []string{"data:text/html,hi", "ftp://example.com", "https://", "example.com"} {
+		s.expect(http.StatusBadRequest, "POST", profile+"/links", alice, gin.H{"kind": "website", "url": url})
+	}
+	s.expect(http.StatusNotFound, "GET", "/api/profiles/999/skills", "", nil)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/ratelimit"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/storage"
+)
+
+// Dependencies are what the route handlers and middlewares are built from
+type Dependencies struct {
+	Repos   repository.Repositories
+	Events  *realtime.Hub      // Pushes events to the clients streaming them
+	Blobs   storage.BlobStore  // Uploaded files
+	Limiter *ratelimit.Limiter // Rate limits of the middleware and of login attempts
+	Mailer  mailer.Mailer      // Verification and password reset email
+}
@@ -0,0 +1,182 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/ratelimit"
+	"gitconnect-backend/realtime"
+	"gitconnect-backend/repository"
+	"gitconnect-backend/storage"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// testServer serves every route from the in-memory repositories
+type testServer struct {
+	t      *testing.T
+	router *gin.Engine
+	repos  repository.Repositories
+	mail   *captureMailer
+	limits map[string]ratelimit.Limit
+}
+
+// newTestServer builds a router as main does on the in-memory repositories, with mail
+// captured, uploads in a temporary directory, and its own rate limiter and event hub
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerOn(t, repository.NewMemory())
+}
+
+// newTestServerOn builds the router of newTestServer on repos
+func newTestServerOn(t *testing.T, repos repository.Repositories) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-bytes-long")
+	t.Setenv("JWT_ALGORITHM", "")
+	t.Setenv("JWT_KEYS_DIR", "")
+	if err := utils.LoadSigningKeys(); err != nil {
+		t.Fatalf("loading signing keys: %v", err)
+	}
+
+	mail := &captureMailer{}
+	limits := make(map[string]ratelimit.Limit, len(ratelimit.DefaultLimits))
+	for group, limit := range ratelimit.DefaultLimits {
+		limits[group] = limit
+	}
+
+	router := gin.New()
+	deps := Dependencies{
+		Repos:   repos,
+		Events:  realtime.NewLocalHub(),
+		Blobs:   &storage.LocalStore{Dir: t.TempDir()},
+		Limiter: &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), Limits: limits},
+		Mailer:  mail,
+	}
+	AuthRoutes(router, deps)
+	PostRoutes(router, deps)
+	CommentRoutes(router, deps)
+	ProfileRoutes(router, deps)
+	FollowRoutes(router, deps)
+	TagRoutes(router, deps)
+	BlockRoutes(router, deps)
+	NotificationRoutes(router, deps)
+	MessageRoutes(router, deps)
+	StreamRoutes(router, deps)
+	SearchRoutes(router, deps)
+	AdminRoutes(router, deps)
+
+	return &testServer{t: t, router: router, repos: repos, mail: mail, limits: limits}
+}
+
+// do sends a request with an optional bearer token and JSON body
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			s.t.Fatalf("encoding body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect sends a request and fails the test unless it gets status, returning the decoded body
+func (s *testServer) expect(status int, method, path, token string, body any) map[string]any {
+	s.t.Helper()
+
+	rec := s.do(method, path, token, body)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: got %d, want %d: %s", method, path, rec.Code, status, rec.Body.String())
+	}
+	var decoded map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &decoded)
+	return decoded
+}
+
+// signUp registers and logs in a user, returning the access token and user ID
+func (s *testServer) signUp(username string) (string, uint) {
+	s.t.Helper()
+
+	email := username + "@example.com"
+	s.expect(http.StatusCreated, "POST", "/api/auth/register", "", gin.H{
+		"username": username, "email": email, "password": "correct horse",
+	})
+	body := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"email": email, "password": "correct horse"})
+	return body["token"].(string), uint(field(body, "user", "id").(float64))
+}
+
+// field walks nested JSON objects by key
+func field(body map[string]any, keys ...string) any {
+	var value any = body
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// path formats a route path with IDs
+func path(format string, args ...any) string {
+	return fmt.Sprintf(format, args...)
+}
+
+// captureMailer keeps sent messages so tests can follow the links in them
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var linkToken = regexp.MustCompile(`\?token=(\S+)`)
+
+// lastToken returns the token in the link of the last message sent to address
+func (m *captureMailer) lastToken(t *testing.T, address string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To != address {
+			continue
+		}
+		match := linkToken.FindStringSubmatch(m.sent[i].Body)
+		if match == nil {
+			t.Fatalf("no link in message to %s: %q", address, m.sent[i].Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("bad token in link: %v", err)
+		}
+		return token
+	}
+	t.Fatalf("no message sent to %s", address)
+	return ""
+}
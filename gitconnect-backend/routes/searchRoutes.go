@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func SearchRoutes(router *gin.Engine, deps Dependencies) {
+	searchCtrl := controllers.NewSearchController(deps.Repos)
+
+	// Public route: search posts, profiles and comments (limited per user when authenticated)
+	router.GET("/api/search", middlewares.OptionalAuthMiddleware(deps.Repos),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupSearch, middlewares.ByUser), searchCtrl.SearchAll)
+}
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"gitconnect-backend/migrations"
+	"github.com/gin-gonic/gin"
+)
+
+// search runs a query and summarizes its results as type:id, best first, with the total
+func (s *testServer) search(query string) ([]string, float64) {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "GET", "/api/search?"+query, "", nil)
+	results := []string{}
+	for _, result := range body["results"].([]any) {
+		r := result.(map[string]any)
+		results = append(results, fmt.Sprintf("%v:%v", r["type"], r["id"]))
+	}
+	return results, body["total"].(float64)
+}
+
+func TestSearch(t *testing.T) {
+	testSearch(t, newTestServer(t))
+}
+
+func TestSearchOnPostgres(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	repos, _ := postgresRepos(t, all[len(all)-1].Version)
+	s := newTestServerOn(t, repos)
+	testSearch(t, s)
+
+	// Postgres also stems words and understands phrases, OR and exclusions
+	alice, _ := s.signUp("alice")
+	first := fmt.Sprintf("post:%d", s.post(alice, "Deploying operators with Helm"))
+	second := fmt.Sprintf("post:%d", s.post(alice, "Deployed a Helm chart by hand"))
+	for query, want := range map[string][]string{
+		"deploy helm":        {first, second},
+		`"helm chart"`:       {second},
+		"helm -operators":    {second},
+		"operators or chart": {first, second},
+	} {
+		results, _ := s.search("type=post&q=" + url.QueryEscape(query))
+		sort.Strings(results)
+		sort.Strings(want)
+		if fmt.Sprint(results) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", query, results, want)
+		}
+	}
+}
+
+// testSearch checks the behavior every search backend shares
+func testSearch(t *testing.T, s *testServer) {
+	alice, aliceID := s.signUp("kube_alice")
+	bob, _ := s.signUp("kube_bob")
+	user := s.expect(http.StatusOK, "GET", path("/api/users/%d", aliceID), "", nil)
+	profileID := int(field(user, "profile", "id").(float64))
+	s.expect(http.StatusOK, "PUT", path("/api/profiles/%d", profileID), alice, gin.H{"full_name": "Alice Liddell", "bio": "Writes Kubernetes operators"})
+
+	post := s.post(bob, "Kubernetes, Kubernetes, Kubernetes: operators everywhere")
+	bread := s.post(alice, "Baking bread")
+	created := s.expect(http.StatusCreated, "POST", path("/api/posts/%d/comments", bread), bob, gin.H{"content": "Kubernetes <3 & bread"})
+	comment := int(field(created, "comment", "id").(float64))
+
+	// Every type matches, and the post that repeats the word ranks above the comment
+	results, total := s.search("q=kubernetes")
+	rank := map[string]int{}
+	for i, result := range results {
+		rank[result] = i + 1
+	}
+	postRank, commentRank := rank[fmt.Sprintf("post:%d", post)], rank[fmt.Sprintf("comment:%d", comment)]
+	if total != 3 || len(results) != 3 || rank[fmt.Sprintf("profile:%d", profileID)] == 0 || postRank == 0 || commentRank < postRank {
+		t.Fatalf("got %v of %v, want the profile, then the post above the comment", results, total)
+	}
+
+	// All the words must match
+	if results, total := s.search("q=" + url.QueryEscape("KUBERNETES bread")); total != 1 || results[0] != fmt.Sprintf("comment:%d", comment) {
+		t.Fatalf("two words gave %v of %v, want only the comment", results, total)
+	}
+
+	// Types filter the results, and results carry their author and an escaped, highlighted snippet
+	body := s.expect(http.StatusOK, "GET", "/api/search?q=bread&type=comment,profile", "", nil)
+	found := body["results"].([]any)
+	if len(found) != 1 {
+		t.Fatalf("got %v, want only the comment", found)
+	}
+	result := found[0].(map[string]any)
+	if result["username"] != "kube_bob" || result["post_id"] != float64(bread) {
+		t.Fatalf("got result %v, want kube_bob's comment on post %d", result, bread)
+	}
+	if snippet := result["snippet"].(string); !strings.Contains(snippet, "&lt;3 &amp;") || !strings.Contains(snippet, "<mark>bread</mark>") {
+		t.Fatalf("got snippet %q, want escaped text and a highlighted match", snippet)
+	}
+
+	// Pages add up to the whole list
+	var paged []string
+	for offset := 0; offset < 4; offset++ {
+		page, total := s.search(fmt.Sprintf("q=kubernetes&limit=1&offset=%d", offset))
+		if total != 3 {
+			t.Fatalf("page at %d has a total of %v, want 3", offset, total)
+		}
+		paged = append(paged, page...)
+	}
+	if fmt.Sprint(paged) != fmt.Sprint(results) {
+		t.Fatalf("pages gave %v, want %v", paged, results)
+	}
+
+	// Edits and deletions show up at once
+	s.expect(http.StatusOK, "PUT", path("/api/posts/%d", post), bob, gin.H{"content": "Nomad everywhere"})
+	s.expect(http.StatusOK, "DELETE", path("/api/comments/%d", comment), bob, nil)
+	if results, total := s.search("q=kubernetes"); total != 1 || results[0] != fmt.Sprintf("profile:%d", profileID) {
+		t.Fatalf("after editing and deleting, got %v of %v, want only the profile", results, total)
+	}
+	if results, _ := s.search("q=nomad"); fmt.Sprint(results) != fmt.Sprint([]string{fmt.Sprintf("post:%d", post)}) {
+		t.Fatalf("searching the new content gave %v", results)
+	}
+
+	for _, query := range []string{"q=", "q=" + strings.Repeat("a", 201), "q=go&type=user", "q=go&limit=0", "q=go&limit=101", "q=go&offset=-1"} {
+		s.expect(http.StatusBadRequest, "GET", "/api/search?"+query, "", nil)
+	}
+}
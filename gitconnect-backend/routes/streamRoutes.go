@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func StreamRoutes(router *gin.Engine, deps Dependencies) {
+	streamCtrl := controllers.NewStreamController(deps.Repos, deps.Events)
+
+	// Tickets open streams from browsers; they are only issued to login sessions
+	router.POST("/api/stream/ticket", middlewares.AuthMiddleware(deps.Repos), middlewares.SessionOnly(), streamCtrl.CreateTicket)
+
+	// Event streams, authenticated by a ticket or the Authorization header
+	stream := router.Group("/api/stream").Use(middlewares.StreamAuthMiddleware(deps.Repos),
+		middlewares.RequireScope(models.ScopePostsRead), middlewares.RequireScope(models.ScopeNotificationsRead))
+	{
+		stream.GET("", streamCtrl.StreamSSE)
+		stream.GET("/ws", streamCtrl.StreamWebSocket)
+	}
+}
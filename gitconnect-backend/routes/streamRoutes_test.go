@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ticket requests a stream ticket for a login session
+func (s *testServer) ticket(session string) string {
+	s.t.Helper()
+	return s.expect(http.StatusOK, "POST", "/api/stream/ticket", session, nil)["ticket"].(string)
+}
+
+func TestStreamTicketsDieWithTheirSession(t *testing.T) {
+	s := newTestServer(t)
+	session, _ := s.signUp("octocat")
+	ticket := s.ticket(session)
+
+	s.expect(http.StatusOK, "POST", "/api/auth/logout", session, nil)
+	body := s.expect(http.StatusUnauthorized, "GET", "/api/stream?ticket="+ticket, "", nil)
+	if body["error"] != "Token has been revoked" {
+		t.Fatalf("got error %q, want the revoked token error", body["error"])
+	}
+}
+
+func TestRestrictedUsersCannotStream(t *testing.T) {
+	s := newTestServer(t)
+	session, userID := s.signUp("octocat")
+	ticket := s.ticket(session)
+	if err := s.repos.Users.Ban(context.Background(), userID, time.Now(), "spam"); err != nil {
+		t.Fatalf("banning: %v", err)
+	}
+
+	s.expect(http.StatusForbidden, "GET", "/api/stream?ticket="+ticket, "", nil)
+	s.expect(http.StatusForbidden, "GET", "/api/stream", session, nil)
+}
+
+func TestSuspendingAUserEndsTheirStreams(t *testing.T) {
+	s := newTestServer(t)
+	session, userID := s.signUp("octocat")
+	admin, adminID := s.signUp("hubot")
+	if err := s.repos.Users.SetRole(context.Background(), adminID, models.RoleAdmin); err != nil {
+		t.Fatalf("promoting: %v", err)
+	}
+
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/stream?ticket="+s.ticket(session), nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("opening the stream: %v, %v", res, err)
+	}
+	defer res.Body.Close()
+
+	s.expect(http.StatusOK, "POST", path("/api/admin/users/%d/suspend", userID), admin, gin.H{"duration_hours": 1})
+
+	// The server ends the stream rather than the client timing out
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event:") {
+			t.Fatalf("got %q after the suspension", scanner.Text())
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the stream was still open after the suspension")
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TagRoutes(router *gin.Engine, deps Dependencies) {
+	tagCtrl := controllers.NewTagController(deps.Repos)
+	postCtrl := controllers.NewPostController(deps.Repos, deps.Events)
+	access := middlewares.Access{Users: deps.Repos.Users}
+
+	// Public routes: trending tags and topic pages (the caller's reactions are included when authenticated)
+	router.GET("/api/tags/trending", tagCtrl.GetTrending)
+	router.GET("/api/tags/:name/posts", middlewares.OptionalAuthMiddleware(deps.Repos), postCtrl.GetTagPosts)
+
+	// Tags the caller follows
+	router.GET("/api/tags/following", middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopeProfileRead), tagCtrl.GetFollowedTags)
+
+	// Protected routes
+	protected := router.Group("/api/tags").Use(middlewares.AuthMiddleware(deps.Repos), middlewares.RequireScope(models.ScopeFollowsWrite),
+		middlewares.RateLimit(deps.Limiter, ratelimit.GroupWrite, middlewares.ByUser))
+	{
+		// Follow a tag
+		protected.POST("/:name/follow", access.RequirePermission(models.PermUserFollow), tagCtrl.FollowTag)
+
+		// Unfollow a tag
+		protected.DELETE("/:name/follow", access.RequirePermission(models.PermUserFollow), tagCtrl.UnfollowTag)
+	}
+}
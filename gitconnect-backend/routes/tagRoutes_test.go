@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trending summarizes the trending tags as name:authors/posts
+func (s *testServer) trending(query string) []string {
+	s.t.Helper()
+	body := s.expect(http.StatusOK, "GET", "/api/tags/trending"+query, "", nil)
+	tags := []string{}
+	for _, tag := range body["tags"].([]any) {
+		t := tag.(map[string]any)
+		tags = append(tags, fmt.Sprintf("%v:%v/%v", t["name"], t["authors"], t["posts"]))
+	}
+	return tags
+}
+
+func TestTopicPages(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+
+	created := s.expect(http.StatusCreated, "POST", "/api/posts", alice, gin.H{"content": "Learning #Go and #GIN, more #go"})
+	if tags := field(created, "post", "tags"); fmt.Sprint(tags) != "[gin go]" {
+		t.Fatalf("got tags %v, want [gin go]", tags)
+	}
+	first := int(field(created, "post", "id").(float64))
+	second := s.post(bob, "#go generics")
+	s.post(bob, "no tags here")
+
+	// Topic pages list the tagged posts, newest first, whatever the case of the name
+	for _, name := range []string{"go", "GO", "%23go"} {
+		if got := s.pages("", "/api/tags/"+name+"/posts", "posts", 1); fmt.Sprint(got) != fmt.Sprint([]int{second, first}) {
+			t.Fatalf("%s: got posts %v, want %v", name, got, []int{second, first})
+		}
+	}
+
+	// Editing rewrites the tags
+	edited := s.expect(http.StatusOK, "PUT", path("/api/posts/%d", first), alice, gin.H{"content": "Now about #rust"})
+	if tags := field(edited, "post", "tags"); fmt.Sprint(tags) != "[rust]" {
+		t.Fatalf("after editing, got tags %v", tags)
+	}
+	if got := s.pages("", "/api/tags/go/posts", "posts", 10); fmt.Sprint(got) != fmt.Sprint([]int{second}) {
+		t.Fatalf("after editing, #go has posts %v, want %v", got, []int{second})
+	}
+	// A tag no longer used keeps its page, which is empty
+	if got := s.pages("", "/api/tags/gin/posts", "posts", 10); len(got) != 0 {
+		t.Fatalf("after editing, #gin has posts %v", got)
+	}
+
+	s.expect(http.StatusNotFound, "GET", "/api/tags/python/posts", "", nil)
+	s.expect(http.StatusBadRequest, "GET", "/api/tags/123/posts", "", nil)
+	s.expect(http.StatusBadRequest, "GET", "/api/tags/c++/posts", "", nil)
+}
+
+func TestTrendingTags(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, _ := s.signUp("bob")
+	carol, _ := s.signUp("carol")
+
+	// One account posting a tag over and over does not beat a tag several people use
+	for i := 0; i < 5; i++ {
+		s.post(alice, fmt.Sprintf("#spam %d", i))
+	}
+	s.post(alice, "#go")
+	s.post(bob, "#go #rust")
+	s.post(carol, "#rust")
+	s.post(carol, "#go")
+
+	want := []string{"go:3/3", "rust:2/2", "spam:1/5"}
+	if got := s.trending(""); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got trending %v, want %v", got, want)
+	}
+	if got := s.trending("?limit=2&window=1h"); fmt.Sprint(got) != fmt.Sprint(want[:2]) {
+		t.Fatalf("got trending %v, want %v", got, want[:2])
+	}
+
+	for _, query := range []string{"?window=30s", "?window=169h", "?window=soon", "?limit=0", "?limit=51"} {
+		s.expect(http.StatusBadRequest, "GET", "/api/tags/trending"+query, "", nil)
+	}
+}
+
+func TestFollowedTagsFillTheFeed(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signUp("alice")
+	bob, bobID := s.signUp("bob")
+	own := s.post(alice, "hello")
+	tagged := s.post(bob, "#golang tips")
+	untagged := s.post(bob, "#rust tips")
+
+	// Tags can be followed before anyone uses them, and following twice is a no-op
+	for _, name := range []string{"GoLang", "golang", "zig"} {
+		s.expect(http.StatusOK, "POST", "/api/tags/"+name+"/follow", alice, nil)
+	}
+	followed := s.expect(http.StatusOK, "GET", "/api/tags/following", alice, nil)["tags"].([]any)
+	if len(followed) != 2 || followed[0].(map[string]any)["name"] != "golang" || followed[1].(map[string]any)["name"] != "zig" {
+		t.Fatalf("got followed tags %v, want golang and zig", followed)
+	}
+	if got := s.pages(alice, "/api/feed", "posts", 10); fmt.Sprint(got) != fmt.Sprint([]int{tagged, own}) {
+		t.Fatalf("got feed %v, want the #golang post and alice's own", got)
+	}
+
+	// Following the author too shows each post once
+	s.expect(http.StatusOK, "POST", path("/api/users/%d/follow", bobID), alice, nil)
+	if got := s.pages(alice, "/api/feed", "posts", 10); fmt.Sprint(got) != fmt.Sprint([]int{untagged, tagged, own}) {
+		t.Fatalf("following the author too gave feed %v", got)
+	}
+
+	// Unfollowing twice is a no-op too
+	s.expect(http.StatusOK, "DELETE", path("/api/users/%d/follow", bobID), alice, nil)
+	for i := 0; i < 2; i++ {
+		s.expect(http.StatusOK, "DELETE", "/api/tags/golang/follow", alice, nil)
+	}
+	if got := s.pages(alice, "/api/feed", "posts", 10); fmt.Sprint(got) != fmt.Sprint([]int{own}) {
+		t.Fatalf("after unfollowing, got feed %v, want alice's own post", got)
+	}
+	s.expect(http.StatusBadRequest, "POST", "/api/tags/42/follow", alice, nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/tags/go/follow", "", nil)
+}
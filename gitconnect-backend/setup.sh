@@ -14,7 +14,7 @@ echo "📦 Installing dependencies..."
 go get -u github.com/gin-gonic/gin
 go get -u gorm.io/gorm
 go get -u gorm.io/driver/postgres
-go get -u github.com/dgrijalva/jwt-go
+go get -u github.com/golang-jwt/jwt/v5
 go get -u github.com/joho/godotenv
 
 # Create essential files
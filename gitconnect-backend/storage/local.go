@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps blobs as files under Dir. Content types are not stored but
+// sniffed back from the content, which suits the images it holds. Use it for a
+// single replica or with Dir on a shared volume.
+type LocalStore struct {
+	Dir string
+}
+
+// Put implements BlobStore. The file is written under a temporary name and
+// renamed, so readers never see a partial blob.
+func (s *LocalStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get implements BlobStore
+func (s *LocalStore) Get(_ context.Context, key string) (*Blob, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Peek at the first bytes to tell the content type without consuming them
+	reader := bufio.NewReaderSize(file, 512)
+	head, _ := reader.Peek(512)
+	return &Blob{
+		ReadCloser:  bufferedFile{reader, file},
+		ContentType: http.DetectContentType(head),
+		Size:        info.Size(),
+	}, nil
+}
+
+// Delete implements BlobStore
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// bufferedFile reads a file through the buffer its head was peeked into
+type bufferedFile struct {
+	*bufio.Reader
+	file *os.File
+}
+
+func (f bufferedFile) Close() error {
+	return f.file.Close()
+}
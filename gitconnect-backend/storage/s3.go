@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Store keeps blobs in a bucket of an S3-compatible service, addressed in path
+// style (Endpoint/Bucket/key) so it works with MinIO and other self-hosted
+// services as well as AWS. Requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	Endpoint        string // Scheme and host, without the bucket
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Client          *http.Client // http.DefaultClient if nil
+}
+
+// Put implements BlobStore
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := s.do(req, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+	return nil
+}
+
+// Get implements BlobStore
+func (s *S3Store) Get(ctx context.Context, key string) (*Blob, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return &Blob{ReadCloser: res.Body, ContentType: res.Header.Get("Content-Type"), Size: res.ContentLength}, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	defer res.Body.Close()
+	return nil, s3Error(res)
+}
+
+// Delete implements BlobStore
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return s3Error(res)
+	}
+	return nil
+}
+
+// request builds an unsigned request for the object stored under key
+func (s *S3Store) request(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, s.Endpoint+"/"+s.Bucket+"/"+key, bytes.NewReader(body))
+}
+
+// do signs and sends req, whose body is payload
+func (s *S3Store) do(req *http.Request, payload []byte) (*http.Response, error) {
+	signV4(req, payload, s.Region, s.AccessKeyID, s.SecretAccessKey, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// s3Error describes an unexpected response, including the start of the error document
+func s3Error(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("s3: %s %s: %s: %s", res.Request.Method, res.Request.URL.Path, res.Status, bytes.TrimSpace(body))
+}
+
+// signV4 adds the AWS Signature Version 4 headers for the s3 service to req.
+// Host and every header already set on req are signed.
+func signV4(req *http.Request, payload []byte, region, accessKeyID, secretAccessKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath encodes each segment of a path the way Signature Version 4 expects
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escapeURIComponent(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts and encodes query parameters the way Signature Version 4 expects
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escapeURIComponent(name)+"="+escapeURIComponent(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapeURIComponent percent-encodes everything but the unreserved characters of RFC 3986
+func escapeURIComponent(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-._~", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			escaped.WriteString("%" + strings.ToUpper(strconv.FormatUint(uint64(b)|0x100, 16)[1:]))
+		}
+	}
+	return escaped.String()
+}
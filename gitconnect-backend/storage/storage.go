@@ -0,0 +1,92 @@
+// Package storage keeps uploaded files in a blob store: a local directory, or a
+// bucket of an S3-compatible service such as AWS S3 or MinIO.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned when no blob is stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys outside the safe character set
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// validKey allows slash-separated segments of lowercase letters, digits, dots,
+// dashes and underscores; keys are built by the server, never taken from clients
+var validKey = regexp.MustCompile(`^[a-z0-9_-][a-z0-9._-]*(/[a-z0-9_-][a-z0-9._-]*)*$`)
+
+// Blob is the content of a stored blob. The caller closes it.
+type Blob struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// BlobStore stores blobs under slash-separated keys. Implementations must be
+// safe for concurrent use.
+type BlobStore interface {
+	// Put stores data under key, replacing any blob stored there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the blob stored under key, or returns ErrNotFound
+	Get(ctx context.Context, key string) (*Blob, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is a no-op.
+	Delete(ctx context.Context, key string) error
+}
+
+// Configure builds the blob store from the environment:
+//
+//	BLOB_STORE            local (default) or s3
+//	BLOB_DIR              directory of the local store (default: uploads)
+//	S3_ENDPOINT           e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+//	S3_REGION             signing region (default: us-east-1)
+//	S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY   for the s3 store
+func Configure() (BlobStore, error) {
+	var blobs BlobStore
+	switch driver := os.Getenv("BLOB_STORE"); driver {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		blobs = &LocalStore{Dir: dir}
+	case "s3":
+		store := &S3Store{
+			Endpoint:        strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}
+		if store.Region == "" {
+			store.Region = "us-east-1"
+		}
+		for name, value := range map[string]string{"S3_ENDPOINT": store.Endpoint, "S3_BUCKET": store.Bucket,
+			"S3_ACCESS_KEY_ID": store.AccessKeyID, "S3_SECRET_ACCESS_KEY": store.SecretAccessKey} {
+			if value == "" {
+				return nil, fmt.Errorf("%s is not set", name)
+			}
+		}
+		blobs = store
+	default:
+		return nil, fmt.Errorf("unsupported BLOB_STORE %q", driver)
+	}
+
+	log.Printf("🗄️ Storing uploads with %T", blobs)
+	return blobs, nil
+}
+
+// checkKey rejects keys that could escape the store's namespace
+func checkKey(key string) error {
+	if !validKey.MatchString(key) || strings.Contains(key, "..") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
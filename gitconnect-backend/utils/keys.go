@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretLength is the shortest HS256 secret accepted (256 bits); anyone who guesses
+// a secret can sign tokens with it
+const minSecretLength = 32
+
+// signingKey is one entry of the keyring. Keys without a private half can only verify.
+type signingKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	private   interface{}
+	public    interface{}
+	symmetric bool
+}
+
+// keyring holds the key used to sign new tokens and every key still accepted for verification
+type keyring struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	issuer string
+}
+
+var keys *keyring
+
+// LoadSigningKeys reads the JWT signing configuration from the environment:
+//
+//	JWT_ALGORITHM          HS256 (default), RS256 or EdDSA; the algorithm new tokens are signed with
+//	JWT_SECRET             HS256 secret; also kept as a verification key when switching to RS256/EdDSA
+//	JWT_SECRET_KEY_ID      kid of JWT_SECRET (default: derived from the secret)
+//	JWT_PREVIOUS_SECRETS   comma-separated retired HS256 secrets still accepted for verification
+//	JWT_KEYS_DIR           directory of PEM files named <kid>.pem (private keys sign, public keys only verify)
+//	JWT_ACTIVE_KEY_ID      kid in JWT_KEYS_DIR used for signing when several private keys are present
+//	JWT_ISSUER             iss claim set and required on tokens (default: gitconnect)
+//
+// Rotating a key means adding the new key, making it active, and keeping the old
+// one (or its public half) around until the tokens signed with it have expired.
+func LoadSigningKeys() error {
+	ring := &keyring{keys: map[string]*signingKey{}, issuer: os.Getenv("JWT_ISSUER")}
+	if ring.issuer == "" {
+		ring.issuer = "gitconnect"
+	}
+
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		if len(secret) < minSecretLength {
+			return fmt.Errorf("JWT_SECRET is shorter than %d bytes", minSecretLength)
+		}
+		key := hmacKey(os.Getenv("JWT_SECRET_KEY_ID"), secret)
+		ring.keys[key.ID] = key
+		if algorithm == jwt.SigningMethodHS256.Alg() {
+			ring.active = key
+		}
+	}
+	for _, secret := range strings.Split(os.Getenv("JWT_PREVIOUS_SECRETS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			if len(secret) < minSecretLength {
+				return fmt.Errorf("a secret in JWT_PREVIOUS_SECRETS is shorter than %d bytes", minSecretLength)
+			}
+			key := hmacKey("", secret)
+			ring.keys[key.ID] = key
+		}
+	}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		if err := ring.loadDir(dir); err != nil {
+			return err
+		}
+	}
+
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if ring.active == nil {
+			return errors.New("JWT_SECRET is not set")
+		}
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		active, err := ring.pickActive(os.Getenv("JWT_ACTIVE_KEY_ID"), algorithm)
+		if err != nil {
+			return err
+		}
+		ring.active = active
+	default:
+		return fmt.Errorf("unsupported JWT_ALGORITHM %q", algorithm)
+	}
+
+	keys = ring
+	log.Printf("🔑 Signing tokens with %s key %q (%d verification keys)", algorithm, ring.active.ID, len(ring.keys))
+	return nil
+}
+
+// algorithms lists the signing methods of every verification key
+func (r *keyring) algorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range r.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// hmacKey builds a symmetric key, deriving a kid from the secret when none is given
+func hmacKey(kid, secret string) *signingKey {
+	if kid == "" {
+		sum := sha256.Sum256([]byte(secret))
+		kid = "hs-" + hex.EncodeToString(sum[:8])
+	}
+	return &signingKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		private:   []byte(secret),
+		public:    []byte(secret),
+		symmetric: true,
+	}
+}
+
+// loadDir adds every <kid>.pem file in dir to the keyring
+func (r *keyring) loadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		r.keys[kid] = key
+	}
+	return nil
+}
+
+// pickActive chooses the private key new tokens are signed with
+func (r *keyring) pickActive(kid, algorithm string) (*signingKey, error) {
+	if kid != "" {
+		key, ok := r.keys[kid]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("JWT_ACTIVE_KEY_ID %q not found in JWT_KEYS_DIR", kid)
+		case key.private == nil:
+			return nil, fmt.Errorf("JWT key %q has no private key", kid)
+		case key.Method.Alg() != algorithm:
+			return nil, fmt.Errorf("JWT key %q is %s, not %s", kid, key.Method.Alg(), algorithm)
+		}
+		return key, nil
+	}
+
+	var found *signingKey
+	for _, key := range r.keys {
+		if key.symmetric || key.private == nil || key.Method.Alg() != algorithm {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("several private keys found; set JWT_ACTIVE_KEY_ID")
+		}
+		found = key
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no %s private key found in JWT_KEYS_DIR", algorithm)
+	}
+	return found, nil
+}
+
+// parsePEMKey decodes an RSA or Ed25519 key, private (PKCS#1/PKCS#8) or public (PKIX)
+func parsePEMKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &signingKey{ID: kid}
+	var parsed interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// PublicJWKS returns the asymmetric verification keys for publication.
+// HS256 secrets are never included.
+func PublicJWKS() []JWK {
+	set := []JWK{}
+	if keys == nil {
+		return set
+	}
+
+	for _, key := range keys.keys {
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			set = append(set, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set = append(set, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	sort.Slice(set, func(i, j int) bool { return set[i].KeyID < set[j].KeyID })
+	return set
+}
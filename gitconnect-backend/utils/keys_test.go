@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// strongSecret is long enough to be accepted as an HS256 secret
+const strongSecret = "test-secret-that-is-at-least-32-bytes-long"
+
+// setKeyEnv clears the JWT configuration and then sets env
+func setKeyEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range []string{"JWT_ALGORITHM", "JWT_SECRET", "JWT_SECRET_KEY_ID", "JWT_PREVIOUS_SECRETS",
+		"JWT_KEYS_DIR", "JWT_ACTIVE_KEY_ID", "JWT_ISSUER"} {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestLoadSigningKeysRejectsShortSecrets(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"active":   {"JWT_SECRET": "abc123"},
+		"previous": {"JWT_SECRET": strongSecret, "JWT_PREVIOUS_SECRETS": "abc123"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setKeyEnv(t, env)
+			if err := LoadSigningKeys(); err == nil || !strings.Contains(err.Error(), "shorter than 32 bytes") {
+				t.Fatalf("got %v, want the secret to be rejected", err)
+			}
+		})
+	}
+
+	setKeyEnv(t, map[string]string{"JWT_SECRET": strongSecret})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("a 32-byte secret was rejected: %v", err)
+	}
+}
+
+func TestRotatedSecretsStillVerify(t *testing.T) {
+	const retired = "retired-secret-that-is-at-least-32-bytes"
+	setKeyEnv(t, map[string]string{"JWT_SECRET": retired})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	old, err := GenerateToken(7)
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	// Rotate: the retired secret now only verifies
+	setKeyEnv(t, map[string]string{"JWT_SECRET": strongSecret, "JWT_PREVIOUS_SECRETS": retired})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	if claims, err := ValidateToken(old); err != nil || claims.UserID != 7 {
+		t.Fatalf("token signed with the retired secret: got %v, %v", claims, err)
+	}
+	fresh, _ := GenerateToken(7)
+	if kid := tokenKeyID(t, fresh); kid != hmacKey("", strongSecret).ID {
+		t.Fatalf("new token signed with kid %q, want the active secret", kid)
+	}
+
+	// Once the retired secret is dropped its kid is unknown
+	setKeyEnv(t, map[string]string{"JWT_SECRET": strongSecret})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	if _, err := ValidateToken(old); err == nil || !strings.Contains(err.Error(), "unknown key id") {
+		t.Fatalf("got %v, want the unknown kid to be rejected", err)
+	}
+	if _, err := ValidateToken(fresh); err != nil {
+		t.Fatalf("token signed with the active secret: %v", err)
+	}
+}
+
+func TestKeysPinnedToRS256RejectOtherAlgorithms(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "rsa-1")
+	// The HS256 secret stays configured, so HS256 is an accepted algorithm overall
+	setKeyEnv(t, map[string]string{"JWT_ALGORITHM": "RS256", "JWT_SECRET": strongSecret, "JWT_KEYS_DIR": dir})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+
+	valid, err := GenerateToken(7)
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	if _, err := ValidateToken(valid); err != nil {
+		t.Fatalf("RS256 token rejected: %v", err)
+	}
+
+	// The classic confusion: an HS256 token keyed with the public key the server publishes
+	public, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "forged",
+			Issuer:    "gitconnect",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	forged.Header["kid"] = "rsa-1"
+	for _, secret := range [][]byte{publicPEM, public} {
+		signed, err := forged.SignedString(secret)
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		if _, err := ValidateToken(signed); err == nil || !strings.Contains(err.Error(), "unexpected signing method") {
+			t.Fatalf("got %v, want HS256 to be refused for an RS256 kid", err)
+		}
+	}
+}
+
+func TestPublicJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "rsa-1")
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	writePEM(t, dir, "ed-1", "PRIVATE KEY", der)
+
+	setKeyEnv(t, map[string]string{"JWT_ALGORITHM": "EdDSA", "JWT_SECRET": strongSecret, "JWT_KEYS_DIR": dir})
+	if err := LoadSigningKeys(); err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+
+	set := PublicJWKS()
+	if len(set) != 2 {
+		t.Fatalf("got %d keys, want the RSA and Ed25519 keys only: %+v", len(set), set)
+	}
+	ed, rs := set[0], set[1]
+	if ed.KeyID != "ed-1" || ed.KeyType != "OKP" || ed.Algorithm != "EdDSA" || ed.Curve != "Ed25519" || ed.Use != "sig" ||
+		ed.X != base64.RawURLEncoding.EncodeToString(edPublic) {
+		t.Fatalf("unexpected Ed25519 JWK %+v", ed)
+	}
+	if rs.KeyID != "rsa-1" || rs.KeyType != "RSA" || rs.Algorithm != "RS256" || rs.Use != "sig" ||
+		rs.N != base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()) || rs.E != "AQAB" {
+		t.Fatalf("unexpected RSA JWK %+v", rs)
+	}
+}
+
+// tokenKeyID returns the kid header of a token without verifying it
+func tokenKeyID(t *testing.T, tokenString string) string {
+	t.Helper()
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		t.Fatalf("parsing token: %v", err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+// writeRSAKey generates a 2048-bit RSA key and writes it to dir as <kid>.pem
+func writeRSAKey(t *testing.T, dir, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	writePEM(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return key
+}
+
+// writePEM writes der to dir as <kid>.pem
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+}
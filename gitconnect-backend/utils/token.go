@@ -1,47 +1,164 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWT Secret Key
-var jwtSecret = []byte("your_secret_key")
+const (
+	// AccessTokenTTL is how long a signed access token is accepted
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long an unused refresh token can be exchanged
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// MFATokenTTL is how long a user has to enter their second factor after the password
+	MFATokenTTL = 5 * time.Minute
+	// StreamTicketTTL is how long a client has to open an event stream with a ticket
+	StreamTicketTTL = time.Minute
+)
+
+// PurposeMFA marks a token that only proves the password step of a two-factor login
+const PurposeMFA = "mfa_pending"
+
+// PurposeStream marks a ticket that only opens an event stream. Browsers cannot
+// set headers on EventSource and WebSocket requests, so the ticket travels in
+// the URL, where it may be logged; hence its short life.
+const PurposeStream = "stream"
 
 // Claims struct
 type Claims struct {
 	UserID uint `json:"user_id"`
-	jwt.StandardClaims
+	// Purpose is empty for access tokens; other tokens are rejected by ValidateToken
+	Purpose string `json:"purpose,omitempty"`
+	// SessionID is the jti of the access token a stream ticket was issued to, so
+	// revoking that token also revokes the ticket
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
 }
 
-// GenerateToken - creates a new JWT token
+// GenerateToken - creates a new short-lived JWT access token with a unique ID (jti),
+// signed with the active key and tagged with its kid
 func GenerateToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+	return signToken(userID, "", "", AccessTokenTTL)
+}
+
+// GenerateMFAToken - creates the short-lived token returned by the password step of a
+// two-factor login. It cannot be used as an access token.
+func GenerateMFAToken(userID uint) (string, error) {
+	return signToken(userID, PurposeMFA, "", MFATokenTTL)
+}
+
+// ValidateToken - verifies a JWT access token against the key named by its kid header.
+// The token's alg must match the algorithm of that key, so a token can never
+// choose how it is verified.
+func ValidateToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, "")
+}
+
+// ValidateMFAToken - verifies a token issued by GenerateMFAToken
+func ValidateMFAToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, PurposeMFA)
+}
+
+// GenerateStreamTicket - creates the short-lived ticket that opens an event stream for
+// the access token with jti sessionID. It cannot be used as an access token.
+func GenerateStreamTicket(userID uint, sessionID string) (string, error) {
+	return signToken(userID, PurposeStream, sessionID, StreamTicketTTL)
+}
+
+// ValidateStreamTicket - verifies a ticket issued by GenerateStreamTicket
+func ValidateStreamTicket(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, PurposeStream)
+}
 
+// signToken signs a token for userID with the active key
+func signToken(userID uint, purpose, sessionID string, ttl time.Duration) (string, error) {
+	if keys == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
+
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+		UserID:    userID,
+		Purpose:   purpose,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    keys.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(keys.active.Method, claims)
+	token.Header["kid"] = keys.active.ID
+	return token.SignedString(keys.active.private)
 }
 
-// ValidateToken - verifies a JWT token
-func ValidateToken(tokenString string) (*Claims, error) {
+// parseToken verifies a token and requires its purpose to match
+func parseToken(tokenString, purpose string) (*Claims, error) {
+	if keys == nil {
+		return nil, errors.New("signing keys are not loaded")
+	}
+
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods(keys.algorithms()),
+		jwt.WithIssuer(keys.issuer),
+		jwt.WithExpirationRequired(),
+	)
 
-	if err != nil || !token.Valid {
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Tokens without a jti cannot be revoked, so they are not accepted
+	if claims.ID == "" {
+		return nil, errors.New("token has no id")
+	}
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("unexpected token purpose %q", claims.Purpose)
+	}
 
 	return claims, nil
 }
 
+// RandomToken - returns n cryptographically random bytes, URL-safe base64 encoded
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken - returns the hex SHA-256 digest used to store opaque tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists eight digits; codes here are the last six
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("computing code: %v", err)
+		}
+		if code != want {
+			t.Errorf("at %d: got %s, want %s", unix, code, want)
+		}
+	}
+
+	// Secrets pasted in lower case still work
+	if code, _ := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", time.Unix(59, 0)); code != "287082" {
+		t.Errorf("lower-case secret: got %s, want 287082", code)
+	}
+}
+
+func TestValidateTOTPAcceptsOneStepOfSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset, accepted := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, _ := TOTPCode(rfcSecret, now.Add(time.Duration(offset*totpPeriod)*time.Second))
+		step, ok := ValidateTOTP(rfcSecret, code, now)
+		if ok != accepted {
+			t.Errorf("code %d steps away: accepted %v, want %v", offset, ok, accepted)
+		}
+		// The matched step is what callers record to refuse replays
+		if ok && step != current+offset {
+			t.Errorf("code %d steps away: matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	if _, ok := ValidateTOTP(rfcSecret, "005 924", now); !ok {
+		t.Error("a code typed with a space was rejected")
+	}
+	for _, code := range []string{"", "05924", "0005924", "005925", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "005924", now); ok {
+		t.Error("a malformed secret validated a code")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generating secret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("got secret %q, want 160 bits of base32", secret)
+	}
+	if other, _ := GenerateTOTPSecret(); other == secret {
+		t.Fatal("two secrets were the same")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatalf("generating code: %v", err)
+	}
+	if !regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`).MatchString(code) {
+		t.Fatalf("got code %q, want xxxxx-xxxxx", code)
+	}
+
+	// However it is typed back, a code hashes the same as when it was issued
+	stored := HashToken(NormalizeRecoveryCode("abcde-fghij"))
+	for _, typed := range []string{"abcde-fghij", "ABCDE-FGHIJ", "abcdefghij", " abcde fghij "} {
+		if HashToken(NormalizeRecoveryCode(typed)) != stored {
+			t.Errorf("%q does not match the stored code", typed)
+		}
+	}
+	if HashToken(NormalizeRecoveryCode("abcde-fghik")) == stored {
+		t.Error("a different code matches the stored code")
+	}
+}